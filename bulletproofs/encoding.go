@@ -0,0 +1,124 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bulletproofs
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+)
+
+const (
+	rangeProofVersion = 1
+	scalarSize        = 32
+)
+
+/*
+	ToBytes: format version, bits, count, the points A, S, T1, T2, the scalars taux, mu, that,
+	the points L and R of every round then the scalars a and b, the points are compressed and the scalars big endian
+*/
+func ToBytes(proof *RangeProof) []byte {
+	buf := []byte{rangeProofVersion, byte(proof.Bits), byte(proof.Count)}
+	for _, p := range []*tebn254.Point{&proof.A, &proof.S, &proof.T1, &proof.T2} {
+		buf = append(buf, tebn254.ToBytes(p)...)
+	}
+	for _, s := range []*big.Int{proof.Taux, proof.Mu, proof.That} {
+		buf = append(buf, s.FillBytes(make([]byte, scalarSize))...)
+	}
+	ipa := proof.InnerProduct
+	for k := range ipa.L {
+		buf = append(buf, tebn254.ToBytes(&ipa.L[k])...)
+		buf = append(buf, tebn254.ToBytes(&ipa.R[k])...)
+	}
+	buf = append(buf, ipa.A.FillBytes(make([]byte, scalarSize))...)
+	return append(buf, ipa.B.FillBytes(make([]byte, scalarSize))...)
+}
+
+/*
+	FromBytes: proof of the bytes written by ToBytes, the points must be canonically encoded and in the subgroup
+	and the scalars below the order
+*/
+func FromBytes(buf []byte) (*RangeProof, error) {
+	if len(buf) < 3 || buf[0] != rangeProofVersion {
+		return nil, ErrInvalidRangeProof
+	}
+	proof := &RangeProof{Bits: int(buf[1]), Count: int(buf[2]), InnerProduct: new(InnerProductProof)}
+	m, err := paddedCount(proof.Bits, proof.Count)
+	if err != nil {
+		return nil, ErrInvalidRangeProof
+	}
+	rounds := 0
+	for 1<<uint(rounds) < proof.Bits*m {
+		rounds++
+	}
+	if len(buf) != 3+(4+2*rounds)*tebn254.PointSize+5*scalarSize {
+		return nil, ErrInvalidRangeProof
+	}
+	r := &proofReader{buf: buf[3:]}
+	for _, p := range []*tebn254.Point{&proof.A, &proof.S, &proof.T1, &proof.T2} {
+		r.readPoint(p)
+	}
+	proof.Taux = r.readScalar()
+	proof.Mu = r.readScalar()
+	proof.That = r.readScalar()
+	ipa := proof.InnerProduct
+	ipa.L = make([]tebn254.Point, rounds)
+	ipa.R = make([]tebn254.Point, rounds)
+	for k := 0; k < rounds; k++ {
+		r.readPoint(&ipa.L[k])
+		r.readPoint(&ipa.R[k])
+	}
+	ipa.A = r.readScalar()
+	ipa.B = r.readScalar()
+	if r.err != nil {
+		return nil, r.err
+	}
+	return proof, nil
+}
+
+type proofReader struct {
+	buf []byte
+	err error
+}
+
+func (r *proofReader) readPoint(p *tebn254.Point) {
+	if r.err != nil {
+		return
+	}
+	pBytes := r.buf[:tebn254.PointSize]
+	r.buf = r.buf[tebn254.PointSize:]
+	point, err := tebn254.FromBytes(pBytes)
+	if err != nil || !tebn254.IsInSubGroup(point) || !bytes.Equal(tebn254.ToBytes(point), pBytes) {
+		r.err = ErrInvalidRangeProof
+		return
+	}
+	p.Set(point)
+}
+
+func (r *proofReader) readScalar() *big.Int {
+	if r.err != nil {
+		return nil
+	}
+	s := new(big.Int).SetBytes(r.buf[:scalarSize])
+	r.buf = r.buf[scalarSize:]
+	if s.Cmp(tebn254.Order) >= 0 {
+		r.err = ErrInvalidRangeProof
+	}
+	return s
+}
@@ -0,0 +1,30 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bulletproofs
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidBits       = errors.New("[bulletproofs] the bits must be a power of two up to MaxBits")
+	ErrInvalidCount      = errors.New("[bulletproofs] invalid number of values")
+	ErrValueOutOfRange   = errors.New("[bulletproofs] value out of range")
+	ErrInvalidRangeProof = errors.New("[bulletproofs] invalid range proof")
+	ErrVerifyFailed      = errors.New("[bulletproofs] range proof verification failed")
+)
@@ -0,0 +1,93 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bulletproofs
+
+import (
+	"log"
+	"math/big"
+	"strconv"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/ffmath"
+)
+
+const (
+	SeedG = "ZkBNBBulletproofsG"
+	SeedH = "ZkBNBBulletproofsH"
+	SeedQ = "ZkBNBBulletproofsQ"
+)
+
+/*
+	generators: the vector generators Gs and Hs and the inner product generator Q, derived with tebn254.MapToGroup,
+	they are derived on first use and kept for the next proofs
+*/
+var generators struct {
+	sync.Mutex
+	gs []*tebn254.Point
+	hs []*tebn254.Point
+	q  *tebn254.Point
+}
+
+/*
+	vectorGenerators: the n first Gs and Hs, and Q
+*/
+func vectorGenerators(n int) (gs, hs []*tebn254.Point, q *tebn254.Point, err error) {
+	generators.Lock()
+	defer generators.Unlock()
+	if generators.q == nil {
+		generators.q, err = tebn254.MapToGroup(SeedQ + "/")
+		if err != nil {
+			log.Println("[bulletproofs] unable to derive generator Q:", err)
+			return nil, nil, nil, err
+		}
+	}
+	for i := len(generators.gs); i < n; i++ {
+		// the separator keeps the index apart from the attempt appended by MapToGroup
+		g, err := tebn254.MapToGroup(SeedG + strconv.Itoa(i) + "/")
+		if err != nil {
+			log.Println("[bulletproofs] unable to derive generator G:", i, err)
+			return nil, nil, nil, err
+		}
+		h, err := tebn254.MapToGroup(SeedH + strconv.Itoa(i) + "/")
+		if err != nil {
+			log.Println("[bulletproofs] unable to derive generator H:", i, err)
+			return nil, nil, nil, err
+		}
+		generators.gs = append(generators.gs, g)
+		generators.hs = append(generators.hs, h)
+	}
+	return generators.gs[:n:n], generators.hs[:n:n], generators.q, nil
+}
+
+/*
+	multiScalarMul: sum of scalars[i]*points[i], accumulated in projective coordinates
+	whose addition doesn't need an inversion
+*/
+func multiScalarMul(points []*tebn254.Point, scalars []*big.Int) *tebn254.Point {
+	var acc, tmp twistededwards.PointProj
+	acc.FromAffine(tebn254.ZeroPoint())
+	for i, p := range points {
+		tmp.FromAffine(p)
+		tmp.ScalarMul(&tmp, ffmath.Mod(scalars[i], tebn254.Order))
+		acc.Add(&acc, &tmp)
+	}
+	return new(tebn254.Point).FromProj(&acc)
+}
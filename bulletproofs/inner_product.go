@@ -0,0 +1,120 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bulletproofs
+
+import (
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/ffmath"
+	"github.com/bnb-chain/zkbnb-crypto/transcript"
+)
+
+/*
+	InnerProductProof: proof that P = <a, Gs> + <b, Hs> + <a, b>*Q, it halves the vectors at every round
+	so it holds log2(len(a)) pairs L, R and the last a and b
+*/
+type InnerProductProof struct {
+	L []tebn254.Point
+	R []tebn254.Point
+	A *big.Int
+	B *big.Int
+}
+
+/*
+	proveInnerProduct: the vectors are folded in place, the challenge u of a round gives
+	a' = a_lo*u + a_hi/u, b' = b_lo/u + b_hi*u, Gs' = Gs_lo/u + Gs_hi*u and Hs' = Hs_lo*u + Hs_hi/u
+*/
+func proveInnerProduct(tr *transcript.Transcript, gs, hs []*tebn254.Point, q *tebn254.Point, a, b []*big.Int) *InnerProductProof {
+	proof := new(InnerProductProof)
+	for n := len(a); n > 1; n /= 2 {
+		k := n / 2
+		cL := innerProduct(a[:k], b[k:n])
+		cR := innerProduct(a[k:n], b[:k])
+		L := multiScalarMul(
+			append(append(append([]*tebn254.Point{}, gs[k:n]...), hs[:k]...), q),
+			append(append(append([]*big.Int{}, a[:k]...), b[k:n]...), cL),
+		)
+		R := multiScalarMul(
+			append(append(append([]*tebn254.Point{}, gs[:k]...), hs[k:n]...), q),
+			append(append(append([]*big.Int{}, a[k:n]...), b[:k]...), cR),
+		)
+		proof.L = append(proof.L, *L)
+		proof.R = append(proof.R, *R)
+		tr.AppendPoint("L", L)
+		tr.AppendPoint("R", R)
+		u := tr.ChallengeScalar("u")
+		uInv := ffmath.ModInverse(u, tebn254.Order)
+		for i := 0; i < k; i++ {
+			a[i] = addMulMod(a[i], u, a[k+i], uInv)
+			b[i] = addMulMod(b[i], uInv, b[k+i], u)
+			gs[i] = multiScalarMul([]*tebn254.Point{gs[i], gs[k+i]}, []*big.Int{uInv, u})
+			hs[i] = multiScalarMul([]*tebn254.Point{hs[i], hs[k+i]}, []*big.Int{u, uInv})
+		}
+	}
+	proof.A = a[0]
+	proof.B = b[0]
+	return proof
+}
+
+/*
+	innerProductChallenges: the challenges of the rounds and the scalars s_i such that the folded Gs is <s, Gs>,
+	s_i is the product of u or 1/u of every round depending on the side of i at that round,
+	the folded Hs is <1/s, Hs>
+*/
+func innerProductChallenges(tr *transcript.Transcript, proof *InnerProductProof, n int) (u, s []*big.Int) {
+	rounds := len(proof.L)
+	u = make([]*big.Int, rounds)
+	uInv := make([]*big.Int, rounds)
+	for k := 0; k < rounds; k++ {
+		tr.AppendPoint("L", &proof.L[k])
+		tr.AppendPoint("R", &proof.R[k])
+		u[k] = tr.ChallengeScalar("u")
+		uInv[k] = ffmath.ModInverse(u[k], tebn254.Order)
+	}
+	s = make([]*big.Int, n)
+	for i := range s {
+		s[i] = big.NewInt(1)
+		for k := 0; k < rounds; k++ {
+			// the first round splits on the most significant bit of the index
+			if i>>uint(rounds-1-k)&1 == 1 {
+				s[i] = ffmath.MultiplyMod(s[i], u[k], tebn254.Order)
+			} else {
+				s[i] = ffmath.MultiplyMod(s[i], uInv[k], tebn254.Order)
+			}
+		}
+	}
+	return u, s
+}
+
+func innerProduct(a, b []*big.Int) *big.Int {
+	res := new(big.Int)
+	for i := range a {
+		res.Add(res, new(big.Int).Mul(a[i], b[i]))
+	}
+	return res.Mod(res, tebn254.Order)
+}
+
+/*
+	addMulMod: a*x + b*y modulo the order
+*/
+func addMulMod(a, x, b, y *big.Int) *big.Int {
+	res := new(big.Int).Mul(a, x)
+	res.Add(res, new(big.Int).Mul(b, y))
+	return res.Mod(res, tebn254.Order)
+}
@@ -0,0 +1,343 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bulletproofs
+
+import (
+	"log"
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/commitment"
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/ffmath"
+	"github.com/bnb-chain/zkbnb-crypto/transcript"
+)
+
+/*
+	Range proofs of Bünz et al. over tebn254 for the Pedersen commitments of the commitment package,
+	V = v*H + gamma*U. An aggregated proof shows that each of several values is in [0, 2^bits),
+	its size grows with the logarithm of bits times the number of values.
+	The transcript binds the proof to its context, the verifier must create it as the prover did,
+	such as transcript.NewMiMC("ZkBNBRangeProof") on both sides.
+*/
+
+const (
+	// wide enough for the state amounts, types.StateAmountBitsSize
+	MaxBits        = 128
+	MaxAggregation = 64
+)
+
+type RangeProof struct {
+	Bits int
+	// number of values, the proof is built for the next power of two with the missing values set to zero
+	Count        int
+	A            tebn254.Point
+	S            tebn254.Point
+	T1           tebn254.Point
+	T2           tebn254.Point
+	Taux         *big.Int
+	Mu           *big.Int
+	That         *big.Int
+	InnerProduct *InnerProductProof
+}
+
+/*
+	Prove: proof that the value is in [0, 2^bits), with its commitment
+*/
+func Prove(tr *transcript.Transcript, value, blinding *big.Int, bits int) (*RangeProof, *commitment.Commitment, error) {
+	proof, commitments, err := ProveAggregated(tr, []*big.Int{value}, []*big.Int{blinding}, bits)
+	if err != nil {
+		return nil, nil, err
+	}
+	return proof, commitments[0], nil
+}
+
+func Verify(tr *transcript.Transcript, proof *RangeProof, c *commitment.Commitment, bits int) error {
+	return VerifyAggregated(tr, proof, []*commitment.Commitment{c}, bits)
+}
+
+/*
+	ProveAggregated: proof that every value is in [0, 2^bits), with the commitments of the values
+*/
+func ProveAggregated(tr *transcript.Transcript, values, blindings []*big.Int, bits int) (*RangeProof, []*commitment.Commitment, error) {
+	m, err := paddedCount(bits, len(values))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(blindings) != len(values) {
+		return nil, nil, ErrInvalidCount
+	}
+	commitments := make([]*commitment.Commitment, len(values))
+	for j, value := range values {
+		if value == nil || value.Sign() < 0 || value.BitLen() > bits {
+			log.Println("[ProveAggregated] value out of range:", j)
+			return nil, nil, ErrValueOutOfRange
+		}
+		commitments[j], err = commitment.Commit(value, blindings[j])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	n := bits * m
+	gs, hs, q, err := vectorGenerators(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	one := big.NewInt(1)
+	aL := make([]*big.Int, n)
+	aR := make([]*big.Int, n)
+	for i := range aL {
+		bit := uint(0)
+		if j := i / bits; j < len(values) {
+			bit = values[j].Bit(i % bits)
+		}
+		aL[i] = new(big.Int).SetUint64(uint64(bit))
+		aR[i] = ffmath.SubMod(aL[i], one, tebn254.Order)
+	}
+	randoms, err := randomScalars(4 + 2*n)
+	if err != nil {
+		return nil, nil, err
+	}
+	alpha, rho, tau1, tau2 := randoms[0], randoms[1], randoms[2], randoms[3]
+	sL, sR := randoms[4:4+n], randoms[4+n:]
+
+	proof := &RangeProof{Bits: bits, Count: len(values)}
+	bases := append(append([]*tebn254.Point{tebn254.U}, gs...), hs...)
+	proof.A = *multiScalarMul(bases, append(append([]*big.Int{alpha}, aL...), aR...))
+	proof.S = *multiScalarMul(bases, append(append([]*big.Int{rho}, sL...), sR...))
+	appendStatement(tr, bits, commitments)
+	tr.AppendPoint("A", &proof.A)
+	tr.AppendPoint("S", &proof.S)
+	y := tr.ChallengeScalar("y")
+	z := tr.ChallengeScalar("z")
+
+	yPow := powers(y, n)
+	zPow := powers(z, m+2)[2:]
+	twoPow := powers(big.NewInt(2), bits)
+	l0 := make([]*big.Int, n)
+	r0 := make([]*big.Int, n)
+	r1 := make([]*big.Int, n)
+	for i := range l0 {
+		l0[i] = ffmath.SubMod(aL[i], z, tebn254.Order)
+		r0[i] = addMulMod(yPow[i], ffmath.Add(aR[i], z), zPow[i/bits], twoPow[i%bits])
+		r1[i] = ffmath.MultiplyMod(yPow[i], sR[i], tebn254.Order)
+	}
+	t1 := ffmath.AddMod(innerProduct(l0, r1), innerProduct(sL, r0), tebn254.Order)
+	t2 := innerProduct(sL, r1)
+	proof.T1 = *multiScalarMul([]*tebn254.Point{tebn254.H, tebn254.U}, []*big.Int{t1, tau1})
+	proof.T2 = *multiScalarMul([]*tebn254.Point{tebn254.H, tebn254.U}, []*big.Int{t2, tau2})
+	tr.AppendPoint("T1", &proof.T1)
+	tr.AppendPoint("T2", &proof.T2)
+	x := tr.ChallengeScalar("x")
+
+	l := make([]*big.Int, n)
+	r := make([]*big.Int, n)
+	for i := range l {
+		l[i] = addMulMod(l0[i], one, sL[i], x)
+		r[i] = addMulMod(r0[i], one, r1[i], x)
+	}
+	proof.That = innerProduct(l, r)
+	proof.Taux = addMulMod(tau2, ffmath.Multiply(x, x), tau1, x)
+	for j, blinding := range blindings {
+		proof.Taux = addMulMod(proof.Taux, one, blinding, zPow[j])
+	}
+	proof.Mu = addMulMod(alpha, one, rho, x)
+	appendScalars(tr, proof)
+	w := tr.ChallengeScalar("w")
+
+	hsPrime := make([]*tebn254.Point, n)
+	yInvPow := powers(ffmath.ModInverse(y, tebn254.Order), n)
+	for i := range hsPrime {
+		hsPrime[i] = multiScalarMul(hs[i:i+1], yInvPow[i:i+1])
+	}
+	// the generators are shared with the next proofs, the folding works on a copy
+	proof.InnerProduct = proveInnerProduct(
+		tr, append([]*tebn254.Point{}, gs...), hsPrime, multiScalarMul([]*tebn254.Point{q}, []*big.Int{w}), l, r,
+	)
+	return proof, commitments, nil
+}
+
+/*
+	VerifyAggregated: check that the proof shows that the values of the commitments are in [0, 2^bits)
+*/
+func VerifyAggregated(tr *transcript.Transcript, proof *RangeProof, commitments []*commitment.Commitment, bits int) error {
+	m, err := paddedCount(bits, len(commitments))
+	if err != nil {
+		return err
+	}
+	n := bits * m
+	if err = proof.validate(n); err != nil || proof.Bits != bits || proof.Count != len(commitments) {
+		return ErrInvalidRangeProof
+	}
+	for _, c := range commitments {
+		if c == nil {
+			return ErrInvalidRangeProof
+		}
+	}
+	gs, hs, q, err := vectorGenerators(n)
+	if err != nil {
+		return err
+	}
+	appendStatement(tr, bits, commitments)
+	tr.AppendPoint("A", &proof.A)
+	tr.AppendPoint("S", &proof.S)
+	y := tr.ChallengeScalar("y")
+	z := tr.ChallengeScalar("z")
+	tr.AppendPoint("T1", &proof.T1)
+	tr.AppendPoint("T2", &proof.T2)
+	x := tr.ChallengeScalar("x")
+	appendScalars(tr, proof)
+	w := tr.ChallengeScalar("w")
+	u, s := innerProductChallenges(tr, proof.InnerProduct, n)
+
+	// that*H + taux*U = sum(z^(2+j)*V_j) + delta*H + x*T1 + x^2*T2
+	yPow := powers(y, n)
+	zPow := powers(z, m+3)[2:]
+	twoPow := powers(big.NewInt(2), bits)
+	zz := ffmath.MultiplyMod(z, z, tebn254.Order)
+	delta := ffmath.MultiplyMod(ffmath.SubMod(z, zz, tebn254.Order), sum(yPow), tebn254.Order)
+	twoSum := sum(twoPow)
+	for j := 0; j < m; j++ {
+		delta = addMulMod(delta, big.NewInt(1), zPow[j+1], ffmath.Neg(twoSum))
+	}
+	points := []*tebn254.Point{tebn254.H, tebn254.U, &proof.T1, &proof.T2}
+	scalars := []*big.Int{
+		ffmath.SubMod(proof.That, delta, tebn254.Order), proof.Taux,
+		ffmath.Neg(x), ffmath.Neg(ffmath.Multiply(x, x)),
+	}
+	for j, c := range commitments {
+		points = append(points, c.Point())
+		scalars = append(scalars, ffmath.Neg(zPow[j]))
+	}
+	if !tebn254.IsZero(multiScalarMul(points, scalars)) {
+		log.Println("[VerifyAggregated] invalid polynomial commitment")
+		return ErrVerifyFailed
+	}
+
+	// A + x*S - z*<1, Gs> + <z*y^i + z^(2+j)*2^k, Hs'> - mu*U + that*Q' + sum(u^2*L + u^-2*R)
+	// = a*<s, Gs> + b*<1/s, Hs'> + a*b*Q' with Hs'_i = y^-i*Hs_i and Q' = w*Q
+	ipa := proof.InnerProduct
+	yInvPow := powers(ffmath.ModInverse(y, tebn254.Order), n)
+	points = []*tebn254.Point{&proof.A, &proof.S, tebn254.U, q}
+	scalars = []*big.Int{
+		big.NewInt(1), x, ffmath.Neg(proof.Mu),
+		ffmath.MultiplyMod(w, ffmath.SubMod(proof.That, ffmath.Multiply(ipa.A, ipa.B), tebn254.Order), tebn254.Order),
+	}
+	for i := 0; i < n; i++ {
+		points = append(points, gs[i], hs[i])
+		gScalar := ffmath.Neg(ffmath.Add(z, ffmath.Multiply(ipa.A, s[i])))
+		// the inverse of s_i is s_(n-1-i), every round takes the other side
+		hScalar := addMulMod(zPow[i/bits], twoPow[i%bits], ffmath.Neg(ipa.B), s[n-1-i])
+		hScalar = addMulMod(z, big.NewInt(1), hScalar, yInvPow[i])
+		scalars = append(scalars, gScalar, hScalar)
+	}
+	for k := range u {
+		uu := ffmath.MultiplyMod(u[k], u[k], tebn254.Order)
+		points = append(points, &ipa.L[k], &ipa.R[k])
+		scalars = append(scalars, uu, ffmath.ModInverse(uu, tebn254.Order))
+	}
+	if !tebn254.IsZero(multiScalarMul(points, scalars)) {
+		log.Println("[VerifyAggregated] invalid inner product proof")
+		return ErrVerifyFailed
+	}
+	return nil
+}
+
+/*
+	paddedCount: number of values rounded up to a power of two
+*/
+func paddedCount(bits, count int) (int, error) {
+	if bits <= 0 || bits > MaxBits || bits&(bits-1) != 0 {
+		return 0, ErrInvalidBits
+	}
+	if count <= 0 || count > MaxAggregation {
+		return 0, ErrInvalidCount
+	}
+	m := 1
+	for m < count {
+		m *= 2
+	}
+	return m, nil
+}
+
+func (proof *RangeProof) validate(n int) error {
+	if proof == nil || proof.InnerProduct == nil {
+		return ErrInvalidRangeProof
+	}
+	rounds := 0
+	for 1<<uint(rounds) < n {
+		rounds++
+	}
+	ipa := proof.InnerProduct
+	if len(ipa.L) != rounds || len(ipa.R) != rounds {
+		return ErrInvalidRangeProof
+	}
+	for _, s := range []*big.Int{proof.Taux, proof.Mu, proof.That, ipa.A, ipa.B} {
+		if s == nil || s.Sign() < 0 || s.Cmp(tebn254.Order) >= 0 {
+			return ErrInvalidRangeProof
+		}
+	}
+	return nil
+}
+
+func appendStatement(tr *transcript.Transcript, bits int, commitments []*commitment.Commitment) {
+	tr.AppendUint64("bits", uint64(bits))
+	tr.AppendUint64("count", uint64(len(commitments)))
+	for _, c := range commitments {
+		tr.AppendPoint("V", c.Point())
+	}
+}
+
+func appendScalars(tr *transcript.Transcript, proof *RangeProof) {
+	tr.AppendScalar("taux", proof.Taux)
+	tr.AppendScalar("mu", proof.Mu)
+	tr.AppendScalar("that", proof.That)
+}
+
+func randomScalars(n int) ([]*big.Int, error) {
+	scalars := make([]*big.Int, n)
+	for i := range scalars {
+		var err error
+		scalars[i], err = ffmath.RandomValue(tebn254.Order)
+		if err != nil {
+			log.Println("[bulletproofs] unable to generate random scalar:", err)
+			return nil, err
+		}
+	}
+	return scalars, nil
+}
+
+/*
+	powers: 1, x, ..., x^(n-1) modulo the order
+*/
+func powers(x *big.Int, n int) []*big.Int {
+	res := make([]*big.Int, n)
+	current := big.NewInt(1)
+	for i := range res {
+		res[i] = current
+		current = ffmath.MultiplyMod(current, x, tebn254.Order)
+	}
+	return res
+}
+
+func sum(v []*big.Int) *big.Int {
+	res := new(big.Int)
+	for _, x := range v {
+		res.Add(res, x)
+	}
+	return res.Mod(res, tebn254.Order)
+}
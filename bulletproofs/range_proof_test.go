@@ -0,0 +1,162 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bulletproofs
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/commitment"
+	"github.com/bnb-chain/zkbnb-crypto/transcript"
+)
+
+const testDomain = "ZkBNBRangeProofTest"
+
+func TestRangeProof(t *testing.T) {
+	blinding, err := commitment.RandomBlinding()
+	require.NoError(t, err)
+	for _, value := range []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(1 << 40), new(big.Int).SetUint64(1<<64 - 1)} {
+		proof, c, err := Prove(transcript.NewMiMC(testDomain), value, blinding, 64)
+		require.NoError(t, err)
+		assert.True(t, commitment.Verify(c, value, blinding))
+		assert.NoError(t, Verify(transcript.NewMiMC(testDomain), proof, c, 64))
+		// another transcript, bit size or commitment
+		assert.Equal(t, ErrVerifyFailed, Verify(transcript.NewKeccak(testDomain), proof, c, 64))
+		assert.Equal(t, ErrVerifyFailed, Verify(transcript.NewMiMC("another domain"), proof, c, 64))
+		assert.Equal(t, ErrInvalidRangeProof, Verify(transcript.NewMiMC(testDomain), proof, c, 32))
+		other, err := commitment.Commit(new(big.Int).Add(value, big.NewInt(1)), blinding)
+		require.NoError(t, err)
+		assert.Equal(t, ErrVerifyFailed, Verify(transcript.NewMiMC(testDomain), proof, other, 64))
+	}
+
+	_, _, err = Prove(transcript.NewMiMC(testDomain), new(big.Int).Lsh(big.NewInt(1), 64), blinding, 64)
+	assert.Equal(t, ErrValueOutOfRange, err)
+	_, _, err = Prove(transcript.NewMiMC(testDomain), big.NewInt(-1), blinding, 64)
+	assert.Equal(t, ErrValueOutOfRange, err)
+	_, _, err = Prove(transcript.NewMiMC(testDomain), big.NewInt(1), blinding, 48)
+	assert.Equal(t, ErrInvalidBits, err)
+
+	// a commitment to a value out of range, such as a negative balance, can't be proven with another opening
+	proof, _, err := Prove(transcript.NewMiMC(testDomain), big.NewInt(5), blinding, 8)
+	require.NoError(t, err)
+	negative, err := commitment.Commit(big.NewInt(-5), blinding)
+	require.NoError(t, err)
+	assert.Equal(t, ErrVerifyFailed, Verify(transcript.NewMiMC(testDomain), proof, negative, 8))
+}
+
+func TestAggregatedRangeProof(t *testing.T) {
+	values := []*big.Int{big.NewInt(3), big.NewInt(1 << 20), big.NewInt(0)}
+	blindings := make([]*big.Int, len(values))
+	for i := range blindings {
+		var err error
+		blindings[i], err = commitment.RandomBlinding()
+		require.NoError(t, err)
+	}
+	bitSizes := []int{32, MaxBits}
+	if testing.Short() {
+		bitSizes = bitSizes[:1]
+	}
+	for _, bits := range bitSizes {
+		proof, commitments, err := ProveAggregated(transcript.NewKeccak(testDomain), values, blindings, bits)
+		require.NoError(t, err)
+		assert.Equal(t, 3, proof.Count)
+		assert.NoError(t, VerifyAggregated(transcript.NewKeccak(testDomain), proof, commitments, bits))
+		// the order and the number of the commitments are part of the statement
+		swapped := []*commitment.Commitment{commitments[1], commitments[0], commitments[2]}
+		assert.Equal(t, ErrVerifyFailed, VerifyAggregated(transcript.NewKeccak(testDomain), proof, swapped, bits))
+		assert.Equal(t, ErrInvalidRangeProof, VerifyAggregated(transcript.NewKeccak(testDomain), proof, commitments[:2], bits))
+
+		buf := ToBytes(proof)
+		decoded, err := FromBytes(buf)
+		require.NoError(t, err)
+		assert.Equal(t, proof, decoded)
+		assert.NoError(t, VerifyAggregated(transcript.NewKeccak(testDomain), decoded, commitments, bits))
+		_, err = FromBytes(buf[:len(buf)-1])
+		assert.Equal(t, ErrInvalidRangeProof, err)
+		// a tampered scalar
+		buf[len(buf)-1] ^= 1
+		decoded, err = FromBytes(buf)
+		require.NoError(t, err)
+		assert.Equal(t, ErrVerifyFailed, VerifyAggregated(transcript.NewKeccak(testDomain), decoded, commitments, bits))
+	}
+	_, _, err := ProveAggregated(transcript.NewKeccak(testDomain), values, blindings[:2], 32)
+	assert.Equal(t, ErrInvalidCount, err)
+}
+
+func BenchmarkProve64(b *testing.B) {
+	blinding, _ := commitment.RandomBlinding()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, err := Prove(transcript.NewMiMC(testDomain), big.NewInt(1000), blinding, 64)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVerify64(b *testing.B) {
+	blinding, _ := commitment.RandomBlinding()
+	proof, c, err := Prove(transcript.NewMiMC(testDomain), big.NewInt(1000), blinding, 64)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err = Verify(transcript.NewMiMC(testDomain), proof, c, 64); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProveAggregated8x64(b *testing.B) {
+	values := make([]*big.Int, 8)
+	blindings := make([]*big.Int, 8)
+	for i := range values {
+		values[i] = big.NewInt(int64(i) * 1000)
+		blindings[i], _ = commitment.RandomBlinding()
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, err := ProveAggregated(transcript.NewMiMC(testDomain), values, blindings, 64)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVerifyAggregated8x64(b *testing.B) {
+	values := make([]*big.Int, 8)
+	blindings := make([]*big.Int, 8)
+	for i := range values {
+		values[i] = big.NewInt(int64(i) * 1000)
+		blindings[i], _ = commitment.RandomBlinding()
+	}
+	proof, commitments, err := ProveAggregated(transcript.NewMiMC(testDomain), values, blindings, 64)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err = VerifyAggregated(transcript.NewMiMC(testDomain), proof, commitments, 64); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
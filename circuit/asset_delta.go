@@ -322,6 +322,7 @@ func GetAssetDeltasAndNftDeltaFromAtomicMatch(
 	buyOfferIdBits := api.ToBinary(txInfo.BuyOffer.OfferId, 23)
 	buyAssetId := api.FromBinary(buyOfferIdBits[7:]...)
 	buyOfferIndex := api.Sub(txInfo.BuyOffer.OfferId, api.Mul(buyAssetId, OfferSizePerAsset))
+	endProfile := types.Profile(api, "gadget/offer_bitmap")
 	buyOfferBits := api.ToBinary(accountsBefore[1].AssetsInfo[1].OfferCanceledOrFinalized)
 	// TODO need to optimize here
 	for i := 0; i < OfferSizePerAsset; i++ {
@@ -330,6 +331,7 @@ func GetAssetDeltasAndNftDeltaFromAtomicMatch(
 		buyOfferBits[i] = api.Select(isChange, 1, buyOfferBits[i])
 	}
 	buyOfferCanceledOrFinalized := api.FromBinary(buyOfferBits...)
+	endProfile()
 	deltas[1] = [NbAccountAssetsPerAccount]AccountAssetDeltaConstraints{
 		{
 			BalanceDelta:             buyerDelta,
@@ -344,6 +346,7 @@ func GetAssetDeltasAndNftDeltaFromAtomicMatch(
 	sellOfferIdBits := api.ToBinary(txInfo.SellOffer.OfferId, 23)
 	sellAssetId := api.FromBinary(sellOfferIdBits[7:]...)
 	sellOfferIndex := api.Sub(txInfo.SellOffer.OfferId, api.Mul(sellAssetId, OfferSizePerAsset))
+	endProfile = types.Profile(api, "gadget/offer_bitmap")
 	sellOfferBits := api.ToBinary(accountsBefore[2].AssetsInfo[1].OfferCanceledOrFinalized)
 	// TODO need to optimize here
 	for i := 0; i < OfferSizePerAsset; i++ {
@@ -352,6 +355,7 @@ func GetAssetDeltasAndNftDeltaFromAtomicMatch(
 		sellOfferBits[i] = api.Select(isChange, 1, sellOfferBits[i])
 	}
 	sellOfferCanceledOrFinalized := api.FromBinary(sellOfferBits...)
+	endProfile()
 	deltas[2] = [NbAccountAssetsPerAccount]AccountAssetDeltaConstraints{
 		{
 			BalanceDelta:             sellerDelta,
@@ -405,6 +409,7 @@ func GetAssetDeltasFromCancelOffer(
 	offerIdBits := api.ToBinary(txInfo.OfferId, 24)
 	assetId := api.FromBinary(offerIdBits[7:]...)
 	offerIndex := api.Sub(txInfo.OfferId, api.Mul(assetId, OfferSizePerAsset))
+	endProfile := types.Profile(api, "gadget/offer_bitmap")
 	fromOfferBits := api.ToBinary(accountsBefore[0].AssetsInfo[1].OfferCanceledOrFinalized)
 	// TODO need to optimize here
 	for i := 0; i < OfferSizePerAsset; i++ {
@@ -413,6 +418,7 @@ func GetAssetDeltasFromCancelOffer(
 		fromOfferBits[i] = api.Select(isChange, 1, fromOfferBits[i])
 	}
 	fromOfferCanceledOrFinalized := api.FromBinary(fromOfferBits...)
+	endProfile()
 	deltas[0] = [NbAccountAssetsPerAccount]AccountAssetDeltaConstraints{
 		// asset Gas
 		{
@@ -23,6 +23,7 @@ type Block struct {
 	OldStateRoot    []byte
 	NewStateRoot    []byte
 	BlockCommitment []byte
+	ChainId         int64
 	Txs             []*Tx
 	Gas             *Gas
 }
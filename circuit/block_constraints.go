@@ -31,14 +31,30 @@ type BlockConstraints struct {
 	OldStateRoot    Variable
 	NewStateRoot    Variable
 	BlockCommitment Variable `gnark:",public"`
+	// ChainId is signed by every layer 2 tx, it is public so a proof only verifies for one chain
+	ChainId         Variable `gnark:",public"`
 	Txs             []TxConstraints
 	TxsCount        int
 	Gas             GasConstraints
 	GasAssetIds     []int64
 	GasAccountIndex int64
+	// Config holds the tree depths, the zero value stands for DefaultConfig
+	Config Config `gnark:"-"`
+	// Profile records the constraint counters of the gadgets, see circuit/profile
+	Profile bool
 }
 
 func (circuit BlockConstraints) Define(api API) error {
+	if circuit.Config.IsZero() {
+		circuit.Config = DefaultConfig()
+	}
+	err := circuit.Config.Validate()
+	if err != nil {
+		return err
+	}
+	if circuit.Profile {
+		api = types.ProfiledAPI{API: api}
+	}
 	// mimc
 	hFunc, err := mimc.NewMiMC(api)
 	if err != nil {
@@ -72,6 +88,8 @@ func VerifyBlock(
 	pendingCommitmentData[2] = block.OldStateRoot
 	pendingCommitmentData[3] = block.NewStateRoot
 	api.AssertIsEqual(block.OldStateRoot, block.Txs[0].StateRootBefore)
+	// the chain id is a uint32 in the tx hashes
+	api.ToBinary(block.ChainId, 32)
 
 	gasAssetCount := len(block.GasAssetIds)
 	blockGasDeltas := make([]Variable, gasAssetCount)
@@ -83,7 +101,7 @@ func VerifyBlock(
 	}
 
 	onChainOpsCount = 0
-	isOnChainOp, pendingPubData, roots, gasDeltas, err := VerifyTransaction(api, block.Txs[0], hFunc, block.CreatedAt, block.GasAssetIds, roots)
+	isOnChainOp, pendingPubData, roots, gasDeltas, err := VerifyTransaction(api, block.Config, block.Txs[0], hFunc, block.CreatedAt, block.ChainId, block.GasAssetIds, roots)
 	if err != nil {
 		log.Println("unable to verify transaction, err:", err)
 		return err
@@ -108,7 +126,7 @@ func VerifyBlock(
 	for i := 1; i < block.TxsCount; i++ {
 		api.AssertIsEqual(block.Txs[i-1].StateRootAfter, block.Txs[i].StateRootBefore)
 		hFunc.Reset()
-		isOnChainOp, pendingPubData, roots, gasDeltas, err = VerifyTransaction(api, block.Txs[i], hFunc, block.CreatedAt, block.GasAssetIds, roots)
+		isOnChainOp, pendingPubData, roots, gasDeltas, err = VerifyTransaction(api, block.Config, block.Txs[i], hFunc, block.CreatedAt, block.ChainId, block.GasAssetIds, roots)
 		if err != nil {
 			log.Println("unable to verify transaction, err:", err)
 			return err
@@ -146,7 +164,7 @@ func VerifyBlock(
 	}
 
 	types.IsVariableEqual(api, needGas, block.Gas.AccountInfoBefore.AccountIndex, block.GasAccountIndex)
-	roots[0], err = VerifyGas(api, block.Gas, needGas, blockGasDeltas, hFunc, roots[0])
+	roots[0], err = VerifyGas(api, block.Config, block.Gas, needGas, blockGasDeltas, hFunc, roots[0])
 	if err != nil {
 		log.Println("unable to verify gas, err:", err)
 		return err
@@ -169,16 +187,28 @@ func VerifyBlock(
 	return nil
 }
 
+/*
+	SetBlockWitness: witness of a block built with the default config
+*/
 func SetBlockWitness(oBlock *Block) (witness BlockConstraints, err error) {
+	return SetBlockWitnessWithConfig(DefaultConfig(), oBlock)
+}
+
+/*
+	SetBlockWitnessWithConfig: same as SetBlockWitness for the tree depths of the config
+*/
+func SetBlockWitnessWithConfig(config Config, oBlock *Block) (witness BlockConstraints, err error) {
 	witness = BlockConstraints{
+		Config:          config,
 		BlockNumber:     oBlock.BlockNumber,
 		CreatedAt:       oBlock.CreatedAt,
 		OldStateRoot:    oBlock.OldStateRoot,
 		NewStateRoot:    oBlock.NewStateRoot,
 		BlockCommitment: oBlock.BlockCommitment,
+		ChainId:         oBlock.ChainId,
 	}
 	for i := 0; i < len(oBlock.Txs); i++ {
-		tx, err := SetTxWitness(oBlock.Txs[i])
+		tx, err := SetTxWitnessWithConfig(config, oBlock.Txs[i])
 		witness.Txs = append(witness.Txs, tx)
 		if err != nil {
 			log.Println("fail to set tx witness: ", err.Error())
@@ -186,7 +216,7 @@ func SetBlockWitness(oBlock *Block) (witness BlockConstraints, err error) {
 		}
 	}
 
-	witness.Gas, err = SetGasWitness(oBlock.Gas)
+	witness.Gas, err = SetGasWitnessWithConfig(config, oBlock.Gas)
 	if err != nil {
 		log.Println("fail to set gas witness: ", err.Error())
 		return witness, err
@@ -194,7 +224,17 @@ func SetBlockWitness(oBlock *Block) (witness BlockConstraints, err error) {
 	return witness, nil
 }
 
+/*
+	GetZeroTxConstraint: empty tx of the circuit with the default config
+*/
 func GetZeroTxConstraint() TxConstraints {
+	return GetZeroTxConstraintWithConfig(DefaultConfig())
+}
+
+/*
+	GetZeroTxConstraintWithConfig: same as GetZeroTxConstraint for the tree depths of the config
+*/
+func GetZeroTxConstraintWithConfig(config Config) TxConstraints {
 	var zeroTxConstraint TxConstraints
 	zeroTxConstraint.TxType = 0
 	zeroTxConstraint.RegisterZnsTxInfo = types.EmptyRegisterZnsTxWitness()
@@ -254,19 +294,64 @@ func GetZeroTxConstraint() TxConstraints {
 		// accounts info before
 		zeroTxConstraint.AccountsInfoBefore[i] = zeroAccountConstraint
 		for j := 0; j < NbAccountAssetsPerAccount; j++ {
-			for k := 0; k < AssetMerkleLevels; k++ {
+			zeroTxConstraint.MerkleProofsAccountAssetsBefore[i][j] = make([]Variable, config.AssetMerkleLevels)
+			for k := 0; k < config.AssetMerkleLevels; k++ {
 				// account assets before
 				zeroTxConstraint.MerkleProofsAccountAssetsBefore[i][j][k] = 0
 			}
 		}
-		for j := 0; j < AccountMerkleLevels; j++ {
+		zeroTxConstraint.MerkleProofsAccountBefore[i] = make([]Variable, config.AccountMerkleLevels)
+		for j := 0; j < config.AccountMerkleLevels; j++ {
 			// account before
 			zeroTxConstraint.MerkleProofsAccountBefore[i][j] = 0
 		}
 	}
-	for i := 0; i < NftMerkleLevels; i++ {
+	zeroTxConstraint.MerkleProofsNftBefore = make([]Variable, config.NftMerkleLevels)
+	for i := 0; i < config.NftMerkleLevels; i++ {
 		// nft assets before
 		zeroTxConstraint.MerkleProofsNftBefore[i] = 0
 	}
 	return zeroTxConstraint
 }
+
+/*
+	GetBlockConstraints: shape of the block circuit with the default config, used to compile it
+*/
+func GetBlockConstraints(txsCount int, gasAssetIds []int64, gasAccountIndex int64) (blockConstraints BlockConstraints) {
+	return newBlockConstraints(DefaultConfig(), txsCount, gasAssetIds, gasAccountIndex)
+}
+
+/*
+	NewBlockConstraints: shape of the block circuit for the given config,
+	the gas account and the gas assets should fit in the trees of the config
+*/
+func NewBlockConstraints(config Config, txsCount int, gasAssetIds []int64, gasAccountIndex int64) (blockConstraints BlockConstraints, err error) {
+	err = config.Validate()
+	if err != nil {
+		return blockConstraints, err
+	}
+	if gasAccountIndex < 0 || gasAccountIndex > config.LastAccountIndex() {
+		log.Println("[NewBlockConstraints] gas account doesn't fit in the account tree")
+		return blockConstraints, ErrInvalidConfig
+	}
+	for _, assetId := range gasAssetIds {
+		if assetId < 0 || assetId > config.LastAccountAssetId() {
+			log.Println("[NewBlockConstraints] gas asset doesn't fit in the asset tree")
+			return blockConstraints, ErrInvalidConfig
+		}
+	}
+	return newBlockConstraints(config, txsCount, gasAssetIds, gasAccountIndex), nil
+}
+
+func newBlockConstraints(config Config, txsCount int, gasAssetIds []int64, gasAccountIndex int64) (blockConstraints BlockConstraints) {
+	blockConstraints.Config = config
+	blockConstraints.TxsCount = txsCount
+	blockConstraints.Txs = make([]TxConstraints, txsCount)
+	for i := 0; i < txsCount; i++ {
+		blockConstraints.Txs[i] = GetZeroTxConstraintWithConfig(config)
+	}
+	blockConstraints.GasAssetIds = gasAssetIds
+	blockConstraints.GasAccountIndex = gasAccountIndex
+	blockConstraints.Gas = GetZeroGasConstraintsWithConfig(config, gasAssetIds)
+	return blockConstraints
+}
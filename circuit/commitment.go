@@ -0,0 +1,179 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package circuit
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+const (
+	// every pub data word is committed as a 32 bytes big endian integer
+	PubDataWordBytes  = 32
+	PubDataBytesPerTx = types.PubDataSizePerTx * PubDataWordBytes
+)
+
+var (
+	ErrInvalidBlock          = errors.New("[Block] invalid block")
+	ErrNegativeCommitmentInt = errors.New("[Block] negative integer in the commitment data")
+)
+
+/*
+	CommitBlockInfo: block data sent to the layer 1 contract when the block is committed
+*/
+type CommitBlockInfo struct {
+	NewStateRoot [32]byte
+	// pub data of all txs, including the empty ones
+	PublicData []byte
+	Timestamp  int64
+	// offsets in bytes of the on chain operations in PublicData
+	PublicDataOffsets []uint32
+	BlockNumber       uint32
+	BlockSize         uint16
+}
+
+/*
+	IsOnChainOp: txs which are processed as priority operations on layer 1,
+	this is the isOnChainOp flag computed by VerifyTransaction
+*/
+func IsOnChainOp(txType uint8) bool {
+	switch txType {
+	case types.TxTypeRegisterZns,
+		types.TxTypeDeposit,
+		types.TxTypeDepositNft,
+		types.TxTypeWithdraw,
+		types.TxTypeWithdrawNft,
+		types.TxTypeFullExit,
+		types.TxTypeFullExitNft:
+		return true
+	default:
+		return false
+	}
+}
+
+/*
+	ComputeBlockPubData: concatenate the pub data words of all txs, in the same order as VerifyBlock
+*/
+func ComputeBlockPubData(txs []*Tx) (pubData []byte, pubDataOffsets []uint32, onChainOpsCount int64, err error) {
+	pubData = make([]byte, 0, len(txs)*PubDataBytesPerTx)
+	pubDataOffsets = make([]uint32, 0)
+	for i, oTx := range txs {
+		if oTx == nil {
+			log.Println("[ComputeBlockPubData] tx is nil:", i)
+			return nil, nil, 0, ErrInvalidBlock
+		}
+		txPubData, err := ComputePubData(oTx)
+		if err != nil {
+			log.Println("[ComputeBlockPubData] unable to compute pub data:", err)
+			return nil, nil, 0, err
+		}
+		if IsOnChainOp(oTx.TxType) {
+			pubDataOffsets = append(pubDataOffsets, uint32(len(pubData)))
+			onChainOpsCount++
+		}
+		for _, word := range txPubData {
+			pubData = append(pubData, word.FillBytes(make([]byte, PubDataWordBytes))...)
+		}
+	}
+	return pubData, pubDataOffsets, onChainOpsCount, nil
+}
+
+/*
+	EncodeBlockCommitmentData: the pending commitment data of VerifyBlock, every value is a 32 bytes word.
+	This is also abi.encodePacked(uint256(blockNumber), uint256(timestamp), oldStateRoot, newStateRoot,
+	publicData, uint256(onChainOpsCount)) on layer 1, so the integers can't be negative
+*/
+func EncodeBlockCommitmentData(
+	blockNumber int64,
+	createdAt int64,
+	oldStateRoot []byte,
+	newStateRoot []byte,
+	pubData []byte,
+	onChainOpsCount int64,
+) ([]byte, error) {
+	if blockNumber < 0 || createdAt < 0 || onChainOpsCount < 0 {
+		log.Println("[EncodeBlockCommitmentData] negative block number, timestamp or on chain ops count")
+		return nil, ErrNegativeCommitmentInt
+	}
+	var buf bytes.Buffer
+	buf.Write(big.NewInt(blockNumber).FillBytes(make([]byte, PubDataWordBytes)))
+	buf.Write(big.NewInt(createdAt).FillBytes(make([]byte, PubDataWordBytes)))
+	buf.Write(types.BytesToFieldElement(oldStateRoot).FillBytes(make([]byte, PubDataWordBytes)))
+	buf.Write(types.BytesToFieldElement(newStateRoot).FillBytes(make([]byte, PubDataWordBytes)))
+	buf.Write(pubData)
+	buf.Write(big.NewInt(onChainOpsCount).FillBytes(make([]byte, PubDataWordBytes)))
+	return buf.Bytes(), nil
+}
+
+/*
+	ComputeBlockCommitment: out-of-circuit version of the commitment checked by VerifyBlock.
+	The keccak hash is returned as it is stored on layer 1, the circuit compares it modulo the field size.
+*/
+func ComputeBlockCommitment(oBlock *Block) (commitment []byte, err error) {
+	if oBlock == nil || len(oBlock.Txs) == 0 {
+		log.Println("[ComputeBlockCommitment] invalid block")
+		return nil, ErrInvalidBlock
+	}
+	pubData, _, onChainOpsCount, err := ComputeBlockPubData(oBlock.Txs)
+	if err != nil {
+		return nil, err
+	}
+	commitmentData, err := EncodeBlockCommitmentData(
+		oBlock.BlockNumber,
+		oBlock.CreatedAt,
+		oBlock.OldStateRoot,
+		oBlock.NewStateRoot,
+		pubData,
+		onChainOpsCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(commitmentData), nil
+}
+
+/*
+	ComputeCommitBlockInfo: layer 1 calldata of the block
+*/
+func ComputeCommitBlockInfo(oBlock *Block) (info *CommitBlockInfo, err error) {
+	if oBlock == nil || len(oBlock.Txs) == 0 || len(oBlock.Txs) > math.MaxUint16 ||
+		oBlock.BlockNumber < 0 || oBlock.BlockNumber > math.MaxUint32 {
+		log.Println("[ComputeCommitBlockInfo] invalid block")
+		return nil, ErrInvalidBlock
+	}
+	pubData, pubDataOffsets, _, err := ComputeBlockPubData(oBlock.Txs)
+	if err != nil {
+		return nil, err
+	}
+	info = &CommitBlockInfo{
+		PublicData:        pubData,
+		Timestamp:         oBlock.CreatedAt,
+		PublicDataOffsets: pubDataOffsets,
+		BlockNumber:       uint32(oBlock.BlockNumber),
+		BlockSize:         uint16(len(oBlock.Txs)),
+	}
+	types.BytesToFieldElement(oBlock.NewStateRoot).FillBytes(info.NewStateRoot[:])
+	return info, nil
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package circuit
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+type CommitmentConstraints struct {
+	Data       []Variable
+	Commitment Variable `gnark:",public"`
+}
+
+func (circuit CommitmentConstraints) Define(api API) error {
+	commitments, err := api.Compiler().NewHint(types.Keccak256, 1, circuit.Data...)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(commitments[0], circuit.Commitment)
+	return nil
+}
+
+func testBlock() *Block {
+	stateRoot := []byte{0x01, 0x02, 0x03}
+	deposit := EmptyTx(stateRoot)
+	deposit.TxType = types.TxTypeDeposit
+	deposit.DepositTxInfo = &DepositTx{
+		AccountIndex:    2,
+		AccountNameHash: []byte{0x01, 0x02},
+		AssetId:         1,
+		AssetAmount:     big.NewInt(100),
+	}
+	transfer := EmptyTx(stateRoot)
+	transfer.TxType = types.TxTypeTransfer
+	transfer.TransferTxInfo = &TransferTx{
+		FromAccountIndex:  2,
+		ToAccountIndex:    3,
+		ToAccountNameHash: []byte{0x03},
+		AssetId:           1,
+		AssetAmount:       10,
+		GasAccountIndex:   1,
+		GasFeeAssetId:     1,
+		GasFeeAssetAmount: 1,
+		CallDataHash:      []byte{},
+	}
+	return &Block{
+		BlockNumber:  5,
+		CreatedAt:    1654656781000,
+		OldStateRoot: stateRoot,
+		NewStateRoot: []byte{0x0a},
+		Txs:          []*Tx{EmptyTx(stateRoot), deposit, transfer, EmptyTx(stateRoot)},
+	}
+}
+
+func TestComputeBlockCommitment(t *testing.T) {
+	oBlock := testBlock()
+	commitment, err := ComputeBlockCommitment(oBlock)
+	assert.Nil(t, err)
+	assert.Equal(t, 32, len(commitment))
+
+	pubData, _, onChainOpsCount, err := ComputeBlockPubData(oBlock.Txs)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), onChainOpsCount)
+	data, err := EncodeBlockCommitmentData(
+		oBlock.BlockNumber, oBlock.CreatedAt, oBlock.OldStateRoot, oBlock.NewStateRoot, pubData, onChainOpsCount)
+	assert.Nil(t, err)
+	assert.Equal(t, (types.PubDataSizePerTx*len(oBlock.Txs)+5)*PubDataWordBytes, len(data))
+
+	// same words as the pending commitment data of VerifyBlock
+	var circuit, witness CommitmentConstraints
+	circuit.Data = make([]Variable, len(data)/PubDataWordBytes)
+	witness.Data = make([]Variable, len(data)/PubDataWordBytes)
+	for i := range witness.Data {
+		witness.Data[i] = data[i*PubDataWordBytes : (i+1)*PubDataWordBytes]
+	}
+	witness.Commitment = commitment
+	err = test.IsSolved(&circuit, &witness, ecc.BN254, backend.GROTH16, backend.WithHints(types.Keccak256))
+	assert.Nil(t, err)
+
+	oBlock.BlockNumber++
+	otherCommitment, err := ComputeBlockCommitment(oBlock)
+	assert.Nil(t, err)
+	witness.Commitment = otherCommitment
+	err = test.IsSolved(&circuit, &witness, ecc.BN254, backend.GROTH16, backend.WithHints(types.Keccak256))
+	assert.NotNil(t, err)
+}
+
+func TestEncodeBlockCommitmentDataNegative(t *testing.T) {
+	oBlock := testBlock()
+	pubData, _, onChainOpsCount, err := ComputeBlockPubData(oBlock.Txs)
+	assert.Nil(t, err)
+	// uint256 on layer 1, a negative value would be encoded as its absolute value
+	_, err = EncodeBlockCommitmentData(-5, oBlock.CreatedAt, oBlock.OldStateRoot, oBlock.NewStateRoot, pubData, onChainOpsCount)
+	assert.Equal(t, ErrNegativeCommitmentInt, err)
+	_, err = EncodeBlockCommitmentData(oBlock.BlockNumber, -oBlock.CreatedAt, oBlock.OldStateRoot, oBlock.NewStateRoot, pubData, onChainOpsCount)
+	assert.Equal(t, ErrNegativeCommitmentInt, err)
+	_, err = EncodeBlockCommitmentData(oBlock.BlockNumber, oBlock.CreatedAt, oBlock.OldStateRoot, oBlock.NewStateRoot, pubData, -1)
+	assert.Equal(t, ErrNegativeCommitmentInt, err)
+
+	oBlock.CreatedAt = -oBlock.CreatedAt
+	_, err = ComputeBlockCommitment(oBlock)
+	assert.Equal(t, ErrNegativeCommitmentInt, err)
+}
+
+func TestComputeCommitBlockInfo(t *testing.T) {
+	oBlock := testBlock()
+	info, err := ComputeCommitBlockInfo(oBlock)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(5), info.BlockNumber)
+	assert.Equal(t, uint16(4), info.BlockSize)
+	assert.Equal(t, oBlock.CreatedAt, info.Timestamp)
+	assert.Equal(t, []uint32{PubDataBytesPerTx}, info.PublicDataOffsets)
+	assert.Equal(t, 4*PubDataBytesPerTx, len(info.PublicData))
+	assert.Equal(t, byte(0x0a), info.NewStateRoot[31])
+
+	// the first byte of every on chain operation is its tx type
+	assert.Equal(t, byte(types.TxTypeDeposit), info.PublicData[info.PublicDataOffsets[0]])
+
+	_, err = ComputeCommitBlockInfo(&Block{})
+	assert.Equal(t, ErrInvalidBlock, err)
+	_, err = ComputeBlockCommitment(nil)
+	assert.Equal(t, ErrInvalidBlock, err)
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package circuit
+
+import (
+	"errors"
+	"log"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+)
+
+const (
+	// widths of the indexes in the pub data, the trees can't be deeper
+	maxAccountMerkleLevels = 32
+	maxAssetMerkleLevels   = 16
+	maxNftMerkleLevels     = 40
+)
+
+var (
+	ErrInvalidConfig       = errors.New("[Config] invalid circuit config")
+	ErrInvalidMerkleProofs = errors.New("[Config] invalid merkle proofs length")
+)
+
+/*
+	Config: parameters of the block circuit which are chosen at compile time, only the tree depths are configurable,
+	up to the widths of the indexes in the pub data. The tx layout (NbAccountsPerTx, NbAccountAssetsPerAccount,
+	NbGasAssetsPerTx, types.PubDataSizePerTx) is part of the tx gadgets and of the layer 1 pub data format.
+*/
+type Config struct {
+	AccountMerkleLevels int
+	AssetMerkleLevels   int
+	NftMerkleLevels     int
+}
+
+/*
+	DefaultConfig: the parameters of the production circuit
+*/
+func DefaultConfig() Config {
+	return Config{
+		AccountMerkleLevels: AccountMerkleLevels,
+		AssetMerkleLevels:   AssetMerkleLevels,
+		NftMerkleLevels:     NftMerkleLevels,
+	}
+}
+
+/*
+	IsZero: the zero config stands for the default one
+*/
+func (c Config) IsZero() bool {
+	return c == Config{}
+}
+
+/*
+	Validate: the tree depths should fit in the indexes of the pub data
+*/
+func (c Config) Validate() error {
+	if c.AccountMerkleLevels < 1 || c.AccountMerkleLevels > maxAccountMerkleLevels ||
+		c.AssetMerkleLevels < 1 || c.AssetMerkleLevels > maxAssetMerkleLevels ||
+		c.NftMerkleLevels < 1 || c.NftMerkleLevels > maxNftMerkleLevels {
+		log.Println("[Validate] invalid merkle levels")
+		return ErrInvalidConfig
+	}
+	return nil
+}
+
+func (c Config) LastAccountIndex() int64 {
+	return 1<<c.AccountMerkleLevels - 1
+}
+
+func (c Config) LastAccountAssetId() int64 {
+	return 1<<c.AssetMerkleLevels - 1
+}
+
+func (c Config) LastNftIndex() int64 {
+	return 1<<c.NftMerkleLevels - 1
+}
+
+/*
+	EmptyAssetRoot: root of an asset tree without any asset, types.EmptyAssetRoot for the default config
+*/
+func (c Config) EmptyAssetRoot() *big.Int {
+	hFunc := mimc.NewMiMC()
+	node := make([]byte, 32)
+	hFunc.Write(node)
+	hFunc.Write(node)
+	node = hFunc.Sum(nil)
+	for i := 0; i < c.AssetMerkleLevels; i++ {
+		hFunc.Reset()
+		hFunc.Write(node)
+		hFunc.Write(node)
+		node = hFunc.Sum(nil)
+	}
+	return new(big.Int).SetBytes(node)
+}
+
+/*
+	CheckTx: the merkle proofs of the tx should match the tree depths
+*/
+func (c Config) CheckTx(oTx *Tx) error {
+	if oTx == nil {
+		return ErrInvalidBlock
+	}
+	for i := 0; i < NbAccountsPerTx; i++ {
+		for j := 0; j < NbAccountAssetsPerAccount; j++ {
+			if len(oTx.MerkleProofsAccountAssetsBefore[i][j]) != c.AssetMerkleLevels {
+				return ErrInvalidMerkleProofs
+			}
+		}
+		if len(oTx.MerkleProofsAccountBefore[i]) != c.AccountMerkleLevels {
+			return ErrInvalidMerkleProofs
+		}
+	}
+	if len(oTx.MerkleProofsNftBefore) != c.NftMerkleLevels {
+		return ErrInvalidMerkleProofs
+	}
+	return nil
+}
+
+/*
+	CheckGas: the merkle proofs of the gas account should match the tree depths
+*/
+func (c Config) CheckGas(oGas *Gas) error {
+	if oGas == nil || len(oGas.MerkleProofsAccountAssetsBefore) < oGas.GasAssetCount {
+		return ErrInvalidBlock
+	}
+	for i := 0; i < oGas.GasAssetCount; i++ {
+		if len(oGas.MerkleProofsAccountAssetsBefore[i]) != c.AssetMerkleLevels {
+			return ErrInvalidMerkleProofs
+		}
+	}
+	if len(oGas.MerkleProofsAccountBefore) != c.AccountMerkleLevels {
+		return ErrInvalidMerkleProofs
+	}
+	return nil
+}
+
+/*
+	CheckBlock: the merkle proofs of every tx and of the gas account should match the tree depths
+*/
+func (c Config) CheckBlock(oBlock *Block) error {
+	if oBlock == nil {
+		return ErrInvalidBlock
+	}
+	for _, oTx := range oBlock.Txs {
+		if err := c.CheckTx(oTx); err != nil {
+			return err
+		}
+	}
+	return c.CheckGas(oBlock.Gas)
+}
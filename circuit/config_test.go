@@ -0,0 +1,105 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package circuit
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/merkleTree"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	config := DefaultConfig()
+	assert.Nil(t, config.Validate())
+	assert.False(t, config.IsZero())
+	assert.Equal(t, int64(LastAccountIndex), config.LastAccountIndex())
+	assert.Equal(t, int64(LastAccountAssetId), config.LastAccountAssetId())
+	assert.Equal(t, int64(LastNftIndex), config.LastNftIndex())
+	assert.Equal(t, types.EmptyAssetRoot, config.EmptyAssetRoot())
+}
+
+func TestConfigValidate(t *testing.T) {
+	invalid := []func(c *Config){
+		func(c *Config) { c.AccountMerkleLevels = 0 },
+		func(c *Config) { c.AccountMerkleLevels = 33 },
+		func(c *Config) { c.AssetMerkleLevels = 17 },
+		func(c *Config) { c.NftMerkleLevels = 41 },
+	}
+	for i, change := range invalid {
+		config := DefaultConfig()
+		change(&config)
+		assert.Equal(t, ErrInvalidConfig, config.Validate(), "case %d", i)
+	}
+
+	config := DefaultConfig()
+	config.AssetMerkleLevels = 4
+	assert.Nil(t, config.Validate())
+	assert.Equal(t, int64(15), config.LastAccountAssetId())
+	assert.NotEqual(t, types.EmptyAssetRoot, config.EmptyAssetRoot())
+
+	_, err := NewBlockConstraints(config, 1, []int64{0, 16}, 1)
+	assert.Equal(t, ErrInvalidConfig, err)
+	blockConstraints, err := NewBlockConstraints(config, 1, []int64{0, 15}, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(blockConstraints.Txs[0].MerkleProofsAccountAssetsBefore[0][0]))
+	assert.Equal(t, 4, len(blockConstraints.Gas.MerkleProofsAccountAssetsBefore[1]))
+}
+
+func TestCheckTx(t *testing.T) {
+	config := DefaultConfig()
+	oTx := EmptyTx(make([]byte, 32))
+	assert.Nil(t, config.CheckTx(oTx))
+	_, err := SetTxWitness(oTx)
+	assert.Nil(t, err)
+	assert.Equal(t, GetZeroTxConstraintWithConfig(config), GetZeroTxConstraint())
+
+	// a tx of smaller trees only fits the witness of its config
+	small := DefaultConfig()
+	small.AccountMerkleLevels = 8
+	smallTx := EmptyTxWithConfig(small, make([]byte, 32))
+	_, err = SetTxWitness(smallTx)
+	assert.Equal(t, ErrInvalidMerkleProofs, err)
+	witness, err := SetTxWitnessWithConfig(small, smallTx)
+	assert.Nil(t, err)
+	assert.Equal(t, 8, len(witness.MerkleProofsAccountBefore[0]))
+
+	oTx.MerkleProofsNftBefore = oTx.MerkleProofsNftBefore[1:]
+	assert.Equal(t, ErrInvalidMerkleProofs, config.CheckTx(oTx))
+	_, err = SetTxWitnessWithConfig(config, oTx)
+	assert.Equal(t, ErrInvalidMerkleProofs, err)
+}
+
+func TestConfigMerkleProofs(t *testing.T) {
+	config := DefaultConfig()
+	config.AssetMerkleLevels = 4
+	tree, err := merkleTree.NewEmptyTree(config.AssetMerkleLevels, merkleTree.NilHash, mimc.NewMiMC())
+	assert.Nil(t, err)
+	proof, err := tree.Proof(2, 3)
+	assert.Nil(t, err)
+	merkleProofs, err := config.AssetMerkleProofs(proof)
+	assert.Nil(t, err)
+	assert.Equal(t, proof.Siblings, merkleProofs)
+	_, err = config.AccountMerkleProofs(proof)
+	assert.Equal(t, ErrInvalidMerkleProofs, err)
+	_, err = config.NftMerkleProofs(nil)
+	assert.Equal(t, ErrInvalidMerkleProofs, err)
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor
+
+import (
+	"log"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+/*
+	BuildBlock: execute the txs on the state and return the block witness,
+	the block is padded with empty txs up to txsCount.
+	The circuit must be compiled with the same txsCount, gasAccountIndex and gasAssetIds.
+	If a tx is rejected the txs before it are reverted, the state is left as it was before the block.
+*/
+func BuildBlock(
+	state *State,
+	chainId int64,
+	blockNumber int64,
+	createdAt int64,
+	txsCount int,
+	gasAccountIndex int64,
+	gasAssetIds []int64,
+	txInfos []txtypes.TxInfo,
+) (oBlock *circuit.Block, err error) {
+	txs := make([]*circuit.Tx, len(txInfos))
+	for i, txInfo := range txInfos {
+		txs[i], err = ConvertTxInfo(txInfo)
+		if err != nil {
+			log.Println("[BuildBlock] unable to convert tx", i, ":", err)
+			return nil, err
+		}
+	}
+	return BuildBlockFromTxs(state, chainId, blockNumber, createdAt, txsCount, gasAccountIndex, gasAssetIds, txs)
+}
+
+/*
+	BuildBlockFromTxs: same as BuildBlock for txs which are already in the circuit format
+*/
+func BuildBlockFromTxs(
+	state *State,
+	chainId int64,
+	blockNumber int64,
+	createdAt int64,
+	txsCount int,
+	gasAccountIndex int64,
+	gasAssetIds []int64,
+	txs []*circuit.Tx,
+) (oBlock *circuit.Block, err error) {
+	if txsCount <= 0 || len(txs) > txsCount {
+		log.Println("[BuildBlock] txs count", len(txs), "does not fit in block of", txsCount)
+		return nil, ErrTooManyTxs
+	}
+	e, err := NewExecutor(state, chainId, createdAt, gasAccountIndex, gasAssetIds)
+	if err != nil {
+		return nil, err
+	}
+	state.begin()
+	defer func() {
+		state.end(err)
+	}()
+	for i, oTx := range txs {
+		_, err = e.ApplyTx(oTx)
+		if err != nil {
+			log.Println("[BuildBlock] unable to apply tx", i, ":", err)
+			return nil, err
+		}
+	}
+	for i := len(txs); i < txsCount; i++ {
+		_, err = e.ApplyTx(&circuit.Tx{TxType: types.TxTypeEmptyTx})
+		if err != nil {
+			return nil, err
+		}
+	}
+	res, err := e.Finalize()
+	if err != nil {
+		return nil, err
+	}
+	oBlock = &circuit.Block{
+		BlockNumber:  blockNumber,
+		CreatedAt:    res.CreatedAt,
+		ChainId:      res.ChainId,
+		OldStateRoot: res.OldStateRoot,
+		NewStateRoot: res.NewStateRoot,
+		Txs:          res.Txs,
+		Gas:          res.Gas,
+	}
+	oBlock.BlockCommitment, err = circuit.ComputeBlockCommitment(oBlock)
+	if err != nil {
+		return nil, err
+	}
+	return oBlock, nil
+}
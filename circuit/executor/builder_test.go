@@ -0,0 +1,271 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+func signTxInfo(t *testing.T, key *curve.PrivateKey, txInfo txtypes.TxInfo) []byte {
+	hashVal, err := txInfo.Hash(mimc.NewMiMC())
+	assert.Nil(t, err)
+	sig, err := key.Sign(hashVal, mimc.NewMiMC())
+	assert.Nil(t, err)
+	return sig
+}
+
+func testTxInfos(t *testing.T, keys map[int64]*curve.PrivateKey) []txtypes.TxInfo {
+	var txInfos []txtypes.TxInfo
+	for _, index := range []int64{gasAccountIndex, aliceIndex, bobIndex} {
+		txInfos = append(txInfos, &txtypes.RegisterZnsTxInfo{
+			TxType:          types.TxTypeRegisterZns,
+			AccountIndex:    index,
+			AccountName:     accountName(index),
+			AccountNameHash: accountNameHash(index),
+			PubKey:          hex.EncodeToString(keys[index].PublicKey.Bytes()),
+		})
+	}
+	txInfos = append(txInfos, &txtypes.DepositTxInfo{
+		TxType:          types.TxTypeDeposit,
+		AccountNameHash: accountNameHash(aliceIndex),
+		AssetId:         0,
+		AssetAmount:     big.NewInt(1000000),
+		AccountIndex:    aliceIndex,
+	})
+	transfer := &txtypes.TransferTxInfo{
+		FromAccountIndex:  aliceIndex,
+		ToAccountIndex:    bobIndex,
+		ToAccountNameHash: hex.EncodeToString(accountNameHash(bobIndex)),
+		AssetId:           0,
+		AssetAmount:       big.NewInt(100000),
+		GasAccountIndex:   gasAccountIndex,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: big.NewInt(100),
+		CallDataHash:      []byte{},
+		ExpiredAt:         testExpiredAt,
+		Nonce:             0,
+		ChainId:           types.DefaultChainId,
+	}
+	transfer.Sig = signTxInfo(t, keys[aliceIndex], transfer)
+	withdraw := &txtypes.WithdrawTxInfo{
+		FromAccountIndex:  bobIndex,
+		AssetId:           0,
+		AssetAmount:       big.NewInt(50000),
+		GasAccountIndex:   gasAccountIndex,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: big.NewInt(100),
+		ToAddress:         "0x299d17c8b4e9967385dc9a3bb78f2a43f5a13bd5",
+		ExpiredAt:         testExpiredAt,
+		Nonce:             0,
+		ChainId:           types.DefaultChainId,
+	}
+	withdraw.Sig = signTxInfo(t, keys[bobIndex], withdraw)
+	return append(txInfos, transfer, withdraw)
+}
+
+func TestConvertTxInfoHash(t *testing.T) {
+	keys := newTxTester(t).keys
+	for _, txInfo := range testTxInfos(t, keys) {
+		oTx, err := ConvertTxInfo(txInfo)
+		assert.Nil(t, err)
+		assert.Equal(t, uint8(txInfo.GetTxType()), oTx.TxType)
+		if !IsLayer2Tx(oTx.TxType) {
+			continue
+		}
+		expected, err := txInfo.Hash(mimc.NewMiMC())
+		assert.Nil(t, err)
+		hashVal, err := ComputeTxHash(types.DefaultChainId, oTx)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, hashVal)
+	}
+}
+
+func TestBuildBlock(t *testing.T) {
+	keys := newTxTester(t).keys
+	txInfos := testTxInfos(t, keys)
+	txsCount := len(txInfos) + 2
+
+	state, err := NewState()
+	assert.Nil(t, err)
+	oldStateRoot := state.StateRoot()
+	oBlock, err := BuildBlock(state, types.DefaultChainId, 1, testCreatedAt, txsCount, gasAccountIndex, testGasAssetIds, txInfos)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(types.DefaultChainId), oBlock.ChainId)
+	assert.Equal(t, oldStateRoot, oBlock.OldStateRoot)
+	assert.Equal(t, state.StateRoot(), oBlock.NewStateRoot)
+	assert.Equal(t, txsCount, len(oBlock.Txs))
+	for i := len(txInfos); i < txsCount; i++ {
+		assert.Equal(t, uint8(types.TxTypeEmptyTx), oBlock.Txs[i].TxType)
+	}
+	assert.Equal(t, int64(200), state.Account(gasAccountIndex).Asset(0).Balance.Int64())
+
+	witness, err := circuit.SetBlockWitness(oBlock)
+	assert.Nil(t, err)
+	witness.TxsCount = txsCount
+	witness.GasAssetIds = testGasAssetIds
+	witness.GasAccountIndex = gasAccountIndex
+
+	blockConstraints := circuit.GetBlockConstraints(txsCount, testGasAssetIds, gasAccountIndex)
+
+	err = test.IsSolved(&blockConstraints, &witness, ecc.BN254, backend.GROTH16, backend.WithHints(types.Keccak256))
+	assert.Nil(t, err)
+
+	// the txs are signed for the chain id of the block
+	witness.ChainId = 2
+	err = test.IsSolved(&blockConstraints, &witness, ecc.BN254, backend.GROTH16, backend.WithHints(types.Keccak256))
+	assert.NotNil(t, err)
+	witness.ChainId = oBlock.ChainId
+
+	// a different commitment must be rejected
+	witness.BlockCommitment = 1
+	err = test.IsSolved(&blockConstraints, &witness, ecc.BN254, backend.GROTH16, backend.WithHints(types.Keccak256))
+	assert.NotNil(t, err)
+}
+
+func TestBuildBlockErrors(t *testing.T) {
+	keys := newTxTester(t).keys
+	txInfos := testTxInfos(t, keys)
+
+	state, err := NewState()
+	assert.Nil(t, err)
+	_, err = BuildBlock(state, types.DefaultChainId, 1, testCreatedAt, len(txInfos)-1, gasAccountIndex, testGasAssetIds, txInfos)
+	assert.Equal(t, ErrTooManyTxs, err)
+	_, err = BuildBlock(state, 0, 1, testCreatedAt, len(txInfos), gasAccountIndex, testGasAssetIds, txInfos)
+	assert.Equal(t, ErrInvalidChainId, err)
+
+	// the txs are signed for chain 1, they are rejected on chain 2
+	otherState, err := NewState()
+	assert.Nil(t, err)
+	_, err = BuildBlock(otherState, 2, 1, testCreatedAt, len(txInfos), gasAccountIndex, testGasAssetIds, txInfos)
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	// the transfer is signed by alice, but bob's key is used
+	txInfos[4].(*txtypes.TransferTxInfo).Sig = signTxInfo(t, keys[bobIndex], txInfos[4])
+	_, err = BuildBlock(state, types.DefaultChainId, 1, testCreatedAt, len(txInfos), gasAccountIndex, testGasAssetIds, txInfos)
+	assert.Equal(t, ErrInvalidSignature, err)
+}
+
+func TestBuildBlockRevert(t *testing.T) {
+	keys := newTxTester(t).keys
+	txInfos := testTxInfos(t, keys)
+	// the withdraw is signed by alice, but bob's key is used
+	txInfos[5].(*txtypes.WithdrawTxInfo).Sig = signTxInfo(t, keys[aliceIndex], txInfos[5])
+
+	// new accounts and asset trees are dropped
+	state, err := NewState()
+	assert.Nil(t, err)
+	emptyStateRoot := state.StateRoot()
+	_, err = BuildBlock(state, types.DefaultChainId, 1, testCreatedAt, len(txInfos), gasAccountIndex, testGasAssetIds, txInfos)
+	assert.Equal(t, ErrInvalidSignature, err)
+	assert.Equal(t, emptyStateRoot, state.StateRoot())
+	assert.Equal(t, 0, len(state.Accounts))
+	assert.Equal(t, 0, len(state.AssetTrees))
+
+	// updated accounts and assets are restored
+	_, err = BuildBlock(state, types.DefaultChainId, 1, testCreatedAt, 4, gasAccountIndex, testGasAssetIds, txInfos[:4])
+	assert.Nil(t, err)
+	stateRoot := state.StateRoot()
+	alice, bob := state.Account(aliceIndex), state.Account(bobIndex)
+	_, err = BuildBlock(state, types.DefaultChainId, 2, testCreatedAt, 2, gasAccountIndex, testGasAssetIds, txInfos[4:])
+	assert.Equal(t, ErrInvalidSignature, err)
+	assert.Equal(t, stateRoot, state.StateRoot())
+	assert.Equal(t, alice, state.Account(aliceIndex))
+	assert.Equal(t, bob, state.Account(bobIndex))
+	assert.Equal(t, 1, len(state.AssetTrees))
+
+	// the state is still usable
+	txInfos[5].(*txtypes.WithdrawTxInfo).Sig = signTxInfo(t, keys[bobIndex], txInfos[5])
+	_, err = BuildBlock(state, types.DefaultChainId, 2, testCreatedAt, 2, gasAccountIndex, testGasAssetIds, txInfos[4:])
+	assert.Nil(t, err)
+	assert.NotEqual(t, stateRoot, state.StateRoot())
+}
+
+func TestLoadState(t *testing.T) {
+	keys := newTxTester(t).keys
+	state, err := NewState()
+	assert.Nil(t, err)
+	_, err = BuildBlock(state, types.DefaultChainId, 1, testCreatedAt, 8, gasAccountIndex, testGasAssetIds, testTxInfos(t, keys))
+	assert.Nil(t, err)
+
+	loaded, err := LoadState(state.AccountTree, state.AssetTrees, state.NftTree, state.Accounts, state.Nfts)
+	assert.Nil(t, err)
+	assert.Equal(t, state.StateRoot(), loaded.StateRoot())
+
+	accounts := make(map[int64]*Account)
+	for index, account := range state.Accounts {
+		accounts[index] = account.Copy()
+	}
+	accounts[aliceIndex].AssetsInfo[0].Balance = big.NewInt(1)
+	_, err = LoadState(state.AccountTree, state.AssetTrees, state.NftTree, accounts, state.Nfts)
+	assert.Equal(t, ErrLeafMismatch, err)
+
+	accounts = map[int64]*Account{bobIndex: state.Account(aliceIndex)}
+	_, err = LoadState(state.AccountTree, state.AssetTrees, state.NftTree, accounts, state.Nfts)
+	assert.Equal(t, ErrInvalidAccountIndex, err)
+}
+
+func TestBuildBlockWithConfig(t *testing.T) {
+	keys := newTxTester(t).keys
+	txInfos := testTxInfos(t, keys)
+	txsCount := len(txInfos)
+	config := circuit.DefaultConfig()
+	config.AccountMerkleLevels = 8
+	config.AssetMerkleLevels = 4
+	config.NftMerkleLevels = 8
+
+	state, err := NewStateWithConfig(config)
+	assert.Nil(t, err)
+	defaultState, err := NewState()
+	assert.Nil(t, err)
+	assert.NotEqual(t, defaultState.StateRoot(), state.StateRoot())
+	oBlock, err := BuildBlock(state, types.DefaultChainId, 1, testCreatedAt, txsCount, gasAccountIndex, testGasAssetIds, txInfos)
+	assert.Nil(t, err)
+	assert.Nil(t, config.CheckBlock(oBlock))
+
+	_, err = circuit.SetBlockWitness(oBlock)
+	assert.Equal(t, circuit.ErrInvalidMerkleProofs, err)
+	witness, err := circuit.SetBlockWitnessWithConfig(config, oBlock)
+	assert.Nil(t, err)
+	blockConstraints, err := circuit.NewBlockConstraints(config, txsCount, testGasAssetIds, gasAccountIndex)
+	assert.Nil(t, err)
+	err = test.IsSolved(&blockConstraints, &witness, ecc.BN254, backend.GROTH16, backend.WithHints(types.Keccak256))
+	assert.Nil(t, err)
+
+	loaded, err := LoadStateWithConfig(config, state.AccountTree, state.AssetTrees, state.NftTree, state.Accounts, state.Nfts)
+	assert.Nil(t, err)
+	assert.Equal(t, state.StateRoot(), loaded.StateRoot())
+	_, err = LoadState(state.AccountTree, state.AssetTrees, state.NftTree, state.Accounts, state.Nfts)
+	assert.Equal(t, ErrInvalidTree, err)
+
+	// indexes beyond the smaller trees are rejected
+	_, err = NewExecutor(state, types.DefaultChainId, testCreatedAt, config.LastAccountIndex()+1, testGasAssetIds)
+	assert.Equal(t, ErrInvalidAccountIndex, err)
+}
@@ -0,0 +1,400 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor
+
+import (
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+/*
+	ConvertTxInfo: convert the tx info used by the wallet into the circuit tx,
+	amounts and fees are packed the same way as they are hashed by txtypes
+*/
+func ConvertTxInfo(txInfo txtypes.TxInfo) (oTx *circuit.Tx, err error) {
+	oTx = &circuit.Tx{
+		Signature: types.EmptySignature(),
+	}
+	switch info := txInfo.(type) {
+	case *txtypes.RegisterZnsTxInfo:
+		oTx.TxType = types.TxTypeRegisterZns
+		oTx.RegisterZnsTxInfo, err = convertRegisterZnsTxInfo(info)
+	case *txtypes.DepositTxInfo:
+		oTx.TxType = types.TxTypeDeposit
+		oTx.DepositTxInfo, err = convertDepositTxInfo(info)
+	case *txtypes.DepositNftTxInfo:
+		oTx.TxType = types.TxTypeDepositNft
+		oTx.DepositNftTxInfo, err = convertDepositNftTxInfo(info)
+	case *txtypes.TransferTxInfo:
+		oTx.TxType = types.TxTypeTransfer
+		oTx.TransferTxInfo, err = convertTransferTxInfo(info)
+	case *txtypes.WithdrawTxInfo:
+		oTx.TxType = types.TxTypeWithdraw
+		oTx.WithdrawTxInfo, err = convertWithdrawTxInfo(info)
+	case *txtypes.CreateCollectionTxInfo:
+		oTx.TxType = types.TxTypeCreateCollection
+		oTx.CreateCollectionTxInfo, err = convertCreateCollectionTxInfo(info)
+	case *txtypes.MintNftTxInfo:
+		oTx.TxType = types.TxTypeMintNft
+		oTx.MintNftTxInfo, err = convertMintNftTxInfo(info)
+	case *txtypes.TransferNftTxInfo:
+		oTx.TxType = types.TxTypeTransferNft
+		oTx.TransferNftTxInfo, err = convertTransferNftTxInfo(info)
+	case *txtypes.AtomicMatchTxInfo:
+		oTx.TxType = types.TxTypeAtomicMatch
+		oTx.AtomicMatchTxInfo, err = convertAtomicMatchTxInfo(info)
+	case *txtypes.CancelOfferTxInfo:
+		oTx.TxType = types.TxTypeCancelOffer
+		oTx.CancelOfferTxInfo, err = convertCancelOfferTxInfo(info)
+	case *txtypes.WithdrawNftTxInfo:
+		oTx.TxType = types.TxTypeWithdrawNft
+		oTx.WithdrawNftTxInfo, err = convertWithdrawNftTxInfo(info)
+	case *txtypes.FullExitTxInfo:
+		oTx.TxType = types.TxTypeFullExit
+		oTx.FullExitTxInfo, err = convertFullExitTxInfo(info)
+	case *txtypes.FullExitNftTxInfo:
+		oTx.TxType = types.TxTypeFullExitNft
+		oTx.FullExitNftTxInfo, err = convertFullExitNftTxInfo(info)
+	default:
+		log.Println("[ConvertTxInfo] invalid tx info")
+		return nil, ErrInvalidTxType
+	}
+	if err != nil {
+		log.Println("[ConvertTxInfo] unable to convert tx info:", err)
+		return nil, err
+	}
+	if IsLayer2Tx(oTx.TxType) {
+		oTx.Nonce = txInfo.GetNonce()
+		oTx.ExpiredAt = txInfo.GetExpiredAt()
+		oTx.Signature, err = parseSignature(layer2Signature(txInfo))
+		if err != nil {
+			log.Println("[ConvertTxInfo] invalid signature:", err)
+			return nil, err
+		}
+	}
+	return oTx, nil
+}
+
+func layer2Signature(txInfo txtypes.TxInfo) []byte {
+	switch info := txInfo.(type) {
+	case *txtypes.TransferTxInfo:
+		return info.Sig
+	case *txtypes.WithdrawTxInfo:
+		return info.Sig
+	case *txtypes.CreateCollectionTxInfo:
+		return info.Sig
+	case *txtypes.MintNftTxInfo:
+		return info.Sig
+	case *txtypes.TransferNftTxInfo:
+		return info.Sig
+	case *txtypes.AtomicMatchTxInfo:
+		return info.Sig
+	case *txtypes.CancelOfferTxInfo:
+		return info.Sig
+	case *txtypes.WithdrawNftTxInfo:
+		return info.Sig
+	default:
+		return nil
+	}
+}
+
+func parseSignature(sigBytes []byte) (sig *circuit.Signature, err error) {
+	if len(sigBytes) == 0 {
+		return nil, ErrInvalidSignature
+	}
+	sig = new(circuit.Signature)
+	_, err = sig.SetBytes(sigBytes)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	return sig, nil
+}
+
+func packAmount(amount *big.Int) (int64, error) {
+	if amount == nil {
+		return 0, ErrInvalidAssetAmount
+	}
+	return txtypes.ToPackedAmount(amount)
+}
+
+func packFee(fee *big.Int) (int64, error) {
+	if fee == nil {
+		return 0, ErrInvalidAssetAmount
+	}
+	return txtypes.ToPackedFee(fee)
+}
+
+func copyBigInt(a *big.Int) *big.Int {
+	if a == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(a)
+}
+
+func convertRegisterZnsTxInfo(info *txtypes.RegisterZnsTxInfo) (tx *types.RegisterZnsTx, err error) {
+	pk, err := txtypes.ParsePublicKey(info.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	// the name is committed as a big endian integer, a right padded name would not fit into the field
+	return &types.RegisterZnsTx{
+		AccountIndex:    info.AccountIndex,
+		AccountName:     []byte(info.AccountName),
+		AccountNameHash: common.CopyBytes(info.AccountNameHash),
+		PubKey:          pk,
+	}, nil
+}
+
+func convertDepositTxInfo(info *txtypes.DepositTxInfo) (tx *types.DepositTx, err error) {
+	return &types.DepositTx{
+		AccountIndex:    info.AccountIndex,
+		AccountNameHash: common.CopyBytes(info.AccountNameHash),
+		AssetId:         info.AssetId,
+		AssetAmount:     copyBigInt(info.AssetAmount),
+	}, nil
+}
+
+func convertDepositNftTxInfo(info *txtypes.DepositNftTxInfo) (tx *types.DepositNftTx, err error) {
+	return &types.DepositNftTx{
+		AccountIndex:        info.AccountIndex,
+		NftIndex:            info.NftIndex,
+		NftL1Address:        info.NftL1Address,
+		AccountNameHash:     common.CopyBytes(info.AccountNameHash),
+		NftContentHash:      common.CopyBytes(info.NftContentHash),
+		NftL1TokenId:        copyBigInt(info.NftL1TokenId),
+		CreatorAccountIndex: info.CreatorAccountIndex,
+		CreatorTreasuryRate: info.CreatorTreasuryRate,
+		CollectionId:        info.CollectionId,
+	}, nil
+}
+
+func convertTransferTxInfo(info *txtypes.TransferTxInfo) (tx *types.TransferTx, err error) {
+	assetAmount, err := packAmount(info.AssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	gasFeeAssetAmount, err := packFee(info.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.TransferTx{
+		FromAccountIndex:  info.FromAccountIndex,
+		ToAccountIndex:    info.ToAccountIndex,
+		ToAccountNameHash: common.FromHex(info.ToAccountNameHash),
+		AssetId:           info.AssetId,
+		AssetAmount:       assetAmount,
+		GasAccountIndex:   info.GasAccountIndex,
+		GasFeeAssetId:     info.GasFeeAssetId,
+		GasFeeAssetAmount: gasFeeAssetAmount,
+		CallDataHash:      common.CopyBytes(info.CallDataHash),
+	}, nil
+}
+
+func convertWithdrawTxInfo(info *txtypes.WithdrawTxInfo) (tx *types.WithdrawTx, err error) {
+	gasFeeAssetAmount, err := packFee(info.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.WithdrawTx{
+		FromAccountIndex:  info.FromAccountIndex,
+		AssetId:           info.AssetId,
+		AssetAmount:       copyBigInt(info.AssetAmount),
+		GasAccountIndex:   info.GasAccountIndex,
+		GasFeeAssetId:     info.GasFeeAssetId,
+		GasFeeAssetAmount: gasFeeAssetAmount,
+		ToAddress:         new(big.Int).SetBytes(txtypes.PaddingAddressToBytes32(info.ToAddress)),
+	}, nil
+}
+
+func convertCreateCollectionTxInfo(info *txtypes.CreateCollectionTxInfo) (tx *types.CreateCollectionTx, err error) {
+	gasFeeAssetAmount, err := packFee(info.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.CreateCollectionTx{
+		AccountIndex:      info.AccountIndex,
+		CollectionId:      info.CollectionId,
+		GasAccountIndex:   info.GasAccountIndex,
+		GasFeeAssetId:     info.GasFeeAssetId,
+		GasFeeAssetAmount: gasFeeAssetAmount,
+		ExpiredAt:         info.ExpiredAt,
+		Nonce:             info.Nonce,
+	}, nil
+}
+
+func convertMintNftTxInfo(info *txtypes.MintNftTxInfo) (tx *types.MintNftTx, err error) {
+	gasFeeAssetAmount, err := packFee(info.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.MintNftTx{
+		CreatorAccountIndex: info.CreatorAccountIndex,
+		ToAccountIndex:      info.ToAccountIndex,
+		ToAccountNameHash:   common.FromHex(info.ToAccountNameHash),
+		NftIndex:            info.NftIndex,
+		NftContentHash:      common.FromHex(info.NftContentHash),
+		CreatorTreasuryRate: info.CreatorTreasuryRate,
+		GasAccountIndex:     info.GasAccountIndex,
+		GasFeeAssetId:       info.GasFeeAssetId,
+		GasFeeAssetAmount:   gasFeeAssetAmount,
+		CollectionId:        info.NftCollectionId,
+		ExpiredAt:           info.ExpiredAt,
+	}, nil
+}
+
+func convertTransferNftTxInfo(info *txtypes.TransferNftTxInfo) (tx *types.TransferNftTx, err error) {
+	gasFeeAssetAmount, err := packFee(info.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.TransferNftTx{
+		FromAccountIndex:  info.FromAccountIndex,
+		ToAccountIndex:    info.ToAccountIndex,
+		ToAccountNameHash: common.FromHex(info.ToAccountNameHash),
+		NftIndex:          info.NftIndex,
+		GasAccountIndex:   info.GasAccountIndex,
+		GasFeeAssetId:     info.GasFeeAssetId,
+		GasFeeAssetAmount: gasFeeAssetAmount,
+		CallDataHash:      common.CopyBytes(info.CallDataHash),
+	}, nil
+}
+
+func convertOfferTxInfo(info *txtypes.OfferTxInfo) (tx *types.OfferTx, err error) {
+	if info == nil {
+		return nil, ErrInvalidOffer
+	}
+	assetAmount, err := packAmount(info.AssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := parseSignature(info.Sig)
+	if err != nil {
+		return nil, err
+	}
+	return &types.OfferTx{
+		Type:         info.Type,
+		OfferId:      info.OfferId,
+		AccountIndex: info.AccountIndex,
+		NftIndex:     info.NftIndex,
+		AssetId:      info.AssetId,
+		AssetAmount:  assetAmount,
+		ListedAt:     info.ListedAt,
+		ExpiredAt:    info.ExpiredAt,
+		TreasuryRate: info.TreasuryRate,
+		Sig:          sig,
+	}, nil
+}
+
+func convertAtomicMatchTxInfo(info *txtypes.AtomicMatchTxInfo) (tx *types.AtomicMatchTx, err error) {
+	buyOffer, err := convertOfferTxInfo(info.BuyOffer)
+	if err != nil {
+		return nil, err
+	}
+	sellOffer, err := convertOfferTxInfo(info.SellOffer)
+	if err != nil {
+		return nil, err
+	}
+	creatorAmount, err := packAmount(info.CreatorAmount)
+	if err != nil {
+		return nil, err
+	}
+	treasuryAmount, err := packAmount(info.TreasuryAmount)
+	if err != nil {
+		return nil, err
+	}
+	gasFeeAssetAmount, err := packFee(info.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.AtomicMatchTx{
+		AccountIndex:      info.AccountIndex,
+		BuyOffer:          buyOffer,
+		SellOffer:         sellOffer,
+		CreatorAmount:     creatorAmount,
+		TreasuryAmount:    treasuryAmount,
+		GasAccountIndex:   info.GasAccountIndex,
+		GasFeeAssetId:     info.GasFeeAssetId,
+		GasFeeAssetAmount: gasFeeAssetAmount,
+	}, nil
+}
+
+func convertCancelOfferTxInfo(info *txtypes.CancelOfferTxInfo) (tx *types.CancelOfferTx, err error) {
+	gasFeeAssetAmount, err := packFee(info.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.CancelOfferTx{
+		AccountIndex:      info.AccountIndex,
+		OfferId:           info.OfferId,
+		GasAccountIndex:   info.GasAccountIndex,
+		GasFeeAssetId:     info.GasFeeAssetId,
+		GasFeeAssetAmount: gasFeeAssetAmount,
+	}, nil
+}
+
+func convertWithdrawNftTxInfo(info *txtypes.WithdrawNftTxInfo) (tx *types.WithdrawNftTx, err error) {
+	gasFeeAssetAmount, err := packFee(info.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.WithdrawNftTx{
+		AccountIndex:           info.AccountIndex,
+		CreatorAccountIndex:    info.CreatorAccountIndex,
+		CreatorAccountNameHash: common.CopyBytes(info.CreatorAccountNameHash),
+		CreatorTreasuryRate:    info.CreatorTreasuryRate,
+		NftIndex:               info.NftIndex,
+		NftContentHash:         common.CopyBytes(info.NftContentHash),
+		NftL1Address:           info.NftL1Address,
+		NftL1TokenId:           copyBigInt(info.NftL1TokenId),
+		ToAddress:              info.ToAddress,
+		GasAccountIndex:        info.GasAccountIndex,
+		GasFeeAssetId:          info.GasFeeAssetId,
+		GasFeeAssetAmount:      gasFeeAssetAmount,
+		CollectionId:           info.CollectionId,
+	}, nil
+}
+
+func convertFullExitTxInfo(info *txtypes.FullExitTxInfo) (tx *types.FullExitTx, err error) {
+	return &types.FullExitTx{
+		AccountIndex:    info.AccountIndex,
+		AccountNameHash: common.CopyBytes(info.AccountNameHash),
+		AssetId:         info.AssetId,
+		AssetAmount:     copyBigInt(info.AssetAmount),
+	}, nil
+}
+
+func convertFullExitNftTxInfo(info *txtypes.FullExitNftTxInfo) (tx *types.FullExitNftTx, err error) {
+	return &types.FullExitNftTx{
+		AccountIndex:           info.AccountIndex,
+		AccountNameHash:        common.CopyBytes(info.AccountNameHash),
+		CreatorAccountIndex:    info.CreatorAccountIndex,
+		CreatorAccountNameHash: common.CopyBytes(info.CreatorAccountNameHash),
+		CreatorTreasuryRate:    info.CreatorTreasuryRate,
+		NftIndex:               info.NftIndex,
+		CollectionId:           info.CollectionId,
+		NftContentHash:         common.CopyBytes(info.NftContentHash),
+		NftL1Address:           info.NftL1Address,
+		NftL1TokenId:           copyBigInt(info.NftL1TokenId),
+	}, nil
+}
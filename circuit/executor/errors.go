@@ -0,0 +1,55 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidTxType             = errors.New("[Executor] invalid tx type")
+	ErrInvalidTxInfo             = errors.New("[Executor] tx info is nil")
+	ErrInvalidAccountIndex       = errors.New("[Executor] invalid account index")
+	ErrInvalidAssetId            = errors.New("[Executor] invalid asset id")
+	ErrInvalidNftIndex           = errors.New("[Executor] invalid nft index")
+	ErrInvalidOfferId            = errors.New("[Executor] invalid offer id")
+	ErrInvalidNonce              = errors.New("[Executor] invalid nonce")
+	ErrInvalidSignature          = errors.New("[Executor] invalid signature")
+	ErrTxExpired                 = errors.New("[Executor] tx is expired")
+	ErrAccountNameHashMismatch   = errors.New("[Executor] account name hash mismatch")
+	ErrAccountNotEmpty           = errors.New("[Executor] account is not empty")
+	ErrInsufficientBalance       = errors.New("[Executor] insufficient balance")
+	ErrInvalidAssetAmount        = errors.New("[Executor] invalid asset amount")
+	ErrNftNotEmpty               = errors.New("[Executor] nft is not empty")
+	ErrNftMismatch               = errors.New("[Executor] nft info mismatch")
+	ErrInvalidNftContentHash     = errors.New("[Executor] invalid nft content hash")
+	ErrInvalidCollectionId       = errors.New("[Executor] invalid collection id")
+	ErrInvalidOffer              = errors.New("[Executor] invalid offer")
+	ErrOfferCanceledOrFinalized  = errors.New("[Executor] offer is canceled or finalized")
+	ErrGasAssetNotFound          = errors.New("[Executor] gas asset not found")
+	ErrInvalidGasAccount         = errors.New("[Executor] invalid gas account")
+	ErrExecutorFinalized         = errors.New("[Executor] block is already finalized")
+	ErrInvalidMerkleProofsLength = errors.New("[Executor] invalid merkle proofs length")
+	ErrInvalidTree               = errors.New("[Executor] invalid merkle tree")
+	ErrLeafMismatch              = errors.New("[Executor] leaf does not match the tree")
+	ErrTooManyTxs                = errors.New("[Executor] too many txs for the block")
+	ErrInvalidChainId            = errors.New("[Executor] invalid chain id")
+	ErrInvalidSnapshot           = errors.New("[Executor] invalid state snapshot")
+	ErrSnapshotFormatVersion     = errors.New("[Executor] unsupported state snapshot format version")
+	ErrSnapshotRootMismatch      = errors.New("[Executor] state snapshot root mismatch")
+)
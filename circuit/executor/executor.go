@@ -0,0 +1,424 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor
+
+import (
+	"log"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+/*
+	GasDelta: out-of-circuit version of circuit.GasDeltaConstraints
+*/
+type GasDelta struct {
+	AssetId      int64
+	BalanceDelta *big.Int
+}
+
+/*
+	TxResult: values returned by VerifyTransaction for a tx
+*/
+type TxResult struct {
+	TxType      uint8
+	IsOnChainOp bool
+	PubData     [types.PubDataSizePerTx]*big.Int
+	GasDeltas   [circuit.NbGasAssetsPerTx]*GasDelta
+	AccountRoot []byte
+	NftRoot     []byte
+	StateRoot   []byte
+}
+
+/*
+	BlockResult: values checked by VerifyBlock for a block
+*/
+type BlockResult struct {
+	ChainId         int64
+	CreatedAt       int64
+	OldStateRoot    []byte
+	NewStateRoot    []byte
+	Txs             []*circuit.Tx
+	TxResults       []*TxResult
+	OnChainOpsCount int64
+	NeedGas         bool
+	GasAccountIndex int64
+	GasAssetIds     []int64
+	GasDeltas       []*big.Int
+	Gas             *circuit.Gas
+}
+
+/*
+	Executor: applies txs to the state with exactly the same rules as VerifyBlock,
+	and fills the circuit witness of every tx on the way
+*/
+type Executor struct {
+	State           *State
+	ChainId         int64
+	CreatedAt       int64
+	GasAccountIndex int64
+	GasAssetIds     []int64
+
+	oldStateRoot    []byte
+	txs             []*circuit.Tx
+	txResults       []*TxResult
+	gasDeltas       []*big.Int
+	needGas         bool
+	onChainOpsCount int64
+	finalized       bool
+}
+
+func NewExecutor(state *State, chainId int64, createdAt int64, gasAccountIndex int64, gasAssetIds []int64) (*Executor, error) {
+	if state == nil {
+		log.Println("[NewExecutor] invalid state")
+		return nil, ErrInvalidTxInfo
+	}
+	// the chain id is a uint32 in the tx hashes
+	if chainId <= 0 || chainId >= 1<<32 {
+		log.Println("[NewExecutor] invalid chain id")
+		return nil, ErrInvalidChainId
+	}
+	if len(gasAssetIds) == 0 {
+		log.Println("[NewExecutor] gas asset ids should not be empty")
+		return nil, ErrGasAssetNotFound
+	}
+	for _, assetId := range gasAssetIds {
+		if assetId < 0 || assetId > state.Config.LastAccountAssetId() {
+			return nil, ErrInvalidAssetId
+		}
+	}
+	if gasAccountIndex < 0 || gasAccountIndex > state.Config.LastAccountIndex() {
+		return nil, ErrInvalidAccountIndex
+	}
+	gasDeltas := make([]*big.Int, len(gasAssetIds))
+	for i := range gasDeltas {
+		gasDeltas[i] = big.NewInt(0)
+	}
+	return &Executor{
+		State:           state,
+		ChainId:         chainId,
+		CreatedAt:       createdAt,
+		GasAccountIndex: gasAccountIndex,
+		GasAssetIds:     gasAssetIds,
+		oldStateRoot:    state.StateRoot(),
+		gasDeltas:       gasDeltas,
+	}, nil
+}
+
+/*
+	ExecuteBlock: apply all txs and the gas account update, txs are filled in place.
+	If a tx is rejected the txs before it are reverted, the state is left as it was before the block.
+*/
+func ExecuteBlock(
+	state *State,
+	chainId int64,
+	createdAt int64,
+	gasAccountIndex int64,
+	gasAssetIds []int64,
+	txs []*circuit.Tx,
+) (res *BlockResult, err error) {
+	e, err := NewExecutor(state, chainId, createdAt, gasAccountIndex, gasAssetIds)
+	if err != nil {
+		return nil, err
+	}
+	state.begin()
+	defer func() {
+		state.end(err)
+	}()
+	for _, oTx := range txs {
+		_, err = e.ApplyTx(oTx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e.Finalize()
+}
+
+/*
+	ApplyTx: verify the tx against the current state and apply it.
+	Only TxType, the tx info, Nonce, ExpiredAt and Signature are read from oTx,
+	the roots, accounts before, nft before and merkle proofs are filled by the executor.
+	The state is left untouched if the tx is rejected.
+*/
+func (e *Executor) ApplyTx(oTx *circuit.Tx) (res *TxResult, err error) {
+	if e.finalized {
+		return nil, ErrExecutorFinalized
+	}
+	if oTx == nil {
+		return nil, ErrInvalidTxInfo
+	}
+	if oTx.TxType == types.TxTypeEmptyTx {
+		res, err = e.applyEmptyTx(oTx)
+	} else {
+		res, err = e.applyTx(oTx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.txs = append(e.txs, oTx)
+	e.txResults = append(e.txResults, res)
+	if res.IsOnChainOp {
+		e.onChainOpsCount++
+	}
+	if IsLayer2Tx(oTx.TxType) {
+		e.needGas = true
+	}
+	return res, nil
+}
+
+func (e *Executor) applyEmptyTx(oTx *circuit.Tx) (res *TxResult, err error) {
+	*oTx = *circuit.EmptyTxWithConfig(e.State.Config, e.State.StateRoot())
+	res = &TxResult{
+		TxType:      types.TxTypeEmptyTx,
+		IsOnChainOp: false,
+		AccountRoot: e.State.AccountRoot(),
+		NftRoot:     e.State.NftRoot(),
+		StateRoot:   e.State.StateRoot(),
+	}
+	res.PubData, _ = circuit.ComputePubData(oTx)
+	for i := 0; i < circuit.NbGasAssetsPerTx; i++ {
+		res.GasDeltas[i] = &GasDelta{AssetId: e.GasAssetIds[0], BalanceDelta: big.NewInt(0)}
+	}
+	_, err = e.matchGasDeltas(res.GasDeltas)
+	return res, err
+}
+
+func (e *Executor) applyTx(oTx *circuit.Tx) (res *TxResult, err error) {
+	plan, err := buildTxPlan(e.State, oTx, e.GasAssetIds[0])
+	if err != nil {
+		log.Println("[ApplyTx] invalid tx:", err)
+		return nil, err
+	}
+
+	// simulate every slot on copies, each slot sees the updates of the previous ones
+	var (
+		accountsBefore [circuit.NbAccountsPerTx]*types.Account
+		accountsAfter  [circuit.NbAccountsPerTx]*Account
+		assetsAfter    [circuit.NbAccountsPerTx][circuit.NbAccountAssetsPerAccount]*types.AccountAsset
+	)
+	accounts := make(map[int64]*Account)
+	for i := 0; i < circuit.NbAccountsPerTx; i++ {
+		account, ok := accounts[plan.AccountIndexes[i]]
+		if !ok {
+			account = e.State.Account(plan.AccountIndexes[i])
+			accounts[plan.AccountIndexes[i]] = account
+		}
+		accountsBefore[i] = &types.Account{
+			AccountIndex:    account.AccountIndex,
+			AccountNameHash: common.CopyBytes(account.AccountNameHash),
+			AccountPk:       &eddsa.PublicKey{A: account.AccountPk.A},
+			Nonce:           account.Nonce,
+			CollectionNonce: account.CollectionNonce,
+		}
+		for j := 0; j < circuit.NbAccountAssetsPerAccount; j++ {
+			asset := account.Asset(plan.AssetIds[i][j])
+			accountsBefore[i].AssetsInfo[j] = copyAccountAsset(asset)
+			delta := plan.AssetDeltas[i][j]
+			asset.Balance = fieldAdd(asset.Balance, delta.BalanceDelta)
+			if delta.OfferIndex >= 0 {
+				asset.OfferCanceledOrFinalized.SetBit(asset.OfferCanceledOrFinalized, int(delta.OfferIndex), 1)
+			}
+			account.AssetsInfo[asset.AssetId] = asset
+			assetsAfter[i][j] = copyAccountAsset(asset)
+		}
+		if i == 0 {
+			if oTx.TxType == types.TxTypeRegisterZns {
+				account.AccountNameHash = common.CopyBytes(oTx.RegisterZnsTxInfo.AccountNameHash)
+				account.AccountPk = &eddsa.PublicKey{A: oTx.RegisterZnsTxInfo.PubKey.A}
+			}
+			if IsLayer2Tx(oTx.TxType) {
+				account.Nonce++
+			}
+			if oTx.TxType == types.TxTypeCreateCollection {
+				account.CollectionNonce++
+			}
+		}
+		accountsAfter[i] = account.Copy()
+	}
+	nftBefore := e.State.Nft(plan.NftIndex)
+	nftAfter := plan.NftAfter
+	if nftAfter == nil {
+		nftAfter = copyNft(nftBefore)
+	}
+
+	// slot 0 is committed first so its asset root is the one in the tree,
+	// the other asset roots are set again while committing
+	for i := 0; i < circuit.NbAccountsPerTx; i++ {
+		accountsBefore[i].AssetRoot = e.State.AssetRoot(plan.AccountIndexes[i])
+	}
+	err = verifyTx(oTx, accountsBefore, nftBefore, e.CreatedAt, e.ChainId, e.State.emptyAssetRoot)
+	if err != nil {
+		log.Println("[ApplyTx] unable to verify tx:", err)
+		return nil, err
+	}
+	pubData, err := circuit.ComputePubData(oTx)
+	if err != nil {
+		log.Println("[ApplyTx] unable to compute pub data:", err)
+		return nil, err
+	}
+	gasDeltas, err := e.matchGasDeltas(plan.GasDeltas)
+	if err != nil {
+		return nil, err
+	}
+
+	// commit every slot and collect the merkle proofs before each update
+	oTx.AccountRootBefore = e.State.AccountRoot()
+	oTx.NftRootBefore = e.State.NftRoot()
+	oTx.StateRootBefore = e.State.StateRoot()
+	for i := 0; i < circuit.NbAccountsPerTx; i++ {
+		accountIndex := plan.AccountIndexes[i]
+		accountsBefore[i].AssetRoot = e.State.AssetRoot(accountIndex)
+		for j := 0; j < circuit.NbAccountAssetsPerAccount; j++ {
+			oTx.MerkleProofsAccountAssetsBefore[i][j], err = e.State.AssetMerkleProofs(accountIndex, plan.AssetIds[i][j])
+			if err != nil {
+				return nil, err
+			}
+			err = e.State.updateAsset(accountIndex, assetsAfter[i][j])
+			if err != nil {
+				return nil, err
+			}
+		}
+		oTx.MerkleProofsAccountBefore[i], err = e.State.AccountMerkleProofs(accountIndex)
+		if err != nil {
+			return nil, err
+		}
+		err = e.State.updateAccount(accountsAfter[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	oTx.MerkleProofsNftBefore, err = e.State.NftMerkleProofs(plan.NftIndex)
+	if err != nil {
+		return nil, err
+	}
+	err = e.State.updateNft(nftAfter)
+	if err != nil {
+		return nil, err
+	}
+	oTx.AccountsInfoBefore = accountsBefore
+	oTx.NftBefore = nftBefore
+	oTx.StateRootAfter = e.State.StateRoot()
+
+	for i, delta := range gasDeltas {
+		e.gasDeltas[i] = fieldAdd(e.gasDeltas[i], delta)
+	}
+	return &TxResult{
+		TxType:      oTx.TxType,
+		IsOnChainOp: circuit.IsOnChainOp(oTx.TxType),
+		PubData:     pubData,
+		GasDeltas:   plan.GasDeltas,
+		AccountRoot: e.State.AccountRoot(),
+		NftRoot:     e.State.NftRoot(),
+		StateRoot:   e.State.StateRoot(),
+	}, nil
+}
+
+/*
+	matchGasDeltas: every tx should pay its gas in one of the block gas assets, same as VerifyBlock
+*/
+func (e *Executor) matchGasDeltas(txGasDeltas [circuit.NbGasAssetsPerTx]*GasDelta) (gasDeltas []*big.Int, err error) {
+	gasDeltas = make([]*big.Int, len(e.GasAssetIds))
+	matched := false
+	for i, assetId := range e.GasAssetIds {
+		gasDeltas[i] = big.NewInt(0)
+		for _, delta := range txGasDeltas {
+			if delta.AssetId == assetId {
+				gasDeltas[i] = fieldAdd(gasDeltas[i], delta.BalanceDelta)
+				matched = true
+			}
+		}
+	}
+	if !matched {
+		log.Println("[matchGasDeltas] gas asset is not supported")
+		return nil, ErrGasAssetNotFound
+	}
+	return gasDeltas, nil
+}
+
+/*
+	Finalize: apply the collected gas to the gas account, no tx can be applied afterwards
+*/
+func (e *Executor) Finalize() (res *BlockResult, err error) {
+	if e.finalized {
+		return nil, ErrExecutorFinalized
+	}
+	gasAccount := e.State.Account(e.GasAccountIndex)
+	if e.needGas && bytesEqual(gasAccount.AccountNameHash, nil) {
+		log.Println("[Finalize] gas account is not registered")
+		return nil, ErrInvalidGasAccount
+	}
+	gas := &circuit.Gas{
+		GasAssetCount: len(e.GasAssetIds),
+		AccountInfoBefore: &types.GasAccount{
+			AccountIndex:    gasAccount.AccountIndex,
+			AccountNameHash: common.CopyBytes(gasAccount.AccountNameHash),
+			AccountPk:       &eddsa.PublicKey{A: gasAccount.AccountPk.A},
+			Nonce:           gasAccount.Nonce,
+			CollectionNonce: gasAccount.CollectionNonce,
+			AssetRoot:       e.State.AssetRoot(e.GasAccountIndex),
+			AssetsInfo:      make([]*types.AccountAsset, len(e.GasAssetIds)),
+		},
+		MerkleProofsAccountAssetsBefore: make([][][]byte, len(e.GasAssetIds)),
+	}
+	for i, assetId := range e.GasAssetIds {
+		gas.AccountInfoBefore.AssetsInfo[i] = gasAccount.Asset(assetId)
+		gas.MerkleProofsAccountAssetsBefore[i], err = e.State.AssetMerkleProofs(e.GasAccountIndex, assetId)
+		if err != nil {
+			return nil, err
+		}
+		if !e.needGas {
+			continue
+		}
+		asset := gasAccount.Asset(assetId)
+		asset.Balance = fieldAdd(asset.Balance, e.gasDeltas[i])
+		gasAccount.AssetsInfo[assetId] = asset
+		err = e.State.updateAsset(e.GasAccountIndex, asset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	gas.MerkleProofsAccountBefore, err = e.State.AccountMerkleProofs(e.GasAccountIndex)
+	if err != nil {
+		return nil, err
+	}
+	if e.needGas {
+		err = e.State.updateAccount(gasAccount)
+		if err != nil {
+			return nil, err
+		}
+	}
+	e.finalized = true
+	return &BlockResult{
+		ChainId:         e.ChainId,
+		CreatedAt:       e.CreatedAt,
+		OldStateRoot:    e.oldStateRoot,
+		NewStateRoot:    e.State.StateRoot(),
+		Txs:             e.txs,
+		TxResults:       e.txResults,
+		OnChainOpsCount: e.onChainOpsCount,
+		NeedGas:         e.needGas,
+		GasAccountIndex: e.GasAccountIndex,
+		GasAssetIds:     e.GasAssetIds,
+		GasDeltas:       e.gasDeltas,
+		Gas:             gas,
+	}, nil
+}
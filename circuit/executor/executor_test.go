@@ -0,0 +1,720 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark/backend"
+	gmimc "github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/merkleTree"
+	"github.com/bnb-chain/zkbnb-crypto/util"
+)
+
+const (
+	testCreatedAt = 1654656781000
+	testExpiredAt = 1654656791000
+
+	gasAccountIndex = 0
+	aliceIndex      = 1
+	bobIndex        = 2
+)
+
+var testGasAssetIds = []int64{0, 1}
+
+type TxResultConstraints struct {
+	Tx          circuit.TxConstraints
+	CreatedAt   circuit.Variable
+	ChainId     circuit.Variable
+	OldRoots    [types.NbRoots]circuit.Variable
+	IsOnChainOp circuit.Variable
+	PubData     [types.PubDataSizePerTx]circuit.Variable
+	Roots       [types.NbRoots]circuit.Variable
+	GasDeltas   [circuit.NbGasAssetsPerTx]circuit.GasDeltaConstraints
+	GasAssetIds []int64        `gnark:"-"`
+	Config      circuit.Config `gnark:"-"`
+}
+
+func (c TxResultConstraints) Define(api circuit.API) error {
+	hFunc, err := gmimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	isOnChainOp, pubData, roots, gasDeltas, err := circuit.VerifyTransaction(
+		api, c.Config, c.Tx, hFunc, c.CreatedAt, c.ChainId, c.GasAssetIds, c.OldRoots)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(isOnChainOp, c.IsOnChainOp)
+	for i := 0; i < types.PubDataSizePerTx; i++ {
+		api.AssertIsEqual(pubData[i], c.PubData[i])
+	}
+	for i := 0; i < types.NbRoots; i++ {
+		api.AssertIsEqual(roots[i], c.Roots[i])
+	}
+	for i := 0; i < circuit.NbGasAssetsPerTx; i++ {
+		api.AssertIsEqual(gasDeltas[i].AssetId, c.GasDeltas[i].AssetId)
+		api.AssertIsEqual(gasDeltas[i].BalanceDelta, c.GasDeltas[i].BalanceDelta)
+	}
+	return nil
+}
+
+type GasResultConstraints struct {
+	Gas            circuit.GasConstraints
+	NeedGas        circuit.Variable
+	GasDeltas      []circuit.Variable
+	AccountRoot    circuit.Variable
+	NewAccountRoot circuit.Variable
+	Config         circuit.Config `gnark:"-"`
+}
+
+func (c GasResultConstraints) Define(api circuit.API) error {
+	hFunc, err := gmimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	newAccountRoot, err := circuit.VerifyGas(api, c.Config, c.Gas, c.NeedGas, c.GasDeltas, hFunc, c.AccountRoot)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(newAccountRoot, c.NewAccountRoot)
+	return nil
+}
+
+type txTester struct {
+	t        *testing.T
+	executor *Executor
+	keys     map[int64]*curve.PrivateKey
+}
+
+func newTxTester(t *testing.T) *txTester {
+	return newTxTesterWithConfig(t, circuit.DefaultConfig())
+}
+
+func newTxTesterWithConfig(t *testing.T, config circuit.Config) *txTester {
+	state, err := NewStateWithConfig(config)
+	assert.Nil(t, err)
+	executor, err := NewExecutor(state, types.DefaultChainId, testCreatedAt, gasAccountIndex, testGasAssetIds)
+	assert.Nil(t, err)
+	keys := make(map[int64]*curve.PrivateKey)
+	for _, index := range []int64{gasAccountIndex, aliceIndex, bobIndex} {
+		keys[index], err = curve.GenerateEddsaPrivateKey(accountName(index))
+		assert.Nil(t, err)
+	}
+	return &txTester{t: t, executor: executor, keys: keys}
+}
+
+func accountName(index int64) string {
+	return []string{"gas.legend", "alice.legend", "bob.legend"}[index]
+}
+
+func accountNameHash(index int64) []byte {
+	return mimcHash(new(big.Int).SetBytes([]byte(accountName(index))))
+}
+
+func packedAmount(t *testing.T, amount int64) int64 {
+	packed, err := util.ToPackedAmount(big.NewInt(amount))
+	assert.Nil(t, err)
+	return packed
+}
+
+func packedFee(t *testing.T, fee int64) int64 {
+	packed, err := util.ToPackedFee(big.NewInt(fee))
+	assert.Nil(t, err)
+	return packed
+}
+
+func (tt *txTester) sign(oTx *circuit.Tx, signer int64) {
+	oTx.Nonce = tt.executor.State.Account(signer).Nonce
+	oTx.ExpiredAt = testExpiredAt
+	hashVal, err := ComputeTxHash(tt.executor.ChainId, oTx)
+	assert.Nil(tt.t, err)
+	oTx.Signature = tt.signHash(signer, hashVal)
+}
+
+func (tt *txTester) signHash(signer int64, hashVal []byte) *circuit.Signature {
+	sigBytes, err := tt.keys[signer].Sign(hashVal, mimc.NewMiMC())
+	assert.Nil(tt.t, err)
+	sig := new(circuit.Signature)
+	_, err = sig.SetBytes(sigBytes)
+	assert.Nil(tt.t, err)
+	return sig
+}
+
+/*
+	apply: apply the tx natively and check the circuit returns exactly the same values
+*/
+func (tt *txTester) apply(oTx *circuit.Tx) *TxResult {
+	if oTx.Signature == nil {
+		oTx.Signature = types.EmptySignature()
+	}
+	oldRoots := [types.NbRoots][]byte{tt.executor.State.AccountRoot(), tt.executor.State.NftRoot()}
+	res, err := tt.executor.ApplyTx(oTx)
+	assert.Nil(tt.t, err)
+	if err != nil {
+		tt.t.FailNow()
+	}
+
+	var witness TxResultConstraints
+	config := tt.executor.State.Config
+	witness.Tx, err = circuit.SetTxWitnessWithConfig(config, oTx)
+	assert.Nil(tt.t, err)
+	witness.CreatedAt = testCreatedAt
+	witness.ChainId = tt.executor.ChainId
+	witness.GasAssetIds = testGasAssetIds
+	witness.IsOnChainOp = 0
+	if res.IsOnChainOp {
+		witness.IsOnChainOp = 1
+	}
+	for i := 0; i < types.NbRoots; i++ {
+		witness.OldRoots[i] = oldRoots[i]
+	}
+	for i := 0; i < types.PubDataSizePerTx; i++ {
+		witness.PubData[i] = res.PubData[i]
+	}
+	witness.Roots[0] = res.AccountRoot
+	witness.Roots[1] = res.NftRoot
+	for i := 0; i < circuit.NbGasAssetsPerTx; i++ {
+		witness.GasDeltas[i].AssetId = res.GasDeltas[i].AssetId
+		witness.GasDeltas[i].BalanceDelta = res.GasDeltas[i].BalanceDelta
+	}
+	c := TxResultConstraints{
+		Tx:          circuit.GetZeroTxConstraintWithConfig(config),
+		GasAssetIds: testGasAssetIds,
+		Config:      config,
+	}
+	err = test.IsSolved(&c, &witness, ecc.BN254, backend.GROTH16)
+	assert.Nil(tt.t, err, "tx type %d", oTx.TxType)
+	assert.Equal(tt.t, ComputeStateRoot(res.AccountRoot, res.NftRoot), oTx.StateRootAfter)
+	return res
+}
+
+func (tt *txTester) registerZns(index int64) {
+	tt.apply(&circuit.Tx{
+		TxType: types.TxTypeRegisterZns,
+		RegisterZnsTxInfo: &circuit.RegisterZnsTx{
+			AccountIndex:    index,
+			AccountName:     []byte(accountName(index)),
+			AccountNameHash: accountNameHash(index),
+			PubKey:          &tt.keys[index].PublicKey,
+		},
+	})
+}
+
+func (tt *txTester) deposit(index, assetId int64, amount *big.Int) {
+	tt.apply(&circuit.Tx{
+		TxType: types.TxTypeDeposit,
+		DepositTxInfo: &circuit.DepositTx{
+			AccountIndex:    index,
+			AccountNameHash: accountNameHash(index),
+			AssetId:         assetId,
+			AssetAmount:     amount,
+		},
+	})
+}
+
+func TestExecutorMatchesCircuit(t *testing.T) {
+	tt := newTxTester(t)
+	state := tt.executor.State
+
+	// register zns
+	tt.registerZns(gasAccountIndex)
+	tt.registerZns(aliceIndex)
+	tt.registerZns(bobIndex)
+	assert.Equal(t, accountNameHash(aliceIndex), state.Account(aliceIndex).AccountNameHash)
+
+	// deposit
+	tt.deposit(aliceIndex, 0, big.NewInt(100000000))
+	tt.deposit(bobIndex, 0, big.NewInt(100000000))
+	tt.deposit(bobIndex, 1, big.NewInt(5000))
+
+	// transfer, the asset and the gas asset share the same slot of alice
+	transferTx := &circuit.Tx{
+		TxType: types.TxTypeTransfer,
+		TransferTxInfo: &circuit.TransferTx{
+			FromAccountIndex:  aliceIndex,
+			ToAccountIndex:    bobIndex,
+			ToAccountNameHash: accountNameHash(bobIndex),
+			AssetId:           0,
+			AssetAmount:       packedAmount(t, 1000000),
+			GasAccountIndex:   gasAccountIndex,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: packedFee(t, 1000),
+			CallDataHash:      []byte{1, 2, 3},
+		},
+	}
+	tt.sign(transferTx, aliceIndex)
+	res := tt.apply(transferTx)
+	assert.Equal(t, big.NewInt(1000), res.GasDeltas[0].BalanceDelta)
+	assert.Equal(t, big.NewInt(100000000-1000000-1000), state.Account(aliceIndex).Asset(0).Balance)
+	assert.Equal(t, big.NewInt(100000000+1000000), state.Account(bobIndex).Asset(0).Balance)
+	assert.Equal(t, int64(1), state.Account(aliceIndex).Nonce)
+
+	// withdraw
+	withdrawTx := &circuit.Tx{
+		TxType: types.TxTypeWithdraw,
+		WithdrawTxInfo: &circuit.WithdrawTx{
+			FromAccountIndex:  bobIndex,
+			AssetId:           1,
+			AssetAmount:       big.NewInt(1000),
+			GasAccountIndex:   gasAccountIndex,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: packedFee(t, 100),
+			ToAddress:         new(big.Int).SetBytes([]byte{0x29, 0x9d, 0x17, 0xc8}),
+		},
+	}
+	tt.sign(withdrawTx, bobIndex)
+	tt.apply(withdrawTx)
+	assert.Equal(t, big.NewInt(4000), state.Account(bobIndex).Asset(1).Balance)
+
+	// create collection
+	createCollectionTx := &circuit.Tx{
+		TxType: types.TxTypeCreateCollection,
+		CreateCollectionTxInfo: &circuit.CreateCollectionTx{
+			AccountIndex:      aliceIndex,
+			CollectionId:      0,
+			GasAccountIndex:   gasAccountIndex,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: packedAmount(t, 100),
+		},
+	}
+	tt.sign(createCollectionTx, aliceIndex)
+	tt.apply(createCollectionTx)
+	assert.Equal(t, int64(1), state.Account(aliceIndex).CollectionNonce)
+
+	// mint nft
+	contentHash := mimcHash(big.NewInt(42))
+	mintNftTx := &circuit.Tx{
+		TxType: types.TxTypeMintNft,
+		MintNftTxInfo: &circuit.MintNftTx{
+			CreatorAccountIndex: aliceIndex,
+			ToAccountIndex:      bobIndex,
+			ToAccountNameHash:   accountNameHash(bobIndex),
+			NftIndex:            0,
+			NftContentHash:      contentHash,
+			CreatorTreasuryRate: 30,
+			GasAccountIndex:     gasAccountIndex,
+			GasFeeAssetId:       0,
+			GasFeeAssetAmount:   packedFee(t, 100),
+			CollectionId:        0,
+		},
+	}
+	tt.sign(mintNftTx, aliceIndex)
+	tt.apply(mintNftTx)
+	assert.Equal(t, int64(bobIndex), state.Nft(0).OwnerAccountIndex)
+
+	// transfer nft
+	transferNftTx := &circuit.Tx{
+		TxType: types.TxTypeTransferNft,
+		TransferNftTxInfo: &circuit.TransferNftTx{
+			FromAccountIndex:  bobIndex,
+			ToAccountIndex:    aliceIndex,
+			ToAccountNameHash: accountNameHash(aliceIndex),
+			NftIndex:          0,
+			GasAccountIndex:   gasAccountIndex,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: packedFee(t, 100),
+			CallDataHash:      []byte{4, 5, 6},
+		},
+	}
+	tt.sign(transferNftTx, bobIndex)
+	tt.apply(transferNftTx)
+	assert.Equal(t, int64(aliceIndex), state.Nft(0).OwnerAccountIndex)
+
+	// cancel offer
+	cancelOfferTx := &circuit.Tx{
+		TxType: types.TxTypeCancelOffer,
+		CancelOfferTxInfo: &circuit.CancelOfferTx{
+			AccountIndex:      aliceIndex,
+			OfferId:           129,
+			GasAccountIndex:   gasAccountIndex,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: packedFee(t, 100),
+		},
+	}
+	tt.sign(cancelOfferTx, aliceIndex)
+	tt.apply(cancelOfferTx)
+	assert.Equal(t, big.NewInt(2), state.Account(aliceIndex).Asset(1).OfferCanceledOrFinalized)
+
+	// atomic match, bob submits his own buy offer, alice is both the seller and the creator
+	buyOffer := &types.OfferTx{
+		Type:         0,
+		OfferId:      0,
+		AccountIndex: bobIndex,
+		NftIndex:     0,
+		AssetId:      0,
+		AssetAmount:  packedAmount(t, 10000),
+		ListedAt:     testCreatedAt,
+		ExpiredAt:    testExpiredAt,
+		TreasuryRate: 200,
+	}
+	buyOffer.Sig = tt.signHash(bobIndex, ComputeOfferHash(tt.executor.ChainId, buyOffer))
+	sellOffer := &types.OfferTx{
+		Type:         1,
+		OfferId:      130,
+		AccountIndex: aliceIndex,
+		NftIndex:     0,
+		AssetId:      0,
+		AssetAmount:  packedAmount(t, 10000),
+		ListedAt:     testCreatedAt,
+		ExpiredAt:    testExpiredAt,
+		TreasuryRate: 200,
+	}
+	sellOffer.Sig = tt.signHash(aliceIndex, ComputeOfferHash(tt.executor.ChainId, sellOffer))
+	atomicMatchTx := &circuit.Tx{
+		TxType: types.TxTypeAtomicMatch,
+		AtomicMatchTxInfo: &circuit.AtomicMatchTx{
+			AccountIndex:      bobIndex,
+			BuyOffer:          buyOffer,
+			SellOffer:         sellOffer,
+			CreatorAmount:     packedAmount(t, 30),
+			TreasuryAmount:    packedAmount(t, 200),
+			GasAccountIndex:   gasAccountIndex,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: packedFee(t, 100),
+		},
+	}
+	// an offer signed for another chain is rejected, even in a tx signed for this chain
+	otherChainOffer := *sellOffer
+	otherChainOffer.Sig = tt.signHash(aliceIndex, ComputeOfferHash(tt.executor.ChainId+1, sellOffer))
+	replayInfo := *atomicMatchTx.AtomicMatchTxInfo
+	replayInfo.SellOffer = &otherChainOffer
+	replayTx := &circuit.Tx{TxType: types.TxTypeAtomicMatch, AtomicMatchTxInfo: &replayInfo}
+	tt.sign(replayTx, bobIndex)
+	_, err := tt.executor.ApplyTx(replayTx)
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	aliceBalance := state.Account(aliceIndex).Asset(0).Balance
+	bobBalance := state.Account(bobIndex).Asset(0).Balance
+	tt.sign(atomicMatchTx, bobIndex)
+	tt.apply(atomicMatchTx)
+	assert.Equal(t, int64(bobIndex), state.Nft(0).OwnerAccountIndex)
+	assert.Equal(t, new(big.Int).Add(aliceBalance, big.NewInt(10000-200)), state.Account(aliceIndex).Asset(0).Balance)
+	assert.Equal(t, new(big.Int).Sub(bobBalance, big.NewInt(10000+100)), state.Account(bobIndex).Asset(0).Balance)
+	assert.Equal(t, big.NewInt(1), state.Account(bobIndex).Asset(0).OfferCanceledOrFinalized)
+	assert.Equal(t, big.NewInt(6), state.Account(aliceIndex).Asset(1).OfferCanceledOrFinalized)
+
+	// withdraw nft
+	withdrawNftTx := &circuit.Tx{
+		TxType: types.TxTypeWithdrawNft,
+		WithdrawNftTxInfo: &circuit.WithdrawNftTx{
+			AccountIndex:           bobIndex,
+			CreatorAccountIndex:    aliceIndex,
+			CreatorAccountNameHash: accountNameHash(aliceIndex),
+			CreatorTreasuryRate:    30,
+			NftIndex:               0,
+			NftContentHash:         contentHash,
+			NftL1Address:           "0x0",
+			NftL1TokenId:           big.NewInt(0),
+			ToAddress:              "0x299d17c8b4e9967385dc9a3bb78f2a43f5a13bdd",
+			GasAccountIndex:        gasAccountIndex,
+			GasFeeAssetId:          0,
+			GasFeeAssetAmount:      packedFee(t, 100),
+			CollectionId:           0,
+		},
+	}
+	tt.sign(withdrawNftTx, bobIndex)
+	tt.apply(withdrawNftTx)
+	assert.Equal(t, NilNftNodeHash, ComputeNftLeafHash(state.Nft(0)))
+
+	// deposit nft
+	tt.apply(&circuit.Tx{
+		TxType: types.TxTypeDepositNft,
+		DepositNftTxInfo: &circuit.DepositNftTx{
+			AccountIndex:        aliceIndex,
+			NftIndex:            1,
+			NftL1Address:        "0x299d17c8b4e9967385dc9a3bb78f2a43f5a13bdd",
+			AccountNameHash:     accountNameHash(aliceIndex),
+			NftContentHash:      contentHash,
+			NftL1TokenId:        big.NewInt(7),
+			CreatorAccountIndex: aliceIndex,
+			CreatorTreasuryRate: 50,
+			CollectionId:        0,
+		},
+	})
+
+	// full exit nft
+	tt.apply(&circuit.Tx{
+		TxType: types.TxTypeFullExitNft,
+		FullExitNftTxInfo: &circuit.FullExitNftTx{
+			AccountIndex:           aliceIndex,
+			AccountNameHash:        accountNameHash(aliceIndex),
+			CreatorAccountIndex:    aliceIndex,
+			CreatorAccountNameHash: accountNameHash(aliceIndex),
+			CreatorTreasuryRate:    50,
+			NftIndex:               1,
+			CollectionId:           0,
+			NftContentHash:         contentHash,
+			NftL1Address:           "0x299d17c8b4e9967385dc9a3bb78f2a43f5a13bdd",
+			NftL1TokenId:           big.NewInt(7),
+		},
+	})
+
+	// full exit
+	tt.apply(&circuit.Tx{
+		TxType: types.TxTypeFullExit,
+		FullExitTxInfo: &circuit.FullExitTx{
+			AccountIndex:    bobIndex,
+			AccountNameHash: accountNameHash(bobIndex),
+			AssetId:         1,
+			AssetAmount:     big.NewInt(4000),
+		},
+	})
+	assert.Equal(t, big.NewInt(0), state.Account(bobIndex).Asset(1).Balance)
+
+	// empty
+	tt.apply(&circuit.Tx{TxType: types.TxTypeEmptyTx})
+
+	// gas account
+	res1, err := tt.executor.Finalize()
+	assert.Nil(t, err)
+	assert.True(t, res1.NeedGas)
+	assert.Equal(t, int64(11), res1.OnChainOpsCount)
+	assert.Equal(t, big.NewInt(1000+100+100+100+100+100+200+100+100), res1.GasDeltas[0])
+	assert.Equal(t, big.NewInt(0), res1.GasDeltas[1])
+	assert.Equal(t, res1.GasDeltas[0], state.Account(gasAccountIndex).Asset(0).Balance)
+	assert.Equal(t, state.StateRoot(), res1.NewStateRoot)
+
+	gasWitness, err := circuit.SetGasWitnessWithConfig(state.Config, res1.Gas)
+	assert.Nil(t, err)
+	witness := GasResultConstraints{
+		Gas:            gasWitness,
+		NeedGas:        1,
+		GasDeltas:      []circuit.Variable{res1.GasDeltas[0], res1.GasDeltas[1]},
+		AccountRoot:    res1.TxResults[len(res1.TxResults)-1].AccountRoot,
+		NewAccountRoot: state.AccountRoot(),
+	}
+	c := GasResultConstraints{
+		Gas:       circuit.GetZeroGasConstraintsWithConfig(state.Config, testGasAssetIds),
+		GasDeltas: make([]circuit.Variable, len(testGasAssetIds)),
+		Config:    state.Config,
+	}
+	assert.Nil(t, test.IsSolved(&c, &witness, ecc.BN254, backend.GROTH16))
+
+	_, err = tt.executor.ApplyTx(&circuit.Tx{TxType: types.TxTypeEmptyTx})
+	assert.Equal(t, ErrExecutorFinalized, err)
+}
+
+func TestExecutorRejectsInvalidTx(t *testing.T) {
+	tt := newTxTester(t)
+	state := tt.executor.State
+	tt.registerZns(aliceIndex)
+	tt.registerZns(bobIndex)
+	tt.deposit(aliceIndex, 0, big.NewInt(1000))
+	tt.deposit(aliceIndex, 2, big.NewInt(1000))
+	root := state.StateRoot()
+
+	// register twice
+	_, err := tt.executor.ApplyTx(&circuit.Tx{
+		TxType: types.TxTypeRegisterZns,
+		RegisterZnsTxInfo: &circuit.RegisterZnsTx{
+			AccountIndex:    aliceIndex,
+			AccountName:     []byte(accountName(aliceIndex)),
+			AccountNameHash: accountNameHash(aliceIndex),
+			PubKey:          &tt.keys[aliceIndex].PublicKey,
+		},
+	})
+	assert.Equal(t, ErrAccountNotEmpty, err)
+
+	newTransferTx := func(amount int64) *circuit.Tx {
+		return &circuit.Tx{
+			TxType: types.TxTypeTransfer,
+			TransferTxInfo: &circuit.TransferTx{
+				FromAccountIndex:  aliceIndex,
+				ToAccountIndex:    bobIndex,
+				ToAccountNameHash: accountNameHash(bobIndex),
+				AssetId:           0,
+				AssetAmount:       packedAmount(t, amount),
+				GasAccountIndex:   gasAccountIndex,
+				GasFeeAssetId:     0,
+				GasFeeAssetAmount: packedFee(t, 10),
+				CallDataHash:      []byte{},
+			},
+		}
+	}
+
+	// not enough balance
+	transferTx := newTransferTx(2000)
+	tt.sign(transferTx, aliceIndex)
+	_, err = tt.executor.ApplyTx(transferTx)
+	assert.Equal(t, ErrInsufficientBalance, err)
+
+	// invalid nonce
+	transferTx = newTransferTx(100)
+	tt.sign(transferTx, aliceIndex)
+	transferTx.Nonce++
+	_, err = tt.executor.ApplyTx(transferTx)
+	assert.Equal(t, ErrInvalidNonce, err)
+
+	// signed by another account
+	transferTx = newTransferTx(100)
+	tt.sign(transferTx, bobIndex)
+	_, err = tt.executor.ApplyTx(transferTx)
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	// expired
+	transferTx = newTransferTx(100)
+	tt.sign(transferTx, aliceIndex)
+	transferTx.ExpiredAt = testCreatedAt - 1
+	_, err = tt.executor.ApplyTx(transferTx)
+	assert.Equal(t, ErrTxExpired, err)
+
+	// gas asset which is not collected by the block
+	transferTx = newTransferTx(100)
+	transferTx.TransferTxInfo.GasFeeAssetId = 2
+	tt.sign(transferTx, aliceIndex)
+	_, err = tt.executor.ApplyTx(transferTx)
+	assert.Equal(t, ErrGasAssetNotFound, err)
+
+	assert.Equal(t, root, state.StateRoot())
+
+	// the gas account is not registered
+	transferTx = newTransferTx(100)
+	tt.sign(transferTx, aliceIndex)
+	tt.apply(transferTx)
+	_, err = tt.executor.Finalize()
+	assert.Equal(t, ErrInvalidGasAccount, err)
+}
+
+func TestEmptyStateRoots(t *testing.T) {
+	state, err := NewState()
+	assert.Nil(t, err)
+	assetTree, err := state.assetTree(0)
+	assert.Nil(t, err)
+	assert.Equal(t, EmptyAssetRoot, assetTree.Root())
+	proofs, err := state.AssetMerkleProofs(1, 3)
+	assert.Nil(t, err)
+	for i := 0; i < circuit.AssetMerkleLevels; i++ {
+		assert.Equal(t, assetTree.NilHashValueConst[i], proofs[i])
+	}
+}
+
+func TestEmptySlotProofs(t *testing.T) {
+	state, err := NewState()
+	assert.Nil(t, err)
+	nft := types.EmptyNft(3)
+	nft.CollectionId = 1
+	assert.Nil(t, state.SetNft(nft))
+	_, err = state.EmptyNftProof(3)
+	assert.Equal(t, merkleTree.ErrLeafNotEmpty, err)
+	proof, err := state.EmptyNftProof(4)
+	assert.Nil(t, err)
+	assert.True(t, merkleTree.VerifyExclusionProof(state.NftRoot(), NilNftNodeHash, state.Config.NftMerkleLevels, proof, merkleTree.NewMiMCHasher()))
+	proof, err = state.EmptyAccountProof(5)
+	assert.Nil(t, err)
+	assert.True(t, merkleTree.VerifyExclusionProof(state.AccountRoot(), state.nilAccountNodeHash, state.Config.AccountMerkleLevels, proof, merkleTree.NewMiMCHasher()))
+}
+
+func TestStateProofs(t *testing.T) {
+	state, err := NewState()
+	assert.Nil(t, err)
+	account := EmptyAccount(2)
+	account.Nonce = 1
+	account.AssetsInfo[3] = &types.AccountAsset{AssetId: 3, Balance: big.NewInt(100), OfferCanceledOrFinalized: big.NewInt(0)}
+	assert.Nil(t, state.SetAccount(account))
+
+	proof, err := state.AccountProof(2)
+	assert.Nil(t, err)
+	assert.Equal(t, AccountTreeId, proof.TreeId)
+	assert.Equal(t, state.AccountRoot(), proof.Root)
+	assert.True(t, proof.Verify(state.AccountRoot(), state.Config.AccountMerkleLevels, merkleTree.NewMiMCHasher()))
+	merkleProofs, err := state.AccountMerkleProofs(2)
+	assert.Nil(t, err)
+	assert.Equal(t, merkleProofs, proof.Siblings)
+
+	proof, err = state.AssetProof(2, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, AssetTreeId(2), proof.TreeId)
+	assert.Equal(t, state.AssetRoot(2), proof.Root)
+	assert.True(t, proof.Verify(state.AssetRoot(2), state.Config.AssetMerkleLevels, merkleTree.NewMiMCHasher()))
+	// an account without assets
+	proof, err = state.AssetProof(5, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, state.AssetRoot(5), proof.Root)
+	assert.True(t, proof.Verify(state.AssetRoot(5), state.Config.AssetMerkleLevels, merkleTree.NewMiMCHasher()))
+	_, err = state.AssetProof(5, state.Config.LastAccountAssetId()+1)
+	assert.Equal(t, ErrInvalidAssetId, err)
+
+	proof, err = state.NftProof(7)
+	assert.Nil(t, err)
+	assert.Equal(t, NftTreeId, proof.TreeId)
+	assert.Equal(t, NilNftNodeHash, proof.Leaf)
+	assert.True(t, proof.Verify(state.NftRoot(), state.Config.NftMerkleLevels, merkleTree.NewMiMCHasher()))
+	_, err = state.NftProof(-1)
+	assert.Equal(t, ErrInvalidNftIndex, err)
+}
+
+func TestStateSnapshot(t *testing.T) {
+	config := circuit.DefaultConfig()
+	config.AccountMerkleLevels = 8
+	state, err := NewStateWithConfig(config)
+	assert.Nil(t, err)
+	for _, accountIndex := range []int64{2, 9} {
+		account := EmptyAccount(accountIndex)
+		account.Nonce = accountIndex
+		account.AssetsInfo[3] = &types.AccountAsset{AssetId: 3, Balance: big.NewInt(accountIndex), OfferCanceledOrFinalized: big.NewInt(0)}
+		assert.Nil(t, state.SetAccount(account))
+	}
+	nft := types.EmptyNft(1 << 30)
+	nft.CollectionId = 1
+	assert.Nil(t, state.SetNft(nft))
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteSnapshot(&buf, state, 12))
+	header, trees, err := ReadSnapshot(bytes.NewReader(buf.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12), header.BlockNumber)
+	assert.Equal(t, config, header.Config)
+	assert.Equal(t, 2, header.AssetTrees)
+	assert.Equal(t, state.AccountRoot(), trees.AccountTree.Root())
+	assert.Equal(t, state.NftRoot(), trees.NftTree.Root())
+	assert.Equal(t, state.AssetRoot(9), trees.AssetTrees[9].Root())
+
+	// the trees hold the leaves of the state
+	loaded, err := LoadStateWithConfig(config, trees.AccountTree, trees.AssetTrees, trees.NftTree, state.Accounts, state.Nfts)
+	assert.Nil(t, err)
+	assert.Equal(t, state.StateRoot(), loaded.StateRoot())
+
+	// a header which doesn't match the trees
+	state.Config = circuit.DefaultConfig()
+	buf.Reset()
+	assert.Nil(t, WriteSnapshot(&buf, state, 12))
+	_, _, err = ReadSnapshot(&buf)
+	assert.NotNil(t, err)
+	state.Config = config
+	buf.Reset()
+	assert.Nil(t, WriteSnapshot(&buf, state, 12))
+	data := bytes.Replace(buf.Bytes(), []byte(hex.EncodeToString(state.NftRoot())), []byte(hex.EncodeToString(state.AccountRoot())), 1)
+	_, _, err = ReadSnapshot(bytes.NewReader(data))
+	assert.Equal(t, ErrSnapshotRootMismatch, err)
+	_, _, err = ReadSnapshot(bytes.NewReader(append(buf.Bytes(), 0)))
+	assert.Equal(t, ErrInvalidSnapshot, err)
+	_, _, err = ReadSnapshot(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,237 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor
+
+import (
+	"log"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+var (
+	// leaf of an asset which has never been touched
+	NilAccountAssetNodeHash = ComputeAccountAssetLeafHash(big.NewInt(0), big.NewInt(0))
+	// root of an asset tree without any asset, for the default config
+	EmptyAssetRoot = types.EmptyAssetRoot.FillBytes(make([]byte, 32))
+	// leaf of an account which has never been registered, for the default config
+	NilAccountNodeHash = ComputeAccountLeafHash(EmptyAccount(0), EmptyAssetRoot)
+	// leaf of an nft which has never been minted
+	NilNftNodeHash = ComputeNftLeafHash(types.EmptyNft(0))
+)
+
+/*
+	mimcHash: write every input as a 32 bytes field element, same as hFunc.Write in the circuit
+*/
+func mimcHash(inputs ...*big.Int) []byte {
+	hFunc := mimc.NewMiMC()
+	for _, input := range inputs {
+		hFunc.Write(types.BigIntToFieldElement(input).FillBytes(make([]byte, 32)))
+	}
+	return hFunc.Sum(nil)
+}
+
+func ComputeAccountAssetLeafHash(balance, offerCanceledOrFinalized *big.Int) []byte {
+	return mimcHash(balance, offerCanceledOrFinalized)
+}
+
+func ComputeAccountLeafHash(account *Account, assetRoot []byte) []byte {
+	x, y := publicKeyToBigInt(account.AccountPk)
+	return mimcHash(
+		new(big.Int).SetBytes(account.AccountNameHash),
+		x,
+		y,
+		big.NewInt(account.Nonce),
+		big.NewInt(account.CollectionNonce),
+		new(big.Int).SetBytes(assetRoot),
+	)
+}
+
+func ComputeNftLeafHash(nft *types.Nft) []byte {
+	return mimcHash(
+		big.NewInt(nft.CreatorAccountIndex),
+		big.NewInt(nft.OwnerAccountIndex),
+		new(big.Int).SetBytes(nft.NftContentHash),
+		nft.NftL1Address,
+		nft.NftL1TokenId,
+		big.NewInt(nft.CreatorTreasuryRate),
+		big.NewInt(nft.CollectionId),
+	)
+}
+
+func ComputeStateRoot(accountRoot, nftRoot []byte) []byte {
+	return mimcHash(new(big.Int).SetBytes(accountRoot), new(big.Int).SetBytes(nftRoot))
+}
+
+func publicKeyToBigInt(pk *eddsa.PublicKey) (x, y *big.Int) {
+	if pk == nil {
+		return new(big.Int), new(big.Int)
+	}
+	return pk.A.X.ToBigIntRegular(new(big.Int)), pk.A.Y.ToBigIntRegular(new(big.Int))
+}
+
+/*
+	packInt64s: out-of-circuit version of types.PackInt64Variables
+*/
+func packInt64s(inputs ...int64) *big.Int {
+	res := big.NewInt(inputs[0])
+	for _, input := range inputs[1:] {
+		res.Lsh(res, 64)
+		res.Add(res, big.NewInt(input))
+	}
+	return res
+}
+
+func signatureToBigInt(sig *eddsa.Signature) (rx, ry, s *big.Int) {
+	if sig == nil {
+		return new(big.Int), new(big.Int), new(big.Int)
+	}
+	return sig.R.X.ToBigIntRegular(new(big.Int)), sig.R.Y.ToBigIntRegular(new(big.Int)), new(big.Int).SetBytes(sig.S[:])
+}
+
+/*
+	ComputeOfferHash: out-of-circuit version of types.ComputeHashFromOfferTx, the offers are signed for a chain
+*/
+func ComputeOfferHash(chainId int64, offer *types.OfferTx) []byte {
+	return mimcHash(
+		packInt64s(chainId, offer.Type, offer.OfferId, offer.AccountIndex),
+		packInt64s(offer.NftIndex, offer.AssetId, offer.AssetAmount, offer.ListedAt),
+		packInt64s(offer.ExpiredAt, offer.TreasuryRate),
+	)
+}
+
+/*
+	ComputeTxHash: out-of-circuit version of the ComputeHashFrom*Tx functions, this is the message
+	which is signed by the layer 2 txs
+*/
+func ComputeTxHash(chainId int64, oTx *circuit.Tx) (hashVal []byte, err error) {
+	switch oTx.TxType {
+	case types.TxTypeTransfer:
+		tx := oTx.TransferTxInfo
+		if tx == nil {
+			break
+		}
+		return mimcHash(
+			packInt64s(chainId, tx.FromAccountIndex, oTx.Nonce, oTx.ExpiredAt),
+			packInt64s(tx.GasAccountIndex, tx.GasFeeAssetId, tx.GasFeeAssetAmount),
+			packInt64s(tx.ToAccountIndex, tx.AssetId, tx.AssetAmount),
+			new(big.Int).SetBytes(tx.ToAccountNameHash),
+			new(big.Int).SetBytes(tx.CallDataHash),
+		), nil
+	case types.TxTypeWithdraw:
+		tx := oTx.WithdrawTxInfo
+		if tx == nil {
+			break
+		}
+		return mimcHash(
+			packInt64s(chainId, tx.FromAccountIndex, oTx.Nonce, oTx.ExpiredAt),
+			packInt64s(tx.GasAccountIndex, tx.GasFeeAssetId, tx.GasFeeAssetAmount),
+			big.NewInt(tx.AssetId),
+			tx.AssetAmount,
+			tx.ToAddress,
+		), nil
+	case types.TxTypeCreateCollection:
+		tx := oTx.CreateCollectionTxInfo
+		if tx == nil {
+			break
+		}
+		return mimcHash(
+			packInt64s(chainId, tx.AccountIndex, oTx.Nonce, oTx.ExpiredAt),
+			packInt64s(tx.GasAccountIndex, tx.GasFeeAssetId, tx.GasFeeAssetAmount),
+		), nil
+	case types.TxTypeMintNft:
+		tx := oTx.MintNftTxInfo
+		if tx == nil {
+			break
+		}
+		return mimcHash(
+			packInt64s(chainId, tx.CreatorAccountIndex, oTx.Nonce, oTx.ExpiredAt),
+			packInt64s(tx.GasAccountIndex, tx.GasFeeAssetId, tx.GasFeeAssetAmount),
+			packInt64s(tx.ToAccountIndex, tx.CreatorTreasuryRate, tx.CollectionId),
+			new(big.Int).SetBytes(tx.ToAccountNameHash),
+			new(big.Int).SetBytes(tx.NftContentHash),
+		), nil
+	case types.TxTypeTransferNft:
+		tx := oTx.TransferNftTxInfo
+		if tx == nil {
+			break
+		}
+		return mimcHash(
+			packInt64s(chainId, tx.FromAccountIndex, oTx.Nonce, oTx.ExpiredAt),
+			packInt64s(tx.GasAccountIndex, tx.GasFeeAssetId, tx.GasFeeAssetAmount),
+			packInt64s(tx.ToAccountIndex, tx.NftIndex),
+			new(big.Int).SetBytes(tx.ToAccountNameHash),
+			new(big.Int).SetBytes(tx.CallDataHash),
+		), nil
+	case types.TxTypeAtomicMatch:
+		tx := oTx.AtomicMatchTxInfo
+		if tx == nil || tx.BuyOffer == nil || tx.SellOffer == nil {
+			break
+		}
+		buyRX, buyRY, buyS := signatureToBigInt(tx.BuyOffer.Sig)
+		sellRX, sellRY, sellS := signatureToBigInt(tx.SellOffer.Sig)
+		return mimcHash(
+			packInt64s(chainId, tx.AccountIndex, oTx.Nonce, oTx.ExpiredAt),
+			packInt64s(tx.GasAccountIndex, tx.GasFeeAssetId, tx.GasFeeAssetAmount),
+			packInt64s(tx.BuyOffer.Type, tx.BuyOffer.OfferId, tx.BuyOffer.AccountIndex, tx.BuyOffer.NftIndex),
+			packInt64s(tx.BuyOffer.AssetId, tx.BuyOffer.AssetAmount, tx.BuyOffer.ListedAt, tx.BuyOffer.ExpiredAt),
+			buyRX,
+			buyRY,
+			buyS,
+			packInt64s(tx.SellOffer.Type, tx.SellOffer.OfferId, tx.SellOffer.AccountIndex, tx.SellOffer.NftIndex),
+			packInt64s(tx.SellOffer.AssetId, tx.SellOffer.AssetAmount, tx.SellOffer.ListedAt, tx.SellOffer.ExpiredAt),
+			sellRX,
+			sellRY,
+			sellS,
+		), nil
+	case types.TxTypeCancelOffer:
+		tx := oTx.CancelOfferTxInfo
+		if tx == nil {
+			break
+		}
+		return mimcHash(
+			packInt64s(chainId, tx.AccountIndex, oTx.Nonce, oTx.ExpiredAt),
+			packInt64s(tx.GasAccountIndex, tx.GasFeeAssetId, tx.GasFeeAssetAmount),
+			big.NewInt(tx.OfferId),
+		), nil
+	case types.TxTypeWithdrawNft:
+		tx := oTx.WithdrawNftTxInfo
+		if tx == nil {
+			break
+		}
+		toAddress, err := types.StringToFieldElement(tx.ToAddress)
+		if err != nil {
+			return nil, err
+		}
+		return mimcHash(
+			packInt64s(chainId, tx.AccountIndex, oTx.Nonce, oTx.ExpiredAt),
+			packInt64s(tx.GasAccountIndex, tx.GasFeeAssetId, tx.GasFeeAssetAmount),
+			big.NewInt(tx.NftIndex),
+			toAddress,
+		), nil
+	default:
+		log.Println("[ComputeTxHash] tx type has no signature")
+		return nil, ErrInvalidTxType
+	}
+	log.Println("[ComputeTxHash] tx info is nil")
+	return nil, ErrInvalidTxInfo
+}
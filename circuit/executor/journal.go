@@ -0,0 +1,191 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor
+
+import (
+	"log"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/merkleTree"
+)
+
+/*
+	stateJournal: the leaves, accounts and nfts before their first update since begin,
+	so that the txs of a failed block are reverted. A nil account or nft didn't exist.
+*/
+type stateJournal struct {
+	accountLeaves map[int64][]byte
+	assetLeaves   map[int64]map[int64][]byte
+	nftLeaves     map[int64][]byte
+	accounts      map[int64]*Account
+	nfts          map[int64]*types.Nft
+	// asset trees created since begin
+	newAssetTrees map[int64]bool
+}
+
+/*
+	begin: record the updates from now on, until revert or discard
+*/
+func (s *State) begin() {
+	s.journal = &stateJournal{
+		accountLeaves: make(map[int64][]byte),
+		assetLeaves:   make(map[int64]map[int64][]byte),
+		nftLeaves:     make(map[int64][]byte),
+		accounts:      make(map[int64]*Account),
+		nfts:          make(map[int64]*types.Nft),
+		newAssetTrees: make(map[int64]bool),
+	}
+}
+
+/*
+	discard: keep the updates since begin
+*/
+func (s *State) discard() {
+	s.journal = nil
+}
+
+/*
+	end: keep the updates since begin if err is nil and revert them otherwise
+*/
+func (s *State) end(err error) {
+	if err == nil {
+		s.discard()
+		return
+	}
+	if rErr := s.revert(); rErr != nil {
+		log.Println("[end] unable to revert the state:", rErr)
+	}
+}
+
+/*
+	revert: restore the leaves, accounts and nfts of begin, the roots are the ones of begin again
+*/
+func (s *State) revert() (err error) {
+	j := s.journal
+	s.journal = nil
+	if j == nil {
+		return nil
+	}
+	for nftIndex, leaf := range j.nftLeaves {
+		err = updateLeaf(s.NftTree, nftIndex, leaf)
+		if err != nil {
+			log.Println("[revert] unable to restore nft leaf:", err)
+			return err
+		}
+	}
+	for nftIndex, nft := range j.nfts {
+		if nft == nil {
+			delete(s.Nfts, nftIndex)
+		} else {
+			s.Nfts[nftIndex] = nft
+		}
+	}
+	for accountIndex, leaves := range j.assetLeaves {
+		assetTree, ok := s.AssetTrees[accountIndex]
+		if !ok || j.newAssetTrees[accountIndex] {
+			continue
+		}
+		for assetId, leaf := range leaves {
+			err = updateLeaf(assetTree, assetId, leaf)
+			if err != nil {
+				log.Println("[revert] unable to restore asset leaf:", err)
+				return err
+			}
+		}
+	}
+	for accountIndex := range j.newAssetTrees {
+		delete(s.AssetTrees, accountIndex)
+	}
+	for accountIndex, leaf := range j.accountLeaves {
+		err = updateLeaf(s.AccountTree, accountIndex, leaf)
+		if err != nil {
+			log.Println("[revert] unable to restore account leaf:", err)
+			return err
+		}
+	}
+	for accountIndex, account := range j.accounts {
+		if account == nil {
+			delete(s.Accounts, accountIndex)
+		} else {
+			s.Accounts[accountIndex] = account
+		}
+	}
+	return nil
+}
+
+func (s *State) recordAccount(accountIndex int64) error {
+	j := s.journal
+	if j == nil {
+		return nil
+	}
+	if _, ok := j.accounts[accountIndex]; !ok {
+		var account *Account
+		if oAccount, ok := s.Accounts[accountIndex]; ok {
+			account = oAccount.Copy()
+		}
+		j.accounts[accountIndex] = account
+		if _, ok := s.AssetTrees[accountIndex]; !ok {
+			j.newAssetTrees[accountIndex] = true
+		}
+	}
+	return recordLeaf(j.accountLeaves, s.AccountTree, accountIndex)
+}
+
+func (s *State) recordAsset(accountIndex, assetId int64) error {
+	err := s.recordAccount(accountIndex)
+	if err != nil || s.journal == nil {
+		return err
+	}
+	assetTree, ok := s.AssetTrees[accountIndex]
+	if !ok {
+		return nil
+	}
+	leaves, ok := s.journal.assetLeaves[accountIndex]
+	if !ok {
+		leaves = make(map[int64][]byte)
+		s.journal.assetLeaves[accountIndex] = leaves
+	}
+	return recordLeaf(leaves, assetTree, assetId)
+}
+
+func (s *State) recordNft(nftIndex int64) error {
+	j := s.journal
+	if j == nil {
+		return nil
+	}
+	if _, ok := j.nfts[nftIndex]; !ok {
+		var nft *types.Nft
+		if oNft, ok := s.Nfts[nftIndex]; ok {
+			nft = copyNft(oNft)
+		}
+		j.nfts[nftIndex] = nft
+	}
+	return recordLeaf(j.nftLeaves, s.NftTree, nftIndex)
+}
+
+func recordLeaf(leaves map[int64][]byte, tree *merkleTree.Tree, index int64) error {
+	if _, ok := leaves[index]; ok {
+		return nil
+	}
+	leaf, err := tree.Leaf(index)
+	if err != nil {
+		return err
+	}
+	leaves[index] = leaf
+	return nil
+}
@@ -0,0 +1,378 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor
+
+import (
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/util"
+)
+
+const (
+	// bit size of the packed amount, see types.UnpackAmount
+	packedAmountBitsSize = 40
+	// bit size of the packed fee, see types.UnpackFee
+	packedFeeBitsSize = 16
+	// bit size of the offer id in GetAssetDeltasAndNftDeltaFromAtomicMatch
+	matchOfferIdBitsSize = 23
+	// bit size of the offer id in VerifyCancelOfferTx
+	cancelOfferIdBitsSize = 24
+)
+
+/*
+	assetDelta: out-of-circuit version of circuit.AccountAssetDeltaConstraints,
+	OfferIndex is the bit which is set in OfferCanceledOrFinalized, -1 means untouched
+*/
+type assetDelta struct {
+	BalanceDelta *big.Int
+	OfferIndex   int64
+}
+
+/*
+	txPlan: the slots a tx occupies in the circuit and the deltas applied to them
+*/
+type txPlan struct {
+	AccountIndexes [circuit.NbAccountsPerTx]int64
+	AssetIds       [circuit.NbAccountsPerTx][circuit.NbAccountAssetsPerAccount]int64
+	NftIndex       int64
+	AssetDeltas    [circuit.NbAccountsPerTx][circuit.NbAccountAssetsPerAccount]*assetDelta
+	GasDeltas      [circuit.NbGasAssetsPerTx]*GasDelta
+	// nil means the nft is untouched
+	NftAfter *types.Nft
+}
+
+func newTxPlan(config circuit.Config, defaultGasAssetId int64) *txPlan {
+	plan := &txPlan{
+		NftIndex: config.LastNftIndex(),
+	}
+	for i := 0; i < circuit.NbAccountsPerTx; i++ {
+		plan.AccountIndexes[i] = config.LastAccountIndex()
+		for j := 0; j < circuit.NbAccountAssetsPerAccount; j++ {
+			plan.AssetIds[i][j] = config.LastAccountAssetId()
+			plan.AssetDeltas[i][j] = &assetDelta{BalanceDelta: big.NewInt(0), OfferIndex: -1}
+		}
+	}
+	for i := 0; i < circuit.NbGasAssetsPerTx; i++ {
+		plan.GasDeltas[i] = &GasDelta{AssetId: defaultGasAssetId, BalanceDelta: big.NewInt(0)}
+	}
+	return plan
+}
+
+func (plan *txPlan) setAccount(slot int, accountIndex int64, assetIds ...int64) {
+	plan.AccountIndexes[slot] = accountIndex
+	for j, assetId := range assetIds {
+		plan.AssetIds[slot][j] = assetId
+	}
+}
+
+func (plan *txPlan) setBalanceDelta(slot, asset int, delta *big.Int) {
+	plan.AssetDeltas[slot][asset].BalanceDelta = delta
+}
+
+func (plan *txPlan) setGasDeltas(gasFeeAssetId int64, gasFeeAssetAmount *big.Int) {
+	plan.GasDeltas[0] = &GasDelta{AssetId: gasFeeAssetId, BalanceDelta: gasFeeAssetAmount}
+	plan.GasDeltas[1] = &GasDelta{AssetId: gasFeeAssetId, BalanceDelta: big.NewInt(0)}
+}
+
+/*
+	buildTxPlan: out-of-circuit version of the Get*Delta* functions used in VerifyTransaction
+*/
+func buildTxPlan(state *State, oTx *circuit.Tx, defaultGasAssetId int64) (plan *txPlan, err error) {
+	plan = newTxPlan(state.Config, defaultGasAssetId)
+	switch oTx.TxType {
+	case types.TxTypeRegisterZns:
+		tx := oTx.RegisterZnsTxInfo
+		if tx == nil || tx.PubKey == nil {
+			return nil, ErrInvalidTxInfo
+		}
+		plan.setAccount(0, tx.AccountIndex)
+	case types.TxTypeDeposit:
+		tx := oTx.DepositTxInfo
+		if tx == nil || tx.AssetAmount == nil {
+			return nil, ErrInvalidTxInfo
+		}
+		plan.setAccount(0, tx.AccountIndex, tx.AssetId)
+		plan.setBalanceDelta(0, 0, tx.AssetAmount)
+	case types.TxTypeDepositNft:
+		tx := oTx.DepositNftTxInfo
+		if tx == nil {
+			return nil, ErrInvalidTxInfo
+		}
+		nftL1Address, err := types.StringToFieldElement(tx.NftL1Address)
+		if err != nil {
+			return nil, ErrInvalidTxInfo
+		}
+		plan.setAccount(0, tx.AccountIndex)
+		plan.NftIndex = tx.NftIndex
+		plan.NftAfter = &types.Nft{
+			NftIndex:            tx.NftIndex,
+			NftContentHash:      tx.NftContentHash,
+			CreatorAccountIndex: tx.CreatorAccountIndex,
+			OwnerAccountIndex:   tx.AccountIndex,
+			NftL1Address:        nftL1Address,
+			NftL1TokenId:        types.BigIntToFieldElement(tx.NftL1TokenId),
+			CreatorTreasuryRate: tx.CreatorTreasuryRate,
+			CollectionId:        tx.CollectionId,
+		}
+	case types.TxTypeTransfer:
+		tx := oTx.TransferTxInfo
+		if tx == nil {
+			return nil, ErrInvalidTxInfo
+		}
+		assetAmount, err := unpackAmount(tx.AssetAmount)
+		if err != nil {
+			return nil, err
+		}
+		gasFeeAssetAmount, err := unpackFee(tx.GasFeeAssetAmount)
+		if err != nil {
+			return nil, err
+		}
+		plan.setAccount(0, tx.FromAccountIndex, tx.AssetId, tx.GasFeeAssetId)
+		plan.setAccount(1, tx.ToAccountIndex, tx.AssetId)
+		plan.setBalanceDelta(0, 0, new(big.Int).Neg(assetAmount))
+		plan.setBalanceDelta(0, 1, new(big.Int).Neg(gasFeeAssetAmount))
+		plan.setBalanceDelta(1, 0, assetAmount)
+		plan.setGasDeltas(tx.GasFeeAssetId, gasFeeAssetAmount)
+	case types.TxTypeWithdraw:
+		tx := oTx.WithdrawTxInfo
+		if tx == nil || tx.AssetAmount == nil || tx.ToAddress == nil {
+			return nil, ErrInvalidTxInfo
+		}
+		gasFeeAssetAmount, err := unpackFee(tx.GasFeeAssetAmount)
+		if err != nil {
+			return nil, err
+		}
+		plan.setAccount(0, tx.FromAccountIndex, tx.AssetId, tx.GasFeeAssetId)
+		plan.setBalanceDelta(0, 0, new(big.Int).Neg(tx.AssetAmount))
+		plan.setBalanceDelta(0, 1, new(big.Int).Neg(gasFeeAssetAmount))
+		plan.setGasDeltas(tx.GasFeeAssetId, gasFeeAssetAmount)
+	case types.TxTypeCreateCollection:
+		tx := oTx.CreateCollectionTxInfo
+		if tx == nil {
+			return nil, ErrInvalidTxInfo
+		}
+		// create collection uses the amount packing for its fee
+		gasFeeAssetAmount, err := unpackAmount(tx.GasFeeAssetAmount)
+		if err != nil {
+			return nil, err
+		}
+		plan.setAccount(0, tx.AccountIndex, tx.GasFeeAssetId)
+		plan.setBalanceDelta(0, 0, new(big.Int).Neg(gasFeeAssetAmount))
+		plan.setGasDeltas(tx.GasFeeAssetId, gasFeeAssetAmount)
+	case types.TxTypeMintNft:
+		tx := oTx.MintNftTxInfo
+		if tx == nil {
+			return nil, ErrInvalidTxInfo
+		}
+		gasFeeAssetAmount, err := unpackFee(tx.GasFeeAssetAmount)
+		if err != nil {
+			return nil, err
+		}
+		plan.setAccount(0, tx.CreatorAccountIndex, tx.GasFeeAssetId)
+		plan.setAccount(1, tx.ToAccountIndex)
+		plan.setBalanceDelta(0, 0, new(big.Int).Neg(gasFeeAssetAmount))
+		plan.setGasDeltas(tx.GasFeeAssetId, gasFeeAssetAmount)
+		plan.NftIndex = tx.NftIndex
+		plan.NftAfter = &types.Nft{
+			NftIndex:            tx.NftIndex,
+			NftContentHash:      tx.NftContentHash,
+			CreatorAccountIndex: tx.CreatorAccountIndex,
+			OwnerAccountIndex:   tx.ToAccountIndex,
+			NftL1Address:        big.NewInt(0),
+			NftL1TokenId:        big.NewInt(0),
+			CreatorTreasuryRate: tx.CreatorTreasuryRate,
+			CollectionId:        tx.CollectionId,
+		}
+	case types.TxTypeTransferNft:
+		tx := oTx.TransferNftTxInfo
+		if tx == nil {
+			return nil, ErrInvalidTxInfo
+		}
+		gasFeeAssetAmount, err := unpackFee(tx.GasFeeAssetAmount)
+		if err != nil {
+			return nil, err
+		}
+		plan.setAccount(0, tx.FromAccountIndex, tx.GasFeeAssetId)
+		plan.setAccount(1, tx.ToAccountIndex)
+		plan.setBalanceDelta(0, 0, new(big.Int).Neg(gasFeeAssetAmount))
+		plan.setGasDeltas(tx.GasFeeAssetId, gasFeeAssetAmount)
+		plan.NftIndex = tx.NftIndex
+		plan.NftAfter = state.Nft(tx.NftIndex)
+		plan.NftAfter.OwnerAccountIndex = tx.ToAccountIndex
+	case types.TxTypeAtomicMatch:
+		tx := oTx.AtomicMatchTxInfo
+		if tx == nil || tx.BuyOffer == nil || tx.SellOffer == nil {
+			return nil, ErrInvalidTxInfo
+		}
+		if !isValidOfferId(tx.BuyOffer.OfferId, matchOfferIdBitsSize) ||
+			!isValidOfferId(tx.SellOffer.OfferId, matchOfferIdBitsSize) {
+			return nil, ErrInvalidOfferId
+		}
+		assetAmount, err := unpackAmount(tx.BuyOffer.AssetAmount)
+		if err != nil {
+			return nil, err
+		}
+		treasuryAmount, err := unpackAmount(tx.TreasuryAmount)
+		if err != nil {
+			return nil, err
+		}
+		gasFeeAssetAmount, err := unpackFee(tx.GasFeeAssetAmount)
+		if err != nil {
+			return nil, err
+		}
+		nftBefore := state.Nft(tx.SellOffer.NftIndex)
+		// the circuit divides in the field, not in the integers
+		creatorAmount := fieldDiv(
+			new(big.Int).Mul(assetAmount, big.NewInt(nftBefore.CreatorTreasuryRate)), big.NewInt(circuit.RateBase))
+		offerTreasuryAmount := fieldDiv(
+			new(big.Int).Mul(assetAmount, big.NewInt(tx.BuyOffer.TreasuryRate)), big.NewInt(circuit.RateBase))
+		sellerAmount := new(big.Int).Sub(assetAmount, new(big.Int).Add(creatorAmount, offerTreasuryAmount))
+
+		plan.setAccount(0, tx.AccountIndex, tx.GasFeeAssetId)
+		plan.setAccount(1, tx.BuyOffer.AccountIndex, tx.BuyOffer.AssetId, tx.BuyOffer.OfferId/circuit.OfferSizePerAsset)
+		plan.setAccount(2, tx.SellOffer.AccountIndex, tx.SellOffer.AssetId, tx.SellOffer.OfferId/circuit.OfferSizePerAsset)
+		plan.setAccount(3, nftBefore.CreatorAccountIndex, tx.SellOffer.AssetId)
+		plan.setBalanceDelta(0, 0, new(big.Int).Neg(gasFeeAssetAmount))
+		plan.setBalanceDelta(1, 0, new(big.Int).Neg(assetAmount))
+		plan.AssetDeltas[1][1].OfferIndex = tx.BuyOffer.OfferId % circuit.OfferSizePerAsset
+		plan.setBalanceDelta(2, 0, sellerAmount)
+		plan.AssetDeltas[2][1].OfferIndex = tx.SellOffer.OfferId % circuit.OfferSizePerAsset
+		plan.setBalanceDelta(3, 0, creatorAmount)
+		plan.GasDeltas[0] = &GasDelta{AssetId: tx.BuyOffer.AssetId, BalanceDelta: treasuryAmount}
+		plan.GasDeltas[1] = &GasDelta{AssetId: tx.GasFeeAssetId, BalanceDelta: gasFeeAssetAmount}
+		plan.NftIndex = tx.SellOffer.NftIndex
+		plan.NftAfter = nftBefore
+		plan.NftAfter.OwnerAccountIndex = tx.BuyOffer.AccountIndex
+	case types.TxTypeCancelOffer:
+		tx := oTx.CancelOfferTxInfo
+		if tx == nil {
+			return nil, ErrInvalidTxInfo
+		}
+		if !isValidOfferId(tx.OfferId, cancelOfferIdBitsSize) {
+			return nil, ErrInvalidOfferId
+		}
+		gasFeeAssetAmount, err := unpackFee(tx.GasFeeAssetAmount)
+		if err != nil {
+			return nil, err
+		}
+		plan.setAccount(0, tx.AccountIndex, tx.GasFeeAssetId, tx.OfferId/circuit.OfferSizePerAsset)
+		plan.setBalanceDelta(0, 0, new(big.Int).Neg(gasFeeAssetAmount))
+		plan.AssetDeltas[0][1].OfferIndex = tx.OfferId % circuit.OfferSizePerAsset
+		plan.setGasDeltas(tx.GasFeeAssetId, gasFeeAssetAmount)
+	case types.TxTypeWithdrawNft:
+		tx := oTx.WithdrawNftTxInfo
+		if tx == nil {
+			return nil, ErrInvalidTxInfo
+		}
+		gasFeeAssetAmount, err := unpackFee(tx.GasFeeAssetAmount)
+		if err != nil {
+			return nil, err
+		}
+		plan.setAccount(0, tx.AccountIndex, tx.GasFeeAssetId)
+		plan.setAccount(1, tx.CreatorAccountIndex)
+		plan.setBalanceDelta(0, 0, new(big.Int).Neg(gasFeeAssetAmount))
+		plan.setGasDeltas(tx.GasFeeAssetId, gasFeeAssetAmount)
+		plan.NftIndex = tx.NftIndex
+		plan.NftAfter = clearedNft(tx.NftIndex)
+	case types.TxTypeFullExit:
+		tx := oTx.FullExitTxInfo
+		if tx == nil || tx.AssetAmount == nil {
+			return nil, ErrInvalidTxInfo
+		}
+		plan.setAccount(0, tx.AccountIndex, tx.AssetId)
+		plan.setBalanceDelta(0, 0, new(big.Int).Neg(tx.AssetAmount))
+	case types.TxTypeFullExitNft:
+		tx := oTx.FullExitNftTxInfo
+		if tx == nil {
+			return nil, ErrInvalidTxInfo
+		}
+		plan.setAccount(0, tx.AccountIndex)
+		plan.NftIndex = tx.NftIndex
+		plan.NftAfter = clearedNft(tx.NftIndex)
+	default:
+		return nil, ErrInvalidTxType
+	}
+	for i := 0; i < circuit.NbAccountsPerTx; i++ {
+		if plan.AccountIndexes[i] < 0 || plan.AccountIndexes[i] > state.Config.LastAccountIndex() {
+			return nil, ErrInvalidAccountIndex
+		}
+		for j := 0; j < circuit.NbAccountAssetsPerAccount; j++ {
+			if plan.AssetIds[i][j] < 0 || plan.AssetIds[i][j] > state.Config.LastAccountAssetId() {
+				return nil, ErrInvalidAssetId
+			}
+		}
+	}
+	if plan.NftIndex < 0 || plan.NftIndex > state.Config.LastNftIndex() {
+		return nil, ErrInvalidNftIndex
+	}
+	return plan, nil
+}
+
+func clearedNft(nftIndex int64) *types.Nft {
+	return &types.Nft{
+		NftIndex:       nftIndex,
+		NftContentHash: []byte{},
+		NftL1Address:   big.NewInt(0),
+		NftL1TokenId:   big.NewInt(0),
+	}
+}
+
+func isValidOfferId(offerId int64, bitsSize uint) bool {
+	return offerId >= 0 && offerId < 1<<bitsSize
+}
+
+func unpackAmount(packedAmount int64) (*big.Int, error) {
+	if packedAmount < 0 || packedAmount >= 1<<packedAmountBitsSize {
+		return nil, ErrInvalidAssetAmount
+	}
+	return util.UnpackAmount(packedAmount), nil
+}
+
+func unpackFee(packedFee int64) (*big.Int, error) {
+	if packedFee < 0 || packedFee >= 1<<packedFeeBitsSize {
+		return nil, ErrInvalidAssetAmount
+	}
+	return util.UnpackFee(packedFee), nil
+}
+
+func fieldDiv(a, b *big.Int) *big.Int {
+	inv := new(big.Int).ModInverse(b, fr.Modulus())
+	res := new(big.Int).Mul(a, inv)
+	return res.Mod(res, fr.Modulus())
+}
+
+func fieldAdd(a, b *big.Int) *big.Int {
+	res := new(big.Int).Add(a, b)
+	return res.Mod(res, fr.Modulus())
+}
+
+func fieldEqual(a, b *big.Int) bool {
+	return types.BigIntToFieldElement(a).Cmp(types.BigIntToFieldElement(b)) == 0
+}
+
+func bytesEqual(a, b []byte) bool {
+	return types.BytesToFieldElement(a).Cmp(types.BytesToFieldElement(b)) == 0
+}
+
+func isLessOrEqual(a, b *big.Int) bool {
+	return types.BigIntToFieldElement(a).Cmp(types.BigIntToFieldElement(b)) <= 0
+}
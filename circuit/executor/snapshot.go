@@ -0,0 +1,207 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"sort"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/merkleTree"
+)
+
+const (
+	SnapshotFormatVersion = 1
+	// maxSnapshotHeaderSize bounds the header read before the trees
+	maxSnapshotHeaderSize = 1 << 20
+)
+
+var snapshotMagic = [4]byte{'Z', 'K', 'B', 'S'}
+
+/*
+	SnapshotHeader: block and roots of a state snapshot, the roots are hex encoded
+*/
+type SnapshotHeader struct {
+	FormatVersion uint32
+	BlockNumber   int64
+	Config        circuit.Config
+	StateRoot     string
+	AccountRoot   string
+	NftRoot       string
+	// number of asset trees after the account and nft trees
+	AssetTrees int
+}
+
+/*
+	SnapshotTrees: trees rebuilt from a state snapshot, pass them to LoadStateWithConfig with the leaves they commit to
+*/
+type SnapshotTrees struct {
+	AccountTree *merkleTree.Tree
+	AssetTrees  map[int64]*merkleTree.Tree
+	NftTree     *merkleTree.Tree
+}
+
+/*
+	WriteSnapshot: writes the magic, the length of the JSON encoded header, the header, then the exports of the
+	account tree, the nft tree and of every non-empty asset tree in account order, each behind its uvarint account index
+*/
+func WriteSnapshot(w io.Writer, s *State, blockNumber int64) error {
+	accountIndexes := make([]int64, 0, len(s.AssetTrees))
+	for accountIndex, assetTree := range s.AssetTrees {
+		if !assetTree.IsEmptyTree() {
+			accountIndexes = append(accountIndexes, accountIndex)
+		}
+	}
+	sort.Slice(accountIndexes, func(i, j int) bool { return accountIndexes[i] < accountIndexes[j] })
+	header := &SnapshotHeader{
+		FormatVersion: SnapshotFormatVersion,
+		BlockNumber:   blockNumber,
+		Config:        s.Config,
+		StateRoot:     hex.EncodeToString(s.StateRoot()),
+		AccountRoot:   hex.EncodeToString(s.AccountRoot()),
+		NftRoot:       hex.EncodeToString(s.NftRoot()),
+		AssetTrees:    len(accountIndexes),
+	}
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	var prefix [8]byte
+	copy(prefix[:4], snapshotMagic[:])
+	binary.BigEndian.PutUint32(prefix[4:], uint32(len(headerBytes)))
+	bw.Write(prefix[:])
+	bw.Write(headerBytes)
+	if err = s.AccountTree.Export(bw); err != nil {
+		log.Println("[WriteSnapshot] unable to export account tree:", err)
+		return err
+	}
+	if err = s.NftTree.Export(bw); err != nil {
+		log.Println("[WriteSnapshot] unable to export nft tree:", err)
+		return err
+	}
+	var buf [binary.MaxVarintLen64]byte
+	for _, accountIndex := range accountIndexes {
+		bw.Write(buf[:binary.PutUvarint(buf[:], uint64(accountIndex))])
+		if err = s.AssetTrees[accountIndex].Export(bw); err != nil {
+			log.Println("[WriteSnapshot] unable to export asset tree:", accountIndex, err)
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+/*
+	ReadSnapshotHeader: reads the header in front of the trees of a snapshot
+*/
+func ReadSnapshotHeader(r io.Reader) (*SnapshotHeader, error) {
+	var prefix [8]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, ErrInvalidSnapshot
+	}
+	if !bytes.Equal(prefix[:4], snapshotMagic[:]) {
+		return nil, ErrInvalidSnapshot
+	}
+	size := binary.BigEndian.Uint32(prefix[4:])
+	if size > maxSnapshotHeaderSize {
+		return nil, ErrInvalidSnapshot
+	}
+	headerBytes := make([]byte, size)
+	if _, err := io.ReadFull(r, headerBytes); err != nil {
+		return nil, ErrInvalidSnapshot
+	}
+	header := new(SnapshotHeader)
+	if err := json.Unmarshal(headerBytes, header); err != nil {
+		return nil, ErrInvalidSnapshot
+	}
+	if header.FormatVersion != SnapshotFormatVersion {
+		return nil, ErrSnapshotFormatVersion
+	}
+	if err := header.Config.Validate(); err != nil || header.AssetTrees < 0 {
+		return nil, ErrInvalidSnapshot
+	}
+	return header, nil
+}
+
+/*
+	ReadSnapshot: rebuilds the trees of a snapshot in memory and checks their roots against the header,
+	the root of each asset tree is checked against the root exported with it
+*/
+func ReadSnapshot(r io.Reader) (*SnapshotHeader, *SnapshotTrees, error) {
+	br := bufio.NewReader(r)
+	header, err := ReadSnapshotHeader(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	config := header.Config
+	s, err := NewStateWithConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = s.AccountTree.Import(br); err != nil {
+		log.Println("[ReadSnapshot] unable to import account tree:", err)
+		return nil, nil, err
+	}
+	if err = s.NftTree.Import(br); err != nil {
+		log.Println("[ReadSnapshot] unable to import nft tree:", err)
+		return nil, nil, err
+	}
+	trees := &SnapshotTrees{
+		AccountTree: s.AccountTree,
+		AssetTrees:  make(map[int64]*merkleTree.Tree, header.AssetTrees),
+		NftTree:     s.NftTree,
+	}
+	next := int64(0)
+	for i := 0; i < header.AssetTrees; i++ {
+		index, err := binary.ReadUvarint(br)
+		// the account indexes are increasing
+		if err != nil || index < uint64(next) || index > uint64(config.LastAccountIndex()) {
+			return nil, nil, ErrInvalidSnapshot
+		}
+		accountIndex := int64(index)
+		next = accountIndex + 1
+		assetTree, err := merkleTree.NewEmptyTree(config.AssetMerkleLevels, NilAccountAssetNodeHash, mimc.NewMiMC())
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = assetTree.Import(br); err != nil {
+			log.Println("[ReadSnapshot] unable to import asset tree:", accountIndex, err)
+			return nil, nil, err
+		}
+		trees.AssetTrees[accountIndex] = assetTree
+	}
+	if _, err = br.ReadByte(); err != io.EOF {
+		log.Println("[ReadSnapshot] trailing data")
+		return nil, nil, ErrInvalidSnapshot
+	}
+	if hex.EncodeToString(trees.AccountTree.Root()) != header.AccountRoot ||
+		hex.EncodeToString(trees.NftTree.Root()) != header.NftRoot ||
+		hex.EncodeToString(ComputeStateRoot(trees.AccountTree.Root(), trees.NftTree.Root())) != header.StateRoot {
+		log.Println("[ReadSnapshot] root mismatch, block:", header.BlockNumber)
+		return nil, nil, ErrSnapshotRootMismatch
+	}
+	return header, trees, nil
+}
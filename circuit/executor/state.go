@@ -0,0 +1,605 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor
+
+import (
+	"bytes"
+	"log"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/merkleTree"
+)
+
+/*
+	Account: account leaf together with all of its non-empty assets
+*/
+type Account struct {
+	AccountIndex    int64
+	AccountNameHash []byte
+	AccountPk       *eddsa.PublicKey
+	Nonce           int64
+	CollectionNonce int64
+	AssetsInfo      map[int64]*types.AccountAsset
+}
+
+func EmptyAccount(accountIndex int64) *Account {
+	return &Account{
+		AccountIndex:    accountIndex,
+		AccountNameHash: []byte{},
+		AccountPk: &eddsa.PublicKey{
+			A: curve.Point{
+				X: fr.NewElement(0),
+				Y: fr.NewElement(0),
+			},
+		},
+		Nonce:           0,
+		CollectionNonce: 0,
+		AssetsInfo:      make(map[int64]*types.AccountAsset),
+	}
+}
+
+func (account *Account) Copy() *Account {
+	nAccount := &Account{
+		AccountIndex:    account.AccountIndex,
+		AccountNameHash: common.CopyBytes(account.AccountNameHash),
+		Nonce:           account.Nonce,
+		CollectionNonce: account.CollectionNonce,
+		AssetsInfo:      make(map[int64]*types.AccountAsset, len(account.AssetsInfo)),
+	}
+	if account.AccountPk != nil {
+		nAccount.AccountPk = &eddsa.PublicKey{A: account.AccountPk.A}
+	}
+	for assetId, asset := range account.AssetsInfo {
+		nAccount.AssetsInfo[assetId] = copyAccountAsset(asset)
+	}
+	return nAccount
+}
+
+/*
+	Asset: returns a copy of the asset, or an empty asset if the account never touched it
+*/
+func (account *Account) Asset(assetId int64) *types.AccountAsset {
+	asset, ok := account.AssetsInfo[assetId]
+	if !ok {
+		return types.EmptyAccountAsset(assetId)
+	}
+	return copyAccountAsset(asset)
+}
+
+func copyAccountAsset(asset *types.AccountAsset) *types.AccountAsset {
+	nAsset := &types.AccountAsset{
+		AssetId:                  asset.AssetId,
+		Balance:                  new(big.Int),
+		OfferCanceledOrFinalized: new(big.Int),
+	}
+	if asset.Balance != nil {
+		nAsset.Balance.Set(asset.Balance)
+	}
+	if asset.OfferCanceledOrFinalized != nil {
+		nAsset.OfferCanceledOrFinalized.Set(asset.OfferCanceledOrFinalized)
+	}
+	return nAsset
+}
+
+func copyNft(nft *types.Nft) *types.Nft {
+	nNft := &types.Nft{
+		NftIndex:            nft.NftIndex,
+		NftContentHash:      common.CopyBytes(nft.NftContentHash),
+		CreatorAccountIndex: nft.CreatorAccountIndex,
+		OwnerAccountIndex:   nft.OwnerAccountIndex,
+		NftL1Address:        new(big.Int),
+		NftL1TokenId:        new(big.Int),
+		CreatorTreasuryRate: nft.CreatorTreasuryRate,
+		CollectionId:        nft.CollectionId,
+	}
+	if nft.NftL1Address != nil {
+		nNft.NftL1Address.Set(nft.NftL1Address)
+	}
+	if nft.NftL1TokenId != nil {
+		nNft.NftL1TokenId.Set(nft.NftL1TokenId)
+	}
+	return nNft
+}
+
+/*
+	State: account, asset and nft trees plus the leaves they commit to
+*/
+type State struct {
+	Config      circuit.Config
+	AccountTree *merkleTree.Tree
+	AssetTrees  map[int64]*merkleTree.Tree
+	NftTree     *merkleTree.Tree
+	Accounts    map[int64]*Account
+	Nfts        map[int64]*types.Nft
+
+	// nodes of an empty asset tree and leaf of an empty account, they depend on the tree depths
+	emptyAssetTreeNodes [][]byte
+	emptyAssetRoot      []byte
+	nilAccountNodeHash  []byte
+
+	// updates of the block in progress, nil out of BuildBlock and ExecuteBlock
+	journal *stateJournal
+}
+
+func NewState() (*State, error) {
+	return NewStateWithConfig(circuit.DefaultConfig())
+}
+
+/*
+	NewStateWithConfig: empty trees with the depths of the config, the circuit must be compiled with the same config
+*/
+func NewStateWithConfig(config circuit.Config) (*State, error) {
+	s, err := newState(config)
+	if err != nil {
+		return nil, err
+	}
+	s.AccountTree, err = merkleTree.NewEmptyTree(config.AccountMerkleLevels, s.nilAccountNodeHash, mimc.NewMiMC())
+	if err != nil {
+		log.Println("[NewState] unable to create account tree:", err)
+		return nil, err
+	}
+	s.NftTree, err = merkleTree.NewEmptyTree(config.NftMerkleLevels, NilNftNodeHash, mimc.NewMiMC())
+	if err != nil {
+		log.Println("[NewState] unable to create nft tree:", err)
+		return nil, err
+	}
+	return s, nil
+}
+
+func newState(config circuit.Config) (*State, error) {
+	err := config.Validate()
+	if err != nil {
+		log.Println("[NewState] invalid config:", err)
+		return nil, err
+	}
+	emptyAssetTreeNodes := computeEmptyTreeNodes(config.AssetMerkleLevels, NilAccountAssetNodeHash)
+	emptyAssetRoot := config.EmptyAssetRoot().FillBytes(make([]byte, 32))
+	return &State{
+		Config:              config,
+		AssetTrees:          make(map[int64]*merkleTree.Tree),
+		Accounts:            make(map[int64]*Account),
+		Nfts:                make(map[int64]*types.Nft),
+		emptyAssetTreeNodes: emptyAssetTreeNodes,
+		emptyAssetRoot:      emptyAssetRoot,
+		nilAccountNodeHash:  ComputeAccountLeafHash(EmptyAccount(0), emptyAssetRoot),
+	}, nil
+}
+
+/*
+	LoadState: wrap existing trees together with the leaves they commit to,
+	every leaf is checked against the hash stored in its tree
+*/
+func LoadState(
+	accountTree *merkleTree.Tree,
+	assetTrees map[int64]*merkleTree.Tree,
+	nftTree *merkleTree.Tree,
+	accounts map[int64]*Account,
+	nfts map[int64]*types.Nft,
+) (s *State, err error) {
+	return LoadStateWithConfig(circuit.DefaultConfig(), accountTree, assetTrees, nftTree, accounts, nfts)
+}
+
+/*
+	LoadStateWithConfig: same as LoadState for trees built with the depths of the config
+*/
+func LoadStateWithConfig(
+	config circuit.Config,
+	accountTree *merkleTree.Tree,
+	assetTrees map[int64]*merkleTree.Tree,
+	nftTree *merkleTree.Tree,
+	accounts map[int64]*Account,
+	nfts map[int64]*types.Nft,
+) (s *State, err error) {
+	s, err = newState(config)
+	if err != nil {
+		return nil, err
+	}
+	if accountTree == nil || accountTree.MaxHeight != config.AccountMerkleLevels ||
+		nftTree == nil || nftTree.MaxHeight != config.NftMerkleLevels {
+		log.Println("[LoadState] invalid account or nft tree")
+		return nil, ErrInvalidTree
+	}
+	if assetTrees == nil {
+		assetTrees = make(map[int64]*merkleTree.Tree)
+	}
+	for _, assetTree := range assetTrees {
+		if assetTree == nil || assetTree.MaxHeight != config.AssetMerkleLevels {
+			log.Println("[LoadState] invalid asset tree")
+			return nil, ErrInvalidTree
+		}
+	}
+	s.AccountTree = accountTree
+	s.AssetTrees = assetTrees
+	s.NftTree = nftTree
+	for accountIndex, account := range accounts {
+		if account == nil || account.AccountIndex != accountIndex {
+			return nil, ErrInvalidAccountIndex
+		}
+		if account.AccountIndex < 0 || account.AccountIndex > config.LastAccountIndex() {
+			return nil, ErrInvalidAccountIndex
+		}
+		for assetId, asset := range account.AssetsInfo {
+			if asset == nil || asset.AssetId != assetId || assetId < 0 || assetId > config.LastAccountAssetId() {
+				return nil, ErrInvalidAssetId
+			}
+			nodeHash := ComputeAccountAssetLeafHash(asset.Balance, asset.OfferCanceledOrFinalized)
+			leaf, err := leafHash(assetTrees[accountIndex], assetId, NilAccountAssetNodeHash)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(leaf, nodeHash) {
+				log.Println("[LoadState] asset leaf mismatch, account:", accountIndex, "asset:", assetId)
+				return nil, ErrLeafMismatch
+			}
+		}
+		nodeHash := ComputeAccountLeafHash(account, s.AssetRoot(accountIndex))
+		leaf, err := leafHash(accountTree, accountIndex, s.nilAccountNodeHash)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(leaf, nodeHash) {
+			log.Println("[LoadState] account leaf mismatch, account:", accountIndex)
+			return nil, ErrLeafMismatch
+		}
+		s.Accounts[accountIndex] = account.Copy()
+	}
+	for nftIndex, nft := range nfts {
+		if nft == nil || nft.NftIndex != nftIndex || nftIndex < 0 || nftIndex > config.LastNftIndex() {
+			return nil, ErrInvalidNftIndex
+		}
+		leaf, err := leafHash(nftTree, nftIndex, NilNftNodeHash)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(leaf, ComputeNftLeafHash(nft)) {
+			log.Println("[LoadState] nft leaf mismatch, nft:", nftIndex)
+			return nil, ErrLeafMismatch
+		}
+		s.Nfts[nftIndex] = copyNft(nft)
+	}
+	return s, nil
+}
+
+func leafHash(tree *merkleTree.Tree, index int64, nilHash []byte) ([]byte, error) {
+	if tree == nil {
+		return nilHash, nil
+	}
+	return tree.Leaf(index)
+}
+
+/*
+	SetAccount: write the account and all of its assets into the trees
+*/
+func (s *State) SetAccount(account *Account) (err error) {
+	if account.AccountIndex < 0 || account.AccountIndex > s.Config.LastAccountIndex() {
+		return ErrInvalidAccountIndex
+	}
+	for assetId, asset := range account.AssetsInfo {
+		if assetId != asset.AssetId {
+			return ErrInvalidAssetId
+		}
+		err = s.updateAsset(account.AccountIndex, asset)
+		if err != nil {
+			return err
+		}
+	}
+	return s.updateAccount(account)
+}
+
+/*
+	SetNft: write the nft into the nft tree
+*/
+func (s *State) SetNft(nft *types.Nft) (err error) {
+	return s.updateNft(nft)
+}
+
+/*
+	Account: returns a copy of the account, or an empty account if it does not exist
+*/
+func (s *State) Account(accountIndex int64) *Account {
+	account, ok := s.Accounts[accountIndex]
+	if !ok {
+		return EmptyAccount(accountIndex)
+	}
+	return account.Copy()
+}
+
+/*
+	Nft: returns a copy of the nft, or an empty nft if it does not exist
+*/
+func (s *State) Nft(nftIndex int64) *types.Nft {
+	nft, ok := s.Nfts[nftIndex]
+	if !ok {
+		return types.EmptyNft(nftIndex)
+	}
+	return copyNft(nft)
+}
+
+func (s *State) AccountRoot() []byte {
+	return s.AccountTree.Root()
+}
+
+func (s *State) NftRoot() []byte {
+	return s.NftTree.Root()
+}
+
+func (s *State) StateRoot() []byte {
+	return ComputeStateRoot(s.AccountTree.Root(), s.NftTree.Root())
+}
+
+func (s *State) AssetRoot(accountIndex int64) []byte {
+	assetTree, ok := s.AssetTrees[accountIndex]
+	if !ok {
+		return common.CopyBytes(s.emptyAssetRoot)
+	}
+	return assetTree.Root()
+}
+
+func (s *State) assetTree(accountIndex int64) (*merkleTree.Tree, error) {
+	assetTree, ok := s.AssetTrees[accountIndex]
+	if ok {
+		return assetTree, nil
+	}
+	assetTree, err := merkleTree.NewEmptyTree(s.Config.AssetMerkleLevels, NilAccountAssetNodeHash, mimc.NewMiMC())
+	if err != nil {
+		log.Println("[assetTree] unable to create asset tree:", err)
+		return nil, err
+	}
+	s.AssetTrees[accountIndex] = assetTree
+	return assetTree, nil
+}
+
+func (s *State) AccountMerkleProofs(accountIndex int64) (proofs [][]byte, err error) {
+	proofs = make([][]byte, s.Config.AccountMerkleLevels)
+	err = buildMerkleProofs(s.AccountTree, accountIndex, proofs)
+	return proofs, err
+}
+
+func (s *State) AssetMerkleProofs(accountIndex, assetId int64) (proofs [][]byte, err error) {
+	proofs = make([][]byte, s.Config.AssetMerkleLevels)
+	assetTree, ok := s.AssetTrees[accountIndex]
+	if !ok {
+		for i := range proofs {
+			proofs[i] = common.CopyBytes(s.emptyAssetTreeNodes[i])
+		}
+		return proofs, nil
+	}
+	err = buildMerkleProofs(assetTree, assetId, proofs)
+	return proofs, err
+}
+
+func (s *State) NftMerkleProofs(nftIndex int64) (proofs [][]byte, err error) {
+	proofs = make([][]byte, s.Config.NftMerkleLevels)
+	err = buildMerkleProofs(s.NftTree, nftIndex, proofs)
+	return proofs, err
+}
+
+// ids of the trees in the merkle proofs of the state, the id of the asset tree of an account is AssetTreeIdBase plus the account index
+const (
+	AccountTreeId   uint64 = 0
+	NftTreeId       uint64 = 1
+	AssetTreeIdBase uint64 = 2
+)
+
+func AssetTreeId(accountIndex int64) uint64 {
+	return AssetTreeIdBase + uint64(accountIndex)
+}
+
+/*
+	AccountProof: merkle proof of the account leaf, see Config.AccountMerkleProofs for the witness
+*/
+func (s *State) AccountProof(accountIndex int64) (*merkleTree.MerkleProof, error) {
+	if accountIndex < 0 || accountIndex > s.Config.LastAccountIndex() {
+		return nil, ErrInvalidAccountIndex
+	}
+	return s.AccountTree.Proof(AccountTreeId, accountIndex)
+}
+
+/*
+	AssetProof: merkle proof of the asset leaf in the asset tree of the account
+*/
+func (s *State) AssetProof(accountIndex, assetId int64) (*merkleTree.MerkleProof, error) {
+	if accountIndex < 0 || accountIndex > s.Config.LastAccountIndex() {
+		return nil, ErrInvalidAccountIndex
+	}
+	if assetId < 0 || assetId > s.Config.LastAccountAssetId() {
+		return nil, ErrInvalidAssetId
+	}
+	assetTree, ok := s.AssetTrees[accountIndex]
+	if !ok {
+		proof := &merkleTree.MerkleProof{
+			TreeId:   AssetTreeId(accountIndex),
+			Index:    assetId,
+			Depth:    s.Config.AssetMerkleLevels,
+			Root:     common.CopyBytes(s.emptyAssetRoot),
+			Leaf:     common.CopyBytes(NilAccountAssetNodeHash),
+			Siblings: make([][]byte, s.Config.AssetMerkleLevels),
+		}
+		for i := range proof.Siblings {
+			proof.Siblings[i] = common.CopyBytes(s.emptyAssetTreeNodes[i])
+		}
+		return proof, nil
+	}
+	return assetTree.Proof(AssetTreeId(accountIndex), assetId)
+}
+
+/*
+	NftProof: merkle proof of the nft leaf
+*/
+func (s *State) NftProof(nftIndex int64) (*merkleTree.MerkleProof, error) {
+	if nftIndex < 0 || nftIndex > s.Config.LastNftIndex() {
+		return nil, ErrInvalidNftIndex
+	}
+	return s.NftTree.Proof(NftTreeId, nftIndex)
+}
+
+/*
+	EmptyAccountProof: proof that the account slot is empty, such as before a RegisterZns tx
+*/
+func (s *State) EmptyAccountProof(accountIndex int64) (*merkleTree.ExclusionProof, error) {
+	return s.AccountTree.BuildExclusionProof(accountIndex)
+}
+
+/*
+	EmptyNftProof: proof that the nft slot is empty, such as before a DepositNft or MintNft tx
+*/
+func (s *State) EmptyNftProof(nftIndex int64) (*merkleTree.ExclusionProof, error) {
+	return s.NftTree.BuildExclusionProof(nftIndex)
+}
+
+func buildMerkleProofs(tree *merkleTree.Tree, index int64, proofs [][]byte) error {
+	merkleProofs, _, err := tree.BuildMerkleProofs(index)
+	if err != nil {
+		log.Println("[buildMerkleProofs] unable to build merkle proofs:", err)
+		return err
+	}
+	if len(merkleProofs) != len(proofs) {
+		log.Println("[buildMerkleProofs] invalid merkle proofs length")
+		return ErrInvalidMerkleProofsLength
+	}
+	for i := range merkleProofs {
+		proofs[i] = common.CopyBytes(merkleProofs[i])
+	}
+	return nil
+}
+
+/*
+	updateLeaf: leaves which keep the same hash are not written, so that the
+	paths of the placeholder indexes used by unused slots are not rehashed
+*/
+func updateLeaf(tree *merkleTree.Tree, index int64, nodeHash []byte) error {
+	leaf, err := tree.Leaf(index)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(leaf, nodeHash) {
+		return nil
+	}
+	return tree.Update(index, nodeHash)
+}
+
+func (s *State) updateAsset(accountIndex int64, asset *types.AccountAsset) (err error) {
+	if asset.AssetId < 0 || asset.AssetId > s.Config.LastAccountAssetId() {
+		return ErrInvalidAssetId
+	}
+	nodeHash := ComputeAccountAssetLeafHash(asset.Balance, asset.OfferCanceledOrFinalized)
+	if bytes.Equal(nodeHash, NilAccountAssetNodeHash) {
+		if _, ok := s.AssetTrees[accountIndex]; !ok {
+			return nil
+		}
+	}
+	err = s.recordAsset(accountIndex, asset.AssetId)
+	if err != nil {
+		return err
+	}
+	assetTree, err := s.assetTree(accountIndex)
+	if err != nil {
+		return err
+	}
+	err = updateLeaf(assetTree, asset.AssetId, nodeHash)
+	if err != nil {
+		log.Println("[updateAsset] unable to update asset tree:", err)
+		return err
+	}
+	account, ok := s.Accounts[accountIndex]
+	if !ok {
+		account = EmptyAccount(accountIndex)
+		s.Accounts[accountIndex] = account
+	}
+	if bytes.Equal(nodeHash, NilAccountAssetNodeHash) {
+		delete(account.AssetsInfo, asset.AssetId)
+	} else {
+		account.AssetsInfo[asset.AssetId] = copyAccountAsset(asset)
+	}
+	return nil
+}
+
+/*
+	updateAccount: update the account leaf, assets should have been written before
+*/
+func (s *State) updateAccount(account *Account) (err error) {
+	if account.AccountIndex < 0 || account.AccountIndex > s.Config.LastAccountIndex() {
+		return ErrInvalidAccountIndex
+	}
+	err = s.recordAccount(account.AccountIndex)
+	if err != nil {
+		return err
+	}
+	nodeHash := ComputeAccountLeafHash(account, s.AssetRoot(account.AccountIndex))
+	err = updateLeaf(s.AccountTree, account.AccountIndex, nodeHash)
+	if err != nil {
+		log.Println("[updateAccount] unable to update account tree:", err)
+		return err
+	}
+	if bytes.Equal(nodeHash, s.nilAccountNodeHash) {
+		delete(s.Accounts, account.AccountIndex)
+		return nil
+	}
+	nAccount, ok := s.Accounts[account.AccountIndex]
+	if !ok {
+		nAccount = EmptyAccount(account.AccountIndex)
+		s.Accounts[account.AccountIndex] = nAccount
+	}
+	nAccount.AccountNameHash = common.CopyBytes(account.AccountNameHash)
+	nAccount.AccountPk = &eddsa.PublicKey{A: account.AccountPk.A}
+	nAccount.Nonce = account.Nonce
+	nAccount.CollectionNonce = account.CollectionNonce
+	return nil
+}
+
+func (s *State) updateNft(nft *types.Nft) (err error) {
+	if nft.NftIndex < 0 || nft.NftIndex > s.Config.LastNftIndex() {
+		return ErrInvalidNftIndex
+	}
+	err = s.recordNft(nft.NftIndex)
+	if err != nil {
+		return err
+	}
+	nodeHash := ComputeNftLeafHash(nft)
+	err = updateLeaf(s.NftTree, nft.NftIndex, nodeHash)
+	if err != nil {
+		log.Println("[updateNft] unable to update nft tree:", err)
+		return err
+	}
+	if bytes.Equal(nodeHash, NilNftNodeHash) {
+		delete(s.Nfts, nft.NftIndex)
+	} else {
+		s.Nfts[nft.NftIndex] = copyNft(nft)
+	}
+	return nil
+}
+
+/*
+	computeEmptyTreeNodes: node of every level of an empty tree, starting from the leaf
+*/
+func computeEmptyTreeNodes(levels int, nilHash []byte) [][]byte {
+	nodes := make([][]byte, levels)
+	nodes[0] = nilHash
+	for i := 1; i < levels; i++ {
+		nodes[i] = mimcHash(new(big.Int).SetBytes(nodes[i-1]), new(big.Int).SetBytes(nodes[i-1]))
+	}
+	return nodes
+}
@@ -0,0 +1,300 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor
+
+import (
+	"log"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+/*
+	IsLayer2Tx: txs which are signed by the account and pay gas
+*/
+func IsLayer2Tx(txType uint8) bool {
+	switch txType {
+	case types.TxTypeTransfer,
+		types.TxTypeWithdraw,
+		types.TxTypeCreateCollection,
+		types.TxTypeMintNft,
+		types.TxTypeTransferNft,
+		types.TxTypeAtomicMatch,
+		types.TxTypeCancelOffer,
+		types.TxTypeWithdrawNft:
+		return true
+	default:
+		return false
+	}
+}
+
+func verifySignature(pk *eddsa.PublicKey, sig *eddsa.Signature, hashVal []byte) error {
+	if pk == nil || sig == nil {
+		return ErrInvalidSignature
+	}
+	isValid, err := pk.Verify(sig.Bytes(), hashVal, mimc.NewMiMC())
+	if err != nil || !isValid {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func isOfferCanceledOrFinalized(offerCanceledOrFinalized *big.Int, offerId int64) bool {
+	return offerCanceledOrFinalized.Bit(int(offerId%circuit.OfferSizePerAsset)) == 1
+}
+
+/*
+	verifyTx: out-of-circuit version of the checks done by VerifyTransaction and the Verify*Tx functions,
+	accountsBefore and nftBefore are the values the circuit sees in each slot
+*/
+func verifyTx(
+	oTx *circuit.Tx,
+	accountsBefore [circuit.NbAccountsPerTx]*types.Account,
+	nftBefore *types.Nft,
+	blockCreatedAt int64,
+	chainId int64,
+	emptyAssetRoot []byte,
+) (err error) {
+	// the offer bitmaps of the buyer and seller slots are checked for every tx
+	var buyOfferId, sellOfferId int64
+	if oTx.TxType == types.TxTypeAtomicMatch {
+		buyOfferId = oTx.AtomicMatchTxInfo.BuyOffer.OfferId
+		sellOfferId = oTx.AtomicMatchTxInfo.SellOffer.OfferId
+	}
+	if accountsBefore[1].AssetsInfo[1].OfferCanceledOrFinalized.BitLen() > circuit.OfferSizePerAsset ||
+		accountsBefore[2].AssetsInfo[1].OfferCanceledOrFinalized.BitLen() > circuit.OfferSizePerAsset {
+		log.Println("[verifyTx] invalid offer bitmap")
+		return ErrOfferCanceledOrFinalized
+	}
+	if isOfferCanceledOrFinalized(accountsBefore[1].AssetsInfo[1].OfferCanceledOrFinalized, buyOfferId) ||
+		isOfferCanceledOrFinalized(accountsBefore[2].AssetsInfo[1].OfferCanceledOrFinalized, sellOfferId) {
+		log.Println("[verifyTx] offer is canceled or finalized")
+		return ErrOfferCanceledOrFinalized
+	}
+
+	if IsLayer2Tx(oTx.TxType) {
+		if oTx.Nonce != accountsBefore[0].Nonce {
+			log.Println("[verifyTx] invalid nonce")
+			return ErrInvalidNonce
+		}
+		if blockCreatedAt > oTx.ExpiredAt {
+			log.Println("[verifyTx] tx expired")
+			return ErrTxExpired
+		}
+		hashVal, err := ComputeTxHash(chainId, oTx)
+		if err != nil {
+			return err
+		}
+		err = verifySignature(accountsBefore[0].AccountPk, oTx.Signature, hashVal)
+		if err != nil {
+			log.Println("[verifyTx] invalid signature")
+			return err
+		}
+	}
+
+	fromAccount := accountsBefore[0]
+	switch oTx.TxType {
+	case types.TxTypeRegisterZns:
+		if !bytesEqual(fromAccount.AccountNameHash, nil) ||
+			fromAccount.Nonce != 0 ||
+			fromAccount.CollectionNonce != 0 ||
+			!bytesEqual(fromAccount.AssetRoot, emptyAssetRoot) {
+			return ErrAccountNotEmpty
+		}
+		x, y := publicKeyToBigInt(fromAccount.AccountPk)
+		if x.Sign() != 0 || y.Sign() != 0 {
+			return ErrAccountNotEmpty
+		}
+	case types.TxTypeDeposit:
+		tx := oTx.DepositTxInfo
+		if !bytesEqual(tx.AccountNameHash, fromAccount.AccountNameHash) {
+			return ErrAccountNameHashMismatch
+		}
+	case types.TxTypeDepositNft:
+		tx := oTx.DepositNftTxInfo
+		if !isEmptyNft(nftBefore) {
+			return ErrNftNotEmpty
+		}
+		if !bytesEqual(tx.AccountNameHash, fromAccount.AccountNameHash) {
+			return ErrAccountNameHashMismatch
+		}
+	case types.TxTypeTransfer:
+		tx := oTx.TransferTxInfo
+		if !bytesEqual(tx.ToAccountNameHash, accountsBefore[1].AccountNameHash) {
+			return ErrAccountNameHashMismatch
+		}
+		assetAmount, _ := unpackAmount(tx.AssetAmount)
+		gasFeeAssetAmount, _ := unpackFee(tx.GasFeeAssetAmount)
+		if !isLessOrEqual(assetAmount, fromAccount.AssetsInfo[0].Balance) ||
+			!isLessOrEqual(gasFeeAssetAmount, fromAccount.AssetsInfo[1].Balance) {
+			return ErrInsufficientBalance
+		}
+	case types.TxTypeWithdraw:
+		tx := oTx.WithdrawTxInfo
+		gasFeeAssetAmount, _ := unpackFee(tx.GasFeeAssetAmount)
+		if !isLessOrEqual(tx.AssetAmount, fromAccount.AssetsInfo[0].Balance) ||
+			!isLessOrEqual(gasFeeAssetAmount, fromAccount.AssetsInfo[1].Balance) {
+			return ErrInsufficientBalance
+		}
+	case types.TxTypeCreateCollection:
+		tx := oTx.CreateCollectionTxInfo
+		if tx.CollectionId < 0 || tx.CollectionId > 65535 || tx.CollectionId != fromAccount.CollectionNonce {
+			return ErrInvalidCollectionId
+		}
+		gasFeeAssetAmount, _ := unpackAmount(tx.GasFeeAssetAmount)
+		if !isLessOrEqual(gasFeeAssetAmount, fromAccount.AssetsInfo[0].Balance) {
+			return ErrInsufficientBalance
+		}
+	case types.TxTypeMintNft:
+		tx := oTx.MintNftTxInfo
+		if !isEmptyNft(nftBefore) {
+			return ErrNftNotEmpty
+		}
+		if !bytesEqual(tx.ToAccountNameHash, accountsBefore[1].AccountNameHash) {
+			return ErrAccountNameHashMismatch
+		}
+		if bytesEqual(tx.NftContentHash, nil) {
+			return ErrInvalidNftContentHash
+		}
+		gasFeeAssetAmount, _ := unpackFee(tx.GasFeeAssetAmount)
+		if !isLessOrEqual(gasFeeAssetAmount, fromAccount.AssetsInfo[0].Balance) {
+			return ErrInsufficientBalance
+		}
+		if tx.CollectionId < 0 || tx.CollectionId >= fromAccount.CollectionNonce {
+			return ErrInvalidCollectionId
+		}
+	case types.TxTypeTransferNft:
+		tx := oTx.TransferNftTxInfo
+		if !bytesEqual(tx.ToAccountNameHash, accountsBefore[1].AccountNameHash) {
+			return ErrAccountNameHashMismatch
+		}
+		if tx.FromAccountIndex != nftBefore.OwnerAccountIndex {
+			return ErrNftMismatch
+		}
+		gasFeeAssetAmount, _ := unpackFee(tx.GasFeeAssetAmount)
+		if !isLessOrEqual(gasFeeAssetAmount, fromAccount.AssetsInfo[0].Balance) {
+			return ErrInsufficientBalance
+		}
+	case types.TxTypeAtomicMatch:
+		tx := oTx.AtomicMatchTxInfo
+		if tx.BuyOffer.Type != 0 || tx.SellOffer.Type != 1 ||
+			tx.BuyOffer.AssetId != tx.SellOffer.AssetId ||
+			tx.BuyOffer.AssetAmount != tx.SellOffer.AssetAmount ||
+			tx.BuyOffer.NftIndex != tx.SellOffer.NftIndex ||
+			tx.BuyOffer.TreasuryRate != tx.SellOffer.TreasuryRate {
+			return ErrInvalidOffer
+		}
+		if blockCreatedAt > tx.BuyOffer.ExpiredAt || blockCreatedAt > tx.SellOffer.ExpiredAt {
+			return ErrTxExpired
+		}
+		if tx.AccountIndex != tx.BuyOffer.AccountIndex {
+			err = verifySignature(accountsBefore[1].AccountPk, tx.BuyOffer.Sig, ComputeOfferHash(chainId, tx.BuyOffer))
+			if err != nil {
+				log.Println("[verifyTx] invalid buy offer signature")
+				return err
+			}
+		}
+		if tx.AccountIndex != tx.SellOffer.AccountIndex {
+			err = verifySignature(accountsBefore[2].AccountPk, tx.SellOffer.Sig, ComputeOfferHash(chainId, tx.SellOffer))
+			if err != nil {
+				log.Println("[verifyTx] invalid sell offer signature")
+				return err
+			}
+		}
+		assetAmount, _ := unpackAmount(tx.BuyOffer.AssetAmount)
+		if !isLessOrEqual(assetAmount, accountsBefore[1].AssetsInfo[0].Balance) {
+			return ErrInsufficientBalance
+		}
+		gasFeeAssetAmount, _ := unpackFee(tx.GasFeeAssetAmount)
+		if !isLessOrEqual(gasFeeAssetAmount, fromAccount.AssetsInfo[0].Balance) {
+			return ErrInsufficientBalance
+		}
+	case types.TxTypeCancelOffer:
+		tx := oTx.CancelOfferTxInfo
+		gasFeeAssetAmount, _ := unpackFee(tx.GasFeeAssetAmount)
+		if !isLessOrEqual(gasFeeAssetAmount, fromAccount.AssetsInfo[0].Balance) {
+			return ErrInsufficientBalance
+		}
+	case types.TxTypeWithdrawNft:
+		tx := oTx.WithdrawNftTxInfo
+		if !bytesEqual(tx.CreatorAccountNameHash, accountsBefore[1].AccountNameHash) {
+			return ErrAccountNameHashMismatch
+		}
+		nftL1Address, err := types.StringToFieldElement(tx.NftL1Address)
+		if err != nil {
+			return ErrInvalidTxInfo
+		}
+		if tx.CollectionId != nftBefore.CollectionId ||
+			tx.CreatorAccountIndex != nftBefore.CreatorAccountIndex ||
+			tx.CreatorTreasuryRate != nftBefore.CreatorTreasuryRate ||
+			tx.AccountIndex != nftBefore.OwnerAccountIndex ||
+			!bytesEqual(tx.NftContentHash, nftBefore.NftContentHash) ||
+			!fieldEqual(tx.NftL1TokenId, nftBefore.NftL1TokenId) ||
+			!fieldEqual(nftL1Address, nftBefore.NftL1Address) {
+			return ErrNftMismatch
+		}
+		gasFeeAssetAmount, _ := unpackFee(tx.GasFeeAssetAmount)
+		if !isLessOrEqual(gasFeeAssetAmount, fromAccount.AssetsInfo[0].Balance) {
+			return ErrInsufficientBalance
+		}
+	case types.TxTypeFullExit:
+		tx := oTx.FullExitTxInfo
+		if !bytesEqual(tx.AccountNameHash, fromAccount.AccountNameHash) {
+			return ErrAccountNameHashMismatch
+		}
+		if !fieldEqual(tx.AssetAmount, fromAccount.AssetsInfo[0].Balance) {
+			return ErrInvalidAssetAmount
+		}
+	case types.TxTypeFullExitNft:
+		tx := oTx.FullExitNftTxInfo
+		if !bytesEqual(tx.AccountNameHash, fromAccount.AccountNameHash) {
+			return ErrAccountNameHashMismatch
+		}
+		if !bytesEqual(tx.CreatorAccountNameHash, nil) &&
+			(tx.CreatorAccountIndex != nftBefore.CreatorAccountIndex ||
+				tx.CreatorTreasuryRate != nftBefore.CreatorTreasuryRate) {
+			return ErrNftMismatch
+		}
+		if tx.AccountIndex == nftBefore.OwnerAccountIndex {
+			nftL1Address, err := types.StringToFieldElement(tx.NftL1Address)
+			if err != nil {
+				return ErrInvalidTxInfo
+			}
+			if !bytesEqual(tx.NftContentHash, nftBefore.NftContentHash) ||
+				!fieldEqual(nftL1Address, nftBefore.NftL1Address) ||
+				!fieldEqual(tx.NftL1TokenId, nftBefore.NftL1TokenId) {
+				return ErrNftMismatch
+			}
+		}
+	}
+	return nil
+}
+
+func isEmptyNft(nft *types.Nft) bool {
+	return bytesEqual(nft.NftContentHash, nil) &&
+		nft.CreatorAccountIndex == 0 &&
+		nft.OwnerAccountIndex == 0 &&
+		fieldEqual(nft.NftL1Address, nil) &&
+		fieldEqual(nft.NftL1TokenId, nil) &&
+		nft.CreatorTreasuryRate == 0 &&
+		nft.CollectionId == 0
+}
@@ -39,17 +39,19 @@ type GasAccountConstraints struct {
 type GasConstraints struct {
 	GasAssetCount                   int
 	AccountInfoBefore               GasAccountConstraints
-	MerkleProofsAccountBefore       [AccountMerkleLevels]Variable
-	MerkleProofsAccountAssetsBefore [][AssetMerkleLevels]Variable
+	MerkleProofsAccountBefore       []Variable
+	MerkleProofsAccountAssetsBefore [][]Variable
 }
 
 func VerifyGas(
 	api API,
+	config Config,
 	gas GasConstraints,
 	needGas Variable,
 	gasAssetDeltas []Variable,
 	hFunc MiMC,
 	accountRoot Variable) (newAccountRoot Variable, err error) {
+	defer types.Profile(api, "block/gas")()
 	newAccountRoot = accountRoot
 	newAccountAssetsRoot := gas.AccountInfoBefore.AssetRoot
 
@@ -58,7 +60,7 @@ func VerifyGas(
 
 	gasAssetCount := len(gasAssetDeltas)
 	for i := 0; i < gasAssetCount; i++ {
-		assetMerkleHelper := AssetIdToMerkleHelper(api, gas.AccountInfoBefore.AssetsInfo[i].AssetId)
+		assetMerkleHelper := AssetIdToMerkleHelper(api, config, gas.AccountInfoBefore.AssetsInfo[i].AssetId)
 		hFunc.Reset()
 		hFunc.Write(
 			gas.AccountInfoBefore.AssetsInfo[i].Balance,
@@ -86,7 +88,7 @@ func VerifyGas(
 			api, hFunc, assetNodeHash, gas.MerkleProofsAccountAssetsBefore[i][:], assetMerkleHelper)
 	}
 	// verify account node hash
-	accountIndexMerkleHelper := AccountIndexToMerkleHelper(api, gas.AccountInfoBefore.AccountIndex)
+	accountIndexMerkleHelper := AccountIndexToMerkleHelper(api, config, gas.AccountInfoBefore.AccountIndex)
 	hFunc.Reset()
 	hFunc.Write(
 		gas.AccountInfoBefore.AccountNameHash,
@@ -124,7 +126,17 @@ func VerifyGas(
 	return newAccountRoot, err
 }
 
+/*
+	GetZeroGasConstraints: empty gas of the circuit with the default config
+*/
 func GetZeroGasConstraints(gasAssets []int64) GasConstraints {
+	return GetZeroGasConstraintsWithConfig(DefaultConfig(), gasAssets)
+}
+
+/*
+	GetZeroGasConstraintsWithConfig: same as GetZeroGasConstraints for the tree depths of the config
+*/
+func GetZeroGasConstraintsWithConfig(config Config, gasAssets []int64) GasConstraints {
 	gasAssetCount := len(gasAssets)
 	var zeroGasConstraint GasConstraints
 	zeroGasConstraint.GasAssetCount = gasAssetCount
@@ -141,22 +153,24 @@ func GetZeroGasConstraints(gasAssets []int64) GasConstraints {
 	}
 	zeroAccountConstraint.AssetsInfo = make([]types.AccountAssetConstraints, gasAssetCount)
 	// set assets witness
-	for _, assetId := range gasAssets {
-		zeroAccountConstraint.AssetsInfo[assetId] = types.AccountAssetConstraints{
+	for i := range gasAssets {
+		zeroAccountConstraint.AssetsInfo[i] = types.AccountAssetConstraints{
 			AssetId: 0,
 			Balance: 0,
 		}
 	}
 	// accounts info before
 	zeroGasConstraint.AccountInfoBefore = zeroAccountConstraint
-	zeroGasConstraint.MerkleProofsAccountAssetsBefore = make([][AssetMerkleLevels]Variable, gasAssetCount)
+	zeroGasConstraint.MerkleProofsAccountAssetsBefore = make([][]Variable, gasAssetCount)
 	for j := 0; j < gasAssetCount; j++ {
-		for k := 0; k < AssetMerkleLevels; k++ {
+		zeroGasConstraint.MerkleProofsAccountAssetsBefore[j] = make([]Variable, config.AssetMerkleLevels)
+		for k := 0; k < config.AssetMerkleLevels; k++ {
 			// account assets before
 			zeroGasConstraint.MerkleProofsAccountAssetsBefore[j][k] = 0
 		}
 	}
-	for j := 0; j < AccountMerkleLevels; j++ {
+	zeroGasConstraint.MerkleProofsAccountBefore = make([]Variable, config.AccountMerkleLevels)
+	for j := 0; j < config.AccountMerkleLevels; j++ {
 		// account before
 		zeroGasConstraint.MerkleProofsAccountBefore[j] = 0
 	}
@@ -190,21 +204,37 @@ func SetGasAccountWitness(account *types.GasAccount, assetCount int) (witness Ga
 	return witness, nil
 }
 
+/*
+	SetGasWitness: witness of the gas of a block built with the default config
+*/
 func SetGasWitness(oGas *Gas) (witness GasConstraints, err error) {
+	return SetGasWitnessWithConfig(DefaultConfig(), oGas)
+}
+
+/*
+	SetGasWitnessWithConfig: same as SetGasWitness for the tree depths of the config
+*/
+func SetGasWitnessWithConfig(config Config, oGas *Gas) (witness GasConstraints, err error) {
+	err = config.CheckGas(oGas)
+	if err != nil {
+		log.Println("[SetGasWitness] invalid merkle proofs:", err)
+		return witness, err
+	}
 	witness.GasAssetCount = oGas.GasAssetCount
 	witness.AccountInfoBefore, err = SetGasAccountWitness(oGas.AccountInfoBefore, oGas.GasAssetCount)
 	if err != nil {
 		log.Println("fail to set gas witness, err:", err.Error())
 		return witness, err
 	}
-	for i := 0; i < AccountMerkleLevels; i++ {
+	witness.MerkleProofsAccountBefore = make([]Variable, config.AccountMerkleLevels)
+	for i := 0; i < config.AccountMerkleLevels; i++ {
 		// account before
 		witness.MerkleProofsAccountBefore[i] = oGas.MerkleProofsAccountBefore[i]
 	}
-	witness.MerkleProofsAccountAssetsBefore = make([][AssetMerkleLevels]Variable, 0)
+	witness.MerkleProofsAccountAssetsBefore = make([][]Variable, 0)
 	for i := 0; i < oGas.GasAssetCount; i++ {
-		merkleProofsAccountAssets := [AssetMerkleLevels]Variable{}
-		for j := 0; j < AssetMerkleLevels; j++ {
+		merkleProofsAccountAssets := make([]Variable, config.AssetMerkleLevels)
+		for j := 0; j < config.AssetMerkleLevels; j++ {
 			// account assets before
 			merkleProofsAccountAssets[j] = oGas.MerkleProofsAccountAssetsBefore[i][j]
 		}
@@ -17,17 +17,17 @@
 
 package circuit
 
-func AccountIndexToMerkleHelper(api API, accountIndex Variable) (merkleHelpers []Variable) {
-	merkleHelpers = api.ToBinary(accountIndex, AccountMerkleLevels)
+func AccountIndexToMerkleHelper(api API, config Config, accountIndex Variable) (merkleHelpers []Variable) {
+	merkleHelpers = api.ToBinary(accountIndex, config.AccountMerkleLevels)
 	return merkleHelpers
 }
 
-func AssetIdToMerkleHelper(api API, assetId Variable) (merkleHelpers []Variable) {
-	merkleHelpers = api.ToBinary(assetId, AssetMerkleLevels)
+func AssetIdToMerkleHelper(api API, config Config, assetId Variable) (merkleHelpers []Variable) {
+	merkleHelpers = api.ToBinary(assetId, config.AssetMerkleLevels)
 	return merkleHelpers
 }
 
-func NftIndexToMerkleHelper(api API, nftIndex Variable) (merkleHelpers []Variable) {
-	merkleHelpers = api.ToBinary(nftIndex, NftMerkleLevels)
+func NftIndexToMerkleHelper(api API, config Config, nftIndex Variable) (merkleHelpers []Variable) {
+	merkleHelpers = api.ToBinary(nftIndex, config.NftMerkleLevels)
 	return merkleHelpers
 }
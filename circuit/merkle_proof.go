@@ -0,0 +1,59 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package circuit
+
+import (
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/bnb-chain/zkbnb-crypto/merkleTree"
+)
+
+/*
+	AccountMerkleProofs: merkle proofs of Tx and Gas from a proof of the account tree
+*/
+func (c Config) AccountMerkleProofs(proof *merkleTree.MerkleProof) ([][]byte, error) {
+	return merkleProofsOf(proof, c.AccountMerkleLevels)
+}
+
+/*
+	AssetMerkleProofs: merkle proofs of Tx and Gas from a proof of an asset tree
+*/
+func (c Config) AssetMerkleProofs(proof *merkleTree.MerkleProof) ([][]byte, error) {
+	return merkleProofsOf(proof, c.AssetMerkleLevels)
+}
+
+/*
+	NftMerkleProofs: merkle proofs of Tx from a proof of the nft tree
+*/
+func (c Config) NftMerkleProofs(proof *merkleTree.MerkleProof) ([][]byte, error) {
+	return merkleProofsOf(proof, c.NftMerkleLevels)
+}
+
+func merkleProofsOf(proof *merkleTree.MerkleProof, levels int) ([][]byte, error) {
+	if proof == nil || proof.Depth != levels || len(proof.Siblings) != levels {
+		log.Println("[Config] the merkle proof doesn't have the depth of the tree")
+		return nil, ErrInvalidMerkleProofs
+	}
+	merkleProofs := make([][]byte, levels)
+	for i := range merkleProofs {
+		merkleProofs[i] = common.CopyBytes(proof.Siblings[i])
+	}
+	return merkleProofs, nil
+}
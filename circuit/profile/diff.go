@@ -0,0 +1,82 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package profile
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+type DiffEntry struct {
+	Name string
+	Old  int
+	New  int
+}
+
+func (d DiffEntry) Delta() int {
+	return d.New - d.Old
+}
+
+/*
+	Diff: constraints of every gadget and tx type in either report, the total first,
+	entries missing from a report count zero constraints
+*/
+func Diff(oldReport, newReport *Report) []DiffEntry {
+	diff := []DiffEntry{{Name: TotalEntry, Old: oldReport.NbConstraints, New: newReport.NbConstraints}}
+	diff = append(diff, diffEntries(oldReport.Entries, newReport.Entries, "")...)
+	diff = append(diff, diffEntries(oldReport.TxTypes(), newReport.TxTypes(), "type/")...)
+	return diff
+}
+
+func diffEntries(oldEntries, newEntries []Entry, prefix string) []DiffEntry {
+	index := make(map[string]int)
+	var diff []DiffEntry
+	for _, entry := range oldEntries {
+		index[entry.Name] = len(diff)
+		diff = append(diff, DiffEntry{Name: prefix + entry.Name, Old: entry.NbConstraints})
+	}
+	for _, entry := range newEntries {
+		i, ok := index[entry.Name]
+		if !ok {
+			i = len(diff)
+			diff = append(diff, DiffEntry{Name: prefix + entry.Name})
+		}
+		diff[i].New = entry.NbConstraints
+	}
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i].Name < diff[j].Name
+	})
+	return diff
+}
+
+/*
+	WriteDiff: only the changed entries are written unless all is set
+*/
+func WriteDiff(w io.Writer, diff []DiffEntry, all bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "gadget\told\tnew\tdelta\t")
+	for _, entry := range diff {
+		if !all && entry.Delta() == 0 && entry.Name != TotalEntry {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%+d\t\n", entry.Name, entry.Old, entry.New, entry.Delta())
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,256 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+)
+
+const (
+	BackendGroth16 = "groth16"
+	BackendPlonk   = "plonk"
+
+	// TotalEntry is the name of the whole block in diffs
+	TotalEntry = "total"
+
+	txEntryPrefix = "tx/"
+)
+
+var (
+	ErrUnknownBackend = errors.New("[Profile] unknown backend")
+	ErrInvalidReport  = errors.New("[Profile] invalid report")
+)
+
+// txSections are the per tx type sections of VerifyTransaction, named tx/<type>/<section>
+var txSections = []string{"hash", "verify", "deltas"}
+
+/*
+	Entry: constraints added by all the calls of a gadget, gadgets may be nested
+	in other ones, eddsa and the merkle updates are evaluated for every tx type
+*/
+type Entry struct {
+	Name          string
+	Calls         int
+	NbConstraints int
+	NbVariables   int
+}
+
+type Report struct {
+	Backend         string
+	Config          circuit.Config
+	TxsCount        int
+	GasAssetIds     []int64
+	GasAccountIndex int64
+	NbConstraints   int
+	Entries         []Entry
+}
+
+/*
+	ProfileBlock: compiles the block circuit with its counters and aggregates them by gadget
+*/
+func ProfileBlock(backendName string, txsCount int, gasAssetIds []int64, gasAccountIndex int64) (*Report, error) {
+	return ProfileBlockWithConfig(backendName, circuit.DefaultConfig(), txsCount, gasAssetIds, gasAccountIndex)
+}
+
+/*
+	ProfileBlockWithConfig: same as ProfileBlock for the block circuit of the config
+*/
+func ProfileBlockWithConfig(backendName string, config circuit.Config, txsCount int, gasAssetIds []int64, gasAccountIndex int64) (*Report, error) {
+	var newBuilder frontend.NewBuilder
+	switch backendName {
+	case BackendGroth16:
+		newBuilder = r1cs.NewBuilder
+	case BackendPlonk:
+		newBuilder = scs.NewBuilder
+	default:
+		return nil, ErrUnknownBackend
+	}
+	blockConstraints, err := circuit.NewBlockConstraints(config, txsCount, gasAssetIds, gasAccountIndex)
+	if err != nil {
+		log.Println("[ProfileBlock] invalid circuit config:", err)
+		return nil, err
+	}
+	blockConstraints.Profile = true
+	ccs, err := frontend.Compile(ecc.BN254, newBuilder, &blockConstraints, frontend.IgnoreUnconstrainedInputs())
+	if err != nil {
+		log.Println("[ProfileBlock] unable to compile circuit:", err)
+		return nil, err
+	}
+	report := NewReport(ccs)
+	report.Backend = backendName
+	report.Config = config
+	report.TxsCount = txsCount
+	report.GasAssetIds = append([]int64{}, gasAssetIds...)
+	report.GasAccountIndex = gasAccountIndex
+	return report, nil
+}
+
+/*
+	NewReport: aggregates the counters of a constraint system by name
+*/
+func NewReport(ccs frontend.CompiledConstraintSystem) *Report {
+	entries := make(map[string]*Entry)
+	for _, counter := range ccs.GetCounters() {
+		name := counterName(counter.From)
+		entry, ok := entries[name]
+		if !ok {
+			entry = &Entry{Name: name}
+			entries[name] = entry
+		}
+		entry.Calls++
+		entry.NbConstraints += counter.NbConstraints
+		entry.NbVariables += counter.NbVariables
+	}
+	report := &Report{
+		NbConstraints: ccs.GetNbConstraints(),
+		Entries:       make([]Entry, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		report.Entries = append(report.Entries, *entry)
+	}
+	sort.Slice(report.Entries, func(i, j int) bool {
+		return report.Entries[i].Name < report.Entries[j].Name
+	})
+	return report
+}
+
+// counterName strips the [file:line] suffix gnark adds to the tags
+func counterName(tag string) string {
+	if i := strings.LastIndex(tag, "["); i > 0 && strings.HasSuffix(tag, "]") {
+		return tag[:i]
+	}
+	return tag
+}
+
+func (r *Report) Entry(name string) (entry Entry, ok bool) {
+	for _, entry = range r.Entries {
+		if entry.Name == name {
+			return entry, true
+		}
+	}
+	return Entry{Name: name}, false
+}
+
+/*
+	TxTypes: sums the sections of each tx type, the sections of every tx type are in each tx
+	of the block since VerifyTransaction selects the results of all of them
+*/
+func (r *Report) TxTypes() []Entry {
+	entries := make(map[string]*Entry)
+	var names []string
+	for _, entry := range r.Entries {
+		txType, ok := txTypeOf(entry.Name)
+		if !ok {
+			continue
+		}
+		sum, ok := entries[txType]
+		if !ok {
+			sum = &Entry{Name: txType, Calls: entry.Calls}
+			entries[txType] = sum
+			names = append(names, txType)
+		}
+		sum.NbConstraints += entry.NbConstraints
+		sum.NbVariables += entry.NbVariables
+	}
+	sort.Strings(names)
+	res := make([]Entry, len(names))
+	for i, name := range names {
+		res[i] = *entries[name]
+	}
+	return res
+}
+
+func txTypeOf(name string) (txType string, ok bool) {
+	if !strings.HasPrefix(name, txEntryPrefix) {
+		return "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(name, txEntryPrefix), "/")
+	if len(parts) != 2 {
+		return "", false
+	}
+	for _, section := range txSections {
+		if parts[1] == section {
+			return parts[0], true
+		}
+	}
+	return "", false
+}
+
+/*
+	WriteReport: human readable report, constraints per call are the cost of the gadget in one tx
+*/
+func WriteReport(w io.Writer, r *Report) error {
+	if _, err := fmt.Fprintf(w, "backend: %s, txs: %d, gas assets: %v, constraints: %d\n",
+		r.Backend, r.TxsCount, r.GasAssetIds, r.NbConstraints); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "gadget\tcalls\tconstraints\tper call\tshare\t")
+	for _, entry := range r.Entries {
+		writeEntry(tw, entry, r.NbConstraints)
+	}
+	fmt.Fprintln(tw, "\t\t\t\t\t")
+	fmt.Fprintln(tw, "tx type\ttxs\tconstraints\tper tx\tshare\t")
+	for _, entry := range r.TxTypes() {
+		writeEntry(tw, entry, r.NbConstraints)
+	}
+	return tw.Flush()
+}
+
+func writeEntry(w io.Writer, entry Entry, total int) {
+	perCall, share := 0, 0.0
+	if entry.Calls > 0 {
+		perCall = entry.NbConstraints / entry.Calls
+	}
+	if total > 0 {
+		share = 100 * float64(entry.NbConstraints) / float64(total)
+	}
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.2f%%\t\n", entry.Name, entry.Calls, entry.NbConstraints, perCall, share)
+}
+
+/*
+	WriteReportJSON: reports are saved to compare revisions of the circuit with Diff
+*/
+func WriteReportJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func ReadReportJSON(r io.Reader) (*Report, error) {
+	report := new(Report)
+	if err := json.NewDecoder(r).Decode(report); err != nil {
+		log.Println("[ReadReportJSON] unable to decode report:", err)
+		return nil, ErrInvalidReport
+	}
+	return report, nil
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package profile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+var txTypeNames = []string{
+	"atomic_match", "cancel_offer", "create_collection", "deposit", "deposit_nft", "full_exit", "full_exit_nft",
+	"mint_nft", "register_zns", "transfer", "transfer_nft", "withdraw", "withdraw_nft",
+}
+
+func TestProfileBlock(t *testing.T) {
+	gasAssetIds := []int64{0, 1}
+	report, err := ProfileBlock(BackendGroth16, 2, gasAssetIds, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, report.TxsCount)
+
+	// counters are only recorded when profiling and don't change the constraints
+	blockConstraints := circuit.GetBlockConstraints(2, gasAssetIds, 1)
+	ccs, err := frontend.Compile(ecc.BN254, r1cs.NewBuilder, &blockConstraints, frontend.IgnoreUnconstrainedInputs())
+	assert.Nil(t, err)
+	assert.Empty(t, ccs.GetCounters())
+	assert.Equal(t, ccs.GetNbConstraints(), report.NbConstraints)
+
+	tx, ok := report.Entry("tx")
+	assert.True(t, ok)
+	assert.Equal(t, 2, tx.Calls)
+	assert.Less(t, tx.NbConstraints, report.NbConstraints)
+	gas, ok := report.Entry("block/gas")
+	assert.True(t, ok)
+	assert.Equal(t, 1, gas.Calls)
+	assert.LessOrEqual(t, tx.NbConstraints+gas.NbConstraints, report.NbConstraints)
+
+	asset, ok := report.Entry("tx/merkle/asset")
+	assert.True(t, ok)
+	assert.Equal(t, 2*types.NbAccountsPerTx*types.NbAccountAssetsPerAccount, asset.Calls)
+	for _, name := range []string{"gadget/eddsa", "gadget/merkle_verify", "gadget/merkle_update",
+		"gadget/offer_bitmap", "gadget/unpack_amount", "gadget/unpack_fee", "tx/merkle/account", "tx/merkle/nft"} {
+		entry, ok := report.Entry(name)
+		assert.True(t, ok, name)
+		assert.True(t, entry.NbConstraints > 0, name)
+	}
+	// the signature of the tx and the ones of the two offers of atomic match
+	eddsa, _ := report.Entry("gadget/eddsa")
+	assert.Equal(t, 2*3, eddsa.Calls)
+
+	txTypes := report.TxTypes()
+	assert.Equal(t, len(txTypeNames), len(txTypes))
+	sum := 0
+	for i, entry := range txTypes {
+		assert.Equal(t, txTypeNames[i], entry.Name)
+		assert.Equal(t, 2, entry.Calls)
+		sum += entry.NbConstraints
+	}
+	assert.Less(t, sum, tx.NbConstraints)
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteReport(&buf, report))
+	assert.Contains(t, buf.String(), "atomic_match")
+	assert.Contains(t, buf.String(), "gadget/offer_bitmap")
+
+	_, err = ProfileBlock("marlin", 1, gasAssetIds, 1)
+	assert.Equal(t, ErrUnknownBackend, err)
+}
+
+func TestProfileBlockWithConfig(t *testing.T) {
+	config := circuit.DefaultConfig()
+	config.AccountMerkleLevels = 8
+	config.AssetMerkleLevels = 4
+	config.NftMerkleLevels = 8
+	report, err := ProfileBlockWithConfig(BackendGroth16, config, 1, []int64{0, 1}, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, config, report.Config)
+	asset, ok := report.Entry("tx/merkle/asset")
+	assert.True(t, ok)
+	assert.Equal(t, types.NbAccountsPerTx*types.NbAccountAssetsPerAccount, asset.Calls)
+
+	config.AssetMerkleLevels = 17
+	_, err = ProfileBlockWithConfig(BackendGroth16, config, 1, []int64{0, 1}, 1)
+	assert.Equal(t, circuit.ErrInvalidConfig, err)
+}
+
+func TestReportJSON(t *testing.T) {
+	report := testReport(100, 40, 30)
+	var buf bytes.Buffer
+	assert.Nil(t, WriteReportJSON(&buf, report))
+	report2, err := ReadReportJSON(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, report, report2)
+
+	_, err = ReadReportJSON(strings.NewReader("{"))
+	assert.Equal(t, ErrInvalidReport, err)
+}
+
+func TestDiff(t *testing.T) {
+	oldReport := testReport(100, 40, 30)
+	newReport := testReport(90, 40, 20)
+	newReport.Entries = append(newReport.Entries, Entry{Name: "gadget/range", Calls: 1, NbConstraints: 5})
+
+	diff := Diff(oldReport, newReport)
+	assert.Equal(t, DiffEntry{Name: TotalEntry, Old: 100, New: 90}, diff[0])
+	deltas := make(map[string]int)
+	for _, entry := range diff {
+		deltas[entry.Name] = entry.Delta()
+	}
+	assert.Equal(t, map[string]int{
+		TotalEntry:           -10,
+		"gadget/eddsa":       0,
+		"gadget/range":       5,
+		"tx/transfer/verify": -10,
+		"type/transfer":      -10,
+	}, deltas)
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteDiff(&buf, diff, false))
+	out := buf.String()
+	assert.Contains(t, out, "tx/transfer/verify")
+	assert.Contains(t, out, "-10")
+	assert.Contains(t, out, "+5")
+	assert.NotContains(t, out, "gadget/eddsa")
+	buf.Reset()
+	assert.Nil(t, WriteDiff(&buf, diff, true))
+	assert.Contains(t, buf.String(), "gadget/eddsa")
+}
+
+func testReport(total, eddsa, transfer int) *Report {
+	return &Report{
+		Backend:         BackendGroth16,
+		TxsCount:        1,
+		GasAssetIds:     []int64{0, 1},
+		GasAccountIndex: 1,
+		NbConstraints:   total,
+		Entries: []Entry{
+			{Name: "gadget/eddsa", Calls: 1, NbConstraints: eddsa, NbVariables: eddsa},
+			{Name: "tx/transfer/verify", Calls: 1, NbConstraints: transfer, NbVariables: transfer},
+		},
+	}
+}
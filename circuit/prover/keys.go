@@ -0,0 +1,461 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"runtime/debug"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/frontend"
+	gnarkio "github.com/consensys/gnark/io"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+)
+
+const (
+	BackendGroth16 = "groth16"
+	BackendPlonk   = "plonk"
+
+	KeyFormatVersion = 1
+
+	// maxKeyHeaderSize bounds the header read before the key itself
+	maxKeyHeaderSize = 1 << 20
+)
+
+var keyMagic = [4]byte{'Z', 'K', 'B', 'K'}
+
+// GnarkVersion is the gnark version the binary is built with, keys and constraint systems
+// of other versions aren't guaranteed to be compatible
+var GnarkVersion = gnarkVersion()
+
+const gnarkModulePath = "github.com/consensys/gnark"
+
+/*
+	gnarkVersion: read from the build info, so that it follows go.mod and its replace directives
+*/
+func gnarkVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != gnarkModulePath {
+			continue
+		}
+		if dep.Replace != nil {
+			if dep.Replace.Version != "" {
+				return dep.Replace.Version
+			}
+			return dep.Replace.Path
+		}
+		return dep.Version
+	}
+	return "unknown"
+}
+
+var (
+	ErrInvalidKeyFile     = errors.New("[Prover] invalid key file")
+	ErrKeyFormatVersion   = errors.New("[Prover] unsupported key format version")
+	ErrKeyGnarkVersion    = errors.New("[Prover] key built with another gnark version")
+	ErrKeyMismatch        = errors.New("[Prover] key doesn't match the circuit")
+	ErrCircuitHashChanged = errors.New("[Prover] constraint system doesn't match its circuit hash")
+	ErrUnknownBackend     = errors.New("[Prover] unknown backend")
+	ErrKeyHashMismatch    = errors.New("[Prover] key doesn't match the key hash of its header")
+)
+
+/*
+	KeyHeader: describes the circuit a constraint system or a key was built for,
+	it is written in front of the serialized object
+*/
+type KeyHeader struct {
+	FormatVersion   uint32
+	Backend         string
+	GnarkVersion    string
+	CircuitHash     string
+	TxsCount        int
+	GasAssetIds     []int64
+	GasAccountIndex int64
+	// tree depths of the circuit, missing in the headers written before it was configurable
+	Config *circuit.Config `json:",omitempty"`
+	// sha256 of the key written after the header, set by WriteKey,
+	// missing in the headers of constraint systems and of the keys written before it was recorded
+	KeyHash string `json:",omitempty"`
+}
+
+/*
+	ComputeCircuitHash: sha256 of the serialized constraint system, the serialization of gnark is deterministic
+*/
+func ComputeCircuitHash(ccs frontend.CompiledConstraintSystem) ([]byte, error) {
+	hFunc := sha256.New()
+	if _, err := ccs.WriteTo(hFunc); err != nil {
+		log.Println("[ComputeCircuitHash] unable to serialize constraint system:", err)
+		return nil, err
+	}
+	return hFunc.Sum(nil), nil
+}
+
+func NewKeyHeader(
+	backendName string, ccs frontend.CompiledConstraintSystem,
+	config circuit.Config, txsCount int, gasAssetIds []int64, gasAccountIndex int64,
+) (*KeyHeader, error) {
+	if backendName != BackendGroth16 && backendName != BackendPlonk {
+		return nil, ErrUnknownBackend
+	}
+	circuitHash, err := ComputeCircuitHash(ccs)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyHeader{
+		FormatVersion:   KeyFormatVersion,
+		Backend:         backendName,
+		GnarkVersion:    GnarkVersion,
+		CircuitHash:     hex.EncodeToString(circuitHash),
+		TxsCount:        txsCount,
+		GasAssetIds:     append([]int64{}, gasAssetIds...),
+		GasAccountIndex: gasAccountIndex,
+		Config:          &config,
+	}, nil
+}
+
+/*
+	BlockConfig: config of the circuit, the default one for headers without config
+*/
+func (h *KeyHeader) BlockConfig() circuit.Config {
+	if h.Config == nil || h.Config.IsZero() {
+		return circuit.DefaultConfig()
+	}
+	return *h.Config
+}
+
+/*
+	Check: returns ErrKeyMismatch if the header doesn't describe the expected circuit,
+	the key hash is checked against the key itself when it is loaded
+*/
+func (h *KeyHeader) Check(expected *KeyHeader) error {
+	switch {
+	case h.Backend != expected.Backend:
+		return fmt.Errorf("%w: backend %s, expected %s", ErrKeyMismatch, h.Backend, expected.Backend)
+	case h.GnarkVersion != expected.GnarkVersion:
+		return fmt.Errorf("%w: gnark version %s, expected %s", ErrKeyMismatch, h.GnarkVersion, expected.GnarkVersion)
+	case h.CircuitHash != expected.CircuitHash:
+		return fmt.Errorf("%w: circuit hash %s, expected %s", ErrKeyMismatch, h.CircuitHash, expected.CircuitHash)
+	case h.TxsCount != expected.TxsCount:
+		return fmt.Errorf("%w: txs count %d, expected %d", ErrKeyMismatch, h.TxsCount, expected.TxsCount)
+	case !equalInt64s(h.GasAssetIds, expected.GasAssetIds):
+		return fmt.Errorf("%w: gas assets %v, expected %v", ErrKeyMismatch, h.GasAssetIds, expected.GasAssetIds)
+	case h.GasAccountIndex != expected.GasAccountIndex:
+		return fmt.Errorf("%w: gas account %d, expected %d", ErrKeyMismatch, h.GasAccountIndex, expected.GasAccountIndex)
+	case h.BlockConfig() != expected.BlockConfig():
+		return fmt.Errorf("%w: config %+v, expected %+v", ErrKeyMismatch, h.BlockConfig(), expected.BlockConfig())
+	}
+	return nil
+}
+
+/*
+	CheckBlock: returns ErrKeyMismatch if the block witness can't be proven with the circuit of the header
+*/
+func (h *KeyHeader) CheckBlock(oBlock *circuit.Block) error {
+	if len(oBlock.Txs) != h.TxsCount {
+		return fmt.Errorf("%w: block has %d txs, expected %d", ErrKeyMismatch, len(oBlock.Txs), h.TxsCount)
+	}
+	if oBlock.Gas == nil || oBlock.Gas.AccountInfoBefore == nil {
+		return circuit.ErrInvalidBlock
+	}
+	gasAccount := oBlock.Gas.AccountInfoBefore
+	if gasAccount.AccountIndex != h.GasAccountIndex {
+		return fmt.Errorf("%w: gas account %d, expected %d", ErrKeyMismatch, gasAccount.AccountIndex, h.GasAccountIndex)
+	}
+	gasAssetIds := make([]int64, len(gasAccount.AssetsInfo))
+	for i, asset := range gasAccount.AssetsInfo {
+		gasAssetIds[i] = asset.AssetId
+	}
+	if !equalInt64s(gasAssetIds, h.GasAssetIds) {
+		return fmt.Errorf("%w: gas assets %v, expected %v", ErrKeyMismatch, gasAssetIds, h.GasAssetIds)
+	}
+	if err := h.BlockConfig().CheckBlock(oBlock); err != nil {
+		return fmt.Errorf("%w: %v", ErrKeyMismatch, err)
+	}
+	return nil
+}
+
+/*
+	ComputeKeyHash: sha256 of the key as it is written by WriteKey
+*/
+func ComputeKeyHash(key io.WriterTo) ([]byte, error) {
+	hFunc := sha256.New()
+	if err := writeObject(hFunc, key); err != nil {
+		log.Println("[ComputeKeyHash] unable to serialize key:", err)
+		return nil, err
+	}
+	return hFunc.Sum(nil), nil
+}
+
+/*
+	WriteKey: writes the magic, the length of the JSON encoded header, the header and the key,
+	the header records the hash of the key
+*/
+func WriteKey(w io.Writer, header *KeyHeader, key io.WriterTo) error {
+	keyHash, err := ComputeKeyHash(key)
+	if err != nil {
+		return err
+	}
+	keyHeader := *header
+	keyHeader.KeyHash = hex.EncodeToString(keyHash)
+	return writeWithHeader(w, &keyHeader, key)
+}
+
+/*
+	writeWithHeader: objects supporting it are written uncompressed
+*/
+func writeWithHeader(w io.Writer, header *KeyHeader, key io.WriterTo) error {
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	var prefix [8]byte
+	copy(prefix[:4], keyMagic[:])
+	binary.BigEndian.PutUint32(prefix[4:], uint32(len(headerBytes)))
+	if _, err = w.Write(prefix[:]); err != nil {
+		return err
+	}
+	if _, err = w.Write(headerBytes); err != nil {
+		return err
+	}
+	if err = writeObject(w, key); err != nil {
+		log.Println("[WriteKey] unable to write key:", err)
+	}
+	return err
+}
+
+func writeObject(w io.Writer, key io.WriterTo) (err error) {
+	if rawKey, ok := key.(gnarkio.WriterRawTo); ok {
+		_, err = rawKey.WriteRawTo(w)
+	} else {
+		_, err = key.WriteTo(w)
+	}
+	return err
+}
+
+/*
+	ReadKeyHeader: reads the header in front of a key, the reader is left at the start of the key
+*/
+func ReadKeyHeader(r io.Reader) (*KeyHeader, error) {
+	var prefix [8]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, ErrInvalidKeyFile
+	}
+	if !bytes.Equal(prefix[:4], keyMagic[:]) {
+		return nil, ErrInvalidKeyFile
+	}
+	size := binary.BigEndian.Uint32(prefix[4:])
+	if size > maxKeyHeaderSize {
+		return nil, ErrInvalidKeyFile
+	}
+	headerBytes := make([]byte, size)
+	if _, err := io.ReadFull(r, headerBytes); err != nil {
+		return nil, ErrInvalidKeyFile
+	}
+	header := new(KeyHeader)
+	if err := json.Unmarshal(headerBytes, header); err != nil {
+		return nil, ErrInvalidKeyFile
+	}
+	if header.FormatVersion != KeyFormatVersion {
+		return nil, ErrKeyFormatVersion
+	}
+	if header.GnarkVersion != GnarkVersion {
+		return nil, ErrKeyGnarkVersion
+	}
+	if header.Backend != BackendGroth16 && header.Backend != BackendPlonk {
+		return nil, ErrUnknownBackend
+	}
+	return header, nil
+}
+
+/*
+	LoadKey: reads the header and refuses the key if it doesn't match the expected one,
+	or if it doesn't match the key hash of the header. Keys written before the key hash was recorded are accepted.
+*/
+func LoadKey(r io.Reader, key io.ReaderFrom, expected *KeyHeader) error {
+	return loadKey(r, key, expected, false)
+}
+
+func loadKey(r io.Reader, key io.ReaderFrom, expected *KeyHeader, requireKeyHash bool) error {
+	header, err := checkKeyHeader(r, expected)
+	if err != nil {
+		return err
+	}
+	if header.KeyHash == "" && requireKeyHash {
+		log.Println("[LoadKey] missing key hash")
+		return ErrKeyHashMismatch
+	}
+	return ReadKey(r, key, header)
+}
+
+/*
+	ReadKey: reads the key following its header, and refuses it if it doesn't match the key hash of the header
+*/
+func ReadKey(r io.Reader, key io.ReaderFrom, header *KeyHeader) error {
+	hFunc := sha256.New()
+	if _, err := key.ReadFrom(io.TeeReader(r, hFunc)); err != nil {
+		log.Println("[ReadKey] unable to read key:", err)
+		return err
+	}
+	if header.KeyHash != "" && hex.EncodeToString(hFunc.Sum(nil)) != header.KeyHash {
+		log.Println("[ReadKey] key hash mismatch")
+		return ErrKeyHashMismatch
+	}
+	return nil
+}
+
+func WriteCircuit(w io.Writer, header *KeyHeader, ccs frontend.CompiledConstraintSystem) error {
+	return writeWithHeader(w, header, ccs)
+}
+
+/*
+	LoadCircuit: reads a constraint system written by WriteCircuit and checks it against its circuit hash,
+	the returned header is the one expected by the keys of the circuit
+*/
+func LoadCircuit(r io.Reader, backendName string) (frontend.CompiledConstraintSystem, *KeyHeader, error) {
+	header, err := ReadKeyHeader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if header.Backend != backendName {
+		return nil, nil, fmt.Errorf("%w: backend %s, expected %s", ErrKeyMismatch, header.Backend, backendName)
+	}
+	var ccs frontend.CompiledConstraintSystem
+	if backendName == BackendGroth16 {
+		ccs = groth16.NewCS(ecc.BN254)
+	} else {
+		ccs = plonk.NewCS(ecc.BN254)
+	}
+	if _, err = ccs.ReadFrom(r); err != nil {
+		log.Println("[LoadCircuit] unable to read constraint system:", err)
+		return nil, nil, err
+	}
+	circuitHash, err := ComputeCircuitHash(ccs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if hex.EncodeToString(circuitHash) != header.CircuitHash {
+		return nil, nil, ErrCircuitHashChanged
+	}
+	return ccs, header, nil
+}
+
+func LoadGroth16ProvingKey(r io.Reader, expected *KeyHeader) (groth16.ProvingKey, error) {
+	pk := groth16.NewProvingKey(ecc.BN254)
+	if err := LoadKey(r, pk, expected); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
+
+func LoadGroth16VerifyingKey(r io.Reader, expected *KeyHeader) (groth16.VerifyingKey, error) {
+	vk := groth16.NewVerifyingKey(ecc.BN254)
+	if err := LoadKey(r, vk, expected); err != nil {
+		return nil, err
+	}
+	return vk, nil
+}
+
+/*
+	LoadPlonkProvingKey: the key hash of the header is required, and the circuit hash of the header must be
+	the one of the constraint system the key is used with, the srs must be the one used by the setup
+*/
+func LoadPlonkProvingKey(r io.Reader, srs *kzg.SRS, ccs frontend.CompiledConstraintSystem, expected *KeyHeader) (plonk.ProvingKey, error) {
+	if err := checkCircuitHash(ccs, expected); err != nil {
+		log.Println("[LoadPlonkProvingKey]", err)
+		return nil, err
+	}
+	pk := plonk.NewProvingKey(ecc.BN254)
+	if err := loadKey(r, pk, expected, true); err != nil {
+		return nil, err
+	}
+	if err := initPlonkProvingKey(pk, srs); err != nil {
+		log.Println("[LoadPlonkProvingKey] invalid proving key:", err)
+		return nil, err
+	}
+	return pk, nil
+}
+
+/*
+	LoadPlonkVerifyingKey: same as LoadPlonkProvingKey for the verifying key
+*/
+func LoadPlonkVerifyingKey(r io.Reader, srs *kzg.SRS, ccs frontend.CompiledConstraintSystem, expected *KeyHeader) (plonk.VerifyingKey, error) {
+	if err := checkCircuitHash(ccs, expected); err != nil {
+		log.Println("[LoadPlonkVerifyingKey]", err)
+		return nil, err
+	}
+	vk := plonk.NewVerifyingKey(ecc.BN254)
+	if err := loadKey(r, vk, expected, true); err != nil {
+		return nil, err
+	}
+	if err := initPlonkVerifyingKey(vk, srs); err != nil {
+		log.Println("[LoadPlonkVerifyingKey] invalid verifying key:", err)
+		return nil, err
+	}
+	return vk, nil
+}
+
+func checkCircuitHash(ccs frontend.CompiledConstraintSystem, expected *KeyHeader) error {
+	circuitHash, err := ComputeCircuitHash(ccs)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(circuitHash) != expected.CircuitHash {
+		return fmt.Errorf("%w: circuit hash %x, expected %s", ErrKeyMismatch, circuitHash, expected.CircuitHash)
+	}
+	return nil
+}
+
+func checkKeyHeader(r io.Reader, expected *KeyHeader) (*KeyHeader, error) {
+	header, err := ReadKeyHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if err = header.Check(expected); err != nil {
+		log.Println("[LoadKey]", err)
+		return nil, err
+	}
+	return header, nil
+}
+
+func equalInt64s(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,252 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/executor"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+func TestGnarkVersion(t *testing.T) {
+	// read from the build info, it must follow go.mod
+	goMod, err := os.ReadFile("../../go.mod")
+	assert.Nil(t, err)
+	assert.NotEqual(t, "unknown", GnarkVersion)
+	assert.True(t, strings.Contains(string(goMod), "github.com/consensys/gnark "+GnarkVersion+"\n"))
+}
+
+func TestLoadCircuit(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254, r1cs.NewBuilder, &squareConstraints{})
+	assert.Nil(t, err)
+	header, err := NewKeyHeader(BackendGroth16, ccs, circuit.DefaultConfig(), 1, []int64{0, 1}, 1)
+	assert.Nil(t, err)
+	var buf bytes.Buffer
+	assert.Nil(t, WriteCircuit(&buf, header, ccs))
+	data := buf.Bytes()
+
+	ccs2, header2, err := LoadCircuit(bytes.NewReader(data), BackendGroth16)
+	assert.Nil(t, err)
+	assert.Equal(t, header, header2)
+	assert.Equal(t, ccs.GetNbConstraints(), ccs2.GetNbConstraints())
+
+	_, _, err = LoadCircuit(bytes.NewReader(data), BackendPlonk)
+	assert.True(t, errors.Is(err, ErrKeyMismatch))
+
+	// constraint system of another circuit under the same header
+	other, err := frontend.Compile(ecc.BN254, r1cs.NewBuilder, &cubeConstraints{})
+	assert.Nil(t, err)
+	buf.Reset()
+	assert.Nil(t, WriteCircuit(&buf, header, other))
+	_, _, err = LoadCircuit(&buf, BackendGroth16)
+	assert.Equal(t, ErrCircuitHashChanged, err)
+
+	_, err = NewKeyHeader("marlin", ccs, circuit.DefaultConfig(), 1, []int64{0, 1}, 1)
+	assert.Equal(t, ErrUnknownBackend, err)
+}
+
+func TestLoadGroth16Keys(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254, r1cs.NewBuilder, &squareConstraints{})
+	assert.Nil(t, err)
+	header, err := NewKeyHeader(BackendGroth16, ccs, circuit.DefaultConfig(), 1, []int64{0, 1}, 1)
+	assert.Nil(t, err)
+	pk, vk, err := groth16.Setup(ccs)
+	assert.Nil(t, err)
+	var pkBuf, vkBuf bytes.Buffer
+	assert.Nil(t, WriteKey(&pkBuf, header, pk))
+	assert.Nil(t, WriteKey(&vkBuf, header, vk))
+
+	pk2, err := LoadGroth16ProvingKey(bytes.NewReader(pkBuf.Bytes()), header)
+	assert.Nil(t, err)
+	vk2, err := LoadGroth16VerifyingKey(bytes.NewReader(vkBuf.Bytes()), header)
+	assert.Nil(t, err)
+	fullWitness, err := frontend.NewWitness(&squareConstraints{X: 3, Y: 9}, ecc.BN254)
+	assert.Nil(t, err)
+	proof, err := groth16.Prove(ccs, pk2, fullWitness)
+	assert.Nil(t, err)
+	publicWitness, err := fullWitness.Public()
+	assert.Nil(t, err)
+	assert.Nil(t, groth16.Verify(proof, vk2, publicWitness))
+
+	// keys written before the key hash was recorded are accepted
+	var legacyBuf bytes.Buffer
+	assert.Nil(t, writeWithHeader(&legacyBuf, header, vk))
+	_, err = LoadGroth16VerifyingKey(bytes.NewReader(legacyBuf.Bytes()), header)
+	assert.Nil(t, err)
+
+	// headers written before the config was recorded stand for the default config
+	legacy := *header
+	legacy.Config = nil
+	assert.Nil(t, header.Check(&legacy))
+
+	mismatches := []func(h *KeyHeader){
+		func(h *KeyHeader) { h.Backend = BackendPlonk },
+		func(h *KeyHeader) { h.CircuitHash = strings.Repeat("00", 32) },
+		func(h *KeyHeader) { h.TxsCount = 10 },
+		func(h *KeyHeader) { h.GasAssetIds = []int64{0, 2} },
+		func(h *KeyHeader) { h.GasAssetIds = []int64{0} },
+		func(h *KeyHeader) { h.GasAccountIndex = 2 },
+		func(h *KeyHeader) { h.Config = &circuit.Config{AssetMerkleLevels: 4} },
+	}
+	for i, mismatch := range mismatches {
+		expected := *header
+		mismatch(&expected)
+		_, err = LoadGroth16ProvingKey(bytes.NewReader(pkBuf.Bytes()), &expected)
+		assert.True(t, errors.Is(err, ErrKeyMismatch), "case %d", i)
+		_, err = LoadGroth16VerifyingKey(bytes.NewReader(vkBuf.Bytes()), &expected)
+		assert.True(t, errors.Is(err, ErrKeyMismatch), "case %d", i)
+	}
+}
+
+func TestReadKeyHeaderErrors(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254, r1cs.NewBuilder, &squareConstraints{})
+	assert.Nil(t, err)
+	header, err := NewKeyHeader(BackendGroth16, ccs, circuit.DefaultConfig(), 1, []int64{0, 1}, 1)
+	assert.Nil(t, err)
+	write := func(h KeyHeader) []byte {
+		var buf bytes.Buffer
+		assert.Nil(t, WriteCircuit(&buf, &h, ccs))
+		return buf.Bytes()
+	}
+
+	_, err = ReadKeyHeader(bytes.NewReader(nil))
+	assert.Equal(t, ErrInvalidKeyFile, err)
+	data := write(*header)
+	data[0] = 'X'
+	_, err = ReadKeyHeader(bytes.NewReader(data))
+	assert.Equal(t, ErrInvalidKeyFile, err)
+	data = write(*header)
+	_, err = ReadKeyHeader(bytes.NewReader(data[:12]))
+	assert.Equal(t, ErrInvalidKeyFile, err)
+
+	h := *header
+	h.FormatVersion = KeyFormatVersion + 1
+	_, err = ReadKeyHeader(bytes.NewReader(write(h)))
+	assert.Equal(t, ErrKeyFormatVersion, err)
+	h = *header
+	h.GnarkVersion = "v0.8.0"
+	_, err = ReadKeyHeader(bytes.NewReader(write(h)))
+	assert.Equal(t, ErrKeyGnarkVersion, err)
+	h = *header
+	h.Backend = "marlin"
+	_, err = ReadKeyHeader(bytes.NewReader(write(h)))
+	assert.Equal(t, ErrUnknownBackend, err)
+}
+
+func TestLoadPlonkKeys(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &squareConstraints{})
+	assert.Nil(t, err)
+	header, err := NewKeyHeader(BackendPlonk, ccs, circuit.DefaultConfig(), 1, []int64{0, 1}, 1)
+	assert.Nil(t, err)
+	srs, err := NewTestSRS(ccs)
+	assert.Nil(t, err)
+	pk, vk, err := SetupPlonk(ccs, srs)
+	assert.Nil(t, err)
+	var pkBuf, vkBuf bytes.Buffer
+	assert.Nil(t, WriteKey(&pkBuf, header, pk))
+	assert.Nil(t, WriteKey(&vkBuf, header, vk))
+
+	pk2, err := LoadPlonkProvingKey(bytes.NewReader(pkBuf.Bytes()), srs, ccs, header)
+	assert.Nil(t, err)
+	vk2, err := LoadPlonkVerifyingKey(bytes.NewReader(vkBuf.Bytes()), srs, ccs, header)
+	assert.Nil(t, err)
+	proof, err := provePlonk(ccs, pk2, &squareConstraints{X: 3, Y: 9})
+	assert.Nil(t, err)
+	assert.Nil(t, verifyPlonk(proof, vk2, &squareConstraints{Y: 9}))
+
+	groth16Header := *header
+	groth16Header.Backend = BackendGroth16
+	_, err = LoadPlonkProvingKey(bytes.NewReader(pkBuf.Bytes()), srs, ccs, &groth16Header)
+	assert.True(t, errors.Is(err, ErrKeyMismatch))
+	_, err = LoadPlonkVerifyingKey(bytes.NewReader(vkBuf.Bytes()), srs, ccs, &groth16Header)
+	assert.True(t, errors.Is(err, ErrKeyMismatch))
+
+	// the keys aren't used with another circuit
+	cubeCcs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &cubeConstraints{})
+	assert.Nil(t, err)
+	_, err = LoadPlonkProvingKey(bytes.NewReader(pkBuf.Bytes()), srs, cubeCcs, header)
+	assert.True(t, errors.Is(err, ErrKeyMismatch))
+	_, err = LoadPlonkVerifyingKey(bytes.NewReader(vkBuf.Bytes()), srs, cubeCcs, header)
+	assert.True(t, errors.Is(err, ErrKeyMismatch))
+
+	// nor restored with another gnark version
+	builtWith := GnarkVersion
+	GnarkVersion = "v0.8.0"
+	otherHeader := *header
+	otherHeader.GnarkVersion = GnarkVersion
+	var otherBuf bytes.Buffer
+	assert.Nil(t, WriteKey(&otherBuf, &otherHeader, vk))
+	_, err = LoadPlonkVerifyingKey(bytes.NewReader(otherBuf.Bytes()), srs, ccs, &otherHeader)
+	assert.Equal(t, ErrPlonkGnarkVersion, err)
+	GnarkVersion = builtWith
+
+	// tampered keys don't match the key hash of their header, both keys start with the size of the circuit
+	tamper := func(data []byte) []byte {
+		tampered := append([]byte{}, data...)
+		keyStart := 8 + int(binary.BigEndian.Uint32(tampered[4:8]))
+		tampered[keyStart+7] ^= 1
+		return tampered
+	}
+	_, err = LoadPlonkVerifyingKey(bytes.NewReader(tamper(vkBuf.Bytes())), srs, ccs, header)
+	assert.Equal(t, ErrKeyHashMismatch, err)
+	_, err = LoadPlonkProvingKey(bytes.NewReader(tamper(pkBuf.Bytes())), srs, ccs, header)
+	assert.Equal(t, ErrKeyHashMismatch, err)
+
+	// and plonk keys without key hash are refused
+	var rawBuf bytes.Buffer
+	assert.Nil(t, writeWithHeader(&rawBuf, header, vk))
+	_, err = LoadPlonkVerifyingKey(bytes.NewReader(rawBuf.Bytes()), srs, ccs, header)
+	assert.Equal(t, ErrKeyHashMismatch, err)
+}
+
+func TestCheckBlock(t *testing.T) {
+	state, err := executor.NewState()
+	assert.Nil(t, err)
+	oBlock, err := executor.BuildBlockFromTxs(state, types.DefaultChainId, 1, 1654656781000, 2, 1, []int64{0, 1}, nil)
+	assert.Nil(t, err)
+
+	header := &KeyHeader{TxsCount: 2, GasAssetIds: []int64{0, 1}, GasAccountIndex: 1}
+	assert.Nil(t, header.CheckBlock(oBlock))
+	assert.True(t, errors.Is((&KeyHeader{TxsCount: 1, GasAssetIds: []int64{0, 1}, GasAccountIndex: 1}).CheckBlock(oBlock), ErrKeyMismatch))
+	assert.True(t, errors.Is((&KeyHeader{TxsCount: 2, GasAssetIds: []int64{0}, GasAccountIndex: 1}).CheckBlock(oBlock), ErrKeyMismatch))
+	assert.True(t, errors.Is((&KeyHeader{TxsCount: 2, GasAssetIds: []int64{0, 1}, GasAccountIndex: 2}).CheckBlock(oBlock), ErrKeyMismatch))
+}
+
+type cubeConstraints struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (circuit cubeConstraints) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Mul(circuit.X, circuit.X, circuit.X), circuit.Y)
+	return nil
+}
@@ -0,0 +1,267 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"log"
+	"reflect"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+// plonkGnarkVersion is the gnark version whose internal key types are restored by restoreProvingKey
+// and restoreVerifyingKey, and whose verifier is replayed by the solidity verifier
+const plonkGnarkVersion = "v0.7.0"
+
+var (
+	ErrInvalidPlonkProvingKey   = errors.New("[Prover] invalid plonk proving key")
+	ErrInvalidPlonkVerifyingKey = errors.New("[Prover] invalid plonk verifying key")
+	ErrPlonkGnarkVersion        = errors.New("[Prover] plonk keys are only supported with gnark " + plonkGnarkVersion)
+)
+
+/*
+	CompileBlockPlonk: compile the block circuit with the sparse constraint system used by plonk
+*/
+func CompileBlockPlonk(config circuit.Config, txsCount int, gasAssetIds []int64, gasAccountIndex int64) (frontend.CompiledConstraintSystem, error) {
+	blockConstraints, err := circuit.NewBlockConstraints(config, txsCount, gasAssetIds, gasAccountIndex)
+	if err != nil {
+		log.Println("[CompileBlockPlonk] invalid config:", err)
+		return nil, err
+	}
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &blockConstraints, frontend.IgnoreUnconstrainedInputs())
+	if err != nil {
+		log.Println("[CompileBlockPlonk] unable to compile circuit:", err)
+		return nil, err
+	}
+	return ccs, nil
+}
+
+/*
+	NewTestSRS: generate a kzg srs large enough for the circuit from a random secret.
+	The secret is known while it is generated, the srs must only be used for tests,
+	production keys should use an srs from a ceremony.
+*/
+func NewTestSRS(ccs frontend.CompiledConstraintSystem) (*kzg.SRS, error) {
+	_, _, nbPublic := ccs.GetNbVariables()
+	size := ecc.NextPowerOfTwo(uint64(ccs.GetNbConstraints()+nbPublic)) + 3
+	alpha, err := rand.Int(rand.Reader, fr.Modulus())
+	if err != nil {
+		return nil, err
+	}
+	return kzg.NewSRS(size, alpha)
+}
+
+func SetupPlonk(ccs frontend.CompiledConstraintSystem, srs *kzg.SRS) (plonk.ProvingKey, plonk.VerifyingKey, error) {
+	pk, vk, err := plonk.Setup(ccs, srs)
+	if err != nil {
+		log.Println("[SetupPlonk] unable to setup:", err)
+		return nil, nil, err
+	}
+	return pk, vk, nil
+}
+
+func ReadPlonkSRS(r io.Reader) (*kzg.SRS, error) {
+	srs := new(kzg.SRS)
+	if _, err := srs.ReadFrom(r); err != nil {
+		log.Println("[ReadPlonkSRS] unable to read srs:", err)
+		return nil, err
+	}
+	return srs, nil
+}
+
+/*
+	ReadPlonkProvingKey: reads a key written by the WriteTo method of the key, LoadPlonkProvingKey
+	should be preferred, it checks the key against the circuit and the key hash of its header.
+	The srs is not serialized with the key, it must be the one used by the setup.
+*/
+func ReadPlonkProvingKey(r io.Reader, srs *kzg.SRS) (plonk.ProvingKey, error) {
+	pk := plonk.NewProvingKey(ecc.BN254)
+	if _, err := pk.ReadFrom(r); err != nil {
+		log.Println("[ReadPlonkProvingKey] unable to read proving key:", err)
+		return nil, err
+	}
+	if err := initPlonkProvingKey(pk, srs); err != nil {
+		log.Println("[ReadPlonkProvingKey] invalid proving key:", err)
+		return nil, err
+	}
+	return pk, nil
+}
+
+/*
+	ReadPlonkVerifyingKey: same as ReadPlonkProvingKey for the verifying key
+*/
+func ReadPlonkVerifyingKey(r io.Reader, srs *kzg.SRS) (plonk.VerifyingKey, error) {
+	vk := plonk.NewVerifyingKey(ecc.BN254)
+	if _, err := vk.ReadFrom(r); err != nil {
+		log.Println("[ReadPlonkVerifyingKey] unable to read verifying key:", err)
+		return nil, err
+	}
+	if err := initPlonkVerifyingKey(vk, srs); err != nil {
+		log.Println("[ReadPlonkVerifyingKey] invalid verifying key:", err)
+		return nil, err
+	}
+	return vk, nil
+}
+
+func initPlonkProvingKey(pk plonk.ProvingKey, srs *kzg.SRS) error {
+	if err := checkPlonkGnarkVersion(); err != nil {
+		return err
+	}
+	if err := pk.InitKZG(srs); err != nil {
+		return err
+	}
+	return restoreProvingKey(pk)
+}
+
+func initPlonkVerifyingKey(vk plonk.VerifyingKey, srs *kzg.SRS) error {
+	if err := checkPlonkGnarkVersion(); err != nil {
+		return err
+	}
+	if err := vk.InitKZG(srs); err != nil {
+		return err
+	}
+	return restoreVerifyingKey(vk)
+}
+
+/*
+	checkPlonkGnarkVersion: the fields read and set by reflection are the ones of gnark v0.7.0,
+	another version may lay out its keys differently, so they are refused until the restore is updated
+*/
+func checkPlonkGnarkVersion() error {
+	if GnarkVersion != plonkGnarkVersion {
+		log.Println("[Prover] gnark version", GnarkVersion, "expected", plonkGnarkVersion)
+		return ErrPlonkGnarkVersion
+	}
+	return nil
+}
+
+/*
+	restoreProvingKey: gnark v0.7.0 doesn't serialize the permutation evaluations on the big domain
+	nor the coset shift, and its key types are internal, so they are computed again from the
+	serialized fields the same way as plonk.Setup does
+*/
+func restoreProvingKey(pk plonk.ProvingKey) error {
+	v := reflect.ValueOf(pk)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return ErrInvalidPlonkProvingKey
+	}
+	v = v.Elem()
+	domains, ok := v.FieldByName("Domain").Interface().([2]fft.Domain)
+	if !ok {
+		return ErrInvalidPlonkProvingKey
+	}
+	evaluations := v.FieldByName("EvaluationPermutationBigDomainBitReversed")
+	if !evaluations.CanSet() {
+		return ErrInvalidPlonkProvingKey
+	}
+	bigDomain := domains[1]
+	n := int(bigDomain.Cardinality)
+	res := make([]fr.Element, 3*n)
+	for i, name := range []string{"S1Canonical", "S2Canonical", "S3Canonical"} {
+		s, ok := v.FieldByName(name).Interface().([]fr.Element)
+		if !ok || len(s) > n {
+			return ErrInvalidPlonkProvingKey
+		}
+		copy(res[i*n:], s)
+		bigDomain.FFT(res[i*n:(i+1)*n], fft.DIF, true)
+	}
+	evaluations.Set(reflect.ValueOf(res))
+	return setCosetShift(v.FieldByName("Vk"), domains[0].FrMultiplicativeGen)
+}
+
+/*
+	restoreVerifyingKey: the coset shift is the multiplicative generator of the small domain
+*/
+func restoreVerifyingKey(vk plonk.VerifyingKey) error {
+	v := reflect.ValueOf(vk)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return ErrInvalidPlonkVerifyingKey
+	}
+	size, ok := v.Elem().FieldByName("Size").Interface().(uint64)
+	if !ok {
+		return ErrInvalidPlonkVerifyingKey
+	}
+	return setCosetShift(v, fft.NewDomain(size).FrMultiplicativeGen)
+}
+
+func setCosetShift(vk reflect.Value, cosetShift fr.Element) error {
+	if vk.Kind() != reflect.Ptr || vk.IsNil() || vk.Elem().Kind() != reflect.Struct {
+		return ErrInvalidPlonkVerifyingKey
+	}
+	field := vk.Elem().FieldByName("CosetShift")
+	if !field.CanSet() || field.Type() != reflect.TypeOf(cosetShift) {
+		return ErrInvalidPlonkVerifyingKey
+	}
+	field.Set(reflect.ValueOf(cosetShift))
+	return nil
+}
+
+/*
+	ProveBlockPlonk: prove the block witness, the circuit must have been compiled for the same block size and config
+*/
+func ProveBlockPlonk(ccs frontend.CompiledConstraintSystem, pk plonk.ProvingKey, config circuit.Config, oBlock *circuit.Block) (plonk.Proof, error) {
+	blockWitness, err := circuit.SetBlockWitnessWithConfig(config, oBlock)
+	if err != nil {
+		log.Println("[ProveBlockPlonk] unable to set block witness:", err)
+		return nil, err
+	}
+	return provePlonk(ccs, pk, &blockWitness)
+}
+
+/*
+	VerifyBlockPlonk: the block commitment and the chain id are the public inputs of the block circuit
+*/
+func VerifyBlockPlonk(proof plonk.Proof, vk plonk.VerifyingKey, chainId int64, blockCommitment []byte) error {
+	return verifyPlonk(proof, vk, &circuit.BlockConstraints{BlockCommitment: blockCommitment, ChainId: chainId})
+}
+
+func provePlonk(ccs frontend.CompiledConstraintSystem, pk plonk.ProvingKey, assignment frontend.Circuit) (plonk.Proof, error) {
+	fullWitness, err := frontend.NewWitness(assignment, ecc.BN254)
+	if err != nil {
+		log.Println("[ProvePlonk] unable to build witness:", err)
+		return nil, err
+	}
+	proof, err := plonk.Prove(ccs, pk, fullWitness, backend.WithHints(types.Keccak256))
+	if err != nil {
+		log.Println("[ProvePlonk] unable to prove:", err)
+		return nil, err
+	}
+	return proof, nil
+}
+
+func verifyPlonk(proof plonk.Proof, vk plonk.VerifyingKey, assignment frontend.Circuit) error {
+	publicWitness, err := frontend.NewWitness(assignment, ecc.BN254, frontend.PublicOnly())
+	if err != nil {
+		log.Println("[VerifyPlonk] unable to build public witness:", err)
+		return err
+	}
+	return plonk.Verify(proof, vk, publicWitness)
+}
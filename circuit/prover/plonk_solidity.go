@@ -0,0 +1,226 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"math/bits"
+	"reflect"
+	"text/template"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bn254 "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/frontend"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+)
+
+// PlonkProofSize is the number of uint256 words of a proof passed to the plonk solidity verifier
+const PlonkProofSize = 26
+
+var (
+	ErrInvalidPlonkProof = errors.New("[Prover] invalid plonk proof")
+)
+
+type plonkSolidityG1 struct {
+	X, Y *big.Int
+}
+
+type plonkSolidityG2 struct {
+	X0, X1, Y0, Y1 *big.Int
+}
+
+/*
+	plonkSolidityKey: the values of a verifying key written in the solidity verifier,
+	the field elements in regular form and the G2 points with their imaginary parts first as expected by the pairing precompile
+*/
+type plonkSolidityKey struct {
+	NbPublic   uint64
+	LogSize    int
+	SizeInv    *big.Int
+	Generator  *big.Int
+	CosetShift *big.Int
+	S          [3]plonkSolidityG1
+	Ql         plonkSolidityG1
+	Qr         plonkSolidityG1
+	Qm         plonkSolidityG1
+	Qo         plonkSolidityG1
+	Qk         plonkSolidityG1
+	G1         plonkSolidityG1
+	G2         [2]plonkSolidityG2
+}
+
+/*
+	ExportPlonkSolidity: writes the solidity verifier of the proofs of the verifying key, it replays plonk.Verify
+	of gnark v0.7.0: the same sha256 transcript for the challenges and the same kzg folding, the two openings are
+	checked with a single pairing. The proofs are passed to it as returned by PlonkProofCalldata and the public
+	inputs in the order of the public witness, see BlockPublicInputs.
+*/
+func ExportPlonkSolidity(w io.Writer, vk plonk.VerifyingKey) error {
+	key, err := newPlonkSolidityKey(vk)
+	if err != nil {
+		log.Println("[ExportPlonkSolidity] invalid verifying key:", err)
+		return err
+	}
+	tmpl, err := template.New("plonk").Parse(plonkSolidityTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, key)
+}
+
+func newPlonkSolidityKey(vk plonk.VerifyingKey) (*plonkSolidityKey, error) {
+	if err := checkPlonkGnarkVersion(); err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(vk)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, ErrInvalidPlonkVerifyingKey
+	}
+	v = v.Elem()
+	size, ok := v.FieldByName("Size").Interface().(uint64)
+	if !ok || size < 2 || size&(size-1) != 0 {
+		return nil, ErrInvalidPlonkVerifyingKey
+	}
+	nbPublic, ok := v.FieldByName("NbPublicVariables").Interface().(uint64)
+	if !ok {
+		return nil, ErrInvalidPlonkVerifyingKey
+	}
+	srs, ok := v.FieldByName("KZGSRS").Interface().(*kzg.SRS)
+	if !ok || srs == nil || len(srs.G1) == 0 {
+		return nil, ErrInvalidPlonkVerifyingKey
+	}
+	key := &plonkSolidityKey{
+		NbPublic: nbPublic,
+		LogSize:  bits.TrailingZeros64(size),
+		G1:       solidityG1(srs.G1[0]),
+		G2:       [2]plonkSolidityG2{solidityG2(srs.G2[0]), solidityG2(srs.G2[1])},
+	}
+	for name, value := range map[string]**big.Int{"SizeInv": &key.SizeInv, "Generator": &key.Generator, "CosetShift": &key.CosetShift} {
+		element, ok := v.FieldByName(name).Interface().(fr.Element)
+		if !ok {
+			return nil, ErrInvalidPlonkVerifyingKey
+		}
+		*value = element.ToBigIntRegular(new(big.Int))
+	}
+	if key.CosetShift.Sign() == 0 {
+		// the coset shift is restored by LoadPlonkVerifyingKey, a key read without it can't be exported
+		return nil, ErrInvalidPlonkVerifyingKey
+	}
+	s, ok := v.FieldByName("S").Interface().([3]kzg.Digest)
+	if !ok {
+		return nil, ErrInvalidPlonkVerifyingKey
+	}
+	for i := range s {
+		key.S[i] = solidityG1(s[i])
+	}
+	for name, value := range map[string]*plonkSolidityG1{"Ql": &key.Ql, "Qr": &key.Qr, "Qm": &key.Qm, "Qo": &key.Qo, "Qk": &key.Qk} {
+		digest, ok := v.FieldByName(name).Interface().(kzg.Digest)
+		if !ok {
+			return nil, ErrInvalidPlonkVerifyingKey
+		}
+		*value = solidityG1(digest)
+	}
+	return key, nil
+}
+
+/*
+	PlonkProofCalldata: the proof as the uint256 words taken by the solidity verifier: the commitments to l, r, o,
+	z and to the three parts of the quotient, the batched opening at zeta with its 7 claimed values
+	(quotient, linearized polynomial, l, r, o, s1, s2) and the opening of z at the shifted zeta
+*/
+func PlonkProofCalldata(proof plonk.Proof) ([PlonkProofSize]*big.Int, error) {
+	var calldata [PlonkProofSize]*big.Int
+	if err := checkPlonkGnarkVersion(); err != nil {
+		return calldata, err
+	}
+	v := reflect.ValueOf(proof)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return calldata, ErrInvalidPlonkProof
+	}
+	v = v.Elem()
+	lro, ok1 := v.FieldByName("LRO").Interface().([3]kzg.Digest)
+	z, ok2 := v.FieldByName("Z").Interface().(kzg.Digest)
+	h, ok3 := v.FieldByName("H").Interface().([3]kzg.Digest)
+	batched, ok4 := v.FieldByName("BatchedProof").Interface().(kzg.BatchOpeningProof)
+	shifted, ok5 := v.FieldByName("ZShiftedOpening").Interface().(kzg.OpeningProof)
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 || len(batched.ClaimedValues) != 7 {
+		return calldata, ErrInvalidPlonkProof
+	}
+	words := make([]*big.Int, 0, PlonkProofSize)
+	points := []kzg.Digest{lro[0], lro[1], lro[2], z, h[0], h[1], h[2], batched.H}
+	for i := range points {
+		p := solidityG1(points[i])
+		words = append(words, p.X, p.Y)
+	}
+	for i := range batched.ClaimedValues {
+		words = append(words, batched.ClaimedValues[i].ToBigIntRegular(new(big.Int)))
+	}
+	p := solidityG1(shifted.H)
+	words = append(words, p.X, p.Y, shifted.ClaimedValue.ToBigIntRegular(new(big.Int)))
+	copy(calldata[:], words)
+	return calldata, nil
+}
+
+/*
+	BlockPublicInputs: public inputs of the block circuit in the order of the public witness,
+	as passed to the solidity verifier with the proof
+*/
+func BlockPublicInputs(chainId int64, blockCommitment []byte) ([]*big.Int, error) {
+	publicWitness, err := frontend.NewWitness(&circuit.BlockConstraints{BlockCommitment: blockCommitment, ChainId: chainId},
+		ecc.BN254, frontend.PublicOnly())
+	if err != nil {
+		log.Println("[BlockPublicInputs] unable to build public witness:", err)
+		return nil, err
+	}
+	data, err := publicWitness.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	// the number of inputs followed by the inputs as 32 bytes big endian integers
+	if len(data) < 4 || (len(data)-4)%fr.Bytes != 0 {
+		return nil, ErrInvalidPlonkProof
+	}
+	inputs := make([]*big.Int, 0, (len(data)-4)/fr.Bytes)
+	for i := 4; i < len(data); i += fr.Bytes {
+		inputs = append(inputs, new(big.Int).SetBytes(data[i:i+fr.Bytes]))
+	}
+	return inputs, nil
+}
+
+func solidityG1(p bn254.G1Affine) plonkSolidityG1 {
+	return plonkSolidityG1{
+		X: p.X.ToBigIntRegular(new(big.Int)),
+		Y: p.Y.ToBigIntRegular(new(big.Int)),
+	}
+}
+
+func solidityG2(p bn254.G2Affine) plonkSolidityG2 {
+	return plonkSolidityG2{
+		X0: p.X.A0.ToBigIntRegular(new(big.Int)),
+		X1: p.X.A1.ToBigIntRegular(new(big.Int)),
+		Y0: p.Y.A0.ToBigIntRegular(new(big.Int)),
+		Y1: p.Y.A1.ToBigIntRegular(new(big.Int)),
+	}
+}
@@ -0,0 +1,337 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+/*
+	plonkSolidityTemplate: the steps of the verifier follow plonk.Verify of gnark v0.7.0,
+	see ExportPlonkSolidity
+*/
+const plonkSolidityTemplate = `// SPDX-License-Identifier: Apache-2.0
+
+// Code generated by prover.ExportPlonkSolidity, verifier of the plonk proofs of gnark v0.7.0 for a single verifying key
+
+pragma solidity ^0.8.0;
+
+contract ZkBNBPlonkVerifier {
+
+    // scalar field and base field of bn254
+    uint256 constant R_MOD = 21888242871839275222246405745257275088548364400416034343698204186575808495617;
+    uint256 constant P_MOD = 21888242871839275222246405745257275088696311157297823662689037894645226208583;
+
+    uint256 constant NB_PUBLIC = {{.NbPublic}};
+    uint256 constant LOG_SIZE = {{.LogSize}};
+    uint256 constant SIZE_INV = {{.SizeInv}};
+    uint256 constant GENERATOR = {{.Generator}};
+    uint256 constant COSET_SHIFT = {{.CosetShift}};
+
+    // commitments to the permutation and to the coefficients of the circuit
+    uint256 constant S1_X = {{(index .S 0).X}};
+    uint256 constant S1_Y = {{(index .S 0).Y}};
+    uint256 constant S2_X = {{(index .S 1).X}};
+    uint256 constant S2_Y = {{(index .S 1).Y}};
+    uint256 constant S3_X = {{(index .S 2).X}};
+    uint256 constant S3_Y = {{(index .S 2).Y}};
+    uint256 constant QL_X = {{.Ql.X}};
+    uint256 constant QL_Y = {{.Ql.Y}};
+    uint256 constant QR_X = {{.Qr.X}};
+    uint256 constant QR_Y = {{.Qr.Y}};
+    uint256 constant QM_X = {{.Qm.X}};
+    uint256 constant QM_Y = {{.Qm.Y}};
+    uint256 constant QO_X = {{.Qo.X}};
+    uint256 constant QO_Y = {{.Qo.Y}};
+    uint256 constant QK_X = {{.Qk.X}};
+    uint256 constant QK_Y = {{.Qk.Y}};
+
+    // kzg srs: [1]G1, [1]G2 and [alpha]G2, the G2 coordinates with their imaginary part first
+    uint256 constant SRS_G1_X = {{.G1.X}};
+    uint256 constant SRS_G1_Y = {{.G1.Y}};
+    uint256 constant SRS_G2_X_1 = {{(index .G2 0).X1}};
+    uint256 constant SRS_G2_X_0 = {{(index .G2 0).X0}};
+    uint256 constant SRS_G2_Y_1 = {{(index .G2 0).Y1}};
+    uint256 constant SRS_G2_Y_0 = {{(index .G2 0).Y0}};
+    uint256 constant SRS_ALPHA_G2_X_1 = {{(index .G2 1).X1}};
+    uint256 constant SRS_ALPHA_G2_X_0 = {{(index .G2 1).X0}};
+    uint256 constant SRS_ALPHA_G2_Y_1 = {{(index .G2 1).Y1}};
+    uint256 constant SRS_ALPHA_G2_Y_0 = {{(index .G2 1).Y0}};
+
+    // words of the proof, see prover.PlonkProofCalldata
+    uint256 constant PROOF_SIZE = 26;
+    uint256 constant PROOF_L = 0;
+    uint256 constant PROOF_R = 2;
+    uint256 constant PROOF_O = 4;
+    uint256 constant PROOF_Z = 6;
+    uint256 constant PROOF_H_0 = 8;
+    uint256 constant PROOF_H_1 = 10;
+    uint256 constant PROOF_H_2 = 12;
+    uint256 constant PROOF_BATCH_OPENING = 14;
+    uint256 constant PROOF_QUOTIENT_AT_ZETA = 16;
+    uint256 constant PROOF_LINEARIZED_AT_ZETA = 17;
+    uint256 constant PROOF_L_AT_ZETA = 18;
+    uint256 constant PROOF_R_AT_ZETA = 19;
+    uint256 constant PROOF_O_AT_ZETA = 20;
+    uint256 constant PROOF_S1_AT_ZETA = 21;
+    uint256 constant PROOF_S2_AT_ZETA = 22;
+    uint256 constant PROOF_Z_SHIFTED_OPENING = 23;
+    uint256 constant PROOF_Z_AT_SHIFTED_ZETA = 25;
+
+    struct Challenges {
+        uint256 gamma;
+        uint256 beta;
+        uint256 alpha;
+        uint256 zeta;
+        // ζⁿ
+        uint256 zetaPowerN;
+        // L₁(ζ) and α²*L₁(ζ)
+        uint256 lagrangeOne;
+        uint256 alphaSquareLagrange;
+        // ∑ᵢLᵢ(ζ)*wᵢ
+        uint256 pi;
+    }
+
+    function verifyProof(uint256[26] memory proof, uint256[{{.NbPublic}}] memory input) public view returns (bool) {
+        for (uint256 i = 0; i < PROOF_SIZE; i++) {
+            if ((i >= PROOF_QUOTIENT_AT_ZETA && i <= PROOF_S2_AT_ZETA) || i == PROOF_Z_AT_SHIFTED_ZETA) {
+                require(proof[i] < R_MOD, "invalid proof scalar");
+            } else {
+                require(proof[i] < P_MOD, "invalid proof point");
+            }
+        }
+        // the public inputs are field elements
+        uint256[] memory publicInputs = new uint256[](NB_PUBLIC);
+        for (uint256 i = 0; i < NB_PUBLIC; i++) {
+            publicInputs[i] = input[i] % R_MOD;
+        }
+
+        Challenges memory c = deriveChallenges(proof, publicInputs);
+        computeLagrange(c, publicInputs);
+        if (!checkQuotient(proof, c)) {
+            return false;
+        }
+
+        uint256[2] memory foldedH = foldQuotient(proof, c);
+        uint256[2] memory linearizedDigest = computeLinearizedDigest(proof, c);
+        (uint256[2] memory foldedDigest, uint256 foldedValue) = foldBatchOpening(proof, c.zeta, foldedH, linearizedDigest);
+        return batchVerifyOpenings(proof, c.zeta, foldedDigest, foldedValue);
+    }
+
+    /*
+        deriveChallenges: fiat-shamir transcript of the prover, every challenge is the sha256 of its name,
+        of the previous challenge before its reduction and of the values it is bound to
+    */
+    function deriveChallenges(uint256[26] memory proof, uint256[] memory publicInputs) internal pure returns (Challenges memory c) {
+        uint256[16] memory vkCommitments = [S1_X, S1_Y, S2_X, S2_Y, S3_X, S3_Y, QL_X, QL_Y, QR_X, QR_Y, QM_X, QM_Y, QO_X, QO_Y, QK_X, QK_Y];
+        bytes32 h = sha256(abi.encodePacked("gamma", vkCommitments, publicInputs));
+        c.gamma = uint256(h) % R_MOD;
+        h = sha256(abi.encodePacked("beta", h));
+        c.beta = uint256(h) % R_MOD;
+        h = sha256(abi.encodePacked("alpha", h, proof[PROOF_Z], proof[PROOF_Z + 1]));
+        c.alpha = uint256(h) % R_MOD;
+        h = sha256(abi.encodePacked("zeta", h, proof[PROOF_H_0], proof[PROOF_H_0 + 1], proof[PROOF_H_1], proof[PROOF_H_1 + 1],
+            proof[PROOF_H_2], proof[PROOF_H_2 + 1]));
+        c.zeta = uint256(h) % R_MOD;
+
+        // the size of the domain is a power of two
+        c.zetaPowerN = c.zeta;
+        for (uint256 i = 0; i < LOG_SIZE; i++) {
+            c.zetaPowerN = mulmod(c.zetaPowerN, c.zetaPowerN, R_MOD);
+        }
+    }
+
+    /*
+        computeLagrange: Lᵢ(ζ) = ωⁱ/n * (ζⁿ-1)/(ζ-ωⁱ), L₁ is needed even without public inputs
+    */
+    function computeLagrange(Challenges memory c, uint256[] memory publicInputs) internal view {
+        uint256 zetaPowerNMinusOne = addmod(c.zetaPowerN, R_MOD - 1, R_MOD);
+        uint256 omegaPowerI = 1;
+        for (uint256 i = 0; i == 0 || i < publicInputs.length; i++) {
+            uint256 lagrange = mulmod(mulmod(omegaPowerI, SIZE_INV, R_MOD), zetaPowerNMinusOne, R_MOD);
+            lagrange = mulmod(lagrange, inverse(addmod(c.zeta, R_MOD - omegaPowerI, R_MOD)), R_MOD);
+            if (i == 0) {
+                c.lagrangeOne = lagrange;
+            }
+            if (i < publicInputs.length) {
+                c.pi = addmod(c.pi, mulmod(lagrange, publicInputs[i], R_MOD), R_MOD);
+            }
+            omegaPowerI = mulmod(omegaPowerI, GENERATOR, R_MOD);
+        }
+        c.alphaSquareLagrange = mulmod(mulmod(c.lagrangeOne, c.alpha, R_MOD), c.alpha, R_MOD);
+    }
+
+    /*
+        checkQuotient: H(ζ)*(ζⁿ-1) = linearizedpolynomial(ζ) + pi(ζ) + α*Z(μζ)*(l(ζ)+β*s1(ζ)+γ)*(r(ζ)+β*s2(ζ)+γ)*(o(ζ)+γ) - α²*L₁(ζ)
+    */
+    function checkQuotient(uint256[26] memory proof, Challenges memory c) internal pure returns (bool) {
+        uint256 t = addmod(addmod(mulmod(proof[PROOF_S1_AT_ZETA], c.beta, R_MOD), proof[PROOF_L_AT_ZETA], R_MOD), c.gamma, R_MOD);
+        t = mulmod(t, addmod(addmod(mulmod(proof[PROOF_S2_AT_ZETA], c.beta, R_MOD), proof[PROOF_R_AT_ZETA], R_MOD), c.gamma, R_MOD), R_MOD);
+        t = mulmod(t, addmod(proof[PROOF_O_AT_ZETA], c.gamma, R_MOD), R_MOD);
+        t = mulmod(mulmod(t, c.alpha, R_MOD), proof[PROOF_Z_AT_SHIFTED_ZETA], R_MOD);
+
+        uint256 linearized = addmod(proof[PROOF_LINEARIZED_AT_ZETA], c.pi, R_MOD);
+        linearized = addmod(linearized, t, R_MOD);
+        linearized = addmod(linearized, R_MOD - c.alphaSquareLagrange, R_MOD);
+        uint256 quotient = mulmod(proof[PROOF_QUOTIENT_AT_ZETA], addmod(c.zetaPowerN, R_MOD - 1, R_MOD), R_MOD);
+        return quotient == linearized;
+    }
+
+    /*
+        foldQuotient: Comm(h₁) + ζⁿ⁺²*Comm(h₂) + ζ²⁽ⁿ⁺²⁾*Comm(h₃)
+    */
+    function foldQuotient(uint256[26] memory proof, Challenges memory c) internal view returns (uint256[2] memory folded) {
+        uint256 zetaPowerNPlusTwo = mulmod(mulmod(c.zetaPowerN, c.zeta, R_MOD), c.zeta, R_MOD);
+        folded = ecMul([proof[PROOF_H_2], proof[PROOF_H_2 + 1]], zetaPowerNPlusTwo);
+        folded = ecAdd(folded, [proof[PROOF_H_1], proof[PROOF_H_1 + 1]]);
+        folded = ecMul(folded, zetaPowerNPlusTwo);
+        folded = ecAdd(folded, [proof[PROOF_H_0], proof[PROOF_H_0 + 1]]);
+    }
+
+    /*
+        computeLinearizedDigest: l(ζ)*ql + r(ζ)*qr + r(ζ)l(ζ)*qm + o(ζ)*qo + qk
+            + α*Z(μζ)*(l(ζ)+β*s₁(ζ)+γ)*(r(ζ)+β*s₂(ζ)+γ)*β*s₃
+            + (α²*L₁(ζ) - α*(l(ζ)+β*ζ+γ)*(r(ζ)+β*μ*ζ+γ)*(o(ζ)+β*μ²*ζ+γ))*Z
+    */
+    function computeLinearizedDigest(uint256[26] memory proof, Challenges memory c) internal view returns (uint256[2] memory digest) {
+        uint256 l = proof[PROOF_L_AT_ZETA];
+        uint256 r = proof[PROOF_R_AT_ZETA];
+        uint256 o = proof[PROOF_O_AT_ZETA];
+        digest = ecMul([QL_X, QL_Y], l);
+        digest = ecAdd(digest, ecMul([QR_X, QR_Y], r));
+        digest = ecAdd(digest, ecMul([QM_X, QM_Y], mulmod(l, r, R_MOD)));
+        digest = ecAdd(digest, ecMul([QO_X, QO_Y], o));
+        digest = ecAdd(digest, [QK_X, QK_Y]);
+
+        uint256 s = mulmod(proof[PROOF_Z_AT_SHIFTED_ZETA], c.beta, R_MOD);
+        s = mulmod(s, addmod(addmod(mulmod(c.beta, proof[PROOF_S1_AT_ZETA], R_MOD), l, R_MOD), c.gamma, R_MOD), R_MOD);
+        s = mulmod(s, addmod(addmod(mulmod(c.beta, proof[PROOF_S2_AT_ZETA], R_MOD), r, R_MOD), c.gamma, R_MOD), R_MOD);
+        s = mulmod(s, c.alpha, R_MOD);
+        digest = ecAdd(digest, ecMul([S3_X, S3_Y], s));
+
+        uint256 betaZeta = mulmod(c.beta, c.zeta, R_MOD);
+        s = addmod(addmod(betaZeta, l, R_MOD), c.gamma, R_MOD);
+        betaZeta = mulmod(betaZeta, COSET_SHIFT, R_MOD);
+        s = mulmod(s, addmod(addmod(betaZeta, r, R_MOD), c.gamma, R_MOD), R_MOD);
+        betaZeta = mulmod(betaZeta, COSET_SHIFT, R_MOD);
+        s = mulmod(s, addmod(addmod(betaZeta, o, R_MOD), c.gamma, R_MOD), R_MOD);
+        s = addmod(R_MOD - mulmod(s, c.alpha, R_MOD), c.alphaSquareLagrange, R_MOD);
+        digest = ecAdd(digest, ecMul([proof[PROOF_Z], proof[PROOF_Z + 1]], s));
+    }
+
+    /*
+        foldBatchOpening: folds the digests and the claimed values of the batched opening at ζ
+        with the powers of a challenge bound to ζ and to the digests
+    */
+    function foldBatchOpening(uint256[26] memory proof, uint256 zeta, uint256[2] memory foldedH, uint256[2] memory linearizedDigest)
+        internal view returns (uint256[2] memory foldedDigest, uint256 foldedValue)
+    {
+        uint256[14] memory digests = [
+            foldedH[0], foldedH[1], linearizedDigest[0], linearizedDigest[1],
+            proof[PROOF_L], proof[PROOF_L + 1], proof[PROOF_R], proof[PROOF_R + 1], proof[PROOF_O], proof[PROOF_O + 1],
+            S1_X, S1_Y, S2_X, S2_Y
+        ];
+        uint256 gamma = uint256(sha256(abi.encodePacked("gamma", zeta, digests))) % R_MOD;
+        uint256 gammaPowerI = 1;
+        for (uint256 i = 0; i < 7; i++) {
+            uint256[2] memory term = ecMul([digests[2 * i], digests[2 * i + 1]], gammaPowerI);
+            foldedDigest = i == 0 ? term : ecAdd(foldedDigest, term);
+            foldedValue = addmod(foldedValue, mulmod(proof[PROOF_QUOTIENT_AT_ZETA + i], gammaPowerI, R_MOD), R_MOD);
+            gammaPowerI = mulmod(gammaPowerI, gamma, R_MOD);
+        }
+    }
+
+    /*
+        batchVerifyOpenings: the folded opening at ζ and the opening of Z at μζ combined with a random λ,
+        e(∑ᵢλᵢ([fᵢ]G₁ - [fᵢ(aᵢ)]G₁ + aᵢ[Hᵢ]G₁), G₂) * e(-∑ᵢλᵢ[Hᵢ]G₁, [α]G₂) == 1
+    */
+    function batchVerifyOpenings(uint256[26] memory proof, uint256 zeta, uint256[2] memory foldedDigest, uint256 foldedValue)
+        internal view returns (bool)
+    {
+        uint256 lambda = uint256(sha256(abi.encodePacked(zeta, foldedDigest, foldedValue, proof))) % R_MOD;
+        uint256[2] memory zetaOpening = [proof[PROOF_BATCH_OPENING], proof[PROOF_BATCH_OPENING + 1]];
+        uint256[2] memory shiftedOpening = [proof[PROOF_Z_SHIFTED_OPENING], proof[PROOF_Z_SHIFTED_OPENING + 1]];
+
+        uint256[2] memory digests = ecAdd(foldedDigest, ecMul([proof[PROOF_Z], proof[PROOF_Z + 1]], lambda));
+        foldedValue = addmod(foldedValue, mulmod(lambda, proof[PROOF_Z_AT_SHIFTED_ZETA], R_MOD), R_MOD);
+        digests = ecAdd(digests, ecNeg(ecMul([SRS_G1_X, SRS_G1_Y], foldedValue)));
+        digests = ecAdd(digests, ecMul(zetaOpening, zeta));
+        // the opening of Z is at μζ
+        digests = ecAdd(digests, ecMul(shiftedOpening, mulmod(lambda, mulmod(zeta, GENERATOR, R_MOD), R_MOD)));
+
+        uint256[2] memory quotients = ecAdd(zetaOpening, ecMul(shiftedOpening, lambda));
+        return pairing(digests, ecNeg(quotients));
+    }
+
+    /*
+        pairing: e(a, G₂) * e(b, [α]G₂) == 1
+    */
+    function pairing(uint256[2] memory a, uint256[2] memory b) internal view returns (bool) {
+        uint256[12] memory input = [
+            a[0], a[1], SRS_G2_X_1, SRS_G2_X_0, SRS_G2_Y_1, SRS_G2_Y_0,
+            b[0], b[1], SRS_ALPHA_G2_X_1, SRS_ALPHA_G2_X_0, SRS_ALPHA_G2_Y_1, SRS_ALPHA_G2_Y_0
+        ];
+        uint256[1] memory out;
+        bool success;
+        // solium-disable-next-line security/no-inline-assembly
+        assembly {
+            success := staticcall(gas(), 8, input, 0x180, out, 0x20)
+        }
+        require(success, "pairing failed");
+        return out[0] == 1;
+    }
+
+    function ecAdd(uint256[2] memory p1, uint256[2] memory p2) internal view returns (uint256[2] memory r) {
+        uint256[4] memory input = [p1[0], p1[1], p2[0], p2[1]];
+        bool success;
+        // solium-disable-next-line security/no-inline-assembly
+        assembly {
+            success := staticcall(gas(), 6, input, 0x80, r, 0x40)
+        }
+        require(success, "ec add failed");
+    }
+
+    function ecMul(uint256[2] memory p, uint256 s) internal view returns (uint256[2] memory r) {
+        uint256[3] memory input = [p[0], p[1], s];
+        bool success;
+        // solium-disable-next-line security/no-inline-assembly
+        assembly {
+            success := staticcall(gas(), 7, input, 0x60, r, 0x40)
+        }
+        require(success, "ec mul failed");
+    }
+
+    function ecNeg(uint256[2] memory p) internal pure returns (uint256[2] memory) {
+        if (p[0] == 0 && p[1] == 0) {
+            return p;
+        }
+        return [p[0], P_MOD - p[1]];
+    }
+
+    /*
+        inverse: x^(r-2) with the modexp precompile, the inverse of 0 is 0 as in gnark
+    */
+    function inverse(uint256 x) internal view returns (uint256) {
+        uint256[6] memory input = [uint256(0x20), 0x20, 0x20, x, R_MOD - 2, R_MOD];
+        uint256[1] memory out;
+        bool success;
+        // solium-disable-next-line security/no-inline-assembly
+        assembly {
+            success := staticcall(gas(), 5, input, 0xc0, out, 0x20)
+        }
+        require(success, "inverse failed");
+        return out[0];
+    }
+}
+`
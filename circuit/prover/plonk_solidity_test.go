@@ -0,0 +1,287 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bn254 "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/stretchr/testify/assert"
+)
+
+type sumOfSquaresConstraints struct {
+	X frontend.Variable
+	Y frontend.Variable
+	Z frontend.Variable `gnark:",public"`
+	W frontend.Variable `gnark:",public"`
+}
+
+func (circuit sumOfSquaresConstraints) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Add(api.Mul(circuit.X, circuit.X), api.Mul(circuit.Y, circuit.Y)), circuit.Z)
+	api.AssertIsEqual(api.Add(circuit.X, circuit.Y), circuit.W)
+	return nil
+}
+
+func TestExportPlonkSolidity(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &sumOfSquaresConstraints{})
+	assert.Nil(t, err)
+	srs, err := NewTestSRS(ccs)
+	assert.Nil(t, err)
+	pk, vk, err := SetupPlonk(ccs, srs)
+	assert.Nil(t, err)
+
+	var contract bytes.Buffer
+	assert.Nil(t, ExportPlonkSolidity(&contract, vk))
+	assert.True(t, strings.Contains(contract.String(), "contract ZkBNBPlonkVerifier"))
+	assert.True(t, strings.Contains(contract.String(), "uint256[2] memory input"))
+	key, err := newPlonkSolidityKey(vk)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(contract.String(), fmt.Sprintf("uint256 constant QK_X = %s;", key.Qk.X)))
+
+	// the contract of a stored key is the same
+	var vkBuf bytes.Buffer
+	_, err = vk.WriteTo(&vkBuf)
+	assert.Nil(t, err)
+	vk2, err := ReadPlonkVerifyingKey(bytes.NewReader(vkBuf.Bytes()), srs)
+	assert.Nil(t, err)
+	var contract2 bytes.Buffer
+	assert.Nil(t, ExportPlonkSolidity(&contract2, vk2))
+	assert.Equal(t, contract.String(), contract2.String())
+
+	// a key read without its srs can't be exported
+	vk3 := plonk.NewVerifyingKey(ecc.BN254)
+	_, err = vk3.ReadFrom(bytes.NewReader(vkBuf.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, ErrInvalidPlonkVerifyingKey, ExportPlonkSolidity(&bytes.Buffer{}, vk3))
+
+	// the steps of the contract accept the proofs accepted by gnark
+	proof, err := provePlonk(ccs, pk, &sumOfSquaresConstraints{X: 3, Y: 4, Z: 25, W: 7})
+	assert.Nil(t, err)
+	assert.Nil(t, verifyPlonk(proof, vk, &sumOfSquaresConstraints{Z: 25, W: 7}))
+	calldata, err := PlonkProofCalldata(proof)
+	assert.Nil(t, err)
+	inputs := []*big.Int{big.NewInt(25), big.NewInt(7)}
+	assert.True(t, replayPlonkSolidity(t, key, calldata, inputs))
+
+	// and refuse the others
+	assert.False(t, replayPlonkSolidity(t, key, calldata, []*big.Int{big.NewInt(25), big.NewInt(8)}))
+	assert.False(t, replayPlonkSolidity(t, key, calldata, []*big.Int{big.NewInt(7), big.NewInt(25)}))
+	for _, i := range []int{0, 6, 8, 14, 23} {
+		tampered := calldata
+		p := g1FromWords(calldata[i], calldata[i+1])
+		p.Add(&p, &p)
+		tampered[i], tampered[i+1] = p.X.ToBigIntRegular(new(big.Int)), p.Y.ToBigIntRegular(new(big.Int))
+		assert.False(t, replayPlonkSolidity(t, key, tampered, inputs), "point %d", i)
+	}
+	for i := 16; i < PlonkProofSize; i++ {
+		if i == 23 || i == 24 {
+			continue
+		}
+		tampered := calldata
+		tampered[i] = new(big.Int).Add(calldata[i], big.NewInt(1))
+		assert.False(t, replayPlonkSolidity(t, key, tampered, inputs), "scalar %d", i)
+	}
+}
+
+func TestBlockPublicInputs(t *testing.T) {
+	commitment := bytes.Repeat([]byte{0xff}, 32)
+	inputs, err := BlockPublicInputs(56, commitment)
+	assert.Nil(t, err)
+	reduced := new(big.Int).Mod(new(big.Int).SetBytes(commitment), fr.Modulus())
+	assert.Equal(t, []*big.Int{reduced, big.NewInt(56)}, inputs)
+}
+
+/*
+	replayPlonkSolidity: the steps of verifyProof in the exported contract, with the values written in it
+*/
+func replayPlonkSolidity(t *testing.T, key *plonkSolidityKey, proof [PlonkProofSize]*big.Int, inputs []*big.Int) bool {
+	r := fr.Modulus()
+	assert.Equal(t, int(key.NbPublic), len(inputs))
+	mod := func(x *big.Int) *big.Int { return x.Mod(x, r) }
+	mul := func(a, b *big.Int) *big.Int { return mod(new(big.Int).Mul(a, b)) }
+	add := func(a, b *big.Int) *big.Int { return mod(new(big.Int).Add(a, b)) }
+	sub := func(a, b *big.Int) *big.Int { return mod(new(big.Int).Sub(a, b)) }
+	challenge := func(words ...interface{}) (*big.Int, []byte) {
+		h := sha256.New()
+		for _, word := range words {
+			switch w := word.(type) {
+			case string:
+				h.Write([]byte(w))
+			case []byte:
+				h.Write(w)
+			case *big.Int:
+				h.Write(w.FillBytes(make([]byte, 32)))
+			}
+		}
+		sum := h.Sum(nil)
+		return mod(new(big.Int).SetBytes(sum)), sum
+	}
+	point := func(i int) bn254.G1Affine { return g1FromWords(proof[i], proof[i+1]) }
+	words := func(p bn254.G1Affine) []interface{} {
+		return []interface{}{p.X.ToBigIntRegular(new(big.Int)), p.Y.ToBigIntRegular(new(big.Int))}
+	}
+	ecMul := func(p bn254.G1Affine, s *big.Int) bn254.G1Affine {
+		var res bn254.G1Affine
+		res.ScalarMultiplication(&p, s)
+		return res
+	}
+	ecAdd := func(a, b bn254.G1Affine) bn254.G1Affine {
+		var res bn254.G1Affine
+		res.Add(&a, &b)
+		return res
+	}
+	publicInputs := make([]interface{}, len(inputs))
+	for i := range inputs {
+		publicInputs[i] = mod(new(big.Int).Set(inputs[i]))
+	}
+
+	// challenges
+	bindings := []interface{}{"gamma"}
+	for _, p := range []plonkSolidityG1{key.S[0], key.S[1], key.S[2], key.Ql, key.Qr, key.Qm, key.Qo, key.Qk} {
+		bindings = append(bindings, p.X, p.Y)
+	}
+	gamma, h := challenge(append(bindings, publicInputs...)...)
+	beta, h := challenge("beta", h)
+	alpha, h := challenge(append([]interface{}{"alpha", h}, words(point(6))...)...)
+	zetaBindings := []interface{}{"zeta", h}
+	for _, i := range []int{8, 10, 12} {
+		zetaBindings = append(zetaBindings, words(point(i))...)
+	}
+	zeta, _ := challenge(zetaBindings...)
+	zetaPowerN := new(big.Int).Set(zeta)
+	for i := 0; i < key.LogSize; i++ {
+		zetaPowerN = mul(zetaPowerN, zetaPowerN)
+	}
+
+	// lagrange
+	zetaPowerNMinusOne := sub(zetaPowerN, big.NewInt(1))
+	omegaPowerI := big.NewInt(1)
+	pi, lagrangeOne := new(big.Int), new(big.Int)
+	for i := 0; i == 0 || i < len(inputs); i++ {
+		lagrange := mul(mul(omegaPowerI, key.SizeInv), zetaPowerNMinusOne)
+		den := sub(zeta, omegaPowerI)
+		lagrange = mul(lagrange, new(big.Int).Exp(den, new(big.Int).Sub(r, big.NewInt(2)), r))
+		if i == 0 {
+			lagrangeOne = lagrange
+		}
+		if i < len(inputs) {
+			pi = add(pi, mul(lagrange, publicInputs[i].(*big.Int)))
+		}
+		omegaPowerI = mul(omegaPowerI, key.Generator)
+	}
+	alphaSquareLagrange := mul(mul(lagrangeOne, alpha), alpha)
+
+	// quotient
+	l, rr, o, s1, s2, zu := proof[18], proof[19], proof[20], proof[21], proof[22], proof[25]
+	t1 := add(add(mul(s1, beta), l), gamma)
+	t1 = mul(t1, add(add(mul(s2, beta), rr), gamma))
+	t1 = mul(t1, add(o, gamma))
+	t1 = mul(mul(t1, alpha), zu)
+	linearized := sub(add(add(proof[17], pi), t1), alphaSquareLagrange)
+	if mul(proof[16], zetaPowerNMinusOne).Cmp(linearized) != 0 {
+		return false
+	}
+
+	// folded quotient
+	zetaPowerNPlusTwo := mul(mul(zetaPowerN, zeta), zeta)
+	foldedH := ecAdd(ecMul(ecAdd(ecMul(point(12), zetaPowerNPlusTwo), point(10)), zetaPowerNPlusTwo), point(8))
+
+	// linearized digest
+	g1 := func(p plonkSolidityG1) bn254.G1Affine { return g1FromWords(p.X, p.Y) }
+	digest := ecMul(g1(key.Ql), l)
+	digest = ecAdd(digest, ecMul(g1(key.Qr), rr))
+	digest = ecAdd(digest, ecMul(g1(key.Qm), mul(l, rr)))
+	digest = ecAdd(digest, ecMul(g1(key.Qo), o))
+	digest = ecAdd(digest, g1(key.Qk))
+	s := mul(zu, beta)
+	s = mul(s, add(add(mul(beta, s1), l), gamma))
+	s = mul(s, add(add(mul(beta, s2), rr), gamma))
+	s = mul(s, alpha)
+	digest = ecAdd(digest, ecMul(g1(key.S[2]), s))
+	betaZeta := mul(beta, zeta)
+	s = add(add(betaZeta, l), gamma)
+	betaZeta = mul(betaZeta, key.CosetShift)
+	s = mul(s, add(add(betaZeta, rr), gamma))
+	betaZeta = mul(betaZeta, key.CosetShift)
+	s = mul(s, add(add(betaZeta, o), gamma))
+	s = add(sub(new(big.Int), mul(s, alpha)), alphaSquareLagrange)
+	digest = ecAdd(digest, ecMul(point(6), s))
+
+	// folded batch opening
+	digests := []bn254.G1Affine{foldedH, digest, point(0), point(2), point(4), g1(key.S[0]), g1(key.S[1])}
+	foldBindings := []interface{}{"gamma", zeta}
+	for _, d := range digests {
+		foldBindings = append(foldBindings, words(d)...)
+	}
+	foldGamma, _ := challenge(foldBindings...)
+	gammaPowerI := big.NewInt(1)
+	var foldedDigest bn254.G1Affine
+	foldedValue := new(big.Int)
+	for i := range digests {
+		foldedDigest = ecAdd(foldedDigest, ecMul(digests[i], gammaPowerI))
+		foldedValue = add(foldedValue, mul(proof[16+i], gammaPowerI))
+		gammaPowerI = mul(gammaPowerI, foldGamma)
+	}
+
+	// batch verification of the openings
+	lambdaBindings := append([]interface{}{zeta}, words(foldedDigest)...)
+	lambdaBindings = append(lambdaBindings, foldedValue)
+	for i := range proof {
+		lambdaBindings = append(lambdaBindings, proof[i])
+	}
+	lambda, _ := challenge(lambdaBindings...)
+	zetaOpening, shiftedOpening := point(14), point(23)
+	acc := ecAdd(foldedDigest, ecMul(point(6), lambda))
+	foldedValue = add(foldedValue, mul(lambda, zu))
+	valueCommitment := ecMul(g1(key.G1), foldedValue)
+	valueCommitment.Neg(&valueCommitment)
+	acc = ecAdd(acc, valueCommitment)
+	acc = ecAdd(acc, ecMul(zetaOpening, zeta))
+	acc = ecAdd(acc, ecMul(shiftedOpening, mul(lambda, mul(zeta, key.Generator))))
+	quotients := ecAdd(zetaOpening, ecMul(shiftedOpening, lambda))
+	quotients.Neg(&quotients)
+
+	g2 := func(p plonkSolidityG2) bn254.G2Affine {
+		var res bn254.G2Affine
+		res.X.A0.SetBigInt(p.X0)
+		res.X.A1.SetBigInt(p.X1)
+		res.Y.A0.SetBigInt(p.Y0)
+		res.Y.A1.SetBigInt(p.Y1)
+		return res
+	}
+	ok, err := bn254.PairingCheck([]bn254.G1Affine{acc, quotients}, []bn254.G2Affine{g2(key.G2[0]), g2(key.G2[1])})
+	assert.Nil(t, err)
+	return ok
+}
+
+func g1FromWords(x, y *big.Int) bn254.G1Affine {
+	var p bn254.G1Affine
+	p.X.SetBigInt(x)
+	p.Y.SetBigInt(y)
+	return p
+}
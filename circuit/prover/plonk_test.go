@@ -0,0 +1,133 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/executor"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+type squareConstraints struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (circuit squareConstraints) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Mul(circuit.X, circuit.X), circuit.Y)
+	return nil
+}
+
+func TestPlonkKeysRoundTrip(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &squareConstraints{})
+	assert.Nil(t, err)
+	srs, err := NewTestSRS(ccs)
+	assert.Nil(t, err)
+	pk, vk, err := SetupPlonk(ccs, srs)
+	assert.Nil(t, err)
+
+	var srsBuf, pkBuf, vkBuf bytes.Buffer
+	_, err = srs.WriteTo(&srsBuf)
+	assert.Nil(t, err)
+	_, err = pk.WriteTo(&pkBuf)
+	assert.Nil(t, err)
+	_, err = vk.WriteTo(&vkBuf)
+	assert.Nil(t, err)
+
+	srs2, err := ReadPlonkSRS(&srsBuf)
+	assert.Nil(t, err)
+	pk2, err := ReadPlonkProvingKey(bytes.NewReader(pkBuf.Bytes()), srs2)
+	assert.Nil(t, err)
+	vk2, err := ReadPlonkVerifyingKey(bytes.NewReader(vkBuf.Bytes()), srs2)
+	assert.Nil(t, err)
+
+	proof, err := provePlonk(ccs, pk2, &squareConstraints{X: 3, Y: 9})
+	assert.Nil(t, err)
+	assert.Nil(t, verifyPlonk(proof, vk2, &squareConstraints{Y: 9}))
+	assert.NotNil(t, verifyPlonk(proof, vk2, &squareConstraints{Y: 10}))
+
+	// proofs round trip too
+	var proofBuf bytes.Buffer
+	_, err = proof.WriteTo(&proofBuf)
+	assert.Nil(t, err)
+	proof2 := plonk.NewProof(ecc.BN254)
+	_, err = proof2.ReadFrom(&proofBuf)
+	assert.Nil(t, err)
+	assert.Nil(t, verifyPlonk(proof2, vk, &squareConstraints{Y: 9}))
+
+	_, err = provePlonk(ccs, pk, &squareConstraints{X: 3, Y: 10})
+	assert.NotNil(t, err)
+
+	// the srs must be large enough for the keys
+	smallSRS, err := kzg.NewSRS(2, big.NewInt(42))
+	assert.Nil(t, err)
+	_, err = ReadPlonkProvingKey(bytes.NewReader(pkBuf.Bytes()), smallSRS)
+	assert.NotNil(t, err)
+	_, err = ReadPlonkVerifyingKey(bytes.NewReader(vkBuf.Bytes()), smallSRS)
+	assert.NotNil(t, err)
+}
+
+func TestProveBlockPlonk(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping plonk block proof in short mode")
+	}
+	sk, err := curve.GenerateEddsaPrivateKey("gas.legend")
+	assert.Nil(t, err)
+	hFunc := mimc.NewMiMC()
+	hFunc.Write([]byte("gas.legend"))
+	state, err := executor.NewState()
+	assert.Nil(t, err)
+	gasAssetIds := []int64{0, 1}
+	oBlock, err := executor.BuildBlock(state, types.DefaultChainId, 1, 1654656781000, 1, 1, gasAssetIds, []txtypes.TxInfo{
+		&txtypes.RegisterZnsTxInfo{
+			TxType:          types.TxTypeRegisterZns,
+			AccountIndex:    1,
+			AccountName:     "gas.legend",
+			AccountNameHash: hFunc.Sum(nil),
+			PubKey:          hex.EncodeToString(sk.PublicKey.Bytes()),
+		},
+	})
+	assert.Nil(t, err)
+
+	ccs, err := CompileBlockPlonk(circuit.DefaultConfig(), 1, gasAssetIds, 1)
+	assert.Nil(t, err)
+	srs, err := NewTestSRS(ccs)
+	assert.Nil(t, err)
+	pk, vk, err := SetupPlonk(ccs, srs)
+	assert.Nil(t, err)
+	proof, err := ProveBlockPlonk(ccs, pk, circuit.DefaultConfig(), oBlock)
+	assert.Nil(t, err)
+	assert.Nil(t, VerifyBlockPlonk(proof, vk, oBlock.ChainId, oBlock.BlockCommitment))
+	assert.NotNil(t, VerifyBlockPlonk(proof, vk, oBlock.ChainId, []byte{1}))
+	assert.NotNil(t, VerifyBlockPlonk(proof, vk, oBlock.ChainId+1, oBlock.BlockCommitment))
+}
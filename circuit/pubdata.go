@@ -0,0 +1,96 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package circuit
+
+import (
+	"errors"
+	"log"
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+/*
+	ComputePubData: out-of-circuit version of the pub data selected in VerifyTransaction
+*/
+func ComputePubData(oTx *Tx) (pubData [types.PubDataSizePerTx]*big.Int, err error) {
+	switch oTx.TxType {
+	case types.TxTypeEmptyTx:
+		for i := 0; i < types.PubDataSizePerTx; i++ {
+			pubData[i] = new(big.Int)
+		}
+		return pubData, nil
+	case types.TxTypeRegisterZns:
+		if oTx.RegisterZnsTxInfo != nil {
+			return types.ComputePubDataFromRegisterZNS(oTx.RegisterZnsTxInfo)
+		}
+	case types.TxTypeDeposit:
+		if oTx.DepositTxInfo != nil {
+			return types.ComputePubDataFromDeposit(oTx.DepositTxInfo)
+		}
+	case types.TxTypeDepositNft:
+		if oTx.DepositNftTxInfo != nil {
+			return types.ComputePubDataFromDepositNft(oTx.DepositNftTxInfo)
+		}
+	case types.TxTypeTransfer:
+		if oTx.TransferTxInfo != nil {
+			return types.ComputePubDataFromTransfer(oTx.TransferTxInfo)
+		}
+	case types.TxTypeWithdraw:
+		if oTx.WithdrawTxInfo != nil {
+			return types.ComputePubDataFromWithdraw(oTx.WithdrawTxInfo)
+		}
+	case types.TxTypeCreateCollection:
+		if oTx.CreateCollectionTxInfo != nil {
+			return types.ComputePubDataFromCreateCollection(oTx.CreateCollectionTxInfo)
+		}
+	case types.TxTypeMintNft:
+		if oTx.MintNftTxInfo != nil {
+			return types.ComputePubDataFromMintNft(oTx.MintNftTxInfo)
+		}
+	case types.TxTypeTransferNft:
+		if oTx.TransferNftTxInfo != nil {
+			return types.ComputePubDataFromTransferNft(oTx.TransferNftTxInfo)
+		}
+	case types.TxTypeAtomicMatch:
+		if oTx.AtomicMatchTxInfo != nil {
+			return types.ComputePubDataFromAtomicMatch(oTx.AtomicMatchTxInfo)
+		}
+	case types.TxTypeCancelOffer:
+		if oTx.CancelOfferTxInfo != nil {
+			return types.ComputePubDataFromCancelOffer(oTx.CancelOfferTxInfo)
+		}
+	case types.TxTypeWithdrawNft:
+		if oTx.WithdrawNftTxInfo != nil {
+			return types.ComputePubDataFromWithdrawNft(oTx.WithdrawNftTxInfo)
+		}
+	case types.TxTypeFullExit:
+		if oTx.FullExitTxInfo != nil {
+			return types.ComputePubDataFromFullExit(oTx.FullExitTxInfo)
+		}
+	case types.TxTypeFullExitNft:
+		if oTx.FullExitNftTxInfo != nil {
+			return types.ComputePubDataFromFullExitNft(oTx.FullExitNftTxInfo)
+		}
+	default:
+		log.Println("[ComputePubData] invalid tx type")
+		return pubData, errors.New("[ComputePubData] invalid tx type")
+	}
+	log.Println("[ComputePubData] tx info is nil")
+	return pubData, errors.New("[ComputePubData] tx info is nil")
+}
@@ -0,0 +1,452 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pubdata
+
+import (
+	"log"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+const (
+	wordBitsSize = circuit.PubDataWordBytes * 8
+
+	// offer types checked by VerifyAtomicMatchTx
+	buyOfferType  = 0
+	sellOfferType = 1
+)
+
+/*
+	Tx: tx decoded from its pub data, only the fields committed in the pub data are set.
+	Encoding it again with circuit.ComputePubData gives back the same pub data.
+*/
+type Tx struct {
+	TxType uint8
+
+	RegisterZnsTxInfo      *types.RegisterZnsTx
+	DepositTxInfo          *types.DepositTx
+	DepositNftTxInfo       *types.DepositNftTx
+	TransferTxInfo         *types.TransferTx
+	CreateCollectionTxInfo *types.CreateCollectionTx
+	MintNftTxInfo          *types.MintNftTx
+	TransferNftTxInfo      *types.TransferNftTx
+	AtomicMatchTxInfo      *types.AtomicMatchTx
+	CancelOfferTxInfo      *types.CancelOfferTx
+	WithdrawTxInfo         *types.WithdrawTx
+	WithdrawNftTxInfo      *types.WithdrawNftTx
+	FullExitTxInfo         *types.FullExitTx
+	FullExitNftTxInfo      *types.FullExitNftTx
+}
+
+/*
+	CircuitTx: convert the decoded tx into a circuit tx with only the tx info set
+*/
+func (tx *Tx) CircuitTx() *circuit.Tx {
+	return &circuit.Tx{
+		TxType:                 tx.TxType,
+		RegisterZnsTxInfo:      tx.RegisterZnsTxInfo,
+		DepositTxInfo:          tx.DepositTxInfo,
+		DepositNftTxInfo:       tx.DepositNftTxInfo,
+		TransferTxInfo:         tx.TransferTxInfo,
+		CreateCollectionTxInfo: tx.CreateCollectionTxInfo,
+		MintNftTxInfo:          tx.MintNftTxInfo,
+		TransferNftTxInfo:      tx.TransferNftTxInfo,
+		AtomicMatchTxInfo:      tx.AtomicMatchTxInfo,
+		CancelOfferTxInfo:      tx.CancelOfferTxInfo,
+		WithdrawTxInfo:         tx.WithdrawTxInfo,
+		WithdrawNftTxInfo:      tx.WithdrawNftTxInfo,
+		FullExitTxInfo:         tx.FullExitTxInfo,
+		FullExitNftTxInfo:      tx.FullExitNftTxInfo,
+	}
+}
+
+/*
+	DecodeBlockPubData: decode the public data of a block, as committed on layer 1
+*/
+func DecodeBlockPubData(pubData []byte) (txs []*Tx, err error) {
+	if len(pubData)%circuit.PubDataBytesPerTx != 0 {
+		log.Println("[DecodeBlockPubData] invalid pub data length:", len(pubData))
+		return nil, ErrInvalidPubDataLength
+	}
+	for i := 0; i < len(pubData); i += circuit.PubDataBytesPerTx {
+		tx, err := DecodeTxPubData(pubData[i : i+circuit.PubDataBytesPerTx])
+		if err != nil {
+			log.Println("[DecodeBlockPubData] unable to decode tx", i/circuit.PubDataBytesPerTx, ":", err)
+			return nil, err
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+/*
+	DecodeTxPubData: decode the PubDataSizePerTx words of a single tx
+*/
+func DecodeTxPubData(pubData []byte) (tx *Tx, err error) {
+	if len(pubData) != circuit.PubDataBytesPerTx {
+		return nil, ErrInvalidPubDataLength
+	}
+	var words [types.PubDataSizePerTx]*big.Int
+	for i := 0; i < types.PubDataSizePerTx; i++ {
+		words[i] = new(big.Int).SetBytes(pubData[i*circuit.PubDataWordBytes : (i+1)*circuit.PubDataWordBytes])
+	}
+	return DecodeTxPubDataWords(words)
+}
+
+/*
+	DecodeTxPubDataWords: decode the pub data words of a single tx, every word must be a field element
+*/
+func DecodeTxPubDataWords(words [types.PubDataSizePerTx]*big.Int) (tx *Tx, err error) {
+	for _, word := range words {
+		if word == nil || word.Sign() < 0 || word.Cmp(fr.Modulus()) >= 0 {
+			return nil, ErrInvalidPubDataWord
+		}
+	}
+	txType := uint8(new(big.Int).Rsh(words[0], wordBitsSize-types.TxTypeBitsSize).Uint64())
+	tx = &Tx{TxType: txType}
+	var usedWords int
+	switch txType {
+	case types.TxTypeEmptyTx:
+		usedWords = 0
+	case types.TxTypeRegisterZns:
+		tx.RegisterZnsTxInfo, usedWords, err = decodeRegisterZns(words)
+	case types.TxTypeDeposit:
+		tx.DepositTxInfo, usedWords, err = decodeDeposit(words)
+	case types.TxTypeDepositNft:
+		tx.DepositNftTxInfo, usedWords, err = decodeDepositNft(words)
+	case types.TxTypeTransfer:
+		tx.TransferTxInfo, usedWords, err = decodeTransfer(words)
+	case types.TxTypeWithdraw:
+		tx.WithdrawTxInfo, usedWords, err = decodeWithdraw(words)
+	case types.TxTypeCreateCollection:
+		tx.CreateCollectionTxInfo, usedWords, err = decodeCreateCollection(words)
+	case types.TxTypeMintNft:
+		tx.MintNftTxInfo, usedWords, err = decodeMintNft(words)
+	case types.TxTypeTransferNft:
+		tx.TransferNftTxInfo, usedWords, err = decodeTransferNft(words)
+	case types.TxTypeAtomicMatch:
+		tx.AtomicMatchTxInfo, usedWords, err = decodeAtomicMatch(words)
+	case types.TxTypeCancelOffer:
+		tx.CancelOfferTxInfo, usedWords, err = decodeCancelOffer(words)
+	case types.TxTypeWithdrawNft:
+		tx.WithdrawNftTxInfo, usedWords, err = decodeWithdrawNft(words)
+	case types.TxTypeFullExit:
+		tx.FullExitTxInfo, usedWords, err = decodeFullExit(words)
+	case types.TxTypeFullExitNft:
+		tx.FullExitNftTxInfo, usedWords, err = decodeFullExitNft(words)
+	default:
+		log.Println("[DecodeTxPubData] invalid tx type:", txType)
+		return nil, ErrInvalidTxType
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the words which are not used by the tx are always zero
+	for i := usedWords; i < types.PubDataSizePerTx; i++ {
+		if words[i].Sign() != 0 {
+			return nil, ErrNonZeroPadding
+		}
+	}
+	return tx, nil
+}
+
+func wordToBytes(word *big.Int) []byte {
+	return word.FillBytes(make([]byte, circuit.PubDataWordBytes))
+}
+
+func decodeRegisterZns(words [types.PubDataSizePerTx]*big.Int) (tx *types.RegisterZnsTx, usedWords int, err error) {
+	r := newTxPubDataReader(words[0])
+	tx = &types.RegisterZnsTx{
+		AccountIndex: r.readInt64(types.AccountIndexBitsSize),
+	}
+	r.padding(216)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	// the account name is committed as a big endian integer, leading zeros are not part of it
+	tx.AccountName = words[1].Bytes()
+	tx.AccountNameHash = wordToBytes(words[2])
+	tx.PubKey = new(types.PublicKey)
+	tx.PubKey.A.X.SetBigInt(words[3])
+	tx.PubKey.A.Y.SetBigInt(words[4])
+	return tx, 5, nil
+}
+
+func decodeDeposit(words [types.PubDataSizePerTx]*big.Int) (tx *types.DepositTx, usedWords int, err error) {
+	r := newTxPubDataReader(words[0])
+	tx = &types.DepositTx{
+		AccountIndex: r.readInt64(types.AccountIndexBitsSize),
+		AssetId:      r.readInt64(types.AssetIdBitsSize),
+		AssetAmount:  r.readBigInt(types.StateAmountBitsSize),
+	}
+	r.padding(72)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	tx.AccountNameHash = wordToBytes(words[1])
+	return tx, 2, nil
+}
+
+func decodeDepositNft(words [types.PubDataSizePerTx]*big.Int) (tx *types.DepositNftTx, usedWords int, err error) {
+	r := newTxPubDataReader(words[0])
+	tx = &types.DepositNftTx{
+		AccountIndex: r.readInt64(types.AccountIndexBitsSize),
+		NftIndex:     r.readInt64(types.NftIndexBitsSize),
+		NftL1Address: r.readAddress(types.AddressBitsSize),
+	}
+	r.padding(16)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	r = newPubDataReader(words[1], types.AccountIndexBitsSize+types.CreatorTreasuryRateBitsSize+types.CollectionIdBitsSize)
+	tx.CreatorAccountIndex = r.readInt64(types.AccountIndexBitsSize)
+	tx.CreatorTreasuryRate = r.readInt64(types.CreatorTreasuryRateBitsSize)
+	tx.CollectionId = r.readInt64(types.CollectionIdBitsSize)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	tx.NftContentHash = wordToBytes(words[2])
+	tx.NftL1TokenId = new(big.Int).Set(words[3])
+	tx.AccountNameHash = wordToBytes(words[4])
+	return tx, 5, nil
+}
+
+func decodeTransfer(words [types.PubDataSizePerTx]*big.Int) (tx *types.TransferTx, usedWords int, err error) {
+	r := newTxPubDataReader(words[0])
+	tx = &types.TransferTx{
+		FromAccountIndex:  r.readInt64(types.AccountIndexBitsSize),
+		ToAccountIndex:    r.readInt64(types.AccountIndexBitsSize),
+		AssetId:           r.readInt64(types.AssetIdBitsSize),
+		AssetAmount:       r.readInt64(types.PackedAmountBitsSize),
+		GasAccountIndex:   r.readInt64(types.AccountIndexBitsSize),
+		GasFeeAssetId:     r.readInt64(types.AssetIdBitsSize),
+		GasFeeAssetAmount: r.readInt64(types.PackedFeeBitsSize),
+	}
+	r.padding(64)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	tx.CallDataHash = wordToBytes(words[1])
+	return tx, 2, nil
+}
+
+func decodeWithdraw(words [types.PubDataSizePerTx]*big.Int) (tx *types.WithdrawTx, usedWords int, err error) {
+	r := newTxPubDataReader(words[0])
+	tx = &types.WithdrawTx{
+		FromAccountIndex: r.readInt64(types.AccountIndexBitsSize),
+		ToAddress:        r.readBigInt(types.AddressBitsSize),
+		AssetId:          r.readInt64(types.AssetIdBitsSize),
+	}
+	r.padding(40)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	r = newPubDataReader(words[1], types.StateAmountBitsSize+types.AccountIndexBitsSize+types.AssetIdBitsSize+types.PackedFeeBitsSize)
+	tx.AssetAmount = r.readBigInt(types.StateAmountBitsSize)
+	tx.GasAccountIndex = r.readInt64(types.AccountIndexBitsSize)
+	tx.GasFeeAssetId = r.readInt64(types.AssetIdBitsSize)
+	tx.GasFeeAssetAmount = r.readInt64(types.PackedFeeBitsSize)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	return tx, 2, nil
+}
+
+func decodeCreateCollection(words [types.PubDataSizePerTx]*big.Int) (tx *types.CreateCollectionTx, usedWords int, err error) {
+	r := newTxPubDataReader(words[0])
+	tx = &types.CreateCollectionTx{
+		AccountIndex:      r.readInt64(types.AccountIndexBitsSize),
+		CollectionId:      r.readInt64(types.CollectionIdBitsSize),
+		GasAccountIndex:   r.readInt64(types.AccountIndexBitsSize),
+		GasFeeAssetId:     r.readInt64(types.AssetIdBitsSize),
+		GasFeeAssetAmount: r.readInt64(types.PackedFeeBitsSize),
+	}
+	r.padding(136)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	return tx, 1, nil
+}
+
+func decodeMintNft(words [types.PubDataSizePerTx]*big.Int) (tx *types.MintNftTx, usedWords int, err error) {
+	r := newTxPubDataReader(words[0])
+	tx = &types.MintNftTx{
+		CreatorAccountIndex: r.readInt64(types.AccountIndexBitsSize),
+		ToAccountIndex:      r.readInt64(types.AccountIndexBitsSize),
+		NftIndex:            r.readInt64(types.NftIndexBitsSize),
+		GasAccountIndex:     r.readInt64(types.AccountIndexBitsSize),
+		GasFeeAssetId:       r.readInt64(types.AssetIdBitsSize),
+		GasFeeAssetAmount:   r.readInt64(types.PackedFeeBitsSize),
+		CreatorTreasuryRate: r.readInt64(types.CreatorTreasuryRateBitsSize),
+		CollectionId:        r.readInt64(types.CollectionIdBitsSize),
+	}
+	r.padding(48)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	tx.NftContentHash = wordToBytes(words[1])
+	return tx, 2, nil
+}
+
+func decodeTransferNft(words [types.PubDataSizePerTx]*big.Int) (tx *types.TransferNftTx, usedWords int, err error) {
+	r := newTxPubDataReader(words[0])
+	tx = &types.TransferNftTx{
+		FromAccountIndex:  r.readInt64(types.AccountIndexBitsSize),
+		ToAccountIndex:    r.readInt64(types.AccountIndexBitsSize),
+		NftIndex:          r.readInt64(types.NftIndexBitsSize),
+		GasAccountIndex:   r.readInt64(types.AccountIndexBitsSize),
+		GasFeeAssetId:     r.readInt64(types.AssetIdBitsSize),
+		GasFeeAssetAmount: r.readInt64(types.PackedFeeBitsSize),
+	}
+	r.padding(80)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	tx.CallDataHash = wordToBytes(words[1])
+	return tx, 2, nil
+}
+
+/*
+	decodeAtomicMatch: only the offer fields committed in the pub data are set,
+	the nft index comes from the buy offer and the asset from the sell offer
+*/
+func decodeAtomicMatch(words [types.PubDataSizePerTx]*big.Int) (tx *types.AtomicMatchTx, usedWords int, err error) {
+	r := newTxPubDataReader(words[0])
+	tx = &types.AtomicMatchTx{
+		BuyOffer:  &types.OfferTx{Type: buyOfferType},
+		SellOffer: &types.OfferTx{Type: sellOfferType},
+	}
+	tx.AccountIndex = r.readInt64(types.AccountIndexBitsSize)
+	tx.BuyOffer.AccountIndex = r.readInt64(types.AccountIndexBitsSize)
+	tx.BuyOffer.OfferId = r.readInt64(types.OfferIdBitsSize)
+	tx.SellOffer.AccountIndex = r.readInt64(types.AccountIndexBitsSize)
+	tx.SellOffer.OfferId = r.readInt64(types.OfferIdBitsSize)
+	tx.BuyOffer.NftIndex = r.readInt64(types.NftIndexBitsSize)
+	tx.SellOffer.AssetId = r.readInt64(types.AssetIdBitsSize)
+	r.padding(48)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	r = newPubDataReader(words[1], 3*types.PackedAmountBitsSize+types.AccountIndexBitsSize+types.AssetIdBitsSize+types.PackedFeeBitsSize)
+	tx.SellOffer.AssetAmount = r.readInt64(types.PackedAmountBitsSize)
+	tx.CreatorAmount = r.readInt64(types.PackedAmountBitsSize)
+	tx.TreasuryAmount = r.readInt64(types.PackedAmountBitsSize)
+	tx.GasAccountIndex = r.readInt64(types.AccountIndexBitsSize)
+	tx.GasFeeAssetId = r.readInt64(types.AssetIdBitsSize)
+	tx.GasFeeAssetAmount = r.readInt64(types.PackedFeeBitsSize)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	// both offers refer to the same nft and asset once they are matched
+	tx.SellOffer.NftIndex = tx.BuyOffer.NftIndex
+	tx.BuyOffer.AssetId = tx.SellOffer.AssetId
+	tx.BuyOffer.AssetAmount = tx.SellOffer.AssetAmount
+	return tx, 2, nil
+}
+
+func decodeCancelOffer(words [types.PubDataSizePerTx]*big.Int) (tx *types.CancelOfferTx, usedWords int, err error) {
+	r := newTxPubDataReader(words[0])
+	tx = &types.CancelOfferTx{
+		AccountIndex:      r.readInt64(types.AccountIndexBitsSize),
+		OfferId:           r.readInt64(types.OfferIdBitsSize),
+		GasAccountIndex:   r.readInt64(types.AccountIndexBitsSize),
+		GasFeeAssetId:     r.readInt64(types.AssetIdBitsSize),
+		GasFeeAssetAmount: r.readInt64(types.PackedFeeBitsSize),
+	}
+	r.padding(128)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	return tx, 1, nil
+}
+
+func decodeWithdrawNft(words [types.PubDataSizePerTx]*big.Int) (tx *types.WithdrawNftTx, usedWords int, err error) {
+	r := newTxPubDataReader(words[0])
+	tx = &types.WithdrawNftTx{
+		AccountIndex:        r.readInt64(types.AccountIndexBitsSize),
+		CreatorAccountIndex: r.readInt64(types.AccountIndexBitsSize),
+		CreatorTreasuryRate: r.readInt64(types.FeeRateBitsSize),
+		NftIndex:            r.readInt64(types.NftIndexBitsSize),
+		CollectionId:        r.readInt64(types.CollectionIdBitsSize),
+	}
+	r.padding(112)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	r = newPubDataReader(words[1], types.AddressBitsSize)
+	tx.NftL1Address = r.readAddress(types.AddressBitsSize)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	r = newPubDataReader(words[2], types.AddressBitsSize+types.AccountIndexBitsSize+types.AssetIdBitsSize+types.PackedFeeBitsSize)
+	tx.ToAddress = r.readAddress(types.AddressBitsSize)
+	tx.GasAccountIndex = r.readInt64(types.AccountIndexBitsSize)
+	tx.GasFeeAssetId = r.readInt64(types.AssetIdBitsSize)
+	tx.GasFeeAssetAmount = r.readInt64(types.PackedFeeBitsSize)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	tx.NftContentHash = wordToBytes(words[3])
+	tx.NftL1TokenId = new(big.Int).Set(words[4])
+	tx.CreatorAccountNameHash = wordToBytes(words[5])
+	return tx, 6, nil
+}
+
+func decodeFullExit(words [types.PubDataSizePerTx]*big.Int) (tx *types.FullExitTx, usedWords int, err error) {
+	r := newTxPubDataReader(words[0])
+	tx = &types.FullExitTx{
+		AccountIndex: r.readInt64(types.AccountIndexBitsSize),
+		AssetId:      r.readInt64(types.AssetIdBitsSize),
+		AssetAmount:  r.readBigInt(types.StateAmountBitsSize),
+	}
+	r.padding(72)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	tx.AccountNameHash = wordToBytes(words[1])
+	return tx, 2, nil
+}
+
+func decodeFullExitNft(words [types.PubDataSizePerTx]*big.Int) (tx *types.FullExitNftTx, usedWords int, err error) {
+	r := newTxPubDataReader(words[0])
+	tx = &types.FullExitNftTx{
+		AccountIndex:        r.readInt64(types.AccountIndexBitsSize),
+		CreatorAccountIndex: r.readInt64(types.AccountIndexBitsSize),
+		CreatorTreasuryRate: r.readInt64(types.FeeRateBitsSize),
+		NftIndex:            r.readInt64(types.NftIndexBitsSize),
+		CollectionId:        r.readInt64(types.CollectionIdBitsSize),
+	}
+	r.padding(112)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	r = newPubDataReader(words[1], types.AddressBitsSize)
+	tx.NftL1Address = r.readAddress(types.AddressBitsSize)
+	if err = r.finish(); err != nil {
+		return nil, 0, err
+	}
+	tx.AccountNameHash = wordToBytes(words[2])
+	tx.CreatorAccountNameHash = wordToBytes(words[3])
+	tx.NftContentHash = wordToBytes(words[4])
+	tx.NftL1TokenId = new(big.Int).Set(words[5])
+	return tx, 6, nil
+}
@@ -0,0 +1,347 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pubdata
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/test"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+)
+
+var (
+	testNameHash    = common.FromHex("0x04b2d6b7a5ef5e2cf9e7ae2fbbd0a3c5b8d5a14a0d6e89b15d6b0bf5e5b4e3a1")
+	testContentHash = common.FromHex("0x1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f809")
+	testL1Address   = common.HexToAddress("0x299d17c8b4e9967385dc9a3bb78f2a43f5a13bd5").Hex()
+)
+
+func testTxs(t *testing.T) []*circuit.Tx {
+	sk, err := curve.GenerateEddsaPrivateKey("alice.legend")
+	assert.Nil(t, err)
+	buyOffer := &types.OfferTx{
+		Type: buyOfferType, OfferId: 3, AccountIndex: 4, NftIndex: 7, AssetId: 1, AssetAmount: 1001,
+		Sig: types.EmptySignature(),
+	}
+	sellOffer := &types.OfferTx{
+		Type: sellOfferType, OfferId: 5, AccountIndex: 5, NftIndex: 7, AssetId: 1, AssetAmount: 1001,
+		Sig: types.EmptySignature(),
+	}
+	return []*circuit.Tx{
+		{TxType: types.TxTypeEmptyTx},
+		{TxType: types.TxTypeRegisterZns, RegisterZnsTxInfo: &types.RegisterZnsTx{
+			AccountIndex:    2,
+			AccountName:     []byte("alice.legend"),
+			AccountNameHash: testNameHash,
+			PubKey:          &sk.PublicKey,
+		}},
+		{TxType: types.TxTypeDeposit, DepositTxInfo: &types.DepositTx{
+			AccountIndex:    2,
+			AccountNameHash: testNameHash,
+			AssetId:         3,
+			AssetAmount:     new(big.Int).Lsh(big.NewInt(1), 127),
+		}},
+		{TxType: types.TxTypeDepositNft, DepositNftTxInfo: &types.DepositNftTx{
+			AccountIndex:        2,
+			NftIndex:            1<<40 - 1,
+			NftL1Address:        testL1Address,
+			AccountNameHash:     testNameHash,
+			NftContentHash:      testContentHash,
+			NftL1TokenId:        big.NewInt(12345),
+			CreatorAccountIndex: 6,
+			CreatorTreasuryRate: 30,
+			CollectionId:        9,
+		}},
+		{TxType: types.TxTypeTransfer, TransferTxInfo: &types.TransferTx{
+			FromAccountIndex:  2,
+			ToAccountIndex:    3,
+			ToAccountNameHash: testNameHash,
+			AssetId:           1,
+			AssetAmount:       1<<40 - 1,
+			GasAccountIndex:   1,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: 1<<16 - 1,
+			CallDataHash:      testContentHash,
+		}},
+		{TxType: types.TxTypeWithdraw, WithdrawTxInfo: &types.WithdrawTx{
+			FromAccountIndex:  2,
+			AssetId:           1,
+			AssetAmount:       big.NewInt(100000),
+			GasAccountIndex:   1,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: 10,
+			ToAddress:         new(big.Int).SetBytes(common.FromHex(testL1Address)),
+		}},
+		{TxType: types.TxTypeCreateCollection, CreateCollectionTxInfo: &types.CreateCollectionTx{
+			AccountIndex:      2,
+			CollectionId:      1<<16 - 1,
+			GasAccountIndex:   1,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: 10,
+		}},
+		{TxType: types.TxTypeMintNft, MintNftTxInfo: &types.MintNftTx{
+			CreatorAccountIndex: 2,
+			ToAccountIndex:      3,
+			ToAccountNameHash:   testNameHash,
+			NftIndex:            7,
+			NftContentHash:      testContentHash,
+			CreatorTreasuryRate: 30,
+			GasAccountIndex:     1,
+			GasFeeAssetId:       0,
+			GasFeeAssetAmount:   10,
+			CollectionId:        9,
+		}},
+		{TxType: types.TxTypeTransferNft, TransferNftTxInfo: &types.TransferNftTx{
+			FromAccountIndex:  2,
+			ToAccountIndex:    3,
+			ToAccountNameHash: testNameHash,
+			NftIndex:          7,
+			GasAccountIndex:   1,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: 10,
+			CallDataHash:      testContentHash,
+		}},
+		{TxType: types.TxTypeAtomicMatch, AtomicMatchTxInfo: &types.AtomicMatchTx{
+			AccountIndex:      2,
+			BuyOffer:          buyOffer,
+			SellOffer:         sellOffer,
+			CreatorAmount:     20,
+			TreasuryAmount:    30,
+			GasAccountIndex:   1,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: 10,
+		}},
+		{TxType: types.TxTypeCancelOffer, CancelOfferTxInfo: &types.CancelOfferTx{
+			AccountIndex:      2,
+			OfferId:           1<<24 - 1,
+			GasAccountIndex:   1,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: 10,
+		}},
+		{TxType: types.TxTypeWithdrawNft, WithdrawNftTxInfo: &types.WithdrawNftTx{
+			AccountIndex:           2,
+			CreatorAccountIndex:    6,
+			CreatorAccountNameHash: testNameHash,
+			CreatorTreasuryRate:    30,
+			NftIndex:               7,
+			NftContentHash:         testContentHash,
+			NftL1Address:           testL1Address,
+			NftL1TokenId:           big.NewInt(12345),
+			ToAddress:              "0x0000000000000000000000000000000000000011",
+			GasAccountIndex:        1,
+			GasFeeAssetId:          0,
+			GasFeeAssetAmount:      10,
+			CollectionId:           9,
+		}},
+		{TxType: types.TxTypeFullExit, FullExitTxInfo: &types.FullExitTx{
+			AccountIndex:    2,
+			AccountNameHash: testNameHash,
+			AssetId:         3,
+			AssetAmount:     big.NewInt(0),
+		}},
+		{TxType: types.TxTypeFullExitNft, FullExitNftTxInfo: &types.FullExitNftTx{
+			AccountIndex:           2,
+			AccountNameHash:        testNameHash,
+			CreatorAccountIndex:    6,
+			CreatorAccountNameHash: testNameHash,
+			CreatorTreasuryRate:    30,
+			NftIndex:               7,
+			CollectionId:           9,
+			NftContentHash:         testContentHash,
+			NftL1Address:           testL1Address,
+			NftL1TokenId:           big.NewInt(12345),
+		}},
+	}
+}
+
+func encodeWords(words [types.PubDataSizePerTx]*big.Int) []byte {
+	var pubData []byte
+	for _, word := range words {
+		pubData = append(pubData, wordToBytes(word)...)
+	}
+	return pubData
+}
+
+func TestDecodeTxPubDataRoundTrip(t *testing.T) {
+	for _, oTx := range testTxs(t) {
+		words, err := circuit.ComputePubData(oTx)
+		assert.Nil(t, err)
+		tx, err := DecodeTxPubData(encodeWords(words))
+		assert.Nil(t, err, "tx type %d", oTx.TxType)
+		assert.Equal(t, oTx.TxType, tx.TxType)
+		nWords, err := circuit.ComputePubData(tx.CircuitTx())
+		assert.Nil(t, err)
+		assert.Equal(t, words, nWords, "tx type %d", oTx.TxType)
+	}
+}
+
+func TestDecodeTxPubDataFields(t *testing.T) {
+	txs := testTxs(t)
+	decode := func(oTx *circuit.Tx) *Tx {
+		words, err := circuit.ComputePubData(oTx)
+		assert.Nil(t, err)
+		tx, err := DecodeTxPubData(encodeWords(words))
+		assert.Nil(t, err)
+		return tx
+	}
+
+	registerZns := decode(txs[1]).RegisterZnsTxInfo
+	assert.Equal(t, txs[1].RegisterZnsTxInfo.AccountName, registerZns.AccountName)
+	assert.Equal(t, testNameHash, registerZns.AccountNameHash)
+	assert.True(t, txs[1].RegisterZnsTxInfo.PubKey.A.Equal(&registerZns.PubKey.A))
+
+	assert.Equal(t, txs[2].DepositTxInfo, decode(txs[2]).DepositTxInfo)
+
+	depositNft := decode(txs[3]).DepositNftTxInfo
+	assert.Equal(t, int64(1<<40-1), depositNft.NftIndex)
+	assert.Equal(t, testL1Address, depositNft.NftL1Address)
+	assert.Equal(t, int64(30), depositNft.CreatorTreasuryRate)
+	assert.Equal(t, testContentHash, depositNft.NftContentHash)
+
+	assert.Equal(t, txs[4].TransferTxInfo.AssetAmount, decode(txs[4]).TransferTxInfo.AssetAmount)
+	assert.Equal(t, txs[5].WithdrawTxInfo.ToAddress, decode(txs[5]).WithdrawTxInfo.ToAddress)
+	assert.Equal(t, txs[6].CreateCollectionTxInfo, decode(txs[6]).CreateCollectionTxInfo)
+	assert.Equal(t, txs[10].CancelOfferTxInfo, decode(txs[10]).CancelOfferTxInfo)
+
+	atomicMatch := decode(txs[9]).AtomicMatchTxInfo
+	assert.Equal(t, int64(4), atomicMatch.BuyOffer.AccountIndex)
+	assert.Equal(t, int64(3), atomicMatch.BuyOffer.OfferId)
+	assert.Equal(t, int64(5), atomicMatch.SellOffer.AccountIndex)
+	assert.Equal(t, int64(5), atomicMatch.SellOffer.OfferId)
+	assert.Equal(t, int64(7), atomicMatch.BuyOffer.NftIndex)
+	assert.Equal(t, int64(1001), atomicMatch.SellOffer.AssetAmount)
+	assert.Equal(t, int64(30), atomicMatch.TreasuryAmount)
+
+	withdrawNft := decode(txs[11]).WithdrawNftTxInfo
+	assert.Equal(t, common.HexToAddress("0x11").Hex(), withdrawNft.ToAddress)
+	assert.Equal(t, big.NewInt(12345), withdrawNft.NftL1TokenId)
+	assert.Equal(t, testNameHash, withdrawNft.CreatorAccountNameHash)
+
+	assert.Equal(t, txs[12].FullExitTxInfo.AssetAmount.Int64(), decode(txs[12]).FullExitTxInfo.AssetAmount.Int64())
+
+	fullExitNft := decode(txs[13]).FullExitNftTxInfo
+	assert.Equal(t, testL1Address, fullExitNft.NftL1Address)
+	assert.Equal(t, testNameHash, fullExitNft.AccountNameHash)
+	assert.Equal(t, int64(9), fullExitNft.CollectionId)
+}
+
+func TestDecodeBlockPubData(t *testing.T) {
+	oTxs := testTxs(t)
+	pubData, offsets, _, err := circuit.ComputeBlockPubData(oTxs)
+	assert.Nil(t, err)
+	txs, err := DecodeBlockPubData(pubData)
+	assert.Nil(t, err)
+	assert.Equal(t, len(oTxs), len(txs))
+	for i := range oTxs {
+		assert.Equal(t, oTxs[i].TxType, txs[i].TxType)
+	}
+	for _, offset := range offsets {
+		assert.True(t, circuit.IsOnChainOp(txs[offset/circuit.PubDataBytesPerTx].TxType))
+	}
+}
+
+func TestDecodeInvalidPubData(t *testing.T) {
+	txs := testTxs(t)
+	words, err := circuit.ComputePubData(txs[4])
+	assert.Nil(t, err)
+	pubData := encodeWords(words)
+
+	_, err = DecodeTxPubData(pubData[1:])
+	assert.Equal(t, ErrInvalidPubDataLength, err)
+	_, err = DecodeBlockPubData(append(pubData, 0))
+	assert.Equal(t, ErrInvalidPubDataLength, err)
+
+	invalid := common.CopyBytes(pubData)
+	invalid[0] = 0x0f
+	_, err = DecodeTxPubData(invalid)
+	assert.Equal(t, ErrInvalidTxType, err)
+
+	// the last byte of the first word is padding
+	invalid = common.CopyBytes(pubData)
+	invalid[circuit.PubDataWordBytes-1] = 1
+	_, err = DecodeTxPubData(invalid)
+	assert.Equal(t, ErrNonZeroPadding, err)
+
+	// transfer only uses two words
+	invalid = common.CopyBytes(pubData)
+	invalid[len(invalid)-1] = 1
+	_, err = DecodeTxPubData(invalid)
+	assert.Equal(t, ErrNonZeroPadding, err)
+
+	invalid = common.CopyBytes(pubData)
+	fr.Modulus().FillBytes(invalid[circuit.PubDataWordBytes : 2*circuit.PubDataWordBytes])
+	_, err = DecodeTxPubData(invalid)
+	assert.Equal(t, ErrInvalidPubDataWord, err)
+}
+
+type PubDataConstraints struct {
+	TransferTxInfo    types.TransferTxConstraints
+	AtomicMatchTxInfo types.AtomicMatchTxConstraints
+	WithdrawNftTxInfo types.WithdrawNftTxConstraints
+	FullExitNftTxInfo types.FullExitNftTxConstraints
+	PubData           [4][types.PubDataSizePerTx]circuit.Variable
+}
+
+func (c PubDataConstraints) Define(api circuit.API) error {
+	pubData := [4][types.PubDataSizePerTx]circuit.Variable{
+		types.CollectPubDataFromTransfer(api, c.TransferTxInfo),
+		types.CollectPubDataFromAtomicMatch(api, c.AtomicMatchTxInfo),
+		types.CollectPubDataFromWithdrawNft(api, c.WithdrawNftTxInfo),
+		types.CollectPubDataFromFullExitNft(api, c.FullExitNftTxInfo),
+	}
+	for i := range pubData {
+		for j := range pubData[i] {
+			api.AssertIsEqual(pubData[i][j], c.PubData[i][j])
+		}
+	}
+	return nil
+}
+
+/*
+	TestDecodeCircuitPubData: the words decoded here are the ones produced by the circuit packers
+*/
+func TestDecodeCircuitPubData(t *testing.T) {
+	txs := testTxs(t)
+	var witness PubDataConstraints
+	witness.TransferTxInfo = types.SetTransferTxWitness(txs[4].TransferTxInfo)
+	witness.AtomicMatchTxInfo = types.SetAtomicMatchTxWitness(txs[9].AtomicMatchTxInfo)
+	witness.WithdrawNftTxInfo = types.SetWithdrawNftTxWitness(txs[11].WithdrawNftTxInfo)
+	witness.FullExitNftTxInfo = types.SetFullExitNftTxWitness(txs[13].FullExitNftTxInfo)
+	for i, oTx := range []*circuit.Tx{txs[4], txs[9], txs[11], txs[13]} {
+		words, err := circuit.ComputePubData(oTx)
+		assert.Nil(t, err)
+		decoded, err := DecodeTxPubData(encodeWords(words))
+		assert.Nil(t, err)
+		nWords, err := circuit.ComputePubData(decoded.CircuitTx())
+		assert.Nil(t, err)
+		for j := range nWords {
+			witness.PubData[i][j] = nWords[j]
+		}
+	}
+	var c PubDataConstraints
+	err := test.IsSolved(&c, &witness, ecc.BN254, backend.GROTH16)
+	assert.Nil(t, err)
+}
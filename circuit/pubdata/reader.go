@@ -0,0 +1,90 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pubdata
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+/*
+	pubDataReader: reads fields from the most significant bits to the least significant bits,
+	the inverse of the packers in circuit/types
+*/
+type pubDataReader struct {
+	word   *big.Int
+	offset int
+	err    error
+}
+
+/*
+	newPubDataReader: bitsSize is the total size of the fields packed into the word
+*/
+func newPubDataReader(word *big.Int, bitsSize int) *pubDataReader {
+	r := &pubDataReader{word: word, offset: bitsSize}
+	if word.BitLen() > bitsSize {
+		r.err = ErrInvalidPubDataWord
+	}
+	return r
+}
+
+/*
+	newTxPubDataReader: reader of the first word of a tx, positioned after the tx type
+*/
+func newTxPubDataReader(word *big.Int) *pubDataReader {
+	r := newPubDataReader(word, wordBitsSize)
+	r.readBigInt(types.TxTypeBitsSize)
+	return r
+}
+
+func (r *pubDataReader) readBigInt(size int) *big.Int {
+	if r.err != nil {
+		return new(big.Int)
+	}
+	if size > r.offset {
+		r.err = ErrInvalidPubDataLength
+		return new(big.Int)
+	}
+	r.offset -= size
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(size)), big.NewInt(1))
+	return mask.And(mask, new(big.Int).Rsh(r.word, uint(r.offset)))
+}
+
+func (r *pubDataReader) readInt64(size int) int64 {
+	return r.readBigInt(size).Int64()
+}
+
+func (r *pubDataReader) readAddress(size int) string {
+	return common.BigToAddress(r.readBigInt(size)).Hex()
+}
+
+func (r *pubDataReader) padding(size int) {
+	if r.readBigInt(size).Sign() != 0 && r.err == nil {
+		r.err = ErrNonZeroPadding
+	}
+}
+
+func (r *pubDataReader) finish() error {
+	if r.err == nil && r.offset != 0 {
+		r.err = ErrInvalidPubDataLength
+	}
+	return r.err
+}
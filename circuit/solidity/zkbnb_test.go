@@ -28,6 +28,7 @@ import (
 	"github.com/consensys/gnark/frontend/cs/r1cs"
 
 	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/prover"
 )
 
 func TestCompileCircuit(t *testing.T) {
@@ -117,3 +118,74 @@ func exportSol(differentBlockSizes []int) {
 		}
 	}
 }
+
+func TestExportSolPlonk(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the plonk setup of the block circuit needs too much memory for -short")
+	}
+	differentBlockSizes := []int{1}
+	gasAssetIds := []int64{0, 1}
+	gasAccountIndex := int64(1)
+	for i := 0; i < len(differentBlockSizes); i++ {
+		oScs, err := prover.CompileBlockPlonk(circuit.DefaultConfig(), differentBlockSizes[i], gasAssetIds, gasAccountIndex)
+		if err != nil {
+			panic(err)
+		}
+		srs, err := prover.NewTestSRS(oScs)
+		if err != nil {
+			panic(err)
+		}
+		pk, vk, err := prover.SetupPlonk(oScs, srs)
+		if err != nil {
+			panic(err)
+		}
+		header, err := prover.NewKeyHeader(prover.BackendPlonk, oScs, circuit.DefaultConfig(), differentBlockSizes[i], gasAssetIds, gasAccountIndex)
+		if err != nil {
+			panic(err)
+		}
+		{
+			f, err := os.Create("zkbnb" + fmt.Sprint(differentBlockSizes[i]) + ".srs_plonk")
+			if err != nil {
+				panic(err)
+			}
+			defer f.Close()
+			_, err = srs.WriteTo(f)
+			if err != nil {
+				panic(err)
+			}
+		}
+		{
+			f, err := os.Create("zkbnb" + fmt.Sprint(differentBlockSizes[i]) + ".vk_plonk")
+			if err != nil {
+				panic(err)
+			}
+			defer f.Close()
+			err = prover.WriteKey(f, header, vk)
+			if err != nil {
+				panic(err)
+			}
+		}
+		{
+			f, err := os.Create("zkbnb" + fmt.Sprint(differentBlockSizes[i]) + ".pk_plonk")
+			if err != nil {
+				panic(err)
+			}
+			defer f.Close()
+			err = prover.WriteKey(f, header, pk)
+			if err != nil {
+				panic(err)
+			}
+		}
+		{
+			f, err := os.Create("ZkBNBPlonkVerifier" + fmt.Sprint(differentBlockSizes[i]) + ".sol")
+			if err != nil {
+				panic(err)
+			}
+			defer f.Close()
+			err = prover.ExportPlonkSolidity(f, vk)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+}
@@ -54,12 +54,12 @@ type Tx struct {
 	NftBefore *types.Nft
 	// state root before
 	StateRootBefore []byte
-	// before account asset merkle proof
-	MerkleProofsAccountAssetsBefore [NbAccountsPerTx][NbAccountAssetsPerAccount][AssetMerkleLevels][]byte
-	// before account merkle proof
-	MerkleProofsAccountBefore [NbAccountsPerTx][AccountMerkleLevels][]byte
-	// before nft tree merkle proof
-	MerkleProofsNftBefore [NftMerkleLevels][]byte
+	// before account asset merkle proof, Config.AssetMerkleLevels long
+	MerkleProofsAccountAssetsBefore [NbAccountsPerTx][NbAccountAssetsPerAccount][][]byte
+	// before account merkle proof, Config.AccountMerkleLevels long
+	MerkleProofsAccountBefore [NbAccountsPerTx][][]byte
+	// before nft tree merkle proof, Config.NftMerkleLevels long
+	MerkleProofsNftBefore [][]byte
 	// state root after
 	StateRootAfter []byte
 }
@@ -60,11 +60,11 @@ type TxConstraints struct {
 	// state root before
 	StateRootBefore Variable
 	// before account asset merkle proof
-	MerkleProofsAccountAssetsBefore [NbAccountsPerTx][NbAccountAssetsPerAccount][AssetMerkleLevels]Variable
+	MerkleProofsAccountAssetsBefore [NbAccountsPerTx][NbAccountAssetsPerAccount][]Variable
 	// before nft tree merkle proof
-	MerkleProofsNftBefore [NftMerkleLevels]Variable
+	MerkleProofsNftBefore []Variable
 	// before account merkle proof
-	MerkleProofsAccountBefore [NbAccountsPerTx][AccountMerkleLevels]Variable
+	MerkleProofsAccountBefore [NbAccountsPerTx][]Variable
 	// state root after
 	StateRootAfter Variable
 }
@@ -76,7 +76,7 @@ func (circuit TxConstraints) Define(api API) error {
 		return err
 	}
 
-	_, _, _, _, err = VerifyTransaction(api, circuit, hFunc, 1633400952228, []int64{0}, [types.NbRoots]Variable{Variable(0), Variable(0)})
+	_, _, _, _, err = VerifyTransaction(api, DefaultConfig(), circuit, hFunc, 1633400952228, types.DefaultChainId, []int64{0}, [types.NbRoots]Variable{Variable(0), Variable(0)})
 	if err != nil {
 		return err
 	}
@@ -85,13 +85,16 @@ func (circuit TxConstraints) Define(api API) error {
 
 func VerifyTransaction(
 	api API,
+	config Config,
 	tx TxConstraints,
 	hFunc MiMC,
 	blockCreatedAt Variable,
+	chainId Variable,
 	gasAssetIds []int64,
 	oldRoots [types.NbRoots]Variable,
 ) (isOnChainOp Variable, pubData [types.PubDataSizePerTx]Variable, roots [types.NbRoots]Variable,
 	gasDeltas [NbGasAssetsPerTx]GasDeltaConstraints, err error) {
+	defer types.Profile(api, "tx")()
 	// compute tx type
 	isEmptyTx := api.IsZero(api.Sub(tx.TxType, types.TxTypeEmptyTx))
 	isRegisterZnsTx := api.IsZero(api.Sub(tx.TxType, types.TxTypeRegisterZns))
@@ -132,28 +135,44 @@ func VerifyTransaction(
 
 	// get hash value from tx based on tx type
 	// transfer tx
-	hashVal := types.ComputeHashFromTransferTx(api, tx.TransferTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+	endProfile := types.Profile(api, "tx/transfer/hash")
+	hashVal := types.ComputeHashFromTransferTx(api, tx.TransferTxInfo, chainId, tx.Nonce, tx.ExpiredAt, hFunc)
+	endProfile()
 	// withdraw tx
-	hashValCheck := types.ComputeHashFromWithdrawTx(api, tx.WithdrawTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+	endProfile = types.Profile(api, "tx/withdraw/hash")
+	hashValCheck := types.ComputeHashFromWithdrawTx(api, tx.WithdrawTxInfo, chainId, tx.Nonce, tx.ExpiredAt, hFunc)
 	hashVal = api.Select(isWithdrawTx, hashValCheck, hashVal)
+	endProfile()
 	// createCollection tx
-	hashValCheck = types.ComputeHashFromCreateCollectionTx(api, tx.CreateCollectionTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+	endProfile = types.Profile(api, "tx/create_collection/hash")
+	hashValCheck = types.ComputeHashFromCreateCollectionTx(api, tx.CreateCollectionTxInfo, chainId, tx.Nonce, tx.ExpiredAt, hFunc)
 	hashVal = api.Select(isCreateCollectionTx, hashValCheck, hashVal)
+	endProfile()
 	// mint nft tx
-	hashValCheck = types.ComputeHashFromMintNftTx(api, tx.MintNftTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+	endProfile = types.Profile(api, "tx/mint_nft/hash")
+	hashValCheck = types.ComputeHashFromMintNftTx(api, tx.MintNftTxInfo, chainId, tx.Nonce, tx.ExpiredAt, hFunc)
 	hashVal = api.Select(isMintNftTx, hashValCheck, hashVal)
+	endProfile()
 	// transfer nft tx
-	hashValCheck = types.ComputeHashFromTransferNftTx(api, tx.TransferNftTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+	endProfile = types.Profile(api, "tx/transfer_nft/hash")
+	hashValCheck = types.ComputeHashFromTransferNftTx(api, tx.TransferNftTxInfo, chainId, tx.Nonce, tx.ExpiredAt, hFunc)
 	hashVal = api.Select(isTransferNftTx, hashValCheck, hashVal)
+	endProfile()
 	// set nft price tx
-	hashValCheck = types.ComputeHashFromAtomicMatchTx(api, tx.AtomicMatchTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+	endProfile = types.Profile(api, "tx/atomic_match/hash")
+	hashValCheck = types.ComputeHashFromAtomicMatchTx(api, tx.AtomicMatchTxInfo, chainId, tx.Nonce, tx.ExpiredAt, hFunc)
 	hashVal = api.Select(isAtomicMatchTx, hashValCheck, hashVal)
+	endProfile()
 	// buy nft tx
-	hashValCheck = types.ComputeHashFromCancelOfferTx(api, tx.CancelOfferTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+	endProfile = types.Profile(api, "tx/cancel_offer/hash")
+	hashValCheck = types.ComputeHashFromCancelOfferTx(api, tx.CancelOfferTxInfo, chainId, tx.Nonce, tx.ExpiredAt, hFunc)
 	hashVal = api.Select(isCancelOfferTx, hashValCheck, hashVal)
+	endProfile()
 	// withdraw nft tx
-	hashValCheck = types.ComputeHashFromWithdrawNftTx(api, tx.WithdrawNftTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+	endProfile = types.Profile(api, "tx/withdraw_nft/hash")
+	hashValCheck = types.ComputeHashFromWithdrawNftTx(api, tx.WithdrawNftTxInfo, chainId, tx.Nonce, tx.ExpiredAt, hFunc)
 	hashVal = api.Select(isWithdrawNftTx, hashValCheck, hashVal)
+	endProfile()
 	hFunc.Reset()
 
 	types.IsVariableEqual(api, isLayer2Tx, tx.AccountsInfoBefore[0].Nonce, tx.Nonce)
@@ -175,39 +194,65 @@ func VerifyTransaction(
 	for i := 0; i < types.PubDataSizePerTx; i++ {
 		pubData[i] = 0
 	}
-	pubDataCheck := types.VerifyRegisterZNSTx(api, isRegisterZnsTx, tx.RegisterZnsTxInfo, tx.AccountsInfoBefore)
+	endProfile = types.Profile(api, "tx/register_zns/verify")
+	pubDataCheck := types.VerifyRegisterZNSTx(api, isRegisterZnsTx, tx.RegisterZnsTxInfo, tx.AccountsInfoBefore, config.EmptyAssetRoot())
 	pubData = SelectPubData(api, isRegisterZnsTx, pubDataCheck, pubData)
+	endProfile()
+	endProfile = types.Profile(api, "tx/deposit/verify")
 	pubDataCheck = types.VerifyDepositTx(api, isDepositTx, tx.DepositTxInfo, tx.AccountsInfoBefore)
 	pubData = SelectPubData(api, isDepositTx, pubDataCheck, pubData)
+	endProfile()
+	endProfile = types.Profile(api, "tx/deposit_nft/verify")
 	pubDataCheck = types.VerifyDepositNftTx(api, isDepositNftTx, tx.DepositNftTxInfo, tx.AccountsInfoBefore, tx.NftBefore)
 	pubData = SelectPubData(api, isDepositNftTx, pubDataCheck, pubData)
+	endProfile()
+	endProfile = types.Profile(api, "tx/transfer/verify")
 	pubDataCheck = types.VerifyTransferTx(api, isTransferTx, &tx.TransferTxInfo, tx.AccountsInfoBefore)
 	pubData = SelectPubData(api, isTransferTx, pubDataCheck, pubData)
+	endProfile()
+	endProfile = types.Profile(api, "tx/create_collection/verify")
 	pubDataCheck = types.VerifyCreateCollectionTx(api, isCreateCollectionTx, &tx.CreateCollectionTxInfo, tx.AccountsInfoBefore)
 	pubData = SelectPubData(api, isCreateCollectionTx, pubDataCheck, pubData)
+	endProfile()
+	endProfile = types.Profile(api, "tx/withdraw/verify")
 	pubDataCheck = types.VerifyWithdrawTx(api, isWithdrawTx, &tx.WithdrawTxInfo, tx.AccountsInfoBefore)
 	pubData = SelectPubData(api, isWithdrawTx, pubDataCheck, pubData)
+	endProfile()
+	endProfile = types.Profile(api, "tx/mint_nft/verify")
 	pubDataCheck = types.VerifyMintNftTx(api, isMintNftTx, &tx.MintNftTxInfo, tx.AccountsInfoBefore, tx.NftBefore)
 	pubData = SelectPubData(api, isMintNftTx, pubDataCheck, pubData)
+	endProfile()
+	endProfile = types.Profile(api, "tx/transfer_nft/verify")
 	pubDataCheck = types.VerifyTransferNftTx(api, isTransferNftTx, &tx.TransferNftTxInfo, tx.AccountsInfoBefore, tx.NftBefore)
 	pubData = SelectPubData(api, isTransferNftTx, pubDataCheck, pubData)
+	endProfile()
 	hFunc.Reset()
+	endProfile = types.Profile(api, "tx/atomic_match/verify")
 	pubDataCheck, err = types.VerifyAtomicMatchTx(
 		api, isAtomicMatchTx, &tx.AtomicMatchTxInfo, tx.AccountsInfoBefore, tx.NftBefore, blockCreatedAt,
-		hFunc,
+		chainId, hFunc,
 	)
 	if err != nil {
 		return nil, pubData, roots, gasDeltas, err
 	}
 	pubData = SelectPubData(api, isAtomicMatchTx, pubDataCheck, pubData)
+	endProfile()
+	endProfile = types.Profile(api, "tx/cancel_offer/verify")
 	pubDataCheck = types.VerifyCancelOfferTx(api, isCancelOfferTx, &tx.CancelOfferTxInfo, tx.AccountsInfoBefore)
 	pubData = SelectPubData(api, isCancelOfferTx, pubDataCheck, pubData)
+	endProfile()
+	endProfile = types.Profile(api, "tx/withdraw_nft/verify")
 	pubDataCheck = types.VerifyWithdrawNftTx(api, isWithdrawNftTx, &tx.WithdrawNftTxInfo, tx.AccountsInfoBefore, tx.NftBefore)
 	pubData = SelectPubData(api, isWithdrawNftTx, pubDataCheck, pubData)
+	endProfile()
+	endProfile = types.Profile(api, "tx/full_exit/verify")
 	pubDataCheck = types.VerifyFullExitTx(api, isFullExitTx, tx.FullExitTxInfo, tx.AccountsInfoBefore)
 	pubData = SelectPubData(api, isFullExitTx, pubDataCheck, pubData)
+	endProfile()
+	endProfile = types.Profile(api, "tx/full_exit_nft/verify")
 	pubDataCheck = types.VerifyFullExitNftTx(api, isFullExitNftTx, tx.FullExitNftTxInfo, tx.AccountsInfoBefore, tx.NftBefore)
 	pubData = SelectPubData(api, isFullExitNftTx, pubDataCheck, pubData)
+	endProfile()
 
 	// verify timestamp
 	types.IsVariableLessOrEqual(api, isLayer2Tx, blockCreatedAt, tx.ExpiredAt)
@@ -239,53 +284,77 @@ func VerifyTransaction(
 	// register
 	accountDelta := GetAccountDeltaFromRegisterZNS(tx.RegisterZnsTxInfo)
 	// deposit
+	endProfile = types.Profile(api, "tx/deposit/deltas")
 	assetDeltasCheck := GetAssetDeltasFromDeposit(tx.DepositTxInfo)
 	assetDeltas = SelectAssetDeltas(api, isDepositTx, assetDeltasCheck, assetDeltas)
+	endProfile()
 	// generic transfer
+	endProfile = types.Profile(api, "tx/transfer/deltas")
 	assetDeltasCheck, gasDeltasCheck := GetAssetDeltasFromTransfer(api, tx.TransferTxInfo)
 	assetDeltas = SelectAssetDeltas(api, isTransferTx, assetDeltasCheck, assetDeltas)
 	gasDeltas = SelectGasDeltas(api, isTransferTx, gasDeltasCheck, gasDeltas)
+	endProfile()
 	// withdraw
+	endProfile = types.Profile(api, "tx/withdraw/deltas")
 	assetDeltasCheck, gasDeltasCheck = GetAssetDeltasFromWithdraw(api, tx.WithdrawTxInfo)
 	assetDeltas = SelectAssetDeltas(api, isWithdrawTx, assetDeltasCheck, assetDeltas)
 	gasDeltas = SelectGasDeltas(api, isWithdrawTx, gasDeltasCheck, gasDeltas)
+	endProfile()
 	// deposit nft
+	endProfile = types.Profile(api, "tx/deposit_nft/deltas")
 	nftDeltaCheck := GetNftDeltaFromDepositNft(tx.DepositNftTxInfo)
 	nftDelta = SelectNftDeltas(api, isDepositNftTx, nftDeltaCheck, nftDelta)
+	endProfile()
 	// create collection
+	endProfile = types.Profile(api, "tx/create_collection/deltas")
 	assetDeltasCheck, gasDeltasCheck = GetAssetDeltasFromCreateCollection(api, tx.CreateCollectionTxInfo)
 	assetDeltas = SelectAssetDeltas(api, isCreateCollectionTx, assetDeltasCheck, assetDeltas)
 	gasDeltas = SelectGasDeltas(api, isCreateCollectionTx, gasDeltasCheck, gasDeltas)
+	endProfile()
 	// mint nft
+	endProfile = types.Profile(api, "tx/mint_nft/deltas")
 	assetDeltasCheck, nftDeltaCheck, gasDeltasCheck = GetAssetDeltasAndNftDeltaFromMintNft(api, tx.MintNftTxInfo)
 	assetDeltas = SelectAssetDeltas(api, isMintNftTx, assetDeltasCheck, assetDeltas)
 	nftDelta = SelectNftDeltas(api, isMintNftTx, nftDeltaCheck, nftDelta)
 	gasDeltas = SelectGasDeltas(api, isMintNftTx, gasDeltasCheck, gasDeltas)
+	endProfile()
 	// transfer nft
+	endProfile = types.Profile(api, "tx/transfer_nft/deltas")
 	assetDeltasCheck, nftDeltaCheck, gasDeltasCheck = GetAssetDeltasAndNftDeltaFromTransferNft(api, tx.TransferNftTxInfo, tx.NftBefore)
 	assetDeltas = SelectAssetDeltas(api, isTransferNftTx, assetDeltasCheck, assetDeltas)
 	nftDelta = SelectNftDeltas(api, isTransferNftTx, nftDeltaCheck, nftDelta)
 	gasDeltas = SelectGasDeltas(api, isTransferNftTx, gasDeltasCheck, gasDeltas)
+	endProfile()
 	// set nft price
+	endProfile = types.Profile(api, "tx/atomic_match/deltas")
 	assetDeltasCheck, nftDeltaCheck, gasDeltasCheck = GetAssetDeltasAndNftDeltaFromAtomicMatch(api, isAtomicMatchTx, tx.AtomicMatchTxInfo, tx.AccountsInfoBefore, tx.NftBefore)
 	assetDeltas = SelectAssetDeltas(api, isAtomicMatchTx, assetDeltasCheck, assetDeltas)
 	nftDelta = SelectNftDeltas(api, isAtomicMatchTx, nftDeltaCheck, nftDelta)
 	gasDeltas = SelectGasDeltas(api, isAtomicMatchTx, gasDeltasCheck, gasDeltas)
+	endProfile()
 	// buy nft
+	endProfile = types.Profile(api, "tx/cancel_offer/deltas")
 	assetDeltasCheck, gasDeltasCheck = GetAssetDeltasFromCancelOffer(api, isCancelOfferTx, tx.CancelOfferTxInfo, tx.AccountsInfoBefore)
 	assetDeltas = SelectAssetDeltas(api, isCancelOfferTx, assetDeltasCheck, assetDeltas)
 	gasDeltas = SelectGasDeltas(api, isCancelOfferTx, gasDeltasCheck, gasDeltas)
+	endProfile()
 	// withdraw nft
+	endProfile = types.Profile(api, "tx/withdraw_nft/deltas")
 	assetDeltasCheck, nftDeltaCheck, gasDeltasCheck = GetAssetDeltasAndNftDeltaFromWithdrawNft(api, tx.WithdrawNftTxInfo)
 	assetDeltas = SelectAssetDeltas(api, isWithdrawNftTx, assetDeltasCheck, assetDeltas)
 	nftDelta = SelectNftDeltas(api, isWithdrawNftTx, nftDeltaCheck, nftDelta)
 	gasDeltas = SelectGasDeltas(api, isWithdrawNftTx, gasDeltasCheck, gasDeltas)
+	endProfile()
 	// full exit
+	endProfile = types.Profile(api, "tx/full_exit/deltas")
 	assetDeltasCheck = GetAssetDeltasFromFullExit(api, tx.FullExitTxInfo)
 	assetDeltas = SelectAssetDeltas(api, isFullExitTx, assetDeltasCheck, assetDeltas)
+	endProfile()
 	// full exit nft
+	endProfile = types.Profile(api, "tx/full_exit_nft/deltas")
 	nftDeltaCheck = GetNftDeltaFromFullExitNft()
 	nftDelta = SelectNftDeltas(api, isFullExitNftTx, nftDeltaCheck, nftDelta)
+	endProfile()
 	// update accounts
 	AccountsInfoAfter := UpdateAccounts(api, tx.AccountsInfoBefore, assetDeltas)
 	AccountsInfoAfter[0].AccountNameHash = api.Select(isRegisterZnsTx, accountDelta.AccountNameHash, AccountsInfoAfter[0].AccountNameHash)
@@ -314,8 +383,9 @@ func VerifyTransaction(
 		)
 		// verify account asset node hash
 		for j := 0; j < NbAccountAssetsPerAccount; j++ {
-			api.AssertIsLessOrEqual(tx.AccountsInfoBefore[i].AssetsInfo[j].AssetId, LastAccountAssetId)
-			assetMerkleHelper := AssetIdToMerkleHelper(api, tx.AccountsInfoBefore[i].AssetsInfo[j].AssetId)
+			endProfile = types.Profile(api, "tx/merkle/asset")
+			api.AssertIsLessOrEqual(tx.AccountsInfoBefore[i].AssetsInfo[j].AssetId, config.LastAccountAssetId())
+			assetMerkleHelper := AssetIdToMerkleHelper(api, config, tx.AccountsInfoBefore[i].AssetsInfo[j].AssetId)
 			hFunc.Reset()
 			hFunc.Write(
 				tx.AccountsInfoBefore[i].AssetsInfo[j].Balance,
@@ -343,10 +413,12 @@ func VerifyTransaction(
 			// update merkle proof
 			NewAccountAssetsRoot = types.UpdateMerkleProof(
 				api, hFunc, assetNodeHash, tx.MerkleProofsAccountAssetsBefore[i][j][:], assetMerkleHelper)
+			endProfile()
 		}
 		// verify account node hash
-		api.AssertIsLessOrEqual(tx.AccountsInfoBefore[i].AccountIndex, LastAccountIndex)
-		accountIndexMerkleHelper := AccountIndexToMerkleHelper(api, tx.AccountsInfoBefore[i].AccountIndex)
+		endProfile = types.Profile(api, "tx/merkle/account")
+		api.AssertIsLessOrEqual(tx.AccountsInfoBefore[i].AccountIndex, config.LastAccountIndex())
+		accountIndexMerkleHelper := AccountIndexToMerkleHelper(api, config, tx.AccountsInfoBefore[i].AccountIndex)
 		hFunc.Reset()
 		hFunc.Write(
 			tx.AccountsInfoBefore[i].AccountNameHash,
@@ -382,12 +454,14 @@ func VerifyTransaction(
 		// update merkle proof
 		newAccountRoot = types.UpdateMerkleProof(api, hFunc, accountNodeHash, tx.MerkleProofsAccountBefore[i][:], accountIndexMerkleHelper)
 		oldRoots[0] = api.Select(isEmptyTx, oldRoots[0], newAccountRoot)
+		endProfile()
 	}
 
 	//// nft tree
+	endProfile = types.Profile(api, "tx/merkle/nft")
 	newNftRoot := tx.NftRootBefore
-	api.AssertIsLessOrEqual(tx.NftBefore.NftIndex, LastNftIndex)
-	nftIndexMerkleHelper := NftIndexToMerkleHelper(api, tx.NftBefore.NftIndex)
+	api.AssertIsLessOrEqual(tx.NftBefore.NftIndex, config.LastNftIndex())
+	nftIndexMerkleHelper := NftIndexToMerkleHelper(api, config, tx.NftBefore.NftIndex)
 	hFunc.Reset()
 	hFunc.Write(
 		tx.NftBefore.CreatorAccountIndex,
@@ -425,6 +499,7 @@ func VerifyTransaction(
 	// update merkle proof
 	newNftRoot = types.UpdateMerkleProof(api, hFunc, nftNodeHash, tx.MerkleProofsNftBefore[:], nftIndexMerkleHelper)
 	oldRoots[1] = api.Select(isEmptyTx, oldRoots[1], newNftRoot)
+	endProfile()
 
 	// check state root
 	hFunc.Reset()
@@ -440,7 +515,17 @@ func VerifyTransaction(
 	return isOnChainOp, pubData, roots, gasDeltas, nil
 }
 
+/*
+	EmptyTx: empty tx with the merkle proofs of the default config
+*/
 func EmptyTx(stateRoot []byte) (oTx *Tx) {
+	return EmptyTxWithConfig(DefaultConfig(), stateRoot)
+}
+
+/*
+	EmptyTxWithConfig: same as EmptyTx for the tree depths of the config
+*/
+func EmptyTxWithConfig(config Config, stateRoot []byte) (oTx *Tx) {
 	oTx = &Tx{
 		TxType:            types.TxTypeEmptyTx,
 		Nonce:             0,
@@ -453,31 +538,46 @@ func EmptyTx(stateRoot []byte) (oTx *Tx) {
 			types.EmptyAccount(0, make([]byte, 32)),
 			types.EmptyAccount(0, make([]byte, 32)),
 		},
-		NftRootBefore:                   make([]byte, 32),
-		NftBefore:                       types.EmptyNft(0),
-		StateRootBefore:                 stateRoot,
-		MerkleProofsAccountAssetsBefore: [NbAccountsPerTx][NbAccountAssetsPerAccount][AssetMerkleLevels][]byte{},
-		MerkleProofsAccountBefore:       [NbAccountsPerTx][AccountMerkleLevels][]byte{},
-		MerkleProofsNftBefore:           [NftMerkleLevels][]byte{},
-		StateRootAfter:                  stateRoot,
+		NftRootBefore:         make([]byte, 32),
+		NftBefore:             types.EmptyNft(0),
+		StateRootBefore:       stateRoot,
+		MerkleProofsNftBefore: make([][]byte, config.NftMerkleLevels),
+		StateRootAfter:        stateRoot,
 	}
 	for i := 0; i < NbAccountsPerTx; i++ {
 		for j := 0; j < NbAccountAssetsPerAccount; j++ {
-			for k := 0; k < AssetMerkleLevels; k++ {
+			oTx.MerkleProofsAccountAssetsBefore[i][j] = make([][]byte, config.AssetMerkleLevels)
+			for k := 0; k < config.AssetMerkleLevels; k++ {
 				oTx.MerkleProofsAccountAssetsBefore[i][j][k] = make([]byte, 32)
 			}
 		}
-		for j := 0; j < AccountMerkleLevels; j++ {
+		oTx.MerkleProofsAccountBefore[i] = make([][]byte, config.AccountMerkleLevels)
+		for j := 0; j < config.AccountMerkleLevels; j++ {
 			oTx.MerkleProofsAccountBefore[i][j] = make([]byte, 32)
 		}
 	}
-	for i := 0; i < NftMerkleLevels; i++ {
+	for i := 0; i < config.NftMerkleLevels; i++ {
 		oTx.MerkleProofsNftBefore[i] = make([]byte, 32)
 	}
 	return oTx
 }
 
+/*
+	SetTxWitness: witness of a tx built with the default config
+*/
 func SetTxWitness(oTx *Tx) (witness TxConstraints, err error) {
+	return SetTxWitnessWithConfig(DefaultConfig(), oTx)
+}
+
+/*
+	SetTxWitnessWithConfig: same as SetTxWitness for the tree depths of the config
+*/
+func SetTxWitnessWithConfig(config Config, oTx *Tx) (witness TxConstraints, err error) {
+	err = config.CheckTx(oTx)
+	if err != nil {
+		log.Println("[SetTxWitness] invalid merkle proofs:", err)
+		return witness, err
+	}
 	witness.TxType = int64(oTx.TxType)
 	witness.RegisterZnsTxInfo = types.EmptyRegisterZnsTxWitness()
 	witness.DepositTxInfo = types.EmptyDepositTxWitness()
@@ -588,17 +688,20 @@ func SetTxWitness(oTx *Tx) (witness TxConstraints, err error) {
 			return witness, err
 		}
 		for j := 0; j < NbAccountAssetsPerAccount; j++ {
-			for k := 0; k < AssetMerkleLevels; k++ {
+			witness.MerkleProofsAccountAssetsBefore[i][j] = make([]Variable, config.AssetMerkleLevels)
+			for k := 0; k < config.AssetMerkleLevels; k++ {
 				// account assets before
 				witness.MerkleProofsAccountAssetsBefore[i][j][k] = oTx.MerkleProofsAccountAssetsBefore[i][j][k]
 			}
 		}
-		for j := 0; j < AccountMerkleLevels; j++ {
+		witness.MerkleProofsAccountBefore[i] = make([]Variable, config.AccountMerkleLevels)
+		for j := 0; j < config.AccountMerkleLevels; j++ {
 			// account before
 			witness.MerkleProofsAccountBefore[i][j] = oTx.MerkleProofsAccountBefore[i][j]
 		}
 	}
-	for i := 0; i < NftMerkleLevels; i++ {
+	witness.MerkleProofsNftBefore = make([]Variable, config.NftMerkleLevels)
+	for i := 0; i < config.NftMerkleLevels; i++ {
 		// nft assets before
 		witness.MerkleProofsNftBefore[i] = oTx.MerkleProofsNftBefore[i]
 	}
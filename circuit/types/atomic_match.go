@@ -52,12 +52,12 @@ func EmptyAtomicMatchTxWitness() (witness AtomicMatchTxConstraints) {
 	}
 }
 
-func ComputeHashFromOfferTx(api API, tx OfferTxConstraints, hFunc MiMC) (hashVal Variable) {
+func ComputeHashFromOfferTx(api API, tx OfferTxConstraints, chainId Variable, hFunc MiMC) (hashVal Variable) {
 	hFunc.Reset()
 	hFunc.Write(
-		PackInt64Variables(api, tx.Type, tx.OfferId, tx.AccountIndex, tx.NftIndex),
-		PackInt64Variables(api, tx.AssetId, tx.AssetAmount, tx.ListedAt, tx.ExpiredAt),
-		tx.TreasuryRate,
+		PackInt64Variables(api, chainId, tx.Type, tx.OfferId, tx.AccountIndex),
+		PackInt64Variables(api, tx.NftIndex, tx.AssetId, tx.AssetAmount, tx.ListedAt),
+		PackInt64Variables(api, tx.ExpiredAt, tx.TreasuryRate),
 	)
 	hashVal = hFunc.Sum()
 	return hashVal
@@ -77,10 +77,10 @@ func SetAtomicMatchTxWitness(tx *AtomicMatchTx) (witness AtomicMatchTxConstraint
 	return witness
 }
 
-func ComputeHashFromAtomicMatchTx(api API, tx AtomicMatchTxConstraints, nonce Variable, expiredAt Variable, hFunc MiMC) (hashVal Variable) {
+func ComputeHashFromAtomicMatchTx(api API, tx AtomicMatchTxConstraints, chainId Variable, nonce Variable, expiredAt Variable, hFunc MiMC) (hashVal Variable) {
 	hFunc.Reset()
 	hFunc.Write(
-		PackInt64Variables(api, ChainId, tx.AccountIndex, nonce, expiredAt),
+		PackInt64Variables(api, chainId, tx.AccountIndex, nonce, expiredAt),
 		PackInt64Variables(api, tx.GasAccountIndex, tx.GasFeeAssetId, tx.GasFeeAssetAmount),
 		PackInt64Variables(api, tx.BuyOffer.Type, tx.BuyOffer.OfferId, tx.BuyOffer.AccountIndex, tx.BuyOffer.NftIndex),
 		PackInt64Variables(api, tx.BuyOffer.AssetId, tx.BuyOffer.AssetAmount, tx.BuyOffer.ListedAt, tx.BuyOffer.ExpiredAt),
@@ -103,6 +103,7 @@ func VerifyAtomicMatchTx(
 	accountsBefore [NbAccountsPerTx]AccountConstraints,
 	nftBefore NftConstraints,
 	blockCreatedAt Variable,
+	chainId Variable,
 	hFunc MiMC,
 ) (pubData [PubDataSizePerTx]Variable, err error) {
 	fromAccount := 0
@@ -127,7 +128,7 @@ func VerifyAtomicMatchTx(
 	IsVariableEqual(api, flag, tx.BuyOffer.TreasuryRate, tx.SellOffer.TreasuryRate)
 	// verify signature
 	hFunc.Reset()
-	buyOfferHash := ComputeHashFromOfferTx(api, tx.BuyOffer, hFunc)
+	buyOfferHash := ComputeHashFromOfferTx(api, tx.BuyOffer, chainId, hFunc)
 	hFunc.Reset()
 	notBuyer := api.IsZero(api.IsZero(api.Sub(tx.AccountIndex, tx.BuyOffer.AccountIndex)))
 	notBuyer = api.And(flag, notBuyer)
@@ -136,7 +137,7 @@ func VerifyAtomicMatchTx(
 		return pubData, err
 	}
 	hFunc.Reset()
-	sellOfferHash := ComputeHashFromOfferTx(api, tx.SellOffer, hFunc)
+	sellOfferHash := ComputeHashFromOfferTx(api, tx.SellOffer, chainId, hFunc)
 	hFunc.Reset()
 	notSeller := api.IsZero(api.IsZero(api.Sub(tx.AccountIndex, tx.SellOffer.AccountIndex)))
 	notSeller = api.And(flag, notSeller)
@@ -157,20 +158,24 @@ func VerifyAtomicMatchTx(
 	buyOfferIdBits := api.ToBinary(tx.BuyOffer.OfferId, 24)
 	buyAssetId := api.FromBinary(buyOfferIdBits[7:]...)
 	buyOfferIndex := api.Sub(tx.BuyOffer.OfferId, api.Mul(buyAssetId, OfferSizePerAsset))
+	endProfile := Profile(api, "gadget/offer_bitmap")
 	buyOfferIndexBits := api.ToBinary(accountsBefore[buyAccount].AssetsInfo[1].OfferCanceledOrFinalized, OfferSizePerAsset)
 	for i := 0; i < OfferSizePerAsset; i++ {
 		isZero := api.IsZero(api.Sub(buyOfferIndex, i))
 		IsVariableEqual(api, isZero, buyOfferIndexBits[i], 0)
 	}
+	endProfile()
 	// verify sell offer id
 	sellOfferIdBits := api.ToBinary(tx.SellOffer.OfferId, 24)
 	sellAssetId := api.FromBinary(sellOfferIdBits[7:]...)
 	sellOfferIndex := api.Sub(tx.SellOffer.OfferId, api.Mul(sellAssetId, OfferSizePerAsset))
+	endProfile = Profile(api, "gadget/offer_bitmap")
 	sellOfferIndexBits := api.ToBinary(accountsBefore[sellAccount].AssetsInfo[1].OfferCanceledOrFinalized, OfferSizePerAsset)
 	for i := 0; i < OfferSizePerAsset; i++ {
 		isZero := api.IsZero(api.Sub(sellOfferIndex, i))
 		IsVariableEqual(api, isZero, sellOfferIndexBits[i], 0)
 	}
+	endProfile()
 	// buyer should have enough balance
 	tx.BuyOffer.AssetAmount = UnpackAmount(api, tx.BuyOffer.AssetAmount)
 	IsVariableLessOrEqual(api, flag, tx.BuyOffer.AssetAmount, accountsBefore[buyAccount].AssetsInfo[0].Balance)
@@ -48,7 +48,7 @@ const (
 
 	OfferSizePerAsset = 128
 
-	ChainId = 1
+	DefaultChainId = 1
 )
 
 const (
@@ -73,5 +73,6 @@ const (
 )
 
 var (
+	// root of an empty asset tree of 16 levels, see circuit.Config.EmptyAssetRoot
 	EmptyAssetRoot, _ = new(big.Int).SetString("1852795521510493758870271888468603317521451107904460550484580901924342463446", 10)
 )
@@ -62,10 +62,10 @@ func SetCreateCollectionTxWitness(tx *CreateCollectionTx) (witness CreateCollect
 	return witness
 }
 
-func ComputeHashFromCreateCollectionTx(api API, tx CreateCollectionTxConstraints, nonce Variable, expiredAt Variable, hFunc MiMC) (hashVal Variable) {
+func ComputeHashFromCreateCollectionTx(api API, tx CreateCollectionTxConstraints, chainId Variable, nonce Variable, expiredAt Variable, hFunc MiMC) (hashVal Variable) {
 	hFunc.Reset()
 	hFunc.Write(
-		PackInt64Variables(api, ChainId, tx.AccountIndex, nonce, expiredAt),
+		PackInt64Variables(api, chainId, tx.AccountIndex, nonce, expiredAt),
 		PackInt64Variables(api, tx.GasAccountIndex, tx.GasFeeAssetId, tx.GasFeeAssetAmount),
 	)
 	hashVal = hFunc.Sum()
@@ -17,6 +17,80 @@
 
 package types
 
+import (
+	"github.com/bnb-chain/zkbnb-crypto/merkleTree"
+)
+
+/*
+	MerkleHasher: in-circuit version of merkleTree.Hasher, the two are changed together
+*/
+type MerkleHasher interface {
+	HashLeaf(inputs ...Variable) Variable
+	HashNode(left, right Variable) Variable
+}
+
+type plainMerkleHasher struct {
+	h MiMC
+}
+
+/*
+	NewMerkleHasher: in-circuit version of merkleTree.NewPlainHasher, it's the hasher of the block circuit
+*/
+func NewMerkleHasher(h MiMC) MerkleHasher {
+	return plainMerkleHasher{h: h}
+}
+
+func (m plainMerkleHasher) HashLeaf(inputs ...Variable) Variable {
+	h := m.h
+	h.Reset()
+	h.Write(inputs...)
+	return h.Sum()
+}
+
+func (m plainMerkleHasher) HashNode(left, right Variable) Variable {
+	return nodeSum(m.h, left, right)
+}
+
+type domainMerkleHasher struct {
+	h MiMC
+}
+
+/*
+	NewDomainMerkleHasher: in-circuit version of merkleTree.NewDomainHasher
+*/
+func NewDomainMerkleHasher(h MiMC) MerkleHasher {
+	return domainMerkleHasher{h: h}
+}
+
+func (m domainMerkleHasher) HashLeaf(inputs ...Variable) Variable {
+	h := m.h
+	h.Reset()
+	h.Write(merkleTree.LeafDomainTag)
+	h.Write(inputs...)
+	return h.Sum()
+}
+
+func (m domainMerkleHasher) HashNode(left, right Variable) Variable {
+	h := m.h
+	h.Reset()
+	h.Write(merkleTree.NodeDomainTag, left, right)
+	return h.Sum()
+}
+
+/*
+	MerkleProofWitness: proof set and helpers of VerifyMerkleProof and UpdateMerkleProof from a merkle proof
+*/
+func MerkleProofWitness(proof *merkleTree.MerkleProof) (proofSet, helper []Variable) {
+	proofSet = make([]Variable, len(proof.Siblings))
+	for i := range proofSet {
+		proofSet[i] = proof.Siblings[i]
+	}
+	for _, h := range proof.Helpers() {
+		helper = append(helper, h)
+	}
+	return proofSet, helper
+}
+
 /*
 VerifyMerkleProof: takes a Merkle root, a proofSet, and a proofIndex and returns
 
@@ -25,29 +99,45 @@ VerifyMerkleProof: takes a Merkle root, a proofSet, and a proofIndex and returns
 	'numLeaves' equals 0.
 */
 func VerifyMerkleProof(api API, isEnabled Variable, h MiMC, merkleRoot Variable, node Variable, proofSet, helper []Variable) {
+	VerifyMerkleProofWithHasher(api, isEnabled, NewMerkleHasher(h), merkleRoot, node, proofSet, helper)
+}
+
+/*
+	VerifyMerkleProofWithHasher: same as VerifyMerkleProof with the nodes hashed by the hasher
+*/
+func VerifyMerkleProofWithHasher(api API, isEnabled Variable, h MerkleHasher, merkleRoot Variable, node Variable, proofSet, helper []Variable) {
+	defer Profile(api, "gadget/merkle_verify")()
 	for i := 0; i < len(proofSet); i++ {
 		api.AssertIsBoolean(helper[i])
 		d1 := api.Select(helper[i], proofSet[i], node)
 		d2 := api.Select(helper[i], node, proofSet[i])
-		node = nodeSum(h, d1, d2)
+		node = h.HashNode(d1, d2)
 	}
 	// Compare our calculated Merkle root to the desired Merkle root.
 	IsVariableEqual(api, isEnabled, merkleRoot, node)
 }
 
 func UpdateMerkleProof(api API, h MiMC, node Variable, proofSet, helper []Variable) (root Variable) {
+	return UpdateMerkleProofWithHasher(api, NewMerkleHasher(h), node, proofSet, helper)
+}
+
+/*
+	UpdateMerkleProofWithHasher: same as UpdateMerkleProof with the nodes hashed by the hasher
+*/
+func UpdateMerkleProofWithHasher(api API, h MerkleHasher, node Variable, proofSet, helper []Variable) (root Variable) {
+	defer Profile(api, "gadget/merkle_update")()
 	for i := 0; i < len(proofSet); i++ {
 		api.AssertIsBoolean(helper[i])
 		d1 := api.Select(helper[i], proofSet[i], node)
 		d2 := api.Select(helper[i], node, proofSet[i])
-		node = nodeSum(h, d1, d2)
+		node = h.HashNode(d1, d2)
 	}
 	root = node
 	return root
 }
 
 // nodeSum returns the hash created from data inserted to form a leaf.
-// Without domain separation.
+// Without domain separation, see NewDomainMerkleHasher.
 func nodeSum(h MiMC, a, b Variable) Variable {
 	h.Write(a)
 	h.Write(b)
@@ -0,0 +1,110 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package types
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	cmimc "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/merkleTree"
+)
+
+const testMerkleLevels = 4
+
+type MerkleHasherConstraints struct {
+	Domain     bool `gnark:"-"`
+	LeafInputs [2]Variable
+	Root       Variable
+	NewLeaf    Variable
+	NewRoot    Variable
+	ProofSet   [testMerkleLevels]Variable
+	Helper     [testMerkleLevels]Variable
+}
+
+func (circuit MerkleHasherConstraints) Define(api API) error {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	hasher := NewMerkleHasher(h)
+	if circuit.Domain {
+		hasher = NewDomainMerkleHasher(h)
+	}
+	leaf := hasher.HashLeaf(circuit.LeafInputs[:]...)
+	VerifyMerkleProofWithHasher(api, 1, hasher, circuit.Root, leaf, circuit.ProofSet[:], circuit.Helper[:])
+	newRoot := UpdateMerkleProofWithHasher(api, hasher, circuit.NewLeaf, circuit.ProofSet[:], circuit.Helper[:])
+	api.AssertIsEqual(newRoot, circuit.NewRoot)
+	return nil
+}
+
+/*
+	merkleHasherWitness: witness of the leaf 5 of a tree built with the hasher, the roots are tampered if invalid
+*/
+func merkleHasherWitness(t *testing.T, domain bool, hasher merkleTree.Hasher) MerkleHasherConstraints {
+	hFunc := cmimc.NewMiMC()
+	values := make([][]byte, 4)
+	for i := range values {
+		hFunc.Reset()
+		hFunc.Write([]byte{byte(i)})
+		values[i] = hFunc.Sum(nil)
+	}
+	tree, err := merkleTree.NewEmptyTreeWithHasher(testMerkleLevels, merkleTree.NilHash, hasher)
+	assert.Nil(t, err)
+	assert.Nil(t, tree.Update(2, values[0]))
+	assert.Nil(t, tree.Update(5, hasher.HashLeaf(values[1], values[2])))
+	witness := MerkleHasherConstraints{
+		Domain:     domain,
+		LeafInputs: [2]Variable{values[1], values[2]},
+		Root:       tree.Root(),
+		NewLeaf:    values[3],
+	}
+	proof, err := tree.Proof(0, 5)
+	assert.Nil(t, err)
+	proofSet, helper := MerkleProofWitness(proof)
+	copy(witness.ProofSet[:], proofSet)
+	copy(witness.Helper[:], helper)
+	assert.Nil(t, tree.Update(5, values[3]))
+	witness.NewRoot = tree.Root()
+	return witness
+}
+
+func TestMerkleHashers(t *testing.T) {
+	for _, domain := range []bool{false, true} {
+		hasher := merkleTree.NewMiMCHasher()
+		if domain {
+			hasher = merkleTree.NewMiMCDomainHasher()
+		}
+		circuit := MerkleHasherConstraints{Domain: domain}
+		witness := merkleHasherWitness(t, domain, hasher)
+		assert.Nil(t, test.IsSolved(&circuit, &witness, ecc.BN254, backend.GROTH16), "domain: %v", domain)
+
+		// the proofs of the other hasher
+		other := merkleTree.NewMiMCDomainHasher()
+		if domain {
+			other = merkleTree.NewMiMCHasher()
+		}
+		witness = merkleHasherWitness(t, domain, other)
+		assert.NotNil(t, test.IsSolved(&circuit, &witness, ecc.BN254, backend.GROTH16), "domain: %v", domain)
+	}
+}
@@ -22,6 +22,7 @@ import (
 )
 
 func UnpackAmount(api API, packedAmount Variable) Variable {
+	defer Profile(api, "gadget/unpack_amount")()
 	amountBits := api.ToBinary(packedAmount, 40)
 	mantissa := api.FromBinary(amountBits[5:]...)
 	exponent := api.FromBinary(amountBits[:5]...)
@@ -34,6 +35,7 @@ func UnpackAmount(api API, packedAmount Variable) Variable {
 }
 
 func UnpackFee(api API, packedFee Variable) Variable {
+	defer Profile(api, "gadget/unpack_fee")()
 	amountBits := api.ToBinary(packedFee, 16)
 	mantissa := api.FromBinary(amountBits[5:]...)
 	exponent := api.FromBinary(amountBits[:5]...)
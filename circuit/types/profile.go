@@ -0,0 +1,43 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package types
+
+import (
+	"github.com/consensys/gnark/frontend"
+)
+
+/*
+	ProfiledAPI: constraints are only counted when the circuit is compiled with this api,
+	the counters are serialized with the constraint system and would change its hash
+*/
+type ProfiledAPI struct {
+	frontend.API
+}
+
+/*
+	Profile: counts the constraints added under name until the returned function is called
+*/
+func Profile(api API, name string) (end func()) {
+	if _, ok := api.(ProfiledAPI); !ok {
+		return func() {}
+	}
+	from := api.Compiler().Tag(name)
+	return func() {
+		api.Compiler().AddCounter(from, api.Compiler().Tag(name))
+	}
+}
@@ -0,0 +1,394 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+/*
+	The functions in this file are the out-of-circuit counterparts of the
+	CollectPubDataFrom* functions in pubdata_helper.go. Every pub data word is
+	returned as a field element, exactly as the circuit sees it.
+*/
+
+var ErrPubDataOverflow = errors.New("[PubData] value does not fit into its bit size")
+
+/*
+	pubDataPacker: appends fields from the most significant bits to the least significant bits
+*/
+type pubDataPacker struct {
+	word *big.Int
+	err  error
+}
+
+func newPubDataPacker() *pubDataPacker {
+	return &pubDataPacker{word: new(big.Int)}
+}
+
+func (p *pubDataPacker) writeBigInt(value *big.Int, size int) {
+	if p.err != nil {
+		return
+	}
+	if value == nil {
+		value = new(big.Int)
+	}
+	if value.Sign() < 0 || value.BitLen() > size {
+		p.err = fmt.Errorf("%w: %s does not fit into %d bits", ErrPubDataOverflow, value.String(), size)
+		return
+	}
+	p.word.Lsh(p.word, uint(size))
+	p.word.Or(p.word, value)
+}
+
+func (p *pubDataPacker) writeInt64(value int64, size int) {
+	p.writeBigInt(big.NewInt(value), size)
+}
+
+func (p *pubDataPacker) writeString(value string, size int) {
+	if p.err != nil {
+		return
+	}
+	v, err := StringToFieldElement(value)
+	if err != nil {
+		p.err = err
+		return
+	}
+	p.writeBigInt(v, size)
+}
+
+func (p *pubDataPacker) padding(size int) {
+	p.writeBigInt(new(big.Int), size)
+}
+
+func (p *pubDataPacker) sum() (*big.Int, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return new(big.Int).Mod(p.word, fr.Modulus()), nil
+}
+
+/*
+	BytesToFieldElement: interpret bytes as a big endian integer and reduce it into the scalar field,
+	the same way the circuit witness does
+*/
+func BytesToFieldElement(b []byte) *big.Int {
+	return new(big.Int).Mod(new(big.Int).SetBytes(b), fr.Modulus())
+}
+
+/*
+	BigIntToFieldElement: reduce a big int into the scalar field, nil is treated as zero
+*/
+func BigIntToFieldElement(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Mod(v, fr.Modulus())
+}
+
+/*
+	StringToFieldElement: parse a string (decimal or 0x prefixed hex) and reduce it into the scalar field
+*/
+func StringToFieldElement(s string) (*big.Int, error) {
+	v, isValid := new(big.Int).SetString(s, 0)
+	if !isValid {
+		log.Println("[StringToFieldElement] invalid string:", s)
+		return nil, fmt.Errorf("[StringToFieldElement] invalid string: %s", s)
+	}
+	return v.Mod(v, fr.Modulus()), nil
+}
+
+func emptyPubData() (pubData [PubDataSizePerTx]*big.Int) {
+	for i := 0; i < PubDataSizePerTx; i++ {
+		pubData[i] = new(big.Int)
+	}
+	return pubData
+}
+
+func ComputePubDataFromRegisterZNS(tx *RegisterZnsTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	packer := newPubDataPacker()
+	packer.writeInt64(TxTypeRegisterZns, TxTypeBitsSize)
+	packer.writeInt64(tx.AccountIndex, AccountIndexBitsSize)
+	packer.padding(216)
+	if pubData[0], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	pubData[1] = BytesToFieldElement(tx.AccountName)
+	pubData[2] = BytesToFieldElement(tx.AccountNameHash)
+	if tx.PubKey != nil {
+		pubData[3] = tx.PubKey.A.X.ToBigIntRegular(new(big.Int))
+		pubData[4] = tx.PubKey.A.Y.ToBigIntRegular(new(big.Int))
+	}
+	return pubData, nil
+}
+
+func ComputePubDataFromDeposit(tx *DepositTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	packer := newPubDataPacker()
+	packer.writeInt64(TxTypeDeposit, TxTypeBitsSize)
+	packer.writeInt64(tx.AccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.AssetId, AssetIdBitsSize)
+	packer.writeBigInt(tx.AssetAmount, StateAmountBitsSize)
+	packer.padding(72)
+	if pubData[0], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	pubData[1] = BytesToFieldElement(tx.AccountNameHash)
+	return pubData, nil
+}
+
+func ComputePubDataFromDepositNft(tx *DepositNftTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	packer := newPubDataPacker()
+	packer.writeInt64(TxTypeDepositNft, TxTypeBitsSize)
+	packer.writeInt64(tx.AccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.NftIndex, NftIndexBitsSize)
+	packer.writeString(tx.NftL1Address, AddressBitsSize)
+	packer.padding(16)
+	if pubData[0], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	packer = newPubDataPacker()
+	packer.writeInt64(tx.CreatorAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.CreatorTreasuryRate, CreatorTreasuryRateBitsSize)
+	packer.writeInt64(tx.CollectionId, CollectionIdBitsSize)
+	if pubData[1], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	pubData[2] = BytesToFieldElement(tx.NftContentHash)
+	pubData[3] = BigIntToFieldElement(tx.NftL1TokenId)
+	pubData[4] = BytesToFieldElement(tx.AccountNameHash)
+	return pubData, nil
+}
+
+func ComputePubDataFromTransfer(tx *TransferTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	packer := newPubDataPacker()
+	packer.writeInt64(TxTypeTransfer, TxTypeBitsSize)
+	packer.writeInt64(tx.FromAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.ToAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.AssetId, AssetIdBitsSize)
+	packer.writeInt64(tx.AssetAmount, PackedAmountBitsSize)
+	packer.writeInt64(tx.GasAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.GasFeeAssetId, AssetIdBitsSize)
+	packer.writeInt64(tx.GasFeeAssetAmount, PackedFeeBitsSize)
+	packer.padding(64)
+	if pubData[0], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	pubData[1] = BytesToFieldElement(tx.CallDataHash)
+	return pubData, nil
+}
+
+func ComputePubDataFromWithdraw(tx *WithdrawTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	packer := newPubDataPacker()
+	packer.writeInt64(TxTypeWithdraw, TxTypeBitsSize)
+	packer.writeInt64(tx.FromAccountIndex, AccountIndexBitsSize)
+	packer.writeBigInt(BigIntToFieldElement(tx.ToAddress), AddressBitsSize)
+	packer.writeInt64(tx.AssetId, AssetIdBitsSize)
+	packer.padding(40)
+	if pubData[0], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	packer = newPubDataPacker()
+	packer.writeBigInt(tx.AssetAmount, StateAmountBitsSize)
+	packer.writeInt64(tx.GasAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.GasFeeAssetId, AssetIdBitsSize)
+	packer.writeInt64(tx.GasFeeAssetAmount, PackedFeeBitsSize)
+	if pubData[1], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	return pubData, nil
+}
+
+func ComputePubDataFromCreateCollection(tx *CreateCollectionTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	packer := newPubDataPacker()
+	packer.writeInt64(TxTypeCreateCollection, TxTypeBitsSize)
+	packer.writeInt64(tx.AccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.CollectionId, CollectionIdBitsSize)
+	packer.writeInt64(tx.GasAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.GasFeeAssetId, AssetIdBitsSize)
+	packer.writeInt64(tx.GasFeeAssetAmount, PackedFeeBitsSize)
+	packer.padding(136)
+	if pubData[0], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	return pubData, nil
+}
+
+func ComputePubDataFromMintNft(tx *MintNftTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	packer := newPubDataPacker()
+	packer.writeInt64(TxTypeMintNft, TxTypeBitsSize)
+	packer.writeInt64(tx.CreatorAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.ToAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.NftIndex, NftIndexBitsSize)
+	packer.writeInt64(tx.GasAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.GasFeeAssetId, AssetIdBitsSize)
+	packer.writeInt64(tx.GasFeeAssetAmount, PackedFeeBitsSize)
+	packer.writeInt64(tx.CreatorTreasuryRate, CreatorTreasuryRateBitsSize)
+	packer.writeInt64(tx.CollectionId, CollectionIdBitsSize)
+	packer.padding(48)
+	if pubData[0], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	pubData[1] = BytesToFieldElement(tx.NftContentHash)
+	return pubData, nil
+}
+
+func ComputePubDataFromTransferNft(tx *TransferNftTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	packer := newPubDataPacker()
+	packer.writeInt64(TxTypeTransferNft, TxTypeBitsSize)
+	packer.writeInt64(tx.FromAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.ToAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.NftIndex, NftIndexBitsSize)
+	packer.writeInt64(tx.GasAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.GasFeeAssetId, AssetIdBitsSize)
+	packer.writeInt64(tx.GasFeeAssetAmount, PackedFeeBitsSize)
+	packer.padding(80)
+	if pubData[0], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	pubData[1] = BytesToFieldElement(tx.CallDataHash)
+	return pubData, nil
+}
+
+func ComputePubDataFromAtomicMatch(tx *AtomicMatchTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	if tx.BuyOffer == nil || tx.SellOffer == nil {
+		log.Println("[ComputePubDataFromAtomicMatch] invalid offers")
+		return pubData, errors.New("[ComputePubDataFromAtomicMatch] invalid offers")
+	}
+	packer := newPubDataPacker()
+	packer.writeInt64(TxTypeAtomicMatch, TxTypeBitsSize)
+	packer.writeInt64(tx.AccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.BuyOffer.AccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.BuyOffer.OfferId, OfferIdBitsSize)
+	packer.writeInt64(tx.SellOffer.AccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.SellOffer.OfferId, OfferIdBitsSize)
+	packer.writeInt64(tx.BuyOffer.NftIndex, NftIndexBitsSize)
+	packer.writeInt64(tx.SellOffer.AssetId, AssetIdBitsSize)
+	packer.padding(48)
+	if pubData[0], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	packer = newPubDataPacker()
+	packer.writeInt64(tx.SellOffer.AssetAmount, PackedAmountBitsSize)
+	packer.writeInt64(tx.CreatorAmount, PackedAmountBitsSize)
+	packer.writeInt64(tx.TreasuryAmount, PackedAmountBitsSize)
+	packer.writeInt64(tx.GasAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.GasFeeAssetId, AssetIdBitsSize)
+	packer.writeInt64(tx.GasFeeAssetAmount, PackedFeeBitsSize)
+	if pubData[1], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	return pubData, nil
+}
+
+func ComputePubDataFromCancelOffer(tx *CancelOfferTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	packer := newPubDataPacker()
+	packer.writeInt64(TxTypeCancelOffer, TxTypeBitsSize)
+	packer.writeInt64(tx.AccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.OfferId, OfferIdBitsSize)
+	packer.writeInt64(tx.GasAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.GasFeeAssetId, AssetIdBitsSize)
+	packer.writeInt64(tx.GasFeeAssetAmount, PackedFeeBitsSize)
+	packer.padding(128)
+	if pubData[0], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	return pubData, nil
+}
+
+func ComputePubDataFromWithdrawNft(tx *WithdrawNftTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	packer := newPubDataPacker()
+	packer.writeInt64(TxTypeWithdrawNft, TxTypeBitsSize)
+	packer.writeInt64(tx.AccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.CreatorAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.CreatorTreasuryRate, FeeRateBitsSize)
+	packer.writeInt64(tx.NftIndex, NftIndexBitsSize)
+	packer.writeInt64(tx.CollectionId, CollectionIdBitsSize)
+	packer.padding(112)
+	if pubData[0], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	if pubData[1], err = StringToFieldElement(tx.NftL1Address); err != nil {
+		return pubData, err
+	}
+	packer = newPubDataPacker()
+	packer.writeString(tx.ToAddress, AddressBitsSize)
+	packer.writeInt64(tx.GasAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.GasFeeAssetId, AssetIdBitsSize)
+	packer.writeInt64(tx.GasFeeAssetAmount, PackedFeeBitsSize)
+	if pubData[2], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	pubData[3] = BytesToFieldElement(tx.NftContentHash)
+	pubData[4] = BigIntToFieldElement(tx.NftL1TokenId)
+	pubData[5] = BytesToFieldElement(tx.CreatorAccountNameHash)
+	return pubData, nil
+}
+
+func ComputePubDataFromFullExit(tx *FullExitTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	packer := newPubDataPacker()
+	packer.writeInt64(TxTypeFullExit, TxTypeBitsSize)
+	packer.writeInt64(tx.AccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.AssetId, AssetIdBitsSize)
+	packer.writeBigInt(tx.AssetAmount, StateAmountBitsSize)
+	packer.padding(72)
+	if pubData[0], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	pubData[1] = BytesToFieldElement(tx.AccountNameHash)
+	return pubData, nil
+}
+
+func ComputePubDataFromFullExitNft(tx *FullExitNftTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	packer := newPubDataPacker()
+	packer.writeInt64(TxTypeFullExitNft, TxTypeBitsSize)
+	packer.writeInt64(tx.AccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.CreatorAccountIndex, AccountIndexBitsSize)
+	packer.writeInt64(tx.CreatorTreasuryRate, FeeRateBitsSize)
+	packer.writeInt64(tx.NftIndex, NftIndexBitsSize)
+	packer.writeInt64(tx.CollectionId, CollectionIdBitsSize)
+	packer.padding(112)
+	if pubData[0], err = packer.sum(); err != nil {
+		return pubData, err
+	}
+	if pubData[1], err = StringToFieldElement(tx.NftL1Address); err != nil {
+		return pubData, err
+	}
+	pubData[2] = BytesToFieldElement(tx.AccountNameHash)
+	pubData[3] = BytesToFieldElement(tx.CreatorAccountNameHash)
+	pubData[4] = BytesToFieldElement(tx.NftContentHash)
+	pubData[5] = BigIntToFieldElement(tx.NftL1TokenId)
+	return pubData, nil
+}
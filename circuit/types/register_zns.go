@@ -58,8 +58,9 @@ func VerifyRegisterZNSTx(
 	api API, flag Variable,
 	tx RegisterZnsTxConstraints,
 	accountsBefore [NbAccountsPerTx]AccountConstraints,
+	emptyAssetRoot Variable,
 ) (pubData [PubDataSizePerTx]Variable) {
 	pubData = CollectPubDataFromRegisterZNS(api, tx)
-	CheckEmptyAccountNode(api, flag, accountsBefore[0])
+	CheckEmptyAccountNode(api, flag, accountsBefore[0], emptyAssetRoot)
 	return pubData
 }
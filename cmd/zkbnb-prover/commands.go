@@ -0,0 +1,231 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/prover"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+func runCompile(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("compile", flag.ContinueOnError)
+	blockSize := fs.Int("block-size", 0, "number of txs in the block")
+	gasAssets := fs.String("gas-assets", "0,1", "comma separated gas asset ids")
+	gasAccountIndex := fs.Int64("gas-account", 1, "gas account index")
+	config := configFlags(fs)
+	r1csPath := fs.String("r1cs", "", "output path of the constraint system")
+	if err := parseFlags(fs, args, "block-size", "r1cs"); err != nil {
+		return err
+	}
+	if *blockSize <= 0 {
+		return fmt.Errorf("%w: block size should be positive", errUsage)
+	}
+	gasAssetIds, err := parseGasAssetIds(*gasAssets)
+	if err != nil {
+		return err
+	}
+	blockConstraints, err := circuit.NewBlockConstraints(*config, *blockSize, gasAssetIds, *gasAccountIndex)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	oR1cs, err := frontend.Compile(ecc.BN254, r1cs.NewBuilder, &blockConstraints, frontend.IgnoreUnconstrainedInputs())
+	if err != nil {
+		return fmt.Errorf("unable to compile circuit: %w", err)
+	}
+	header, err := prover.NewKeyHeader(prover.BackendGroth16, oR1cs, *config, *blockSize, gasAssetIds, *gasAccountIndex)
+	if err != nil {
+		return err
+	}
+	if err = writeKeyFile(*r1csPath, header, oR1cs); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Number of constraints: %d\n", oR1cs.GetNbConstraints())
+	fmt.Fprintf(stdout, "Circuit hash: %s\n", header.CircuitHash)
+	return nil
+}
+
+/*
+	configFlags: flags of the circuit config, the default config if they are not set
+*/
+func configFlags(fs *flag.FlagSet) *circuit.Config {
+	config := circuit.DefaultConfig()
+	fs.IntVar(&config.AccountMerkleLevels, "account-levels", config.AccountMerkleLevels, "depth of the account tree")
+	fs.IntVar(&config.AssetMerkleLevels, "asset-levels", config.AssetMerkleLevels, "depth of the asset trees")
+	fs.IntVar(&config.NftMerkleLevels, "nft-levels", config.NftMerkleLevels, "depth of the nft tree")
+	return &config
+}
+
+func runSetup(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("setup", flag.ContinueOnError)
+	r1csPath := fs.String("r1cs", "", "path of the constraint system")
+	pkPath := fs.String("pk", "", "output path of the proving key")
+	vkPath := fs.String("vk", "", "output path of the verifying key")
+	if err := parseFlags(fs, args, "r1cs", "pk", "vk"); err != nil {
+		return err
+	}
+	oR1cs, header, err := readCircuitFile(*r1csPath)
+	if err != nil {
+		return err
+	}
+	pk, vk, err := groth16.Setup(oR1cs)
+	if err != nil {
+		return fmt.Errorf("unable to run setup: %w", err)
+	}
+	if err = writeKeyFile(*pkPath, header, pk); err != nil {
+		return err
+	}
+	return writeKeyFile(*vkPath, header, vk)
+}
+
+func runProve(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("prove", flag.ContinueOnError)
+	r1csPath := fs.String("r1cs", "", "path of the constraint system")
+	pkPath := fs.String("pk", "", "path of the proving key")
+	witnessPath := fs.String("witness", "", "path of the JSON encoded circuit.Block")
+	proofPath := fs.String("proof", "", "output path of the proof")
+	if err := parseFlags(fs, args, "r1cs", "pk", "witness", "proof"); err != nil {
+		return err
+	}
+	oR1cs, header, err := readCircuitFile(*r1csPath)
+	if err != nil {
+		return err
+	}
+	pk := groth16.NewProvingKey(ecc.BN254)
+	if _, err = readKeyFile(*pkPath, header, pk); err != nil {
+		return err
+	}
+	oBlock, err := readBlock(*witnessPath)
+	if err != nil {
+		return err
+	}
+	if err = header.CheckBlock(oBlock); err != nil {
+		return err
+	}
+	blockWitness, err := circuit.SetBlockWitnessWithConfig(header.BlockConfig(), oBlock)
+	if err != nil {
+		return fmt.Errorf("unable to set block witness: %w", err)
+	}
+	fullWitness, err := frontend.NewWitness(&blockWitness, ecc.BN254)
+	if err != nil {
+		return fmt.Errorf("unable to build witness: %w", err)
+	}
+	proof, err := groth16.Prove(oR1cs, pk, fullWitness, backend.WithHints(types.Keccak256))
+	if err != nil {
+		return fmt.Errorf("unable to prove block: %w", err)
+	}
+	return writeRawFile(*proofPath, proof)
+}
+
+func runVerify(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	vkPath := fs.String("vk", "", "path of the verifying key")
+	proofPath := fs.String("proof", "", "path of the proof")
+	witnessPath := fs.String("witness", "", "path of the JSON encoded circuit.Block, used for its commitment")
+	commitmentHex := fs.String("commitment", "", "hex encoded block commitment, instead of -witness")
+	chainId := fs.Int64("chain-id", types.DefaultChainId, "chain id of the block, used with -commitment")
+	if err := parseFlags(fs, args, "vk", "proof"); err != nil {
+		return err
+	}
+	if (*witnessPath == "") == (*commitmentHex == "") {
+		return fmt.Errorf("%w: exactly one of -witness and -commitment is required", errUsage)
+	}
+	vk := groth16.NewVerifyingKey(ecc.BN254)
+	header, err := readKeyFile(*vkPath, nil, vk)
+	if err != nil {
+		return err
+	}
+	var commitment []byte
+	if *witnessPath != "" {
+		oBlock, err := readBlock(*witnessPath)
+		if err != nil {
+			return err
+		}
+		if err = header.CheckBlock(oBlock); err != nil {
+			return err
+		}
+		commitment = oBlock.BlockCommitment
+		*chainId = oBlock.ChainId
+	} else {
+		var err error
+		commitment, err = hex.DecodeString(strings.TrimPrefix(*commitmentHex, "0x"))
+		if err != nil {
+			return fmt.Errorf("%w: invalid commitment: %v", errUsage, err)
+		}
+	}
+	proof := groth16.NewProof(ecc.BN254)
+	if err := readFile(*proofPath, proof); err != nil {
+		return err
+	}
+	publicWitness, err := frontend.NewWitness(&circuit.BlockConstraints{BlockCommitment: commitment, ChainId: *chainId}, ecc.BN254, frontend.PublicOnly())
+	if err != nil {
+		return fmt.Errorf("unable to build public witness: %w", err)
+	}
+	if err = groth16.Verify(proof, vk, publicWitness); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidProof, err)
+	}
+	fmt.Fprintln(stdout, "proof is valid")
+	return nil
+}
+
+func runExportSol(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("export-sol", flag.ContinueOnError)
+	vkPath := fs.String("vk", "", "path of the verifying key")
+	outPath := fs.String("out", "", "output path of the Solidity verifier")
+	if err := parseFlags(fs, args, "vk", "out"); err != nil {
+		return err
+	}
+	vk := groth16.NewVerifyingKey(ecc.BN254)
+	if _, err := readKeyFile(*vkPath, nil, vk); err != nil {
+		return err
+	}
+	f, err := os.Create(*outPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err = vk.ExportSolidity(f); err != nil {
+		return fmt.Errorf("unable to export verifier: %w", err)
+	}
+	return f.Close()
+}
+
+func parseGasAssetIds(s string) (gasAssetIds []int64, err error) {
+	for _, field := range strings.Split(s, ",") {
+		assetId, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+		if err != nil || assetId < 0 || assetId > circuit.LastAccountAssetId {
+			return nil, fmt.Errorf("%w: invalid gas asset id %q", errUsage, field)
+		}
+		gasAssetIds = append(gasAssetIds, assetId)
+	}
+	return gasAssetIds, nil
+}
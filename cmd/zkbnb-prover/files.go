@@ -0,0 +1,136 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/consensys/gnark/frontend"
+	gnarkio "github.com/consensys/gnark/io"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/prover"
+)
+
+func readFile(path string, r io.ReaderFrom) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err = r.ReadFrom(f); err != nil {
+		return fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	return nil
+}
+
+/*
+	readCircuitFile: the constraint system is stored with the header its keys are checked against
+*/
+func readCircuitFile(path string) (ccs frontend.CompiledConstraintSystem, header *prover.KeyHeader, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	ccs, header, err = prover.LoadCircuit(bufio.NewReader(f), prover.BackendGroth16)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	return ccs, header, nil
+}
+
+/*
+	readKeyFile: refuses keys whose header doesn't match the expected one, any groth16 key is accepted
+	when there is no expected header
+*/
+func readKeyFile(path string, expected *prover.KeyHeader, key io.ReaderFrom) (header *prover.KeyHeader, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	header, err = prover.ReadKeyHeader(r)
+	if err == nil && expected != nil {
+		err = header.Check(expected)
+	}
+	if err == nil && header.Backend != prover.BackendGroth16 {
+		err = fmt.Errorf("%w: backend %s", prover.ErrKeyMismatch, header.Backend)
+	}
+	if err == nil {
+		err = prover.ReadKey(r, key, header)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	return header, nil
+}
+
+func writeKeyFile(path string, header *prover.KeyHeader, key io.WriterTo) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err = prover.WriteKey(w, header, key); err != nil {
+		return fmt.Errorf("unable to write %s: %w", path, err)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+/*
+	writeRawFile: proofs are written uncompressed, the same way as the solidity tests
+*/
+func writeRawFile(path string, w gnarkio.WriterRawTo) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err = w.WriteRawTo(f); err != nil {
+		return fmt.Errorf("unable to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+/*
+	readBlock: the witness file is a JSON encoded circuit.Block, as returned by executor.BuildBlock
+*/
+func readBlock(path string) (oBlock *circuit.Block, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	oBlock = new(circuit.Block)
+	if err = json.Unmarshal(data, oBlock); err != nil {
+		return nil, fmt.Errorf("unable to decode block witness %s: %w", path, err)
+	}
+	if len(oBlock.Txs) == 0 || oBlock.Gas == nil {
+		return nil, fmt.Errorf("invalid block witness %s", path)
+	}
+	return oBlock, nil
+}
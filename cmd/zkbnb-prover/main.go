@@ -0,0 +1,127 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+/*
+	zkbnb prover: compile the block circuit, run the setup, prove and verify blocks
+*/
+
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitInvalidProof
+)
+
+var (
+	errUsage        = errors.New("invalid usage")
+	errInvalidProof = errors.New("invalid proof")
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, stdout io.Writer) error
+}
+
+var commands = []*command{
+	{name: "compile", usage: "compile the block circuit into a constraint system", run: runCompile},
+	{name: "setup", usage: "run the groth16 setup and write the proving and verifying keys", run: runSetup},
+	{name: "prove", usage: "generate a proof from a JSON block witness", run: runProve},
+	{name: "verify", usage: "verify a proof against the block commitment", run: runVerify},
+	{name: "export-sol", usage: "export the Solidity verifier of a verifying key", run: runExportSol},
+	{name: "profile", usage: "report the constraints of the block circuit per gadget and tx type", run: runProfile},
+	{name: "profile-diff", usage: "compare two profile reports", run: runProfileDiff},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return exitUsage
+	}
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(args[1:], stdout)
+		switch {
+		case err == nil:
+			return exitOK
+		case errors.Is(err, flag.ErrHelp):
+			return exitOK
+		case errors.Is(err, errUsage):
+			fmt.Fprintf(stderr, "%s: %v\n", cmd.name, err)
+			return exitUsage
+		case errors.Is(err, errInvalidProof):
+			fmt.Fprintf(stderr, "%s: %v\n", cmd.name, err)
+			return exitInvalidProof
+		default:
+			fmt.Fprintf(stderr, "%s: %v\n", cmd.name, err)
+			return exitError
+		}
+	}
+	fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+	printUsage(stderr)
+	return exitUsage
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: zkbnb-prover <command> [flags]")
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(w, "exit codes: 0 success, 1 error, 2 invalid usage, 3 invalid proof")
+}
+
+/*
+	parseFlags: parse the flags of a command, every name in required must be set
+*/
+func parseFlags(fs *flag.FlagSet, args []string, required ...string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("%w: unexpected arguments %v", errUsage, fs.Args())
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for _, name := range required {
+		if !set[name] {
+			return fmt.Errorf("%w: flag -%s is required", errUsage, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/executor"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/profile"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+func runCmd(args ...string) (code int, stdout, stderr string) {
+	var outBuf, errBuf bytes.Buffer
+	code = run(args, &outBuf, &errBuf)
+	return code, outBuf.String(), errBuf.String()
+}
+
+func writeTestBlock(t *testing.T, path string) []byte {
+	sk, err := curve.GenerateEddsaPrivateKey("gas.legend")
+	assert.Nil(t, err)
+	hFunc := mimc.NewMiMC()
+	hFunc.Write([]byte("gas.legend"))
+	state, err := executor.NewState()
+	assert.Nil(t, err)
+	oBlock, err := executor.BuildBlock(state, types.DefaultChainId, 1, 1654656781000, 1, 1, []int64{0, 1}, []txtypes.TxInfo{
+		&txtypes.RegisterZnsTxInfo{
+			TxType:          types.TxTypeRegisterZns,
+			AccountIndex:    1,
+			AccountName:     "gas.legend",
+			AccountNameHash: hFunc.Sum(nil),
+			PubKey:          hex.EncodeToString(sk.PublicKey.Bytes()),
+		},
+	})
+	assert.Nil(t, err)
+	data, err := json.Marshal(oBlock)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(path, data, 0644))
+	return oBlock.BlockCommitment
+}
+
+func TestUsage(t *testing.T) {
+	code, _, stderr := runCmd()
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "usage: zkbnb-prover")
+
+	code, _, _ = runCmd("unknown")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, stderr = runCmd("compile", "-block-size", "1")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "-r1cs is required")
+
+	code, _, _ = runCmd("compile", "-block-size", "1", "-gas-assets", "0,x", "-r1cs", "out")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, _ = runCmd("compile", "-block-size", "1", "-asset-levels", "17", "-r1cs", "out")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, _ = runCmd("compile", "-block-size", "1", "-gas-assets", "0,16", "-asset-levels", "4", "-r1cs", "out")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, _ = runCmd("verify", "-vk", "vk", "-proof", "proof")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, _ = runCmd("setup", "-r1cs", filepath.Join(t.TempDir(), "missing"), "-pk", "pk", "-vk", "vk")
+	assert.Equal(t, exitError, code)
+}
+
+func TestProverCommands(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping groth16 setup in short mode")
+	}
+	dir := t.TempDir()
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	commitment := writeTestBlock(t, path("block.json"))
+
+	code, stdout, stderr := runCmd("compile", "-block-size", "1", "-gas-assets", "0,1", "-gas-account", "1", "-r1cs", path("zkbnb1.r1cs"))
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "Number of constraints")
+
+	code, _, stderr = runCmd("setup", "-r1cs", path("zkbnb1.r1cs"), "-pk", path("zkbnb1.pk"), "-vk", path("zkbnb1.vk"))
+	assert.Equal(t, exitOK, code, stderr)
+
+	code, _, stderr = runCmd("prove", "-r1cs", path("zkbnb1.r1cs"), "-pk", path("zkbnb1.pk"),
+		"-witness", path("block.json"), "-proof", path("block.proof"))
+	assert.Equal(t, exitOK, code, stderr)
+
+	// keys are refused with a circuit compiled for another gas account
+	code, _, stderr = runCmd("compile", "-block-size", "1", "-gas-assets", "0,1", "-gas-account", "2", "-r1cs", path("zkbnb1_2.r1cs"))
+	assert.Equal(t, exitOK, code, stderr)
+	code, _, stderr = runCmd("prove", "-r1cs", path("zkbnb1_2.r1cs"), "-pk", path("zkbnb1.pk"),
+		"-witness", path("block.json"), "-proof", path("block2.proof"))
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "key doesn't match the circuit")
+
+	code, stdout, stderr = runCmd("verify", "-vk", path("zkbnb1.vk"), "-proof", path("block.proof"), "-witness", path("block.json"))
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "proof is valid")
+
+	code, _, stderr = runCmd("verify", "-vk", path("zkbnb1.vk"), "-proof", path("block.proof"), "-commitment", hex.EncodeToString(commitment))
+	assert.Equal(t, exitOK, code, stderr)
+
+	code, _, _ = runCmd("verify", "-vk", path("zkbnb1.vk"), "-proof", path("block.proof"), "-commitment", "0x01")
+	assert.Equal(t, exitInvalidProof, code)
+
+	// the proof is bound to the chain id of the block
+	code, _, _ = runCmd("verify", "-vk", path("zkbnb1.vk"), "-proof", path("block.proof"), "-commitment", hex.EncodeToString(commitment), "-chain-id", "2")
+	assert.Equal(t, exitInvalidProof, code)
+
+	code, _, stderr = runCmd("export-sol", "-vk", path("zkbnb1.vk"), "-out", path("ZkBNBVerifier1.sol"))
+	assert.Equal(t, exitOK, code, stderr)
+	sol, err := os.ReadFile(path("ZkBNBVerifier1.sol"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(sol), "pragma solidity")
+}
+
+func TestProfileCommands(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.json")
+	code, stdout, stderr := runCmd("profile", "-block-size", "1", "-out", oldPath)
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "gadget/eddsa")
+	assert.Contains(t, stdout, "atomic_match")
+
+	// a revision saving 100 constraints in the eddsa gadget
+	data, err := os.ReadFile(oldPath)
+	assert.Nil(t, err)
+	var report profile.Report
+	assert.Nil(t, json.Unmarshal(data, &report))
+	report.NbConstraints -= 100
+	for i := range report.Entries {
+		if report.Entries[i].Name == "gadget/eddsa" {
+			report.Entries[i].NbConstraints -= 100
+		}
+	}
+	data, err = json.Marshal(&report)
+	assert.Nil(t, err)
+	newPath := filepath.Join(dir, "new.json")
+	assert.Nil(t, os.WriteFile(newPath, data, 0644))
+
+	code, stdout, stderr = runCmd("profile-diff", "-old", oldPath, "-new", newPath)
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "gadget/eddsa")
+	assert.Contains(t, stdout, "-100")
+	assert.NotContains(t, stdout, "tx/merkle/nft")
+
+	code, _, _ = runCmd("profile", "-backend", "marlin")
+	assert.Equal(t, exitUsage, code)
+	code, _, _ = runCmd("profile-diff", "-old", oldPath)
+	assert.Equal(t, exitUsage, code)
+	code, _, _ = runCmd("profile-diff", "-old", oldPath, "-new", filepath.Join(dir, "missing.json"))
+	assert.Equal(t, exitError, code)
+}
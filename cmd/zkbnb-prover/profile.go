@@ -0,0 +1,100 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/profile"
+)
+
+func runProfile(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("profile", flag.ContinueOnError)
+	blockSize := fs.Int("block-size", 1, "number of txs in the block")
+	gasAssets := fs.String("gas-assets", "0,1", "comma separated gas asset ids")
+	gasAccountIndex := fs.Int64("gas-account", 1, "gas account index")
+	backendName := fs.String("backend", profile.BackendGroth16, "groth16 or plonk")
+	outPath := fs.String("out", "", "output path of the JSON report, used by profile-diff")
+	config := configFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *blockSize <= 0 {
+		return fmt.Errorf("%w: block size should be positive", errUsage)
+	}
+	if *backendName != profile.BackendGroth16 && *backendName != profile.BackendPlonk {
+		return fmt.Errorf("%w: unknown backend %q", errUsage, *backendName)
+	}
+	gasAssetIds, err := parseGasAssetIds(*gasAssets)
+	if err != nil {
+		return err
+	}
+	report, err := profile.ProfileBlockWithConfig(*backendName, *config, *blockSize, gasAssetIds, *gasAccountIndex)
+	if err != nil {
+		return fmt.Errorf("unable to profile circuit: %w", err)
+	}
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err = profile.WriteReportJSON(f, report); err != nil {
+			return fmt.Errorf("unable to write %s: %w", *outPath, err)
+		}
+		if err = f.Close(); err != nil {
+			return err
+		}
+	}
+	return profile.WriteReport(stdout, report)
+}
+
+func runProfileDiff(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("profile-diff", flag.ContinueOnError)
+	oldPath := fs.String("old", "", "JSON report of the old revision")
+	newPath := fs.String("new", "", "JSON report of the new revision")
+	all := fs.Bool("all", false, "also print the unchanged entries")
+	if err := parseFlags(fs, args, "old", "new"); err != nil {
+		return err
+	}
+	oldReport, err := readReport(*oldPath)
+	if err != nil {
+		return err
+	}
+	newReport, err := readReport(*newPath)
+	if err != nil {
+		return err
+	}
+	return profile.WriteDiff(stdout, profile.Diff(oldReport, newReport), *all)
+}
+
+func readReport(path string) (*profile.Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	report, err := profile.ReadReportJSON(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	return report, nil
+}
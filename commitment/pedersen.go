@@ -0,0 +1,211 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package commitment
+
+import (
+	"bytes"
+	"log"
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/ffmath"
+)
+
+/*
+	Pedersen commitments over tebn254: C = value*H + blinding*U, H and U are the generators derived from
+	SeedH and SeedU so that nobody knows the discrete log of one in base of the other. The values and
+	the blindings are scalars modulo tebn254.Order, a negative value is committed as its opposite modulo the order.
+*/
+
+const CommitmentSize = tebn254.PointSize
+
+/*
+	Commitment: Pedersen commitment, the zero value is not a valid commitment
+*/
+type Commitment struct {
+	point tebn254.Point
+}
+
+/*
+	Opening: value and blinding of a commitment
+*/
+type Opening struct {
+	Value    *big.Int
+	Blinding *big.Int
+}
+
+/*
+	RandomBlinding: uniform blinding in [0, tebn254.Order)
+*/
+func RandomBlinding() (*big.Int, error) {
+	blinding, err := ffmath.RandomValue(tebn254.Order)
+	if err != nil {
+		log.Println("[RandomBlinding] unable to generate blinding:", err)
+		return nil, err
+	}
+	return blinding, nil
+}
+
+/*
+	Commit: commitment to the value with the blinding
+*/
+func Commit(value, blinding *big.Int) (*Commitment, error) {
+	if value == nil || blinding == nil {
+		log.Println("[Commit] nil value or blinding")
+		return nil, ErrInvalidOpening
+	}
+	c := new(Commitment)
+	c.point.Add(
+		tebn254.ScalarMul(tebn254.H, ffmath.Mod(value, tebn254.Order)),
+		tebn254.ScalarMul(tebn254.U, ffmath.Mod(blinding, tebn254.Order)),
+	)
+	return c, nil
+}
+
+/*
+	CommitRandom: commitment to the value with a random blinding, the opening is returned with it
+*/
+func CommitRandom(value *big.Int) (*Commitment, *Opening, error) {
+	blinding, err := RandomBlinding()
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := Commit(value, blinding)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, &Opening{Value: new(big.Int).Set(value), Blinding: blinding}, nil
+}
+
+/*
+	Verify: check that the commitment opens to the value with the blinding
+*/
+func Verify(c *Commitment, value, blinding *big.Int) bool {
+	if c == nil {
+		return false
+	}
+	expected, err := Commit(value, blinding)
+	if err != nil {
+		return false
+	}
+	return c.Equal(expected)
+}
+
+/*
+	Verify: check that the commitment opens to the opening
+*/
+func (o *Opening) Verify(c *Commitment) bool {
+	return o != nil && Verify(c, o.Value, o.Blinding)
+}
+
+/*
+	Add: opening of the sum of the commitments of the two openings
+*/
+func (o *Opening) Add(other *Opening) *Opening {
+	return &Opening{
+		Value:    ffmath.AddMod(o.Value, other.Value, tebn254.Order),
+		Blinding: ffmath.AddMod(o.Blinding, other.Blinding, tebn254.Order),
+	}
+}
+
+/*
+	Sub: opening of the difference of the commitments of the two openings
+*/
+func (o *Opening) Sub(other *Opening) *Opening {
+	return &Opening{
+		Value:    ffmath.SubMod(o.Value, other.Value, tebn254.Order),
+		Blinding: ffmath.SubMod(o.Blinding, other.Blinding, tebn254.Order),
+	}
+}
+
+/*
+	Add: commitment to the sum of the values with the sum of the blindings
+*/
+func (c *Commitment) Add(other *Commitment) *Commitment {
+	res := new(Commitment)
+	res.point.Add(&c.point, &other.point)
+	return res
+}
+
+/*
+	Sub: commitment to the difference of the values with the difference of the blindings
+*/
+func (c *Commitment) Sub(other *Commitment) *Commitment {
+	res := new(Commitment)
+	res.point.Add(&c.point, tebn254.Neg(&other.point))
+	return res
+}
+
+/*
+	ScalarMul: commitment to the value times k with the blinding times k
+*/
+func (c *Commitment) ScalarMul(k *big.Int) *Commitment {
+	res := new(Commitment)
+	res.point.ScalarMul(&c.point, ffmath.Mod(k, tebn254.Order))
+	return res
+}
+
+func (c *Commitment) Equal(other *Commitment) bool {
+	return other != nil && c.point.Equal(&other.point)
+}
+
+/*
+	Point: copy of the curve point of the commitment
+*/
+func (c *Commitment) Point() *tebn254.Point {
+	return new(tebn254.Point).Set(&c.point)
+}
+
+/*
+	FromPoint: commitment of a curve point, such as the right part of a twisted ElGamal ciphertext
+*/
+func FromPoint(p *tebn254.Point) (*Commitment, error) {
+	if p == nil || !tebn254.IsInSubGroup(p) {
+		return nil, ErrInvalidCommitment
+	}
+	c := new(Commitment)
+	c.point.Set(p)
+	return c, nil
+}
+
+/*
+	ToBytes: compressed point of the commitment, CommitmentSize bytes
+*/
+func ToBytes(c *Commitment) []byte {
+	return tebn254.ToBytes(&c.point)
+}
+
+/*
+	FromBytes: commitment of the bytes written by ToBytes, the point must be canonically encoded and in the subgroup
+*/
+func FromBytes(buf []byte) (*Commitment, error) {
+	if len(buf) != CommitmentSize {
+		return nil, ErrInvalidCommitment
+	}
+	p, err := tebn254.FromBytes(buf)
+	if err != nil {
+		return nil, ErrInvalidCommitment
+	}
+	if !tebn254.IsInSubGroup(p) || !bytes.Equal(tebn254.ToBytes(p), buf) {
+		log.Println("[FromBytes] invalid commitment point")
+		return nil, ErrInvalidCommitment
+	}
+	c := new(Commitment)
+	c.point.Set(p)
+	return c, nil
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package commitment
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+)
+
+func TestCommit(t *testing.T) {
+	c, opening, err := CommitRandom(big.NewInt(100))
+	require.NoError(t, err)
+	assert.True(t, opening.Verify(c))
+	assert.True(t, Verify(c, big.NewInt(100), opening.Blinding))
+	assert.False(t, Verify(c, big.NewInt(101), opening.Blinding))
+	assert.False(t, Verify(c, big.NewInt(100), new(big.Int).Add(opening.Blinding, big.NewInt(1))))
+	assert.False(t, Verify(nil, big.NewInt(100), opening.Blinding))
+	_, err = Commit(nil, opening.Blinding)
+	assert.Equal(t, ErrInvalidOpening, err)
+
+	// the values are taken modulo the order
+	c1, err := Commit(big.NewInt(-5), big.NewInt(7))
+	require.NoError(t, err)
+	c2, err := Commit(new(big.Int).Sub(tebn254.Order, big.NewInt(5)), big.NewInt(7))
+	require.NoError(t, err)
+	assert.True(t, c1.Equal(c2))
+}
+
+func TestCommitmentHomomorphism(t *testing.T) {
+	c1, o1, err := CommitRandom(big.NewInt(100))
+	require.NoError(t, err)
+	c2, o2, err := CommitRandom(big.NewInt(30))
+	require.NoError(t, err)
+
+	sum := c1.Add(c2)
+	assert.True(t, o1.Add(o2).Verify(sum))
+	assert.Equal(t, big.NewInt(130), o1.Add(o2).Value)
+	diff := c1.Sub(c2)
+	assert.True(t, o1.Sub(o2).Verify(diff))
+	assert.Equal(t, big.NewInt(70), o1.Sub(o2).Value)
+	assert.True(t, diff.Add(c2).Equal(c1))
+	assert.True(t, Verify(c2.Sub(c1), big.NewInt(-70), o2.Sub(o1).Blinding))
+	assert.True(t, Verify(c1.ScalarMul(big.NewInt(3)), big.NewInt(300), new(big.Int).Mul(o1.Blinding, big.NewInt(3))))
+}
+
+func TestCommitmentBytes(t *testing.T) {
+	c, _, err := CommitRandom(big.NewInt(42))
+	require.NoError(t, err)
+	buf := ToBytes(c)
+	assert.Equal(t, CommitmentSize, len(buf))
+	decoded, err := FromBytes(buf)
+	require.NoError(t, err)
+	assert.True(t, c.Equal(decoded))
+	assert.True(t, decoded.Point().Equal(c.Point()))
+
+	_, err = FromBytes(buf[1:])
+	assert.Equal(t, ErrInvalidCommitment, err)
+	// (0, -1) is on the curve but has order 2
+	var lowOrder tebn254.Point
+	lowOrder.Y.SetOne()
+	lowOrder.Y.Neg(&lowOrder.Y)
+	_, err = FromBytes(tebn254.ToBytes(&lowOrder))
+	assert.Equal(t, ErrInvalidCommitment, err)
+	_, err = FromPoint(&lowOrder)
+	assert.Equal(t, ErrInvalidCommitment, err)
+	// y = modulus + 1 is the encoding of y = 1 which is not reduced
+	nonCanonical := make([]byte, CommitmentSize)
+	modulus := fr.Modulus().Bytes()
+	for i := range modulus {
+		nonCanonical[i] = modulus[len(modulus)-1-i]
+	}
+	nonCanonical[0]++
+	_, err = FromBytes(nonCanonical)
+	assert.Equal(t, ErrInvalidCommitment, err)
+}
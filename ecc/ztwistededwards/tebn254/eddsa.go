@@ -19,19 +19,46 @@ package tebn254
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"crypto/subtle"
 	"io"
 	"math/big"
 
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/hkdf"
+)
+
+type KeyDerivationVersion uint8
+
+const (
+	// KeyDerivationLegacy: the seed is copied into 32 bytes, longer seeds are truncated and shorter ones zero-padded
+	KeyDerivationLegacy KeyDerivationVersion = 0
+	// KeyDerivationV1: argon2id of the whole seed with KeyDerivationDomainV1 as salt, then HKDF-SHA256
+	KeyDerivationV1 KeyDerivationVersion = 1
+
+	KeyDerivationDomainV1 = "ZkBNB/EdDSA/KeyDerivation/v1"
+	keyDerivationInfoV1   = "tebn254 eddsa private key"
+	// argon2id parameters of KeyDerivationV1 (RFC 9106, second recommended option): the seed is stretched
+	// so that a short seed such as a passphrase costs 64 MiB and 3 passes per guess
+	KeyDerivationV1Time    = 3
+	KeyDerivationV1Memory  = 64 * 1024
+	KeyDerivationV1Threads = 4
 )
 
 /*
-	GenerateEddsaPrivateKey: generate eddsa private key
+	GenerateEddsaPrivateKey: generate eddsa private key with the legacy derivation, kept for the existing accounts
 */
 func GenerateEddsaPrivateKey(seed string) (sk *PrivateKey, err error) {
+	return GenerateEddsaPrivateKeyLegacy(seed)
+}
+
+/*
+	GenerateEddsaPrivateKeyLegacy: generate eddsa private key from the first 32 bytes of the seed, zero-padded
+*/
+func GenerateEddsaPrivateKeyLegacy(seed string) (sk *PrivateKey, err error) {
 	buf := make([]byte, 32)
 	copy(buf, seed)
 	reader := bytes.NewReader(buf)
@@ -39,10 +66,49 @@ func GenerateEddsaPrivateKey(seed string) (sk *PrivateKey, err error) {
 	return sk, err
 }
 
+/*
+	DeriveEddsaPrivateKey: derive eddsa private key from seed material of any length with the given derivation version
+*/
+func DeriveEddsaPrivateKey(seed []byte, version KeyDerivationVersion) (sk *PrivateKey, err error) {
+	switch version {
+	case KeyDerivationLegacy:
+		return GenerateEddsaPrivateKeyLegacy(string(seed))
+	case KeyDerivationV1:
+		if len(seed) == 0 {
+			return nil, ErrInvalidSeed
+		}
+		stretched := argon2.IDKey(seed, []byte(KeyDerivationDomainV1),
+			KeyDerivationV1Time, KeyDerivationV1Memory, KeyDerivationV1Threads, 32)
+		buf := make([]byte, 32)
+		kdf := hkdf.New(sha256.New, stretched, []byte(KeyDerivationDomainV1), []byte(keyDerivationInfoV1))
+		if _, err = io.ReadFull(kdf, buf); err != nil {
+			return nil, err
+		}
+		return GenerateKey(bytes.NewReader(buf))
+	default:
+		return nil, ErrUnknownKeyDerivation
+	}
+}
+
 const (
 	sizeFr = fr.Bytes
 )
 
+/*
+	PrivateKeyScalar: secret scalar of the private key modulo Order, the public key is this scalar times G
+*/
+func PrivateKeyScalar(sk *PrivateKey) (*big.Int, error) {
+	if sk == nil {
+		return nil, ErrInvalidPrivKey
+	}
+	s := new(big.Int).SetBytes(sk.Bytes()[sizeFr : 2*sizeFr])
+	s.Mod(s, Order)
+	if s.Sign() == 0 || !ScalarBaseMul(s).Equal(&sk.PublicKey.A) {
+		return nil, ErrInvalidPrivKey
+	}
+	return s, nil
+}
+
 func GenerateKey(r io.Reader) (*PrivateKey, error) {
 
 	c := twistededwards.GetEdwardsCurve()
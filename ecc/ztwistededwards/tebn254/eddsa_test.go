@@ -18,6 +18,7 @@
 package tebn254
 
 import (
+	"bytes"
 	"log"
 	"math/big"
 	"testing"
@@ -46,3 +47,75 @@ func TestGenerateEddsaPrivateKey(t *testing.T) {
 	}
 	log.Println(isValid)
 }
+
+func TestDeriveEddsaPrivateKey(t *testing.T) {
+	seed := "testeeetgcxsaahsadcastzxbmjhgmgjhcarwewfseasdasdavacsafaewe"
+	legacy, err := GenerateEddsaPrivateKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk, err := DeriveEddsaPrivateKey([]byte(seed), KeyDerivationLegacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sk.Bytes(), legacy.Bytes()) {
+		t.Fatal("the legacy derivation changed")
+	}
+	// the legacy derivation only reads the first 32 bytes
+	truncated, err := GenerateEddsaPrivateKeyLegacy(seed[:32] + "another suffix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(truncated.Bytes(), legacy.Bytes()) {
+		t.Fatal("the legacy derivation should truncate the seed")
+	}
+
+	v1, err := DeriveEddsaPrivateKey([]byte(seed), KeyDerivationV1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := DeriveEddsaPrivateKey([]byte(seed), KeyDerivationV1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v1.Bytes(), again.Bytes()) {
+		t.Fatal("the derivation isn't deterministic")
+	}
+	if bytes.Equal(v1.Bytes(), legacy.Bytes()) {
+		t.Fatal("v1 should differ from the legacy derivation")
+	}
+	// every byte of the seed matters
+	other, err := DeriveEddsaPrivateKey([]byte(seed[:32]+"another suffix"), KeyDerivationV1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(v1.Bytes(), other.Bytes()) {
+		t.Fatal("v1 should use the whole seed")
+	}
+	s, err := PrivateKeyScalar(v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ScalarBaseMul(s).Equal(&v1.PublicKey.A) {
+		t.Fatal("invalid public key")
+	}
+
+	// short seeds are stretched by argon2id
+	short, err := DeriveEddsaPrivateKey([]byte("short seed"), KeyDerivationV1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortOther, err := DeriveEddsaPrivateKey([]byte("short seee"), KeyDerivationV1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(short.Bytes(), shortOther.Bytes()) {
+		t.Fatal("v1 should use every byte of a short seed")
+	}
+	if _, err = DeriveEddsaPrivateKey(nil, KeyDerivationV1); err != ErrInvalidSeed {
+		t.Fatal("empty seeds should be refused, got", err)
+	}
+	if _, err = DeriveEddsaPrivateKey([]byte(seed), KeyDerivationVersion(2)); err != ErrUnknownKeyDerivation {
+		t.Fatal("unknown versions should be refused, got", err)
+	}
+}
@@ -24,4 +24,8 @@ import (
 var (
 	ErrMapToGroup       = errors.New("Failed to Hash-to-point.")
 	ErrInvalidPointSize = errors.New("err: invalid point size")
+	ErrInvalidPrivKey   = errors.New("err: invalid private key")
+	ErrInvalidSeed      = errors.New("err: seed is empty")
+
+	ErrUnknownKeyDerivation = errors.New("err: unknown key derivation version")
 )
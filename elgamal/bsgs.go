@@ -0,0 +1,104 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package elgamal
+
+import (
+	"log"
+	"math/big"
+	"sync"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+)
+
+const (
+	DefaultBabySteps  = 1 << 16
+	DefaultGiantSteps = 1 << 16
+)
+
+var (
+	defaultTable     *Table
+	defaultTableOnce sync.Once
+)
+
+/*
+	Table: baby-step giant-step solver of amount*H = P for the amounts in [0, MaxAmount],
+	it holds the babySteps first multiples of H and is safe for concurrent use once built
+*/
+type Table struct {
+	babySteps  map[tebn254.Point]uint64
+	m          uint64
+	giantSteps uint64
+	// -m*H
+	giantStep tebn254.Point
+}
+
+/*
+	NewTable: table of babySteps points which solves in at most giantSteps additions,
+	the memory grows with babySteps and the decryption time with giantSteps
+*/
+func NewTable(babySteps, giantSteps uint64) (*Table, error) {
+	if babySteps == 0 || giantSteps == 0 || babySteps > 1<<32 || giantSteps > 1<<32 {
+		log.Println("[NewTable] invalid table size:", babySteps, giantSteps)
+		return nil, ErrInvalidTableSize
+	}
+	t := &Table{
+		babySteps:  make(map[tebn254.Point]uint64, babySteps),
+		m:          babySteps,
+		giantSteps: giantSteps,
+	}
+	p := tebn254.ZeroPoint()
+	for j := uint64(0); j < babySteps; j++ {
+		t.babySteps[*p] = j
+		p = tebn254.Add(p, tebn254.H)
+	}
+	// p = m*H
+	t.giantStep.Neg(p)
+	return t, nil
+}
+
+/*
+	DefaultTable: table of DefaultBabySteps points, built on the first call, which solves the amounts below 2^32
+*/
+func DefaultTable() *Table {
+	defaultTableOnce.Do(func() {
+		defaultTable, _ = NewTable(DefaultBabySteps, DefaultGiantSteps)
+	})
+	return defaultTable
+}
+
+func (t *Table) MaxAmount() *big.Int {
+	max := new(big.Int).SetUint64(t.m)
+	max.Mul(max, new(big.Int).SetUint64(t.giantSteps))
+	return max.Sub(max, big.NewInt(1))
+}
+
+/*
+	Solve: amount such that amount*H = p, ErrAmountOutOfRange if it is above MaxAmount
+*/
+func (t *Table) Solve(p *tebn254.Point) (*big.Int, error) {
+	current := new(tebn254.Point).Set(p)
+	for i := uint64(0); i < t.giantSteps; i++ {
+		if j, ok := t.babySteps[*current]; ok {
+			amount := new(big.Int).SetUint64(i)
+			amount.Mul(amount, new(big.Int).SetUint64(t.m))
+			return amount.Add(amount, new(big.Int).SetUint64(j)), nil
+		}
+		current.Add(current, &t.giantStep)
+	}
+	return nil, ErrAmountOutOfRange
+}
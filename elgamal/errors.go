@@ -0,0 +1,31 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package elgamal
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidPublicKey  = errors.New("[elgamal] invalid public key")
+	ErrInvalidPrivateKey = errors.New("[elgamal] invalid private key")
+	ErrInvalidAmount     = errors.New("[elgamal] invalid amount")
+	ErrInvalidCiphertext = errors.New("[elgamal] invalid ciphertext")
+	ErrInvalidTableSize  = errors.New("[elgamal] invalid table size")
+	ErrAmountOutOfRange  = errors.New("[elgamal] amount out of the range of the table")
+)
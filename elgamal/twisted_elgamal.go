@@ -0,0 +1,169 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package elgamal
+
+import (
+	"bytes"
+	"log"
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/ffmath"
+)
+
+/*
+	Twisted ElGamal on tebn254 under the EdDSA keys of the accounts: with the private scalar sk and pk = sk*G,
+	an amount b is encrypted with a random r as CL = r*pk, CR = r*G + b*H.
+	CR - sk^-1*CL = b*H, so the amount is recovered with a Table as long as it is small.
+	The ciphertexts are additively homomorphic, the amounts are taken modulo tebn254.Order.
+*/
+
+const CiphertextSize = 2 * tebn254.PointSize
+
+type Ciphertext struct {
+	CL tebn254.Point
+	CR tebn254.Point
+}
+
+/*
+	ZeroCiphertext: encryption of 0 with a zero randomness, such as the balance of a new account
+*/
+func ZeroCiphertext() *Ciphertext {
+	return &Ciphertext{CL: *tebn254.ZeroPoint(), CR: *tebn254.ZeroPoint()}
+}
+
+/*
+	Encrypt: encryption of the amount under the public key with a random randomness
+*/
+func Encrypt(amount *big.Int, pk *tebn254.PublicKey) (*Ciphertext, error) {
+	r, err := ffmath.RandomValue(tebn254.Order)
+	if err != nil {
+		log.Println("[Encrypt] unable to generate randomness:", err)
+		return nil, err
+	}
+	return EncryptWithRandomness(amount, r, pk)
+}
+
+/*
+	EncryptWithRandomness: encryption of the amount under the public key with the randomness r
+*/
+func EncryptWithRandomness(amount, r *big.Int, pk *tebn254.PublicKey) (*Ciphertext, error) {
+	if amount == nil || r == nil {
+		return nil, ErrInvalidAmount
+	}
+	if pk == nil || tebn254.IsZero(&pk.A) || !tebn254.IsInSubGroup(&pk.A) {
+		log.Println("[EncryptWithRandomness] invalid public key")
+		return nil, ErrInvalidPublicKey
+	}
+	r = ffmath.Mod(r, tebn254.Order)
+	c := new(Ciphertext)
+	c.CL.ScalarMul(&pk.A, r)
+	c.CR.Add(tebn254.ScalarBaseMul(r), tebn254.ScalarMul(tebn254.H, ffmath.Mod(amount, tebn254.Order)))
+	return c, nil
+}
+
+/*
+	Add: encryption of the sum of the amounts, both ciphertexts must be under the same key
+*/
+func (c *Ciphertext) Add(other *Ciphertext) *Ciphertext {
+	res := new(Ciphertext)
+	res.CL.Add(&c.CL, &other.CL)
+	res.CR.Add(&c.CR, &other.CR)
+	return res
+}
+
+/*
+	Sub: encryption of the difference of the amounts, both ciphertexts must be under the same key
+*/
+func (c *Ciphertext) Sub(other *Ciphertext) *Ciphertext {
+	res := new(Ciphertext)
+	res.CL.Add(&c.CL, tebn254.Neg(&other.CL))
+	res.CR.Add(&c.CR, tebn254.Neg(&other.CR))
+	return res
+}
+
+func (c *Ciphertext) Equal(other *Ciphertext) bool {
+	return other != nil && c.CL.Equal(&other.CL) && c.CR.Equal(&other.CR)
+}
+
+/*
+	PrivateKeyScalar: secret scalar of the EdDSA private key, the public key is this scalar times G
+*/
+func PrivateKeyScalar(sk *tebn254.PrivateKey) (*big.Int, error) {
+	s, err := tebn254.PrivateKeyScalar(sk)
+	if err != nil {
+		log.Println("[PrivateKeyScalar] the scalar doesn't match the public key")
+		return nil, ErrInvalidPrivateKey
+	}
+	return s, nil
+}
+
+/*
+	DecryptToPoint: amount*H of the ciphertext
+*/
+func DecryptToPoint(c *Ciphertext, sk *tebn254.PrivateKey) (*tebn254.Point, error) {
+	s, err := PrivateKeyScalar(sk)
+	if err != nil {
+		return nil, err
+	}
+	p := tebn254.ScalarMul(&c.CL, ffmath.ModInverse(s, tebn254.Order))
+	return tebn254.Add(&c.CR, tebn254.Neg(p)), nil
+}
+
+/*
+	Decrypt: amount of the ciphertext, solved with the table or with DefaultTable if it is nil
+*/
+func Decrypt(c *Ciphertext, sk *tebn254.PrivateKey, table *Table) (*big.Int, error) {
+	p, err := DecryptToPoint(c, sk)
+	if err != nil {
+		return nil, err
+	}
+	if table == nil {
+		table = DefaultTable()
+	}
+	return table.Solve(p)
+}
+
+/*
+	ToBytes: CL then CR as compressed points, CiphertextSize bytes
+*/
+func ToBytes(c *Ciphertext) []byte {
+	buf := make([]byte, 0, CiphertextSize)
+	buf = append(buf, tebn254.ToBytes(&c.CL)...)
+	return append(buf, tebn254.ToBytes(&c.CR)...)
+}
+
+/*
+	FromBytes: ciphertext of the bytes written by ToBytes, the points must be canonically encoded and in the subgroup
+*/
+func FromBytes(buf []byte) (*Ciphertext, error) {
+	if len(buf) != CiphertextSize {
+		return nil, ErrInvalidCiphertext
+	}
+	c := new(Ciphertext)
+	for i, p := range []*tebn254.Point{&c.CL, &c.CR} {
+		pBytes := buf[i*tebn254.PointSize : (i+1)*tebn254.PointSize]
+		point, err := tebn254.FromBytes(pBytes)
+		if err != nil || !tebn254.IsInSubGroup(point) || !bytes.Equal(tebn254.ToBytes(point), pBytes) {
+			log.Println("[FromBytes] invalid ciphertext point")
+			return nil, ErrInvalidCiphertext
+		}
+		p.Set(point)
+	}
+	return c, nil
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package elgamal
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+)
+
+func TestTwistedElgamal(t *testing.T) {
+	sk, err := tebn254.GenerateEddsaPrivateKey("elgamal test seed")
+	require.NoError(t, err)
+	table, err := NewTable(1<<8, 1<<8)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(1<<16-1), table.MaxAmount())
+
+	c1, err := Encrypt(big.NewInt(1000), &sk.PublicKey)
+	require.NoError(t, err)
+	amount, err := Decrypt(c1, sk, table)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(1000), amount)
+	c2, err := Encrypt(big.NewInt(300), &sk.PublicKey)
+	require.NoError(t, err)
+	assert.False(t, c1.Equal(c2))
+
+	amount, err = Decrypt(c1.Add(c2), sk, table)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(1300), amount)
+	amount, err = Decrypt(c1.Sub(c2), sk, table)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(700), amount)
+	amount, err = Decrypt(ZeroCiphertext().Add(c2), sk, table)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(300), amount)
+	// negative and too large amounts are out of the range of the table
+	_, err = Decrypt(c2.Sub(c1), sk, table)
+	assert.Equal(t, ErrAmountOutOfRange, err)
+	c3, err := Encrypt(big.NewInt(1<<16), &sk.PublicKey)
+	require.NoError(t, err)
+	_, err = Decrypt(c3, sk, table)
+	assert.Equal(t, ErrAmountOutOfRange, err)
+
+	// another key
+	other, err := tebn254.GenerateEddsaPrivateKey("another elgamal test seed")
+	require.NoError(t, err)
+	_, err = Decrypt(c1, other, table)
+	assert.Equal(t, ErrAmountOutOfRange, err)
+	_, err = Encrypt(big.NewInt(1), &tebn254.PublicKey{A: *tebn254.ZeroPoint()})
+	assert.Equal(t, ErrInvalidPublicKey, err)
+
+	// same randomness, same ciphertext
+	c4, err := EncryptWithRandomness(big.NewInt(5), big.NewInt(11), &sk.PublicKey)
+	require.NoError(t, err)
+	c5, err := EncryptWithRandomness(big.NewInt(5), big.NewInt(11), &sk.PublicKey)
+	require.NoError(t, err)
+	assert.True(t, c4.Equal(c5))
+}
+
+func TestDefaultTable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the default table in short mode")
+	}
+	sk, err := tebn254.GenerateEddsaPrivateKey("elgamal test seed")
+	require.NoError(t, err)
+	for _, amount := range []int64{0, 1, 1<<32 - 1} {
+		c, err := Encrypt(big.NewInt(amount), &sk.PublicKey)
+		require.NoError(t, err)
+		decrypted, err := Decrypt(c, sk, nil)
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(amount), decrypted)
+	}
+}
+
+func TestCiphertextBytes(t *testing.T) {
+	sk, err := tebn254.GenerateEddsaPrivateKey("elgamal test seed")
+	require.NoError(t, err)
+	c, err := Encrypt(big.NewInt(42), &sk.PublicKey)
+	require.NoError(t, err)
+	buf := ToBytes(c)
+	assert.Equal(t, CiphertextSize, len(buf))
+	decoded, err := FromBytes(buf)
+	require.NoError(t, err)
+	assert.True(t, c.Equal(decoded))
+	decoded, err = FromBytes(ToBytes(ZeroCiphertext()))
+	require.NoError(t, err)
+	assert.True(t, ZeroCiphertext().Equal(decoded))
+
+	_, err = FromBytes(buf[1:])
+	assert.Equal(t, ErrInvalidCiphertext, err)
+	// (0, -1) is on the curve but has order 2
+	var lowOrder tebn254.Point
+	lowOrder.Y.SetOne()
+	lowOrder.Y.Neg(&lowOrder.Y)
+	copy(buf[tebn254.PointSize:], tebn254.ToBytes(&lowOrder))
+	_, err = FromBytes(buf)
+	assert.Equal(t, ErrInvalidCiphertext, err)
+}
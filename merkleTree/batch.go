@@ -0,0 +1,173 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// levels with fewer nodes to hash are hashed by one worker
+const minParallelNodes = 64
+
+var ErrHasherMismatch = errors.New("[smt] the hashers of NewHasher don't hash as Hasher")
+
+/*
+	BatchUpdate: set several leaves and rehash their paths level by level, the ancestors shared by several leaves
+	are hashed once and the nodes of a level are split between workers which each own a hasher from NewHasher.
+	The nodes are staged until the whole batch is hashed, the tree is unchanged if it fails.
+*/
+func (t *Tree) BatchUpdate(leaves map[int64][]byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.batchUpdate(leaves)
+}
+
+func (t *Tree) batchUpdate(leaves map[int64][]byte) error {
+	if len(leaves) == 0 {
+		return nil
+	}
+	indexes := make([]int64, 0, len(leaves))
+	for index := range leaves {
+		if index < 0 || index >= 1<<t.MaxHeight {
+			log.Println("[BatchUpdate] invalid index:", index)
+			return ErrInvalidIndex
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	hashers, err := t.batchHashers()
+	if err != nil {
+		return err
+	}
+	staged := make(map[nodeKey][]byte)
+	node := func(height int, index int64) ([]byte, error) {
+		if value, ok := staged[nodeKey{height: height, index: index}]; ok {
+			return value, nil
+		}
+		return t.node(height, index)
+	}
+	for _, index := range indexes {
+		staged[nodeKey{height: 0, index: index}] = common.CopyBytes(leaves[index])
+	}
+	for height := 0; height < t.MaxHeight; height++ {
+		// the indexes are sorted, so the duplicated parents are next to each other
+		parents := indexes[:0]
+		for _, index := range indexes {
+			if len(parents) == 0 || parents[len(parents)-1] != index>>1 {
+				parents = append(parents, index>>1)
+			}
+		}
+		values, err := hashParents(height, parents, hashers, node)
+		if err != nil {
+			log.Println("[BatchUpdate] unable to hash nodes:", err)
+			return err
+		}
+		for i, index := range parents {
+			staged[nodeKey{height: height + 1, index: index}] = values[i]
+		}
+		indexes = parents
+	}
+	for key, value := range staged {
+		t.dirty[key] = value
+	}
+	t.root = staged[nodeKey{height: t.MaxHeight, index: 0}]
+	return nil
+}
+
+/*
+	batchHashers: the hashers of the workers, Hasher is shared with HashSubTrees so it's only used through it.
+	Every hasher of NewHasher must hash two different nodes as Hasher does, or the batch would write wrong roots.
+*/
+func (t *Tree) batchHashers() ([]func(l, r []byte) []byte, error) {
+	if t.NewHasher == nil {
+		return []func(l, r []byte) []byte{t.HashSubTrees}, nil
+	}
+	left, right := t.NilHashValueConst[0], t.NilHashValueConst[1]
+	expected := t.HashSubTrees(left, right)
+	hashers := make([]func(l, r []byte) []byte, runtime.GOMAXPROCS(0))
+	for i := range hashers {
+		hasher := t.NewHasher()
+		if hasher == nil || !bytes.Equal(hasher.HashNode(left, right), expected) {
+			log.Println("[BatchUpdate] NewHasher doesn't match Hasher")
+			return nil, ErrHasherMismatch
+		}
+		hashers[i] = hasher.HashNode
+	}
+	return hashers, nil
+}
+
+/*
+	hashParents: hash the children of the parents, the nodes are only read while the workers run
+*/
+func hashParents(
+	height int, parents []int64, hashers []func(l, r []byte) []byte,
+	node func(height int, index int64) ([]byte, error),
+) ([][]byte, error) {
+	values := make([][]byte, len(parents))
+	hashRange := func(hasher func(l, r []byte) []byte, from, to int) error {
+		for i := from; i < to; i++ {
+			left, err := node(height, parents[i]<<1)
+			if err != nil {
+				return err
+			}
+			right, err := node(height, parents[i]<<1|1)
+			if err != nil {
+				return err
+			}
+			values[i] = hasher(left, right)
+		}
+		return nil
+	}
+	workers := len(hashers)
+	if len(parents) < minParallelNodes || workers == 1 {
+		return values, hashRange(hashers[0], 0, len(parents))
+	}
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, workers)
+		size = (len(parents) + workers - 1) / workers
+	)
+	for w := 0; w < workers; w++ {
+		from, to := w*size, (w+1)*size
+		if from >= len(parents) {
+			break
+		}
+		if to > len(parents) {
+			to = len(parents)
+		}
+		wg.Add(1)
+		go func(w, from, to int) {
+			defer wg.Done()
+			errs[w] = hashRange(hashers[w], from, to)
+		}(w, from, to)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockBatch(size int, maxHeight int, seed int64) map[int64][]byte {
+	hashState := MockState(size)
+	r := rand.New(rand.NewSource(seed))
+	leaves := make(map[int64][]byte)
+	for i := 0; i < size; i++ {
+		// dense and scattered leaves
+		if i%2 == 0 {
+			leaves[int64(i)] = hashState[i]
+		} else {
+			leaves[r.Int63n(1<<maxHeight)] = hashState[i]
+		}
+	}
+	return leaves
+}
+
+func TestBatchUpdate(t *testing.T) {
+	for _, newHasher := range []func() Hasher{nil, NewMiMCHasher} {
+		tree, err := NewEmptyTree(32, NilHash, mimc.NewMiMC())
+		require.NoError(t, err)
+		tree.NewHasher = newHasher
+		expected, err := NewEmptyTree(32, NilHash, mimc.NewMiMC())
+		require.NoError(t, err)
+		for i, batch := range []map[int64][]byte{mockBatch(1000, 32, 1), mockBatch(300, 32, 2), {}} {
+			require.NoError(t, tree.BatchUpdate(batch))
+			for index, value := range batch {
+				require.NoError(t, expected.Update(index, value))
+			}
+			assert.Equal(t, expected.Root(), tree.Root(), "batch %d", i)
+		}
+		for _, index := range []int64{0, 1, 998, 1 << 20, 1<<32 - 1} {
+			proofs, helpers, err := tree.BuildMerkleProofs(index)
+			require.NoError(t, err)
+			expectedProofs, expectedHelpers, err := expected.BuildMerkleProofs(index)
+			require.NoError(t, err)
+			assert.Equal(t, expectedProofs, proofs)
+			assert.Equal(t, expectedHelpers, helpers)
+		}
+		require.NoError(t, tree.Commit(1))
+
+		root := tree.Root()
+		err = tree.BatchUpdate(map[int64][]byte{0: NilHash, 1 << 32: NilHash})
+		assert.Equal(t, ErrInvalidIndex, err)
+		assert.Equal(t, root, tree.Root())
+	}
+}
+
+/*
+	failingGetStore: the reads fail once failGets is set
+*/
+type failingGetStore struct {
+	NodeStore
+	failGets bool
+}
+
+func (s *failingGetStore) Get(key []byte) ([]byte, error) {
+	if s.failGets {
+		return nil, errors.New("get failed")
+	}
+	return s.NodeStore.Get(key)
+}
+
+func TestBatchUpdateFailure(t *testing.T) {
+	store := &failingGetStore{NodeStore: NewMemoryNodeStore()}
+	tree, err := OpenTree(store, 16, NilHash, NewMiMCHasher())
+	require.NoError(t, err)
+	require.NoError(t, tree.BatchUpdate(mockBatch(100, 16, 1)))
+	require.NoError(t, tree.Commit(1))
+	root := tree.Root()
+	leaf, err := tree.Leaf(2)
+	require.NoError(t, err)
+
+	// the leaves aren't written under the old root when the batch can't be hashed
+	store.failGets = true
+	assert.NotNil(t, tree.BatchUpdate(map[int64][]byte{2: MockState(200)[150], 1 << 10: MockState(200)[151]}))
+	store.failGets = false
+	assert.Equal(t, root, tree.Root())
+	current, err := tree.Leaf(2)
+	require.NoError(t, err)
+	assert.Equal(t, leaf, current)
+	assert.Equal(t, 0, len(tree.dirty))
+
+	// the hashers of the workers must hash as Hasher
+	tree.NewHasher = NewMiMCDomainHasher
+	assert.Equal(t, ErrHasherMismatch, tree.BatchUpdate(map[int64][]byte{2: NilHash}))
+	assert.Equal(t, root, tree.Root())
+	tree.NewHasher = NewMiMCHasher
+	require.NoError(t, tree.BatchUpdate(map[int64][]byte{2: NilHash}))
+	assert.NotEqual(t, root, tree.Root())
+}
+
+func BenchmarkUpdate(b *testing.B) {
+	batch := mockBatch(1000, 32, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree, err := NewEmptyTree(32, NilHash, mimc.NewMiMC())
+		if err != nil {
+			b.Fatal(err)
+		}
+		for index, value := range batch {
+			err = tree.Update(index, value)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkBatchUpdate(b *testing.B) {
+	batch := mockBatch(1000, 32, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree, err := NewEmptyTree(32, NilHash, mimc.NewMiMC())
+		if err != nil {
+			b.Fatal(err)
+		}
+		tree.NewHasher = NewMiMCHasher
+		err = tree.BatchUpdate(batch)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
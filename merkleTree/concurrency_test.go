@@ -0,0 +1,110 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+	TestConcurrentReads: run with -race, the readers check that every proof they get matches the root they read it at,
+	the snapshots pruned by the writer return ErrVersionNotFound
+*/
+func TestConcurrentReads(t *testing.T) {
+	const versions = 20
+	hashState := MockState(4 * (versions + 1))
+	tree, err := NewEmptyTree(16, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	tree.NewHasher = NewMiMCHasher
+	commitVersion := func(v int64) {
+		require.NoError(t, tree.Update(v, hashState[4*v]))
+		require.NoError(t, tree.BatchUpdate(map[int64][]byte{
+			100 + v: hashState[4*v+1],
+			200 + v: hashState[4*v+2],
+		}))
+		require.NoError(t, tree.Commit(v))
+	}
+	commitVersion(1)
+	shared, err := tree.LatestSnapshot()
+	require.NoError(t, err)
+	sharedRoot := shared.Root()
+
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for v := int64(2); v <= versions; v++ {
+			commitVersion(v)
+			if v%5 == 0 {
+				require.NoError(t, tree.Prune(v-3))
+			}
+		}
+	}()
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			hasher := NewMiMCHasher()
+			for i := int64(0); ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				index := 100 + i%versions
+				// the snapshot taken before the writer started
+				merkleProofs, _, err := shared.BuildMerkleProofs(index)
+				if err != ErrVersionNotFound {
+					assert.NoError(t, err)
+					leaf, err := shared.Leaf(index)
+					if err != ErrVersionNotFound {
+						assert.NoError(t, err)
+						assert.True(t, VerifyInclusion(sharedRoot, leaf, index, merkleProofs, tree.MaxHeight, hasher))
+					}
+				}
+				// the last committed version
+				snapshot, err := tree.LatestSnapshot()
+				assert.NoError(t, err)
+				proof, err := snapshot.BuildMultiProof([]int64{index, index + 100, int64(r)})
+				if err != ErrVersionNotFound {
+					assert.NoError(t, err)
+					assert.True(t, VerifyMultiProof(snapshot.Root(), tree.MaxHeight, proof, hasher))
+				}
+				// the tree itself
+				_ = tree.Root()
+				merkleProofs, helpers, err := tree.BuildMerkleProofs(index)
+				assert.NoError(t, err)
+				assert.Len(t, merkleProofs, 16)
+				assert.Len(t, helpers, 16)
+				_ = tree.HashSubTrees(NilHash, NilHash)
+			}
+		}(r)
+	}
+	wg.Wait()
+	assert.Equal(t, int64(versions), tree.Version())
+	assert.Equal(t, int64(versions-3), tree.OldestVersion())
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bytes"
+	"errors"
+	"log"
+)
+
+var ErrLeafNotEmpty = errors.New("[smt] leaf is not empty")
+
+/*
+	ExclusionProof: proof that the leaf at Index is the nil leaf, MerkleProofs are the siblings from the leaf to the root
+*/
+type ExclusionProof struct {
+	Index        int64
+	MerkleProofs [][]byte
+}
+
+/*
+	BuildExclusionProof: prove that the leaf is empty, such as the slot of a new account or nft
+*/
+func (t *Tree) BuildExclusionProof(index int64) (*ExclusionProof, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.buildExclusionProof(index, t.node)
+}
+
+/*
+	BuildExclusionProof: same as Tree.BuildExclusionProof at the version of the snapshot
+*/
+func (s *TreeSnapshot) BuildExclusionProof(index int64) (proof *ExclusionProof, err error) {
+	err = s.read(func() error {
+		proof, err = s.tree.buildExclusionProof(index, s.node)
+		return err
+	})
+	return proof, err
+}
+
+func (t *Tree) buildExclusionProof(index int64, node func(height int, index int64) ([]byte, error)) (*ExclusionProof, error) {
+	leaf, err := t.leaf(index, node)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(leaf, t.NilHashValueConst[0]) {
+		log.Println("[BuildExclusionProof] leaf is not empty:", index)
+		return nil, ErrLeafNotEmpty
+	}
+	merkleProofs, _, err := t.buildMerkleProofs(index, node)
+	if err != nil {
+		return nil, err
+	}
+	return &ExclusionProof{
+		Index:        index,
+		MerkleProofs: merkleProofs,
+	}, nil
+}
+
+/*
+	ComputeRoot: root of a tree holding the leaf at the index, the merkle proofs are the siblings
+	from the leaf to the root and the bits of the index tell on which side they are
+*/
+func ComputeRoot(leaf []byte, index int64, merkleProofs [][]byte, hasher Hasher) ([]byte, error) {
+	if len(merkleProofs) == 0 || len(merkleProofs) > maxTreeHeight || index < 0 || index >= 1<<len(merkleProofs) {
+		log.Println("[ComputeRoot] invalid index or merkle proofs length")
+		return nil, ErrInvalidIndex
+	}
+	node := leaf
+	for _, sibling := range merkleProofs {
+		if index&1 == Left {
+			node = hasher.HashNode(node, sibling)
+		} else {
+			node = hasher.HashNode(sibling, node)
+		}
+		index >>= 1
+	}
+	return node, nil
+}
+
+/*
+	VerifyInclusion: check that the leaf is at the index of the tree with the given root and height,
+	proofs of another height are refused, a proof of a smaller tree would hold for the root of any subtree
+*/
+func VerifyInclusion(root, leaf []byte, index int64, merkleProofs [][]byte, maxHeight int, hasher Hasher) bool {
+	if maxHeight <= 0 || len(merkleProofs) != maxHeight {
+		return false
+	}
+	node, err := ComputeRoot(leaf, index, merkleProofs, hasher)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(node, root)
+}
+
+/*
+	VerifyExclusionProof: check that the leaf at the index of the proof is the nil leaf of the tree with the given root and height
+*/
+func VerifyExclusionProof(root, nilHash []byte, maxHeight int, proof *ExclusionProof, hasher Hasher) bool {
+	if proof == nil {
+		return false
+	}
+	return VerifyInclusion(root, nilHash, proof.Index, proof.MerkleProofs, maxHeight, hasher)
+}
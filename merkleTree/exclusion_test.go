@@ -0,0 +1,80 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExclusionProof(t *testing.T) {
+	hashState := MockState(2)
+	tree, err := NewEmptyTree(40, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	// empty tree
+	proof, err := tree.BuildExclusionProof(7)
+	require.NoError(t, err)
+	assert.True(t, VerifyExclusionProof(tree.Root(), NilHash, 40, proof, NewMiMCHasher()))
+
+	require.NoError(t, tree.Update(7, hashState[0]))
+	require.NoError(t, tree.Update(1<<39, hashState[1]))
+	_, err = tree.BuildExclusionProof(7)
+	assert.Equal(t, ErrLeafNotEmpty, err)
+	for _, index := range []int64{6, 8, 1<<39 + 1, 1<<40 - 1} {
+		proof, err := tree.BuildExclusionProof(index)
+		require.NoError(t, err)
+		assert.True(t, VerifyExclusionProof(tree.Root(), NilHash, 40, proof, NewMiMCHasher()))
+		// the proof only holds for the nil leaf, and not at the index of a non-empty leaf
+		assert.False(t, VerifyExclusionProof(tree.Root(), hashState[0], 40, proof, NewMiMCHasher()))
+		proof.Index = 7
+		assert.False(t, VerifyExclusionProof(tree.Root(), NilHash, 40, proof, NewMiMCHasher()))
+	}
+
+	// the sibling of a non-empty leaf proves its inclusion
+	merkleProofs, _, err := tree.BuildMerkleProofs(7)
+	require.NoError(t, err)
+	assert.True(t, VerifyInclusion(tree.Root(), hashState[0], 7, merkleProofs, 40, NewMiMCHasher()))
+	assert.False(t, VerifyInclusion(tree.Root(), hashState[0], 1<<40, merkleProofs, 40, NewMiMCHasher()))
+	assert.False(t, VerifyExclusionProof(tree.Root(), NilHash, 40, &ExclusionProof{Index: 7, MerkleProofs: merkleProofs}, NewMiMCHasher()))
+	assert.False(t, VerifyExclusionProof(tree.Root(), NilHash, 40, nil, NewMiMCHasher()))
+
+	// proofs of another height are refused, a node of the tree is the root of a smaller tree
+	shallowProof := &ExclusionProof{Index: 3, MerkleProofs: merkleProofs[:2]}
+	node, err := ComputeRoot(NilHash, 3, shallowProof.MerkleProofs, NewMiMCHasher())
+	require.NoError(t, err)
+	assert.True(t, VerifyExclusionProof(node, NilHash, 2, shallowProof, NewMiMCHasher()))
+	assert.False(t, VerifyExclusionProof(node, NilHash, 40, shallowProof, NewMiMCHasher()))
+	assert.False(t, VerifyInclusion(node, NilHash, 3, shallowProof.MerkleProofs, 40, NewMiMCHasher()))
+	assert.False(t, VerifyExclusionProof(tree.Root(), NilHash, 41, proof, NewMiMCHasher()))
+
+	// a past version
+	require.NoError(t, tree.Commit(1))
+	root := tree.Root()
+	require.NoError(t, tree.Update(9, hashState[0]))
+	require.NoError(t, tree.Commit(2))
+	snapshot, err := tree.Snapshot(1)
+	require.NoError(t, err)
+	proof, err = snapshot.BuildExclusionProof(9)
+	require.NoError(t, err)
+	assert.True(t, VerifyExclusionProof(root, NilHash, 40, proof, NewMiMCHasher()))
+	_, err = tree.BuildExclusionProof(9)
+	assert.Equal(t, ErrLeafNotEmpty, err)
+}
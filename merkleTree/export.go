@@ -0,0 +1,200 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+)
+
+// format version of Export
+const treeExportVersion = 1
+
+var (
+	ErrInvalidTreeExport = errors.New("[smt] invalid tree export")
+	ErrTreeNotEmpty      = errors.New("[smt] the tree is not empty")
+	ErrRootMismatch      = errors.New("[smt] the root doesn't match the exported root")
+)
+
+/*
+	ForEachLeaf: call f with the non-empty leaves in index order, the empty subtrees are skipped
+*/
+func (t *Tree) ForEachLeaf(f func(index int64, leaf []byte) error) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.forEachLeaf(t.MaxHeight, 0, t.node, f)
+}
+
+/*
+	ForEachLeaf: same as Tree.ForEachLeaf at the version of the snapshot
+*/
+func (s *TreeSnapshot) ForEachLeaf(f func(index int64, leaf []byte) error) error {
+	return s.read(func() error {
+		return s.tree.forEachLeaf(s.tree.MaxHeight, 0, s.node, f)
+	})
+}
+
+func (t *Tree) forEachLeaf(
+	height int, index int64,
+	node func(height int, index int64) ([]byte, error),
+	f func(index int64, leaf []byte) error,
+) error {
+	value, err := node(height, index)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(value, t.NilHashValueConst[height]) {
+		return nil
+	}
+	if height == 0 {
+		return f(index, value)
+	}
+	if err = t.forEachLeaf(height-1, index<<1, node, f); err != nil {
+		return err
+	}
+	return t.forEachLeaf(height-1, index<<1|1, node, f)
+}
+
+/*
+	Export: write the format version, uvarint height, uvarint hash size, the root, uvarint leaf count,
+	then the uvarint index and the value of every non-empty leaf in index order
+*/
+func (t *Tree) Export(w io.Writer) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.export(w, t.root, t.node)
+}
+
+/*
+	Export: same as Tree.Export at the version of the snapshot
+*/
+func (s *TreeSnapshot) Export(w io.Writer) error {
+	return s.read(func() error {
+		return s.tree.export(w, s.root, s.node)
+	})
+}
+
+func (t *Tree) export(w io.Writer, root []byte, node func(height int, index int64) ([]byte, error)) error {
+	var (
+		indexes []int64
+		leaves  [][]byte
+	)
+	err := t.forEachLeaf(t.MaxHeight, 0, node, func(index int64, leaf []byte) error {
+		if len(leaf) != len(root) {
+			log.Println("[Export] leaf of another size than the root:", index)
+			return ErrInvalidTreeExport
+		}
+		indexes = append(indexes, index)
+		leaves = append(leaves, leaf)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	buf := []byte{treeExportVersion}
+	buf = appendUvarint(buf, uint64(t.MaxHeight))
+	buf = appendUvarint(buf, uint64(len(root)))
+	buf = append(buf, root...)
+	buf = appendUvarint(buf, uint64(len(indexes)))
+	for i, index := range indexes {
+		buf = appendUvarint(buf, uint64(index))
+		buf = append(buf, leaves[i]...)
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+/*
+	Import: set the leaves of an export into the tree and check the root, the tree must be empty with no updates
+	since its last commit, and it's left empty if the export is invalid. The tree keeps the imported leaves
+	uncommitted. Unless r is an io.ByteReader, it may be read past the end of the export.
+*/
+func (t *Tree) Import(r io.Reader) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.dirty) != 0 || !t.isEmptyTree() {
+		return ErrTreeNotEmpty
+	}
+	br, ok := r.(byteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	root, leaves, err := t.readExport(br)
+	if err == nil {
+		err = t.batchUpdate(leaves)
+	}
+	if err == nil && !bytes.Equal(t.root, root) {
+		log.Println("[Import] root mismatch")
+		err = ErrRootMismatch
+	}
+	if err != nil {
+		t.dirty = make(map[nodeKey][]byte)
+		t.root = t.NilHashValueConst[t.MaxHeight]
+		return err
+	}
+	return nil
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+func (t *Tree) readExport(r byteReader) (root []byte, leaves map[int64][]byte, err error) {
+	version, err := r.ReadByte()
+	if err != nil || version != treeExportVersion {
+		return nil, nil, ErrInvalidTreeExport
+	}
+	height, err := binary.ReadUvarint(r)
+	if err != nil || height != uint64(t.MaxHeight) {
+		log.Println("[Import] tree of another height:", height)
+		return nil, nil, ErrInvalidTreeExport
+	}
+	hashSize, err := binary.ReadUvarint(r)
+	if err != nil || hashSize != uint64(len(t.root)) {
+		return nil, nil, ErrInvalidTreeExport
+	}
+	root = make([]byte, hashSize)
+	if _, err = io.ReadFull(r, root); err != nil {
+		return nil, nil, ErrInvalidTreeExport
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil || count > 1<<t.MaxHeight {
+		return nil, nil, ErrInvalidTreeExport
+	}
+	leaves = make(map[int64][]byte)
+	next := uint64(0)
+	for i := uint64(0); i < count; i++ {
+		index, err := binary.ReadUvarint(r)
+		// the indexes are increasing
+		if err != nil || index < next || index >= 1<<t.MaxHeight {
+			return nil, nil, ErrInvalidTreeExport
+		}
+		next = index + 1
+		leaf := make([]byte, hashSize)
+		if _, err = io.ReadFull(r, leaf); err != nil {
+			return nil, nil, ErrInvalidTreeExport
+		}
+		leaves[int64(index)] = leaf
+	}
+	return root, leaves, nil
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeExport(t *testing.T) {
+	hashState := MockState(3)
+	tree, err := NewEmptyTree(40, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	leaves := map[int64][]byte{1<<39 + 3: hashState[0], 7: hashState[1], 8: hashState[2]}
+	require.NoError(t, tree.BatchUpdate(leaves))
+	var indexes []int64
+	require.NoError(t, tree.ForEachLeaf(func(index int64, leaf []byte) error {
+		assert.Equal(t, leaves[index], leaf)
+		indexes = append(indexes, index)
+		return nil
+	}))
+	assert.Equal(t, []int64{7, 8, 1<<39 + 3}, indexes)
+
+	var buf bytes.Buffer
+	require.NoError(t, tree.Export(&buf))
+	imported, err := NewEmptyTree(40, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	require.NoError(t, imported.Import(bytes.NewReader(buf.Bytes())))
+	assert.Equal(t, tree.Root(), imported.Root())
+	assert.Equal(t, ErrTreeNotEmpty, imported.Import(bytes.NewReader(buf.Bytes())))
+
+	// a past version
+	require.NoError(t, tree.Commit(1))
+	snapshot, err := tree.Snapshot(1)
+	require.NoError(t, err)
+	require.NoError(t, tree.Update(7, NilHash))
+	var snapshotBuf bytes.Buffer
+	require.NoError(t, snapshot.Export(&snapshotBuf))
+	assert.Equal(t, buf.Bytes(), snapshotBuf.Bytes())
+	buf.Reset()
+	require.NoError(t, tree.Export(&buf))
+	imported, err = NewEmptyTree(40, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	require.NoError(t, imported.Import(&buf))
+	assert.Equal(t, tree.Root(), imported.Root())
+	leaf, err := imported.Leaf(7)
+	require.NoError(t, err)
+	assert.Equal(t, NilHash, leaf)
+}
+
+func TestTreeImportInvalid(t *testing.T) {
+	hashState := MockState(2)
+	tree, err := NewEmptyTree(8, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	require.NoError(t, tree.Update(3, hashState[0]))
+	require.NoError(t, tree.Update(9, hashState[1]))
+	var buf bytes.Buffer
+	require.NoError(t, tree.Export(&buf))
+	export := buf.Bytes()
+
+	invalid := map[string][]byte{
+		"truncated": export[:len(export)-1],
+		"version":   append([]byte{2}, export[1:]...),
+		"height":    append([]byte{1, 9}, export[2:]...),
+	}
+	// a tampered leaf changes the root
+	tampered := append([]byte{}, export...)
+	tampered[len(tampered)-1] ^= 1
+	for name, data := range invalid {
+		imported, err := NewEmptyTree(8, NilHash, mimc.NewMiMC())
+		require.NoError(t, err)
+		assert.Equal(t, ErrInvalidTreeExport, imported.Import(bytes.NewReader(data)), name)
+		assert.True(t, imported.IsEmptyTree(), name)
+	}
+	imported, err := NewEmptyTree(8, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	assert.Equal(t, ErrRootMismatch, imported.Import(bytes.NewReader(tampered)))
+	assert.True(t, imported.IsEmptyTree())
+	require.NoError(t, imported.Import(bytes.NewReader(export)))
+	assert.Equal(t, tree.Root(), imported.Root())
+}
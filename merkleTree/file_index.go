@@ -0,0 +1,481 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"hash/fnv"
+	"log"
+	"os"
+)
+
+const (
+	fileIndexMagic  = "ZKBNBIX1"
+	indexHeaderSize = 64
+	indexSlotSize   = 32
+	// slots read at once by a lookup
+	indexProbeSlots = 8
+	// slots read at once when the whole index is read
+	indexScanSlots = 1 << 10
+	minIndexSlots  = 1 << 10
+
+	slotEmpty   = 0
+	slotDeleted = 1
+)
+
+var (
+	ErrInvalidIndexFile = errors.New("[FileNodeStore] invalid index file")
+)
+
+/*
+	fileIndex: on-disk hash table with linear probing from the keys of a FileNodeStore to their records.
+	A slot holds the hash of the key and the offsets of the key and the value in the log, the key is read
+	back from the log to check it. The slots are written in place, the header is marked dirty before
+	the first change and clean once the slots are synced, a dirty index is rebuilt from the log.
+*/
+type fileIndex struct {
+	path    string
+	file    *os.File
+	log     *os.File
+	nbSlots uint64
+	// live keys
+	count uint64
+	// live keys and deleted slots
+	used uint64
+	// the records of the log before this offset are in the index
+	logSize int64
+	// size of the live keys and values in the log
+	liveBytes int64
+	dirty     bool
+}
+
+type indexSlot struct {
+	hash        uint64
+	keyOffset   int64
+	valueOffset int64
+	keyLen      uint32
+	valueLen    uint32
+}
+
+func indexPath(path string) string {
+	return path + ".index"
+}
+
+/*
+	indexSlotsFor: number of slots of an index holding count keys at most half full
+*/
+func indexSlotsFor(count uint64) uint64 {
+	nbSlots := uint64(minIndexSlots)
+	for (count+1)*2 > nbSlots {
+		nbSlots *= 2
+	}
+	return nbSlots
+}
+
+func keyHash(key []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(key)
+	hash := h.Sum64()
+	if hash <= slotDeleted {
+		hash += slotDeleted + 1
+	}
+	return hash
+}
+
+func (s indexSlot) size() int64 {
+	return s.valueOffset + int64(s.valueLen) - s.keyOffset
+}
+
+func decodeSlot(buf []byte) indexSlot {
+	return indexSlot{
+		hash:        binary.BigEndian.Uint64(buf[0:8]),
+		keyOffset:   int64(binary.BigEndian.Uint64(buf[8:16])),
+		valueOffset: int64(binary.BigEndian.Uint64(buf[16:24])),
+		keyLen:      binary.BigEndian.Uint32(buf[24:28]),
+		valueLen:    binary.BigEndian.Uint32(buf[28:32]),
+	}
+}
+
+func encodeSlot(s indexSlot) []byte {
+	buf := make([]byte, indexSlotSize)
+	binary.BigEndian.PutUint64(buf[0:8], s.hash)
+	binary.BigEndian.PutUint64(buf[8:16], uint64(s.keyOffset))
+	binary.BigEndian.PutUint64(buf[16:24], uint64(s.valueOffset))
+	binary.BigEndian.PutUint32(buf[24:28], s.keyLen)
+	binary.BigEndian.PutUint32(buf[28:32], s.valueLen)
+	return buf
+}
+
+func slotOffset(pos uint64) int64 {
+	return indexHeaderSize + int64(pos)*indexSlotSize
+}
+
+/*
+	newFileIndex: create an empty dirty index at path, replacing the file if it exists
+*/
+func newFileIndex(path string, logFile *os.File, nbSlots uint64) (*fileIndex, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		log.Println("[FileNodeStore] unable to create index:", err)
+		return nil, err
+	}
+	x := &fileIndex{
+		path:    path,
+		file:    file,
+		log:     logFile,
+		nbSlots: nbSlots,
+		logSize: int64(len(fileStoreMagic)),
+		dirty:   true,
+	}
+	err = file.Truncate(slotOffset(nbSlots))
+	if err == nil {
+		err = x.writeHeader()
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return x, nil
+}
+
+/*
+	loadFileIndex: open the index of a log of logSize bytes, a missing, dirty or invalid index is replaced by an empty one
+*/
+func loadFileIndex(path string, logFile *os.File, logSize int64) (*fileIndex, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("[FileNodeStore] unable to open index:", err)
+			return nil, err
+		}
+		return newFileIndex(path, logFile, minIndexSlots)
+	}
+	x, err := readIndexHeader(file, logFile, logSize)
+	if err != nil {
+		log.Println("[FileNodeStore] rebuilding the index:", err)
+		file.Close()
+		return newFileIndex(path, logFile, minIndexSlots)
+	}
+	x.path = path
+	return x, nil
+}
+
+func readIndexHeader(file, logFile *os.File, logSize int64) (*fileIndex, error) {
+	header := make([]byte, indexHeaderSize)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return nil, ErrInvalidIndexFile
+	}
+	if string(header[:len(fileIndexMagic)]) != fileIndexMagic ||
+		crc32.ChecksumIEEE(header[:56]) != binary.BigEndian.Uint32(header[56:60]) {
+		return nil, ErrInvalidIndexFile
+	}
+	if header[8] != 1 {
+		// the process stopped while the index was written
+		return nil, ErrInvalidIndexFile
+	}
+	x := &fileIndex{
+		file:      file,
+		log:       logFile,
+		nbSlots:   binary.BigEndian.Uint64(header[16:24]),
+		count:     binary.BigEndian.Uint64(header[24:32]),
+		used:      binary.BigEndian.Uint64(header[32:40]),
+		logSize:   int64(binary.BigEndian.Uint64(header[40:48])),
+		liveBytes: int64(binary.BigEndian.Uint64(header[48:56])),
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if x.nbSlots < minIndexSlots || x.nbSlots&(x.nbSlots-1) != 0 || info.Size() != slotOffset(x.nbSlots) ||
+		x.used < x.count || x.used >= x.nbSlots ||
+		x.logSize < int64(len(fileStoreMagic)) || x.logSize > logSize {
+		return nil, ErrInvalidIndexFile
+	}
+	return x, nil
+}
+
+func (x *fileIndex) writeHeader() error {
+	header := make([]byte, indexHeaderSize)
+	copy(header, fileIndexMagic)
+	if !x.dirty {
+		header[8] = 1
+	}
+	binary.BigEndian.PutUint64(header[16:24], x.nbSlots)
+	binary.BigEndian.PutUint64(header[24:32], x.count)
+	binary.BigEndian.PutUint64(header[32:40], x.used)
+	binary.BigEndian.PutUint64(header[40:48], uint64(x.logSize))
+	binary.BigEndian.PutUint64(header[48:56], uint64(x.liveBytes))
+	binary.BigEndian.PutUint32(header[56:60], crc32.ChecksumIEEE(header[:56]))
+	_, err := x.file.WriteAt(header, 0)
+	return err
+}
+
+/*
+	markDirty: mark the index dirty before its first change since it was synced
+*/
+func (x *fileIndex) markDirty() error {
+	if x.dirty {
+		return nil
+	}
+	x.dirty = true
+	err := x.writeHeader()
+	if err == nil {
+		err = x.file.Sync()
+	}
+	return err
+}
+
+/*
+	flush: sync the slots and mark the index clean
+*/
+func (x *fileIndex) flush() error {
+	if !x.dirty {
+		return nil
+	}
+	if err := x.file.Sync(); err != nil {
+		return err
+	}
+	x.dirty = false
+	err := x.writeHeader()
+	if err == nil {
+		err = x.file.Sync()
+	}
+	return err
+}
+
+/*
+	find: slot of the key, or the first free slot of its probe sequence if the key isn't in the index
+*/
+func (x *fileIndex) find(key []byte) (pos uint64, slot indexSlot, found bool, err error) {
+	hash := keyHash(key)
+	buf := make([]byte, indexProbeSlots*indexSlotSize)
+	hasFree := false
+	var free indexSlot
+	var freePos uint64
+	pos = hash & (x.nbSlots - 1)
+	for probed := uint64(0); probed < x.nbSlots; {
+		n := uint64(indexProbeSlots)
+		if pos+n > x.nbSlots {
+			n = x.nbSlots - pos
+		}
+		if _, err = x.file.ReadAt(buf[:n*indexSlotSize], slotOffset(pos)); err != nil {
+			log.Println("[FileNodeStore] unable to read index:", err)
+			return 0, indexSlot{}, false, err
+		}
+		for i := uint64(0); i < n; i++ {
+			slot = decodeSlot(buf[i*indexSlotSize:])
+			switch slot.hash {
+			case slotEmpty:
+				if hasFree {
+					return freePos, free, false, nil
+				}
+				return pos + i, slot, false, nil
+			case slotDeleted:
+				if !hasFree {
+					hasFree, free, freePos = true, slot, pos+i
+				}
+			case hash:
+				stored := make([]byte, slot.keyLen)
+				if _, err = x.log.ReadAt(stored, slot.keyOffset); err != nil {
+					log.Println("[FileNodeStore] unable to read key:", err)
+					return 0, indexSlot{}, false, err
+				}
+				if bytes.Equal(stored, key) {
+					return pos + i, slot, true, nil
+				}
+			}
+		}
+		probed += n
+		pos = (pos + n) & (x.nbSlots - 1)
+	}
+	if hasFree {
+		return freePos, free, false, nil
+	}
+	return 0, indexSlot{}, false, ErrInvalidIndexFile
+}
+
+func (x *fileIndex) writeSlot(pos uint64, slot indexSlot) error {
+	_, err := x.file.WriteAt(encodeSlot(slot), slotOffset(pos))
+	if err != nil {
+		log.Println("[FileNodeStore] unable to write index:", err)
+	}
+	return err
+}
+
+func (x *fileIndex) get(key []byte) (indexSlot, bool, error) {
+	_, slot, found, err := x.find(key)
+	return slot, found, err
+}
+
+func (x *fileIndex) put(key []byte, op recordOp) error {
+	if (x.used+1)*4 > x.nbSlots*3 {
+		if err := x.rehash(indexSlotsFor(x.count + 1)); err != nil {
+			return err
+		}
+	}
+	pos, old, found, err := x.find(key)
+	if err != nil {
+		return err
+	}
+	slot := indexSlot{
+		hash:        keyHash(key),
+		keyOffset:   op.keyOffset,
+		valueOffset: op.ref.offset,
+		keyLen:      uint32(len(key)),
+		valueLen:    uint32(op.ref.length),
+	}
+	if err = x.writeSlot(pos, slot); err != nil {
+		return err
+	}
+	if found {
+		x.liveBytes -= old.size()
+	} else {
+		x.count++
+		if old.hash == slotEmpty {
+			x.used++
+		}
+	}
+	x.liveBytes += slot.size()
+	return nil
+}
+
+func (x *fileIndex) delete(key []byte) error {
+	pos, old, found, err := x.find(key)
+	if err != nil || !found {
+		return err
+	}
+	if err = x.writeSlot(pos, indexSlot{hash: slotDeleted}); err != nil {
+		return err
+	}
+	x.count--
+	x.liveBytes -= old.size()
+	return nil
+}
+
+func (x *fileIndex) apply(ops []recordOp) error {
+	for _, op := range ops {
+		var err error
+		if op.delete {
+			err = x.delete([]byte(op.key))
+		} else {
+			err = x.put([]byte(op.key), op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+	forEach: call f with every live slot
+*/
+func (x *fileIndex) forEach(f func(slot indexSlot) error) error {
+	buf := make([]byte, indexScanSlots*indexSlotSize)
+	for pos := uint64(0); pos < x.nbSlots; pos += indexScanSlots {
+		n := uint64(indexScanSlots)
+		if pos+n > x.nbSlots {
+			n = x.nbSlots - pos
+		}
+		if _, err := x.file.ReadAt(buf[:n*indexSlotSize], slotOffset(pos)); err != nil {
+			log.Println("[FileNodeStore] unable to read index:", err)
+			return err
+		}
+		for i := uint64(0); i < n; i++ {
+			slot := decodeSlot(buf[i*indexSlotSize:])
+			if slot.hash == slotEmpty || slot.hash == slotDeleted {
+				continue
+			}
+			if err := f(slot); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/*
+	insertSlot: put a slot whose key isn't in the index into the first free slot of its probe sequence
+*/
+func (x *fileIndex) insertSlot(slot indexSlot) error {
+	buf := make([]byte, indexSlotSize)
+	pos := slot.hash & (x.nbSlots - 1)
+	for probed := uint64(0); probed < x.nbSlots; probed++ {
+		if _, err := x.file.ReadAt(buf, slotOffset(pos)); err != nil {
+			return err
+		}
+		if decodeSlot(buf).hash == slotEmpty {
+			if err := x.writeSlot(pos, slot); err != nil {
+				return err
+			}
+			x.count++
+			x.used++
+			x.liveBytes += slot.size()
+			return nil
+		}
+		pos = (pos + 1) & (x.nbSlots - 1)
+	}
+	return ErrInvalidIndexFile
+}
+
+/*
+	rehash: copy the live slots into a new index of nbSlots slots which then replaces the index file,
+	the index stays dirty until it's flushed
+*/
+func (x *fileIndex) rehash(nbSlots uint64) (err error) {
+	tmpPath := x.path + ".tmp"
+	next, err := newFileIndex(tmpPath, x.log, nbSlots)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			next.file.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+	if err = x.forEach(next.insertSlot); err != nil {
+		return err
+	}
+	next.logSize = x.logSize
+	if err = next.writeHeader(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, x.path); err != nil {
+		log.Println("[FileNodeStore] unable to replace the index file:", err)
+		return err
+	}
+	x.file.Close()
+	x.file = next.file
+	x.nbSlots = next.nbSlots
+	x.count = next.count
+	x.used = next.used
+	x.liveBytes = next.liveBytes
+	return nil
+}
+
+func (x *fileIndex) close() error {
+	err := x.flush()
+	if closeErr := x.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,454 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sync"
+)
+
+const (
+	fileStoreMagic = "ZKBNBNS1"
+	// a record starts with the length and the crc32 of its payload
+	recordHeaderSize = 8
+
+	opPut    = 1
+	opDelete = 2
+
+	// the records written by Compact are cut at this size
+	compactRecordSize = 1 << 20
+
+	DefaultAutoCompactSize = 64 << 20
+)
+
+var (
+	ErrInvalidStoreFile = errors.New("[FileNodeStore] invalid store file")
+)
+
+/*
+	FileNodeStore: embedded NodeStore kept in an append-only log file and an on-disk hash index (the file
+	with the ".index" suffix), nothing is kept in memory per key.
+	Every Write appends one checksummed record and syncs the file, a record cut by a crash is dropped
+	when the file is opened again. Close syncs the index so that Open only replays the records written
+	after it, an index left dirty by a crash is rebuilt from the log.
+	The space of overwritten and deleted values is reclaimed by Compact, Write calls it when this space
+	is over AutoCompactSize and over the size of the live values, a failed compaction doesn't fail the Write.
+*/
+type FileNodeStore struct {
+	// 0 disables the compaction by Write
+	AutoCompactSize int64
+
+	mu    sync.RWMutex
+	path  string
+	file  *os.File
+	size  int64
+	index *fileIndex
+	// bytes of the log replayed by Open
+	replayed int64
+}
+
+type valueRef struct {
+	offset int64
+	length int
+}
+
+type recordOp struct {
+	key       string
+	keyOffset int64
+	ref       valueRef
+	delete    bool
+}
+
+func OpenFileNodeStore(path string) (*FileNodeStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		log.Println("[OpenFileNodeStore] unable to open file:", err)
+		return nil, err
+	}
+	s := &FileNodeStore{AutoCompactSize: DefaultAutoCompactSize, path: path, file: file}
+	err = s.load()
+	if err != nil {
+		if s.index != nil {
+			s.index.file.Close()
+		}
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+/*
+	load: open the index and replay the records of the file it doesn't hold yet
+*/
+func (s *FileNodeStore) load() (err error) {
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	fileSize := info.Size()
+	if fileSize == 0 {
+		_, err = s.file.WriteAt([]byte(fileStoreMagic), 0)
+		if err == nil {
+			err = s.file.Sync()
+		}
+		if err != nil {
+			return err
+		}
+		s.size = int64(len(fileStoreMagic))
+		s.index, err = newFileIndex(indexPath(s.path), s.file, minIndexSlots)
+		if err != nil {
+			return err
+		}
+		return s.index.flush()
+	}
+	magic := make([]byte, len(fileStoreMagic))
+	if _, err = s.file.ReadAt(magic, 0); err != nil || string(magic) != fileStoreMagic {
+		log.Println("[OpenFileNodeStore] invalid magic")
+		return ErrInvalidStoreFile
+	}
+	s.index, err = loadFileIndex(indexPath(s.path), s.file, fileSize)
+	if err != nil {
+		return err
+	}
+	start := s.index.logSize
+	reader := bufio.NewReader(io.NewSectionReader(s.file, start, fileSize-start))
+	offset := start
+	header := make([]byte, recordHeaderSize)
+	for offset < fileSize {
+		if _, err = io.ReadFull(reader, header); err != nil {
+			break
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		if offset+recordHeaderSize+length > fileSize {
+			break
+		}
+		payload := make([]byte, length)
+		if _, err = io.ReadFull(reader, payload); err != nil {
+			break
+		}
+		end := offset + recordHeaderSize + length
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+			if end == fileSize {
+				// the last record was not completely written
+				break
+			}
+			log.Println("[OpenFileNodeStore] corrupted record at offset", offset)
+			return ErrInvalidStoreFile
+		}
+		ops, err := decodeRecord(payload, offset+recordHeaderSize)
+		if err != nil {
+			return err
+		}
+		if err = s.index.markDirty(); err != nil {
+			return err
+		}
+		if err = s.index.apply(ops); err != nil {
+			return err
+		}
+		offset = end
+	}
+	if offset < fileSize {
+		log.Println("[OpenFileNodeStore] dropping", fileSize-offset, "bytes of an incomplete record")
+		err = s.file.Truncate(offset)
+		if err != nil {
+			return err
+		}
+	}
+	s.size = offset
+	s.replayed = offset - start
+	s.index.logSize = offset
+	return s.index.flush()
+}
+
+func encodeRecord(batch *Batch) ([]byte, error) {
+	var (
+		payload bytes.Buffer
+		lenBuf  [binary.MaxVarintLen64]byte
+	)
+	payload.Write(make([]byte, recordHeaderSize))
+	for _, op := range batch.ops {
+		if op.delete {
+			payload.WriteByte(opDelete)
+		} else {
+			payload.WriteByte(opPut)
+		}
+		n := binary.PutUvarint(lenBuf[:], uint64(len(op.key)))
+		payload.Write(lenBuf[:n])
+		payload.Write(op.key)
+		if !op.delete {
+			n = binary.PutUvarint(lenBuf[:], uint64(len(op.value)))
+			payload.Write(lenBuf[:n])
+			payload.Write(op.value)
+		}
+	}
+	record := payload.Bytes()
+	if len(record)-recordHeaderSize > math.MaxUint32 {
+		log.Println("[FileNodeStore] batch is too large")
+		return nil, ErrInvalidStoreFile
+	}
+	binary.BigEndian.PutUint32(record[:4], uint32(len(record)-recordHeaderSize))
+	binary.BigEndian.PutUint32(record[4:recordHeaderSize], crc32.ChecksumIEEE(record[recordHeaderSize:]))
+	return record, nil
+}
+
+/*
+	decodeRecord: operations of a record payload, base is the offset of the payload in the file
+*/
+func decodeRecord(payload []byte, base int64) (ops []recordOp, err error) {
+	readLength := func(pos int) (int, int, error) {
+		length, n := binary.Uvarint(payload[pos:])
+		if n <= 0 || length > uint64(len(payload)-pos-n) {
+			log.Println("[FileNodeStore] invalid record")
+			return 0, 0, ErrInvalidStoreFile
+		}
+		return int(length), pos + n, nil
+	}
+	pos := 0
+	for pos < len(payload) {
+		op := payload[pos]
+		keyLen, keyPos, err := readLength(pos + 1)
+		if err != nil {
+			return nil, err
+		}
+		key := string(payload[keyPos : keyPos+keyLen])
+		pos = keyPos + keyLen
+		switch op {
+		case opPut:
+			valueLen, valuePos, err := readLength(pos)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, recordOp{
+				key:       key,
+				keyOffset: base + int64(keyPos),
+				ref:       valueRef{offset: base + int64(valuePos), length: valueLen},
+			})
+			pos = valuePos + valueLen
+		case opDelete:
+			ops = append(ops, recordOp{key: key, delete: true})
+		default:
+			log.Println("[FileNodeStore] invalid record operation")
+			return nil, ErrInvalidStoreFile
+		}
+	}
+	return ops, nil
+}
+
+func (s *FileNodeStore) Get(key []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.file == nil {
+		return nil, ErrStoreClosed
+	}
+	slot, found, err := s.index.get(key)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNodeNotFound
+	}
+	value := make([]byte, slot.valueLen)
+	_, err = s.file.ReadAt(value, slot.valueOffset)
+	if err != nil {
+		log.Println("[FileNodeStore] unable to read value:", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (s *FileNodeStore) Write(batch *Batch) error {
+	if batch.Len() == 0 {
+		return nil
+	}
+	record, err := encodeRecord(batch)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrStoreClosed
+	}
+	if err = s.index.markDirty(); err != nil {
+		log.Println("[FileNodeStore] unable to write index:", err)
+		return err
+	}
+	_, err = s.file.WriteAt(record, s.size)
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		log.Println("[FileNodeStore] unable to write record:", err)
+		// the next record overwrites this one
+		return err
+	}
+	ops, err := decodeRecord(record[recordHeaderSize:], s.size+recordHeaderSize)
+	if err != nil {
+		return err
+	}
+	// the record is in the log and the index may point into it even if apply fails,
+	// the next record goes after it and the dirty index is rebuilt by Open
+	s.size += int64(len(record))
+	if err = s.index.apply(ops); err != nil {
+		log.Println("[FileNodeStore] unable to index record:", err)
+		return err
+	}
+	s.index.logSize = s.size
+	dead := s.size - int64(len(fileStoreMagic)) - s.index.liveBytes
+	if s.AutoCompactSize > 0 && dead > s.AutoCompactSize && dead > s.index.liveBytes {
+		// the batch is written, the compaction is tried again by the next Write
+		if err = s.compact(); err != nil {
+			log.Println("[FileNodeStore] unable to compact:", err)
+		}
+	}
+	return nil
+}
+
+/*
+	Compact: copy the live values into a new file which then replaces the store file, and build its index
+*/
+func (s *FileNodeStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrStoreClosed
+	}
+	return s.compact()
+}
+
+func (s *FileNodeStore) compact() (err error) {
+	tmpPath := s.path + ".compact"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		log.Println("[FileNodeStore] unable to create compacted file:", err)
+		return err
+	}
+	index, err := newFileIndex(indexPath(tmpPath), tmp, indexSlotsFor(s.index.count))
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			index.file.Close()
+			os.Remove(index.path)
+		}
+	}()
+	if _, err = tmp.Write([]byte(fileStoreMagic)); err != nil {
+		return err
+	}
+
+	offset := int64(len(fileStoreMagic))
+	batch := new(Batch)
+	batchSize := 0
+	flush := func() error {
+		record, err := encodeRecord(batch)
+		if err != nil {
+			return err
+		}
+		if _, err = tmp.Write(record); err != nil {
+			return err
+		}
+		ops, err := decodeRecord(record[recordHeaderSize:], offset+recordHeaderSize)
+		if err != nil {
+			return err
+		}
+		if err = index.apply(ops); err != nil {
+			return err
+		}
+		offset += int64(len(record))
+		batch.Reset()
+		batchSize = 0
+		return nil
+	}
+	err = s.index.forEach(func(slot indexSlot) error {
+		// the key, the length of the value and the value
+		entry := make([]byte, slot.size())
+		if _, err := s.file.ReadAt(entry, slot.keyOffset); err != nil {
+			return err
+		}
+		batch.Put(entry[:slot.keyLen], entry[len(entry)-int(slot.valueLen):])
+		batchSize += len(entry)
+		if batchSize >= compactRecordSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if batch.Len() > 0 {
+		if err = flush(); err != nil {
+			return err
+		}
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	index.logSize = offset
+	if err = index.flush(); err != nil {
+		return err
+	}
+	// the old index doesn't match the new file, Open rebuilds it if the second rename doesn't happen
+	if err = s.index.markDirty(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, s.path); err != nil {
+		log.Println("[FileNodeStore] unable to replace the store file:", err)
+		return err
+	}
+	s.file.Close()
+	s.file = tmp
+	s.size = offset
+	if err = os.Rename(index.path, indexPath(s.path)); err != nil {
+		log.Println("[FileNodeStore] unable to replace the index file:", err)
+		// the store keeps the new index under its temporary name, the old one is dirty
+		s.index.file.Close()
+		s.index = index
+		return nil
+	}
+	s.index.file.Close()
+	index.path = indexPath(s.path)
+	s.index = index
+	return nil
+}
+
+func (s *FileNodeStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.index.close()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	s.file = nil
+	return err
+}
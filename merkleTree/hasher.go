@@ -0,0 +1,119 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"hash"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+)
+
+// domain tags written before the inputs by the domain separated hashers, as 32 bytes field elements
+const (
+	LeafDomainTag = 1
+	NodeDomainTag = 2
+)
+
+/*
+	Hasher: hash of the leaves and of the inner nodes of a tree, a hasher is not safe for concurrent use.
+	The tree hashes its inner nodes with HashNode, the leaves are hashed by the caller with HashLeaf.
+	The in-circuit version is types.MerkleHasher.
+*/
+type Hasher interface {
+	HashLeaf(inputs ...[]byte) []byte
+	HashNode(left, right []byte) []byte
+}
+
+type plainHasher struct {
+	hFunc hash.Hash
+}
+
+/*
+	NewPlainHasher: hasher without domain separation, the leaves and the nodes are the hash of their inputs,
+	it's the hasher of the block circuit
+*/
+func NewPlainHasher(hFunc hash.Hash) Hasher {
+	return &plainHasher{hFunc: hFunc}
+}
+
+func (h *plainHasher) HashLeaf(inputs ...[]byte) []byte {
+	h.hFunc.Reset()
+	for _, input := range inputs {
+		h.hFunc.Write(input)
+	}
+	return h.hFunc.Sum([]byte{})
+}
+
+func (h *plainHasher) HashNode(left, right []byte) []byte {
+	return h.HashLeaf(left, right)
+}
+
+type domainHasher struct {
+	hFunc   hash.Hash
+	leafTag []byte
+	nodeTag []byte
+}
+
+/*
+	NewDomainHasher: hasher which writes LeafDomainTag before the inputs of a leaf and NodeDomainTag before
+	the children of a node, so that a leaf can't be taken for a node
+*/
+func NewDomainHasher(hFunc hash.Hash) Hasher {
+	return &domainHasher{
+		hFunc:   hFunc,
+		leafTag: domainTag(LeafDomainTag),
+		nodeTag: domainTag(NodeDomainTag),
+	}
+}
+
+func domainTag(tag byte) []byte {
+	buf := make([]byte, 32)
+	buf[31] = tag
+	return buf
+}
+
+func (h *domainHasher) HashLeaf(inputs ...[]byte) []byte {
+	h.hFunc.Reset()
+	h.hFunc.Write(h.leafTag)
+	for _, input := range inputs {
+		h.hFunc.Write(input)
+	}
+	return h.hFunc.Sum([]byte{})
+}
+
+func (h *domainHasher) HashNode(left, right []byte) []byte {
+	h.hFunc.Reset()
+	h.hFunc.Write(h.nodeTag)
+	h.hFunc.Write(left)
+	h.hFunc.Write(right)
+	return h.hFunc.Sum([]byte{})
+}
+
+/*
+	NewMiMCHasher: plain hasher on MiMC, such as Tree.NewHasher
+*/
+func NewMiMCHasher() Hasher {
+	return NewPlainHasher(mimc.NewMiMC())
+}
+
+/*
+	NewMiMCDomainHasher: domain separated hasher on MiMC
+*/
+func NewMiMCDomainHasher() Hasher {
+	return NewDomainHasher(mimc.NewMiMC())
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashers(t *testing.T) {
+	hashState := MockState(2)
+	plain := NewMiMCHasher()
+	assert.Equal(t, plain.HashLeaf(hashState[0], hashState[1]), plain.HashNode(hashState[0], hashState[1]))
+	domain := NewMiMCDomainHasher()
+	assert.NotEqual(t, domain.HashLeaf(hashState[0], hashState[1]), domain.HashNode(hashState[0], hashState[1]))
+	assert.NotEqual(t, plain.HashNode(hashState[0], hashState[1]), domain.HashNode(hashState[0], hashState[1]))
+
+	// the plain hasher is the hasher of the trees built on a hash function
+	tree, err := NewEmptyTreeWithHasher(16, NilHash, NewMiMCHasher())
+	require.NoError(t, err)
+	assert.Equal(t, "0766473e337fea6d25a271ca700d2d322969eefc3e25f72871f3db87f62ffb0b", common.Bytes2Hex(tree.Root()))
+	legacy, err := NewEmptyTree(16, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	require.NoError(t, tree.Update(5, hashState[0]))
+	require.NoError(t, legacy.Update(5, hashState[0]))
+	assert.Equal(t, legacy.Root(), tree.Root())
+
+	store := NewMemoryNodeStore()
+	tree, err = OpenTree(store, 16, NilHash, NewMiMCDomainHasher())
+	require.NoError(t, err)
+	assert.NotEqual(t, legacy.NilHashValueConst[16], tree.Root())
+	require.NoError(t, tree.Update(5, hashState[0]))
+	proofs, _, err := tree.BuildMerkleProofs(5)
+	require.NoError(t, err)
+	assert.True(t, VerifyInclusion(tree.Root(), hashState[0], 5, proofs, 16, NewMiMCDomainHasher()))
+	assert.False(t, VerifyInclusion(tree.Root(), hashState[0], 5, proofs, 16, NewMiMCHasher()))
+	require.NoError(t, tree.Commit(1))
+	_, err = OpenTree(store, 16, NilHash, NewMiMCHasher())
+	assert.Equal(t, ErrTreeMismatch, err)
+	_, err = OpenTree(store, 16, NilHash, NewMiMCDomainHasher())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,280 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const multiProofFormatVersion = 1
+
+var ErrInvalidMultiProof = errors.New("[smt] invalid multiproof")
+
+/*
+	MultiProof: proof of several leaves of a tree, the siblings shared by their paths or computed from
+	other leaves of the proof are not repeated. Indexes are sorted and Leaves[i] is the leaf at Indexes[i],
+	Siblings are the missing siblings from the leaves to the root, level by level and by increasing index in a level.
+*/
+type MultiProof struct {
+	MaxHeight int
+	Indexes   []int64
+	Leaves    [][]byte
+	Siblings  [][]byte
+}
+
+/*
+	BuildMultiProof: prove the leaves at the indexes, the duplicated indexes are proved once
+*/
+func (t *Tree) BuildMultiProof(indexes []int64) (*MultiProof, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.buildMultiProof(indexes, t.node)
+}
+
+/*
+	BuildMultiProof: same as Tree.BuildMultiProof at the version of the snapshot
+*/
+func (s *TreeSnapshot) BuildMultiProof(indexes []int64) (proof *MultiProof, err error) {
+	err = s.read(func() error {
+		proof, err = s.tree.buildMultiProof(indexes, s.node)
+		return err
+	})
+	return proof, err
+}
+
+func (t *Tree) buildMultiProof(indexes []int64, node func(height int, index int64) ([]byte, error)) (*MultiProof, error) {
+	if len(indexes) == 0 {
+		log.Println("[BuildMultiProof] no index to prove")
+		return nil, ErrInvalidIndex
+	}
+	for _, index := range indexes {
+		if index < 0 || index >= 1<<t.MaxHeight {
+			log.Println("[BuildMultiProof] invalid index:", index)
+			return nil, ErrInvalidIndex
+		}
+	}
+	level := sortedIndexes(indexes)
+	proof := &MultiProof{
+		MaxHeight: t.MaxHeight,
+		Indexes:   append([]int64{}, level...),
+		Leaves:    make([][]byte, len(level)),
+	}
+	for i, index := range level {
+		leaf, err := node(0, index)
+		if err != nil {
+			log.Println("[BuildMultiProof] unable to read leaf:", err)
+			return nil, err
+		}
+		proof.Leaves[i] = common.CopyBytes(leaf)
+	}
+	for height := 0; height < t.MaxHeight; height++ {
+		for i := 0; i < len(level); i++ {
+			// both children are in the proof
+			if level[i]&1 == Left && i+1 < len(level) && level[i+1] == level[i]|1 {
+				i++
+				continue
+			}
+			sibling, err := node(height, level[i]^1)
+			if err != nil {
+				log.Println("[BuildMultiProof] unable to read node:", err)
+				return nil, err
+			}
+			proof.Siblings = append(proof.Siblings, common.CopyBytes(sibling))
+		}
+		level = parentIndexes(level)
+	}
+	return proof, nil
+}
+
+/*
+	VerifyMultiProof: check the leaves of the proof against the root of a tree of the given max height,
+	the height and the number of siblings of the proof must match the tree
+*/
+func VerifyMultiProof(root []byte, maxHeight int, proof *MultiProof, hasher Hasher) bool {
+	if proof == nil || maxHeight <= 0 || maxHeight > maxTreeHeight || proof.MaxHeight != maxHeight ||
+		len(proof.Indexes) == 0 || len(proof.Indexes) != len(proof.Leaves) {
+		return false
+	}
+	for i, index := range proof.Indexes {
+		if index < 0 || index >= 1<<maxHeight || (i > 0 && index <= proof.Indexes[i-1]) {
+			return false
+		}
+	}
+	if len(proof.Siblings) != multiProofSiblings(proof.Indexes, maxHeight) {
+		return false
+	}
+	level := append([]int64{}, proof.Indexes...)
+	values := append([][]byte{}, proof.Leaves...)
+	siblings := proof.Siblings
+	for height := 0; height < maxHeight; height++ {
+		parents := make([][]byte, 0, len(values))
+		for i := 0; i < len(level); i++ {
+			var left, right []byte
+			switch {
+			case level[i]&1 == Left && i+1 < len(level) && level[i+1] == level[i]|1:
+				left, right = values[i], values[i+1]
+				i++
+			case level[i]&1 == Left:
+				left, right = values[i], siblings[0]
+				siblings = siblings[1:]
+			default:
+				left, right = siblings[0], values[i]
+				siblings = siblings[1:]
+			}
+			parents = append(parents, hasher.HashNode(left, right))
+		}
+		level = parentIndexes(level)
+		values = parents
+	}
+	return bytes.Equal(values[0], root)
+}
+
+/*
+	multiProofSiblings: number of siblings a multiproof of the sorted indexes has in a tree of the given height
+*/
+func multiProofSiblings(level []int64, maxHeight int) int {
+	count := 0
+	for height := 0; height < maxHeight; height++ {
+		for i := 0; i < len(level); i++ {
+			if level[i]&1 == Left && i+1 < len(level) && level[i+1] == level[i]|1 {
+				i++
+				continue
+			}
+			count++
+		}
+		level = parentIndexes(level)
+	}
+	return count
+}
+
+func sortedIndexes(indexes []int64) []int64 {
+	sorted := append([]int64{}, indexes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return parentIndexesOf(sorted, 0)
+}
+
+func parentIndexes(level []int64) []int64 {
+	return parentIndexesOf(level, 1)
+}
+
+/*
+	parentIndexesOf: the sorted indexes shifted right, without the duplicates
+*/
+func parentIndexesOf(level []int64, shift uint) []int64 {
+	parents := make([]int64, 0, len(level))
+	for _, index := range level {
+		if len(parents) == 0 || parents[len(parents)-1] != index>>shift {
+			parents = append(parents, index>>shift)
+		}
+	}
+	return parents
+}
+
+/*
+	MarshalBinary: format version, max height, uvarint count of leaves and siblings, uvarint hash size,
+	the indexes as uvarint gaps, then the leaves and the siblings
+*/
+func (p *MultiProof) MarshalBinary() ([]byte, error) {
+	if p.MaxHeight <= 0 || p.MaxHeight > maxTreeHeight || len(p.Indexes) == 0 || len(p.Indexes) != len(p.Leaves) {
+		return nil, ErrInvalidMultiProof
+	}
+	hashSize := len(p.Leaves[0])
+	for _, value := range append(append([][]byte{}, p.Leaves...), p.Siblings...) {
+		if len(value) != hashSize {
+			log.Println("[MultiProof] the hashes should have the same size")
+			return nil, ErrInvalidMultiProof
+		}
+	}
+	buf := []byte{multiProofFormatVersion, byte(p.MaxHeight)}
+	buf = appendUvarint(buf, uint64(len(p.Indexes)))
+	buf = appendUvarint(buf, uint64(len(p.Siblings)))
+	buf = appendUvarint(buf, uint64(hashSize))
+	prev := int64(0)
+	for i, index := range p.Indexes {
+		if index < prev || (i > 0 && index == prev) || index >= 1<<p.MaxHeight {
+			log.Println("[MultiProof] indexes should be sorted and in the tree")
+			return nil, ErrInvalidMultiProof
+		}
+		buf = appendUvarint(buf, uint64(index-prev))
+		prev = index
+	}
+	for _, leaf := range p.Leaves {
+		buf = append(buf, leaf...)
+	}
+	for _, sibling := range p.Siblings {
+		buf = append(buf, sibling...)
+	}
+	return buf, nil
+}
+
+func (p *MultiProof) UnmarshalBinary(buf []byte) error {
+	if len(buf) < 2 || buf[0] != multiProofFormatVersion || buf[1] == 0 || buf[1] > maxTreeHeight {
+		return ErrInvalidMultiProof
+	}
+	maxHeight := int(buf[1])
+	pos := 2
+	var fields [3]uint64
+	for i := range fields {
+		value, n := binary.Uvarint(buf[pos:])
+		if n <= 0 || value > uint64(len(buf)) {
+			return ErrInvalidMultiProof
+		}
+		fields[i] = value
+		pos += n
+	}
+	nbLeaves, nbSiblings, hashSize := int(fields[0]), int(fields[1]), int(fields[2])
+	if nbLeaves == 0 {
+		return ErrInvalidMultiProof
+	}
+	indexes := make([]int64, nbLeaves)
+	prev := uint64(0)
+	for i := range indexes {
+		gap, n := binary.Uvarint(buf[pos:])
+		if n <= 0 || (i > 0 && gap == 0) || gap >= 1<<maxHeight-prev {
+			return ErrInvalidMultiProof
+		}
+		prev += gap
+		indexes[i] = int64(prev)
+		pos += n
+	}
+	if (len(buf)-pos)/(nbLeaves+nbSiblings) != hashSize || (len(buf)-pos)%(nbLeaves+nbSiblings) != 0 {
+		return ErrInvalidMultiProof
+	}
+	values := make([][]byte, nbLeaves+nbSiblings)
+	for i := range values {
+		values[i] = common.CopyBytes(buf[pos : pos+hashSize])
+		pos += hashSize
+	}
+	p.MaxHeight = maxHeight
+	p.Indexes = indexes
+	p.Leaves = values[:nbLeaves]
+	p.Siblings = values[nbLeaves:]
+	return nil
+}
+
+func appendUvarint(buf []byte, value uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], value)
+	return append(buf, tmp[:n]...)
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiProof(t *testing.T) {
+	tree, err := NewEmptyTree(32, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	require.NoError(t, tree.BatchUpdate(mockBatch(200, 32, 3)))
+
+	indexes := []int64{10, 3, 2, 11, 1 << 30, 4, 3, 1<<32 - 1}
+	proof, err := tree.BuildMultiProof(indexes)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 3, 4, 10, 11, 1 << 30, 1<<32 - 1}, proof.Indexes)
+	assert.True(t, VerifyMultiProof(tree.Root(), 32, proof, NewMiMCHasher()))
+	siblings := 0
+	for i, index := range proof.Indexes {
+		leaf, err := tree.Leaf(index)
+		require.NoError(t, err)
+		assert.Equal(t, leaf, proof.Leaves[i])
+		merkleProofs, _, err := tree.BuildMerkleProofs(index)
+		require.NoError(t, err)
+		siblings += len(merkleProofs)
+	}
+	assert.Less(t, len(proof.Siblings), siblings/2)
+
+	// binary and json
+	buf, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	decoded := new(MultiProof)
+	require.NoError(t, decoded.UnmarshalBinary(buf))
+	assert.Equal(t, proof, decoded)
+	assert.True(t, VerifyMultiProof(tree.Root(), 32, decoded, NewMiMCHasher()))
+	buf, err = json.Marshal(proof)
+	require.NoError(t, err)
+	decoded = new(MultiProof)
+	require.NoError(t, json.Unmarshal(buf, decoded))
+	assert.True(t, VerifyMultiProof(tree.Root(), 32, decoded, NewMiMCHasher()))
+
+	// tampered proofs
+	decoded.Leaves[1] = decoded.Leaves[0]
+	assert.False(t, VerifyMultiProof(tree.Root(), 32, decoded, NewMiMCHasher()))
+	require.NoError(t, json.Unmarshal(buf, decoded))
+	decoded.Siblings = decoded.Siblings[1:]
+	assert.False(t, VerifyMultiProof(tree.Root(), 32, decoded, NewMiMCHasher()))
+	require.NoError(t, json.Unmarshal(buf, decoded))
+	decoded.Indexes[3], decoded.Indexes[4] = decoded.Indexes[4], decoded.Indexes[3]
+	assert.False(t, VerifyMultiProof(tree.Root(), 32, decoded, NewMiMCHasher()))
+	buf, err = proof.MarshalBinary()
+	require.NoError(t, err)
+	assert.Equal(t, ErrInvalidMultiProof, decoded.UnmarshalBinary(buf[:len(buf)-1]))
+
+	// a single leaf is the same as its merkle proofs
+	proof, err = tree.BuildMultiProof([]int64{7})
+	require.NoError(t, err)
+	merkleProofs, _, err := tree.BuildMerkleProofs(7)
+	require.NoError(t, err)
+	assert.Equal(t, merkleProofs, proof.Siblings)
+
+	_, err = tree.BuildMultiProof(nil)
+	assert.Equal(t, ErrInvalidIndex, err)
+	_, err = tree.BuildMultiProof([]int64{1 << 32})
+	assert.Equal(t, ErrInvalidIndex, err)
+}
+
+func TestMultiProofForgedHeight(t *testing.T) {
+	tree, err := NewEmptyTree(4, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	require.NoError(t, tree.BatchUpdate(mockBatch(5, 4, 1)))
+
+	// an inner node of height 1 passed as a leaf of a tree of height 3
+	inner, err := tree.node(1, 0)
+	require.NoError(t, err)
+	merkleProofs, _, err := tree.BuildMerkleProofs(0)
+	require.NoError(t, err)
+	forged := &MultiProof{
+		MaxHeight: 3,
+		Indexes:   []int64{0},
+		Leaves:    [][]byte{inner},
+		Siblings:  merkleProofs[1:],
+	}
+	// it only holds for a tree of height 3
+	assert.True(t, VerifyMultiProof(tree.Root(), 3, forged, NewMiMCHasher()))
+	assert.False(t, VerifyMultiProof(tree.Root(), 4, forged, NewMiMCHasher()))
+	forged.MaxHeight = 4
+	assert.False(t, VerifyMultiProof(tree.Root(), 4, forged, NewMiMCHasher()))
+
+	proof, err := tree.BuildMultiProof([]int64{0})
+	require.NoError(t, err)
+	assert.True(t, VerifyMultiProof(tree.Root(), 4, proof, NewMiMCHasher()))
+	assert.False(t, VerifyMultiProof(tree.Root(), 3, proof, NewMiMCHasher()))
+}
@@ -0,0 +1,237 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const merkleProofFormatVersion = 1
+
+var ErrInvalidMerkleProof = errors.New("[smt] invalid merkle proof")
+
+/*
+	MerkleProof: merkle proof of a leaf with what it proves, it is the wire format of the proofs between
+	the state service and the prover. TreeId tells the tree the proof is about, its values are chosen by the caller.
+	Siblings are the siblings from the leaf to the root, the helpers are the bits of Index.
+*/
+type MerkleProof struct {
+	TreeId   uint64
+	Index    int64
+	Depth    int
+	Root     []byte
+	Leaf     []byte
+	Siblings [][]byte
+}
+
+/*
+	Proof: merkle proof of the leaf at the current root
+*/
+func (t *Tree) Proof(treeId uint64, index int64) (*MerkleProof, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.proof(treeId, index, t.root, t.node)
+}
+
+/*
+	Proof: same as Tree.Proof at the version of the snapshot
+*/
+func (s *TreeSnapshot) Proof(treeId uint64, index int64) (proof *MerkleProof, err error) {
+	err = s.read(func() error {
+		proof, err = s.tree.proof(treeId, index, s.root, s.node)
+		return err
+	})
+	return proof, err
+}
+
+func (t *Tree) proof(treeId uint64, index int64, root []byte, node func(height int, index int64) ([]byte, error)) (*MerkleProof, error) {
+	leaf, err := t.leaf(index, node)
+	if err != nil {
+		return nil, err
+	}
+	siblings, _, err := t.buildMerkleProofs(index, node)
+	if err != nil {
+		return nil, err
+	}
+	return &MerkleProof{
+		TreeId:   treeId,
+		Index:    index,
+		Depth:    t.MaxHeight,
+		Root:     common.CopyBytes(root),
+		Leaf:     leaf,
+		Siblings: siblings,
+	}, nil
+}
+
+/*
+	Helpers: side of the node at every level, same as the helpers returned by BuildMerkleProofs
+*/
+func (p *MerkleProof) Helpers() []int {
+	helpers := make([]int, p.Depth)
+	for i := range helpers {
+		helpers[i] = int(p.Index>>uint(i)) & 1
+	}
+	return helpers
+}
+
+/*
+	Verify: check that the leaf is at the index of the tree with the trusted root and depth,
+	the root and the depth of the proof must be the same
+*/
+func (p *MerkleProof) Verify(root []byte, depth int, hasher Hasher) bool {
+	if p.validate() != nil || p.Depth != depth || !bytes.Equal(p.Root, root) {
+		return false
+	}
+	return VerifyInclusion(root, p.Leaf, p.Index, p.Siblings, depth, hasher)
+}
+
+func (p *MerkleProof) validate() error {
+	if p.Depth <= 0 || p.Depth > maxTreeHeight || p.Index < 0 || p.Index >= 1<<p.Depth || len(p.Siblings) != p.Depth {
+		return ErrInvalidMerkleProof
+	}
+	for _, sibling := range p.Siblings {
+		if len(sibling) != len(p.Root) {
+			return ErrInvalidMerkleProof
+		}
+	}
+	if len(p.Leaf) != len(p.Root) {
+		return ErrInvalidMerkleProof
+	}
+	return nil
+}
+
+/*
+	MarshalBinary: format version, uvarint tree id, uvarint index, depth, uvarint hash size,
+	then the root, the leaf and the siblings
+*/
+func (p *MerkleProof) MarshalBinary() ([]byte, error) {
+	if err := p.validate(); err != nil {
+		log.Println("[MerkleProof] invalid proof")
+		return nil, err
+	}
+	buf := []byte{merkleProofFormatVersion}
+	buf = appendUvarint(buf, p.TreeId)
+	buf = appendUvarint(buf, uint64(p.Index))
+	buf = append(buf, byte(p.Depth))
+	buf = appendUvarint(buf, uint64(len(p.Root)))
+	buf = append(buf, p.Root...)
+	buf = append(buf, p.Leaf...)
+	for _, sibling := range p.Siblings {
+		buf = append(buf, sibling...)
+	}
+	return buf, nil
+}
+
+func (p *MerkleProof) UnmarshalBinary(buf []byte) error {
+	if len(buf) == 0 || buf[0] != merkleProofFormatVersion {
+		return ErrInvalidMerkleProof
+	}
+	pos := 1
+	treeId, n := binary.Uvarint(buf[pos:])
+	if n <= 0 {
+		return ErrInvalidMerkleProof
+	}
+	pos += n
+	index, n := binary.Uvarint(buf[pos:])
+	if n <= 0 || index >= 1<<maxTreeHeight || pos+n >= len(buf) {
+		return ErrInvalidMerkleProof
+	}
+	pos += n
+	depth := int(buf[pos])
+	pos++
+	hashSize, n := binary.Uvarint(buf[pos:])
+	if n <= 0 || depth > maxTreeHeight || hashSize > uint64(len(buf)) || hashSize*uint64(depth+2) != uint64(len(buf)-pos-n) {
+		return ErrInvalidMerkleProof
+	}
+	pos += n
+	values := make([][]byte, depth+2)
+	for i := range values {
+		values[i] = common.CopyBytes(buf[pos : pos+int(hashSize)])
+		pos += int(hashSize)
+	}
+	proof := MerkleProof{
+		TreeId:   treeId,
+		Index:    int64(index),
+		Depth:    depth,
+		Root:     values[0],
+		Leaf:     values[1],
+		Siblings: values[2:],
+	}
+	if err := proof.validate(); err != nil {
+		return err
+	}
+	*p = proof
+	return nil
+}
+
+type merkleProofJSON struct {
+	Version  int
+	TreeId   uint64
+	Index    int64
+	Depth    int
+	Root     []byte
+	Leaf     []byte
+	Siblings [][]byte
+}
+
+func (p *MerkleProof) MarshalJSON() ([]byte, error) {
+	if err := p.validate(); err != nil {
+		log.Println("[MerkleProof] invalid proof")
+		return nil, err
+	}
+	return json.Marshal(merkleProofJSON{
+		Version:  merkleProofFormatVersion,
+		TreeId:   p.TreeId,
+		Index:    p.Index,
+		Depth:    p.Depth,
+		Root:     p.Root,
+		Leaf:     p.Leaf,
+		Siblings: p.Siblings,
+	})
+}
+
+func (p *MerkleProof) UnmarshalJSON(buf []byte) error {
+	var aux merkleProofJSON
+	err := json.Unmarshal(buf, &aux)
+	if err != nil {
+		return err
+	}
+	if aux.Version != merkleProofFormatVersion {
+		log.Println("[MerkleProof] unknown format version:", aux.Version)
+		return ErrInvalidMerkleProof
+	}
+	proof := MerkleProof{
+		TreeId:   aux.TreeId,
+		Index:    aux.Index,
+		Depth:    aux.Depth,
+		Root:     aux.Root,
+		Leaf:     aux.Leaf,
+		Siblings: aux.Siblings,
+	}
+	if err := proof.validate(); err != nil {
+		return err
+	}
+	*p = proof
+	return nil
+}
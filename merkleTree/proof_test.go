@@ -0,0 +1,115 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerkleProofEncoding(t *testing.T) {
+	hashState := MockState(2)
+	tree, err := NewEmptyTree(40, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	require.NoError(t, tree.Update(5, hashState[0]))
+	require.NoError(t, tree.Update(1<<39, hashState[1]))
+
+	proof, err := tree.Proof(3, 1<<39)
+	require.NoError(t, err)
+	assert.True(t, proof.Verify(tree.Root(), 40, NewMiMCHasher()))
+	assert.Equal(t, hashState[1], proof.Leaf)
+	merkleProofs, helpers, err := tree.BuildMerkleProofs(1 << 39)
+	require.NoError(t, err)
+	assert.Equal(t, merkleProofs, proof.Siblings)
+	assert.Equal(t, helpers, proof.Helpers())
+
+	buf, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	decoded := new(MerkleProof)
+	require.NoError(t, decoded.UnmarshalBinary(buf))
+	assert.Equal(t, proof, decoded)
+	assert.True(t, decoded.Verify(tree.Root(), 40, NewMiMCHasher()))
+
+	jsonBuf, err := json.Marshal(proof)
+	require.NoError(t, err)
+	decoded = new(MerkleProof)
+	require.NoError(t, json.Unmarshal(jsonBuf, decoded))
+	assert.Equal(t, proof, decoded)
+
+	// unknown format version, truncated input and trailing bytes
+	badVersion := append([]byte{}, buf...)
+	badVersion[0] = 2
+	assert.Equal(t, ErrInvalidMerkleProof, new(MerkleProof).UnmarshalBinary(badVersion))
+	assert.Equal(t, ErrInvalidMerkleProof, new(MerkleProof).UnmarshalBinary(buf[:len(buf)-1]))
+	assert.Equal(t, ErrInvalidMerkleProof, new(MerkleProof).UnmarshalBinary(append(buf, 0)))
+	assert.Equal(t, ErrInvalidMerkleProof, new(MerkleProof).UnmarshalBinary(nil))
+	var jsonProof map[string]interface{}
+	require.NoError(t, json.Unmarshal(jsonBuf, &jsonProof))
+	jsonProof["Version"] = 2
+	jsonBuf, err = json.Marshal(jsonProof)
+	require.NoError(t, err)
+	assert.Error(t, json.Unmarshal(jsonBuf, new(MerkleProof)))
+
+	// the proof doesn't hold for another leaf or index
+	decoded.Leaf = hashState[0]
+	assert.False(t, decoded.Verify(tree.Root(), 40, NewMiMCHasher()))
+	decoded.Leaf = proof.Leaf
+	decoded.Index = 5
+	assert.False(t, decoded.Verify(tree.Root(), 40, NewMiMCHasher()))
+	decoded.Siblings = decoded.Siblings[1:]
+	_, err = decoded.MarshalBinary()
+	assert.Equal(t, ErrInvalidMerkleProof, err)
+
+	// a past version
+	require.NoError(t, tree.Commit(1))
+	snapshot, err := tree.Snapshot(1)
+	require.NoError(t, err)
+	require.NoError(t, tree.Update(5, hashState[1]))
+	proof, err = snapshot.Proof(3, 5)
+	require.NoError(t, err)
+	assert.Equal(t, hashState[0], proof.Leaf)
+	assert.True(t, proof.Verify(snapshot.Root(), 40, NewMiMCHasher()))
+	assert.NotEqual(t, tree.Root(), proof.Root)
+	assert.False(t, proof.Verify(tree.Root(), 40, NewMiMCHasher()))
+}
+
+func TestMerkleProofTrustedRoot(t *testing.T) {
+	hashState := MockState(2)
+	tree, err := NewEmptyTree(8, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	require.NoError(t, tree.Update(5, hashState[0]))
+	proof, err := tree.Proof(0, 5)
+	require.NoError(t, err)
+	assert.True(t, proof.Verify(tree.Root(), 8, NewMiMCHasher()))
+	assert.False(t, proof.Verify(tree.Root(), 7, NewMiMCHasher()))
+
+	// a self consistent proof of another tree
+	other, err := NewEmptyTree(8, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	require.NoError(t, other.Update(5, hashState[1]))
+	forged, err := other.Proof(0, 5)
+	require.NoError(t, err)
+	assert.True(t, forged.Verify(other.Root(), 8, NewMiMCHasher()))
+	assert.False(t, forged.Verify(tree.Root(), 8, NewMiMCHasher()))
+	forged.Root = tree.Root()
+	assert.False(t, forged.Verify(tree.Root(), 8, NewMiMCHasher()))
+}
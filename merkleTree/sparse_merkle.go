@@ -19,10 +19,13 @@ package merkleTree
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"hash"
 	"log"
+	"math"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -32,40 +35,73 @@ const (
 	Right = 1
 )
 
+const (
+	// indexes are int64
+	maxTreeHeight = 62
+	// key of a node: prefix, height and index
+	nodeKeySize   = 10
+	nodeKeyPrefix = 'n'
+)
+
 var (
 	NilHash = common.FromHex("01ef55cdf3b9b0d65e6fb6317f79627534d971fd96c811281af618c0028d5e7a")
+
+	treeMetaKey = []byte("m")
+)
+
+var (
+	ErrInvalidTreeHeight = errors.New("[smt] invalid tree height")
+	ErrInvalidIndex      = errors.New("[smt] invalid index")
+	ErrTreeMismatch      = errors.New("[smt] the stored tree has another height, nil hash or hasher")
 )
 
 /*
-	Tree: sparse merkle tree
+	Tree: sparse merkle tree, the nodes are kept in a NodeStore and addressed by their height and index,
+	the nodes which are not in the store are the roots of empty subtrees.
+	The tree is safe for concurrent use, the updates wait for the reads and the other way around, a reader which
+	needs several reads at the same root uses a TreeSnapshot. The exported fields must not be changed after creation.
+	Breaking change: the RootNode, Leaves and HashFunc fields are removed, see the migration notes of the README.
 */
 type Tree struct {
-	// root Node
-	RootNode *Node
-	// leaves
-	Leaves []*Node
 	// max height
 	MaxHeight int
-	// nil hash tree
+	// nil hash tree, NilHashValueConst[i] is the root of an empty subtree of height i
 	NilHashValueConst [][]byte
-	// hash function
-	HashFunc hash.Hash
+	// hash of the inner nodes
+	Hasher Hasher
+	// creates the hashers of the BatchUpdate workers, the batch is hashed on Hasher alone if nil
+	NewHasher func() Hasher
+
+	// mu guards the fields below, hashMu guards Hasher
+	mu     sync.RWMutex
+	hashMu sync.Mutex
+
+	store NodeStore
+	// nodes updated since the last commit
+	dirty map[nodeKey][]byte
+	root  []byte
+	// last committed version and oldest version which can still be read
+	version       int64
+	oldestVersion int64
+	// versions restored by the rollbacks in order, so that the snapshots drop what they loaded
+	// and the snapshots of the versions rolled back stay invalid.
+	// Prune trims the first ones, trimmedRollbacks is their number.
+	rollbacks        []int64
+	trimmedRollbacks int
 }
 
 /*
-	Node: node in the tree
+	Node: leaf passed to NewTree and NewTreeByMap.
+	Breaking change: the Left, Right, Parent and Height fields are removed, the inner nodes aren't kept in memory.
 */
 type Node struct {
 	// node value
 	Value []byte
-	// left node
-	Left *Node
-	// right node
-	Right *Node
-	// parent node
-	Parent *Node
-	// height
-	Height int
+}
+
+type nodeKey struct {
+	height int
+	index  int64
 }
 
 /*
@@ -76,31 +112,20 @@ func CreateLeaves(hashState [][]byte) []*Node {
 	// construct leaves
 	var leaves []*Node
 	for i := 0; i < len(hashState); i++ {
-		node := &Node{
-			Value:  hashState[i],
-			Left:   nil,
-			Right:  nil,
-			Parent: nil,
-			Height: 0,
-		}
-		leaves = append(leaves, node)
+		leaves = append(leaves, CreateLeafNode(hashState[i]))
 	}
 	return leaves
 }
 
 func CreateLeafNode(hashVal []byte) *Node {
 	return &Node{
-		Value:  hashVal,
-		Left:   nil,
-		Right:  nil,
-		Parent: nil,
-		Height: 0,
+		Value: hashVal,
 	}
 }
 
 func (t *Tree) InitNilHashValueConst() (err error) {
 	nilHash := t.NilHashValueConst[0]
-	for i := 1; i < t.MaxHeight; i++ {
+	for i := 1; i <= t.MaxHeight; i++ {
 		var (
 			nHash []byte
 		)
@@ -111,154 +136,117 @@ func (t *Tree) InitNilHashValueConst() (err error) {
 	return err
 }
 
-func NewEmptyTree(maxHeight int, nilHash []byte, hFunc hash.Hash) (*Tree, error) {
-	root := &Node{
-		Value:  nilHash,
-		Left:   nil,
-		Right:  nil,
-		Parent: nil,
-		Height: maxHeight,
+func newTree(store NodeStore, maxHeight int, nilHash []byte, hasher Hasher) (*Tree, error) {
+	if maxHeight <= 0 || maxHeight > maxTreeHeight {
+		log.Println("[smt.NewTree] invalid tree height:", maxHeight)
+		return nil, ErrInvalidTreeHeight
 	}
 	// init nil hash values for different heights
-	nilHashValueConst := make([][]byte, maxHeight)
+	nilHashValueConst := make([][]byte, maxHeight+1)
 	nilHashValueConst[0] = nilHash
 	// init tree
 	tree := &Tree{
-		RootNode:          root,
 		MaxHeight:         maxHeight,
-		Leaves:            *new([]*Node),
 		NilHashValueConst: nilHashValueConst,
-		HashFunc:          hFunc,
+		Hasher:            hasher,
+		store:             store,
+		dirty:             make(map[nodeKey][]byte),
 	}
 	err := tree.InitNilHashValueConst()
 	if err != nil {
-		errInfo := fmt.Sprintf("[smt.NewEmptyTree] InitNilHashValueConst error: %s", err.Error())
+		errInfo := fmt.Sprintf("[smt.NewTree] InitNilHashValueConst error: %s", err.Error())
 		log.Println(errInfo)
 		return nil, errors.New(errInfo)
 	}
-	hFunc.Reset()
-	hFunc.Write(nilHashValueConst[maxHeight-1])
-	hFunc.Write(nilHashValueConst[maxHeight-1])
-	tree.RootNode.Value = hFunc.Sum(nil)
-	hFunc.Reset()
+	tree.root = nilHashValueConst[maxHeight]
 	return tree, nil
 }
 
 /*
-	func: NewTree
-	params: leaves []*Node, maxHeight int, nilHash []byte, hFunc hash.Hash
-    desp: Use leaf nodes to initialize the tree,
-          and call the BuildTree method through the root to initialize the hash value of the entire tree
+	NewEmptyTree: empty tree kept in memory, the nodes are hashed without domain separation
 */
-func NewTreeByMap(leaves map[int64]*Node, maxHeight int, nilHash []byte, hFunc hash.Hash) (*Tree, error) {
-	// define variables
-	var (
-		root *Node
-	)
-	// empty tree
-	if leaves == nil {
-		return NewEmptyTree(maxHeight, nilHash, hFunc)
-	}
-	// construct root node
-	root = &Node{
-		Value:  nilHash,
-		Left:   nil,
-		Right:  nil,
-		Parent: nil,
-		Height: maxHeight,
+func NewEmptyTree(maxHeight int, nilHash []byte, hFunc hash.Hash) (*Tree, error) {
+	return NewEmptyTreeWithHasher(maxHeight, nilHash, NewPlainHasher(hFunc))
+}
+
+/*
+	NewEmptyTreeWithHasher: empty tree kept in memory with the nodes hashed by the hasher
+*/
+func NewEmptyTreeWithHasher(maxHeight int, nilHash []byte, hasher Hasher) (*Tree, error) {
+	return newTree(NewMemoryNodeStore(), maxHeight, nilHash, hasher)
+}
+
+/*
+	OpenTree: open the tree last committed into the store, or an empty tree at version 0 if nothing was committed yet.
+	The store must have been written by a tree with the same height, nil hash and hasher.
+*/
+func OpenTree(store NodeStore, maxHeight int, nilHash []byte, hasher Hasher) (*Tree, error) {
+	tree, err := newTree(store, maxHeight, nilHash, hasher)
+	if err != nil {
+		return nil, err
 	}
-	// init nil hash values for different heights
-	nilHashValueConst := make([][]byte, maxHeight+1)
-	nilHashValueConst[0] = nilHash
-	// scan map
-	maxIndex := int64(-1)
-	for index := range leaves {
-		if index > maxIndex {
-			maxIndex = index
-		}
+	meta, err := store.Get(treeMetaKey)
+	if errors.Is(err, ErrNodeNotFound) {
+		return tree, nil
 	}
-	if maxIndex == -1 {
-		return NewEmptyTree(maxHeight, nilHash, hFunc)
+	if err != nil {
+		log.Println("[OpenTree] unable to read tree meta:", err)
+		return nil, err
 	}
-	var nodes []*Node
-	for i := int64(0); i <= maxIndex; i++ {
-		if leaves[i] != nil {
-			nodes = append(nodes, leaves[i])
-		} else {
-			nodes = append(nodes, &Node{
-				Value:  nilHash,
-				Left:   nil,
-				Right:  nil,
-				Parent: nil,
-				Height: 0,
-			})
-		}
+	height, version, oldestVersion, emptyRoot, err := decodeTreeMeta(meta)
+	if err != nil {
+		return nil, err
 	}
-	// init tree
-	tree := &Tree{
-		RootNode:          root,
-		Leaves:            nodes,
-		MaxHeight:         maxHeight,
-		NilHashValueConst: nilHashValueConst,
-		HashFunc:          hFunc,
+	if height != maxHeight || !bytes.Equal(emptyRoot, tree.NilHashValueConst[maxHeight]) {
+		log.Println("[OpenTree] tree mismatch, stored height:", height)
+		return nil, ErrTreeMismatch
 	}
-	err := tree.InitNilHashValueConst()
+	tree.version = version
+	tree.oldestVersion = oldestVersion
+	tree.root, err = tree.node(maxHeight, 0)
 	if err != nil {
-		errInfo := fmt.Sprintf("[smt.NewTree] InitNilHashValueConst error: %s", err.Error())
-		log.Println(errInfo)
-		return nil, errors.New(errInfo)
+		log.Println("[OpenTree] unable to read root:", err)
+		return nil, err
 	}
+	return tree, nil
+}
 
-	err = tree.BuildTree(tree.Leaves)
+/*
+	func: NewTreeByMap
+	params: leaves map[int64]*Node, maxHeight int, nilHash []byte, hFunc hash.Hash
+    desp: tree kept in memory with the leaves at their indexes, the other leaves are nil leaves
+*/
+func NewTreeByMap(leaves map[int64]*Node, maxHeight int, nilHash []byte, hFunc hash.Hash) (*Tree, error) {
+	tree, err := NewEmptyTree(maxHeight, nilHash, hFunc)
 	if err != nil {
-		log.Println("[NewTree] unable to build tree: ", err)
 		return nil, err
 	}
+	for index, leaf := range leaves {
+		err = tree.Update(index, leaf.Value)
+		if err != nil {
+			log.Println("[NewTreeByMap] unable to update leaf:", index)
+			return nil, err
+		}
+	}
 	return tree, nil
 }
 
 /*
 	func: NewTree
 	params: leaves []*Node, maxHeight int, nilHash []byte, hFunc hash.Hash
-    desp: Use leaf nodes to initialize the tree,
-          and call the BuildTree method through the root to initialize the hash value of the entire tree
+    desp: Use leaf nodes to initialize the tree kept in memory,
+          and call the BuildTree method to initialize the hash value of the entire tree
 */
 func NewTree(leaves []*Node, maxHeight int, nilHash []byte, hFunc hash.Hash) (*Tree, error) {
-	// define variables
-	var (
-		root *Node
-	)
-	// empty tree
-	if len(leaves) == 0 || leaves == nil {
-		return NewEmptyTree(maxHeight, nilHash, hFunc)
-	}
-	// construct root node
-	root = &Node{
-		Value:  nilHash,
-		Left:   nil,
-		Right:  nil,
-		Parent: nil,
-		Height: maxHeight,
-	}
-	// init nil hash values for different heights
-	nilHashValueConst := make([][]byte, maxHeight+1)
-	nilHashValueConst[0] = nilHash
-	// init tree
-	tree := &Tree{
-		RootNode:          root,
-		Leaves:            leaves,
-		MaxHeight:         maxHeight,
-		NilHashValueConst: nilHashValueConst,
-		HashFunc:          hFunc,
-	}
-	err := tree.InitNilHashValueConst()
+	tree, err := NewEmptyTree(maxHeight, nilHash, hFunc)
 	if err != nil {
-		errInfo := fmt.Sprintf("[smt.NewTree] InitNilHashValueConst error: %s", err.Error())
-		log.Println(errInfo)
-		return nil, errors.New(errInfo)
+		return nil, err
 	}
-
-	err = tree.BuildTree(tree.Leaves)
+	// empty tree
+	if len(leaves) == 0 {
+		return tree, nil
+	}
+	err = tree.BuildTree(leaves)
 	if err != nil {
 		log.Println("[NewTree] unable to build tree: ", err)
 		return nil, err
@@ -270,340 +258,179 @@ func NewTree(leaves []*Node, maxHeight int, nilHash []byte, hFunc hash.Hash) (*T
 	HashSubTrees: hash sub-tree nodes
 */
 func (t *Tree) HashSubTrees(l []byte, r []byte) []byte {
-	t.HashFunc.Reset()
-	t.HashFunc.Write(l)
-	t.HashFunc.Write(r)
-	val := t.HashFunc.Sum([]byte{})
-	return val
+	t.hashMu.Lock()
+	defer t.hashMu.Unlock()
+	return t.Hasher.HashNode(l, r)
 }
 
 /*
-	BuildTree: build sparse merkle tree
+	BuildTree: write the leaves from index 0 and hash the tree level by level
 */
-func (t *Tree) BuildTree(nodes []*Node) (err error) {
-	// get to the max height
-	if len(nodes) == 0 {
+func (t *Tree) BuildTree(leaves []*Node) (err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(leaves) == 0 {
 		log.Println("[BuildTree] smt BuildTree error, nodes length == 0")
 		return errors.New("[BuildTree] nodes length == 0")
-	} else {
-		if nodes[0].Height == t.MaxHeight && len(nodes) == 1 {
-			t.RootNode = nodes[0]
-			return nil
-		}
 	}
-	if len(nodes)%2 != 0 {
-		nodes = append(nodes, &Node{
-			Value:  t.NilHashValueConst[nodes[0].Height],
-			Left:   nil,
-			Right:  nil,
-			Parent: nil,
-			Height: nodes[0].Height,
-		})
-	}
-	var parents []*Node
-	for i := 0; i < len(nodes); i += 2 {
-		nodes[i].Parent = &Node{
-			Value:  t.HashSubTrees(nodes[i].Value, nodes[i+1].Value),
-			Left:   nodes[i],
-			Right:  nodes[i+1],
-			Parent: nil,
-			Height: nodes[i].Height + 1,
+	if int64(len(leaves)) > 1<<t.MaxHeight {
+		log.Println("[BuildTree] too many leaves")
+		return ErrInvalidIndex
+	}
+	level := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		level[i] = common.CopyBytes(leaf.Value)
+	}
+	for height := 0; height < t.MaxHeight; height++ {
+		for i, value := range level {
+			t.dirty[nodeKey{height: height, index: int64(i)}] = value
 		}
-		nodes[i+1].Parent = nodes[i].Parent
+		parents := make([][]byte, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			right := t.NilHashValueConst[height]
+			if i+1 < len(level) {
+				right = level[i+1]
+			}
+			parents[i/2] = t.HashSubTrees(level[i], right)
+		}
+		level = parents
+	}
+	t.dirty[nodeKey{height: t.MaxHeight, index: 0}] = level[0]
+	t.root = level[0]
+	return nil
+}
 
-		parents = append(parents, nodes[i].Parent)
+/*
+	node: value of the node, from the nodes updated since the last commit, then from the store
+*/
+func (t *Tree) node(height int, index int64) ([]byte, error) {
+	key := nodeKey{height: height, index: index}
+	if value, ok := t.dirty[key]; ok {
+		return value, nil
 	}
-	err = t.BuildTree(parents)
-	return err
+	value, err := t.store.Get(encodeNodeKey(key))
+	if errors.Is(err, ErrNodeNotFound) {
+		return t.NilHashValueConst[height], nil
+	}
+	return value, err
 }
 
 /*
-	BuildMerkleProofs: construct merkle proofs
+	Root: root of the tree, including the updates which are not committed
+*/
+func (t *Tree) Root() []byte {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return common.CopyBytes(t.root)
+}
+
+/*
+	Leaf: value of the leaf, the nil hash if it is empty
+*/
+func (t *Tree) Leaf(index int64) ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.leaf(index, t.node)
+}
+
+func (t *Tree) leaf(index int64, node func(height int, index int64) ([]byte, error)) ([]byte, error) {
+	if index < 0 || index >= 1<<t.MaxHeight {
+		log.Println("[Leaf] invalid index")
+		return nil, ErrInvalidIndex
+	}
+	value, err := node(0, index)
+	if err != nil {
+		log.Println("[Leaf] unable to read leaf:", err)
+		return nil, err
+	}
+	return common.CopyBytes(value), nil
+}
+
+/*
+	BuildMerkleProofs: construct merkle proofs, the siblings from the leaf to the root
 */
 func (t *Tree) BuildMerkleProofs(index int64) (
 	rMerkleProof [][]byte,
 	rProofHelper []int,
 	err error,
 ) {
-	var proofs [][]byte
-	var proofHelpers []int
-	if index >= (1 << t.MaxHeight) {
-		errInfo := fmt.Sprintf("[BuildMerkleProofs] index error, index: %v is bigger than tree capacity: %v.",
-			index, 1<<t.MaxHeight)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.buildMerkleProofs(index, t.node)
+}
+
+func (t *Tree) buildMerkleProofs(index int64, node func(height int, index int64) ([]byte, error)) (
+	rMerkleProof [][]byte,
+	rProofHelper []int,
+	err error,
+) {
+	if index < 0 || index >= (1<<t.MaxHeight) {
+		errInfo := fmt.Sprintf("[BuildMerkleProofs] index error, index: %v is not in tree capacity: %v.",
+			index, int64(1)<<t.MaxHeight)
 		log.Println(errInfo)
 		return nil, nil, errors.New(errInfo)
 	}
-	// empty tree
-	if len(t.Leaves) == 0 {
-		rProofHelper = make([]int, t.MaxHeight)
-		for i := 0; i < t.MaxHeight; i++ {
-			rProofHelper[i] = 0
-		}
-		return t.NilHashValueConst, rProofHelper, nil
-	}
-	// if index belongs to leaves
-	if index < int64(len(t.Leaves)) {
-		node := t.Leaves[index]
-		//proofs = append(proofs, node.Value)
-		for node.Parent != nil {
-			if node.Parent.Left == node {
-				if node.Parent.Right == nil {
-					proofs = append(proofs, t.NilHashValueConst[node.Height])
-				} else {
-					proofs = append(proofs, node.Parent.Right.Value)
-				}
-				proofHelpers = append(proofHelpers, Left)
-			} else if node.Parent.Right == node {
-				proofs = append(proofs, node.Parent.Left.Value)
-				proofHelpers = append(proofHelpers, Right)
-			} else {
-				errInfo := fmt.Sprintf("[BuildMerkleProofs] node error, node is neither left node nor right node.")
-				log.Println(errInfo)
-				return nil, nil, errors.New(errInfo)
-			}
-			node = node.Parent
-		}
-	} else {
-		// add itself
-		//proofs = append(proofs, t.NilHashValueConst[0])
-		// get last index
-		lastIndex := int64(len(t.Leaves) - 1)
-		// get last leave node
-		node := t.Leaves[lastIndex]
-		for lastIndex+1 != index {
-			proofs = append(proofs, t.NilHashValueConst[node.Height])
-			if index%2 == 0 {
-				proofHelpers = append(proofHelpers, Right)
-			} else {
-				proofHelpers = append(proofHelpers, Left)
-			}
-			// update value
-			lastIndex /= 2
-			index /= 2
-			node = node.Parent
-		}
-		for node.Parent != nil && node.Parent.Right == node {
-			proofs = append(proofs, t.NilHashValueConst[node.Height])
-			proofHelpers = append(proofHelpers, Right)
-			node = node.Parent
-		}
-		proofs = append(proofs, node.Value)
-		proofHelpers = append(proofHelpers, Left)
-		node = node.Parent
-		for node.Parent != nil {
-			if node.Parent.Left == node {
-				if node.Parent.Right == nil {
-					proofs = append(proofs, t.NilHashValueConst[node.Height])
-				} else {
-					proofs = append(proofs, node.Parent.Right.Value)
-				}
-				proofHelpers = append(proofHelpers, Left)
-			} else if node.Parent.Right == node {
-				proofs = append(proofs, node.Parent.Left.Value)
-				proofHelpers = append(proofHelpers, Right)
-			} else {
-				errInfo := fmt.Sprintf("[BuildMerkleProofs] node error, node is neither left node nor right node.")
-				log.Println(errInfo)
-				return nil, nil, errors.New(errInfo)
-			}
-			node = node.Parent
+	rMerkleProof = make([][]byte, t.MaxHeight)
+	rProofHelper = make([]int, t.MaxHeight)
+	for height := 0; height < t.MaxHeight; height++ {
+		sibling, err := node(height, index^1)
+		if err != nil {
+			log.Println("[BuildMerkleProofs] unable to read node:", err)
+			return nil, nil, err
 		}
+		rMerkleProof[height] = common.CopyBytes(sibling)
+		rProofHelper[height] = int(index & 1)
+		index >>= 1
 	}
-	return proofs, proofHelpers, nil
+	return rMerkleProof, rProofHelper, nil
 }
 
+/*
+	Update: set the leaf and rehash its path, the siblings which were never written are the roots of empty subtrees
+*/
 func (t *Tree) Update(index int64, nVal []byte) (err error) {
-	if index >= 1<<t.MaxHeight {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if index < 0 || index >= 1<<t.MaxHeight {
 		log.Println("[Update] invalid index")
-		return errors.New("[Update] invalid index")
-	}
-	if index <= int64(len(t.Leaves)) {
-		return t.updateExistOrNext(index, nVal)
-	} else {
-		for i := int64(len(t.Leaves)); i < index; i++ {
-			err = t.updateExistOrNext(i, t.NilHashValueConst[0])
-			if err != nil {
-				log.Println("[Update] unable to update exist or next:", err)
-				return err
-			}
-		}
-		err = t.updateExistOrNext(index, nVal)
+		return ErrInvalidIndex
+	}
+	value := common.CopyBytes(nVal)
+	for height := 0; height < t.MaxHeight; height++ {
+		t.dirty[nodeKey{height: height, index: index}] = value
+		sibling, err := t.node(height, index^1)
 		if err != nil {
-			log.Println("[Update] unable to update exist or next:", err)
+			log.Println("[Update] unable to read node:", err)
 			return err
 		}
-	}
-	return nil
-}
-
-func (t *Tree) updateExistOrNext(index int64, nVal []byte) (err error) {
-	if index >= 1<<t.MaxHeight {
-		log.Println("[updateExistOrNext] invalid index")
-		return errors.New("[updateExistOrNext] invalid index")
-	}
-	// empty tree
-	if len(t.Leaves) == 0 {
-		if index != 0 {
-			log.Println("[updateExistOrNext] invalid index")
-			return errors.New("[updateExistOrNext] invalid index")
-		}
-		nodeInfo := &Node{
-			Value:  nVal,
-			Height: 0,
-		}
-		t.Leaves = append(t.Leaves, nodeInfo)
-		err = t.BuildTree(t.Leaves)
-		return err
-	}
-	// index belong to leaves
-	if index < int64(len(t.Leaves)) {
-		node := t.Leaves[index]
-		node.Value = nVal
-		node = node.Parent
-		for node != nil {
-			if node.Right != nil {
-				node.Value = t.HashSubTrees(node.Left.Value, node.Right.Value)
-			} else {
-				node.Value = t.HashSubTrees(node.Left.Value, t.NilHashValueConst[node.Left.Height])
-			}
-			node = node.Parent
-		}
-	} else { // index larger than leaves
-		// that's also insert
-		if index != int64(len(t.Leaves)) {
-			return errors.New("[updateExistOrNext] the index should only be lastIndex+1")
-		}
-		// get last index
-		lastIndex := len(t.Leaves) - 1
-		// get last leave node
-		node := t.Leaves[lastIndex]
-		// even
-		if (index+1)%2 == 0 {
-			// construct node
-			nNode := &Node{
-				Value:  nVal,
-				Parent: node.Parent,
-				Height: 0,
-			}
-			node.Parent.Right = nNode
-			t.Leaves = append(t.Leaves, nNode)
-			node = node.Parent
-			for node != nil {
-				if node.Right != nil {
-					node.Value = t.HashSubTrees(node.Left.Value, node.Right.Value)
-				} else {
-					node.Value = t.HashSubTrees(node.Left.Value, t.NilHashValueConst[node.Left.Height])
-				}
-				node = node.Parent
-			}
-		} else { // odd
-			// Create new Node and append Leaves
-			var (
-				commonLength = 0
-				nearValue    = index ^ (index - 1)
-			)
-			for nearValue != 0 {
-				nearValue /= 2
-				commonLength += 1
-			}
-			// Create new Node and append Leaves
-			nNode := &Node{
-				Value:  nVal,
-				Parent: nil,
-				Height: 0,
-			}
-			t.Leaves = append(t.Leaves, nNode)
-			// search common Parent
-			for commonLength != 0 {
-				commonLength--
-				// handle searching common parentNode
-				node = node.Parent
-
-				if commonLength != 0 { // if find common parentNode, then there is no need to create a new parentNode.
-					// handle parentNode creation
-					parentNode := &Node{
-						Value:  t.HashSubTrees(nNode.Value, t.NilHashValueConst[nNode.Height]),
-						Left:   nNode,
-						Height: nNode.Height + 1,
-					}
-
-					nNode.Parent = parentNode
-					nNode = nNode.Parent
-				}
-
-			}
-			// connect common parent with nNode
-			node.Right = nNode
-			nNode.Parent = node
-
-			for node != nil {
-				if node.Right != nil {
-					node.Value = t.HashSubTrees(node.Left.Value, node.Right.Value)
-				} else {
-					node.Value = t.HashSubTrees(node.Left.Value, t.NilHashValueConst[node.Left.Height])
-				}
-				node = node.Parent
-			}
+		if index&1 == Left {
+			value = t.HashSubTrees(value, sibling)
+		} else {
+			value = t.HashSubTrees(sibling, value)
 		}
+		index >>= 1
 	}
+	t.dirty[nodeKey{height: t.MaxHeight, index: 0}] = value
+	t.root = value
 	return nil
 }
 
-func (t *Tree) insert(nodes []*Node) {
-
-	if len(nodes) == 0 {
-		log.Println("[BuildTree] smt BuildTree error, nodes length == 0")
-		return
-	} else {
-		if nodes[0].Height == 0 && len(nodes) == 1 {
-			t.RootNode = nodes[0]
-			return
-		}
-	}
-	var parents []*Node
-
-	for i := 0; i < len(nodes); i += 2 {
-		if nodes[i].Parent == nil {
-			logInfo := fmt.Sprintf("[insert] boundary one: len(nodes) = %v. i = %v.", len(nodes), i)
-			log.Println(logInfo)
-			// The left node has no parent, need to create a parent node
-			nodes[i].Parent = &Node{
-				Value:  t.HashSubTrees(nodes[i].Value, t.NilHashValueConst[nodes[i].Height]),
-				Left:   nodes[i],
-				Right:  nil,
-				Parent: nil,
-				Height: nodes[i].Height - 1,
-			}
-		} else if i+1 <= len(nodes)-1 {
-			if nodes[i+1].Parent == nil && nodes[i].Parent != nil {
-				logInfo := fmt.Sprintf("[insert] boundary two:len(nodes) = %v. i = %v.", len(nodes), i)
-				log.Println(logInfo)
-				// The right node has no parent, but the left node has a parent, do not need to create a parent node
-				nodes[i+1].Parent = nodes[i].Parent
-				nodes[i+1].Parent.Right = nodes[i+1]
-				nodes[i+1].Parent.Value = t.HashSubTrees(nodes[i].Value, nodes[i+1].Value)
-			}
-		}
-
-		parents = append(parents, nodes[i].Parent)
-	}
-	t.insert(parents)
-}
-
 /*
 	VerifyMerkleProofs: verify merkle proofs
 	@inclusionProofs: inclusion proofs
 	@helperProofs: helper function
 */
 func (t *Tree) VerifyMerkleProofs(inclusionProofs [][]byte, helperProofs []int) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	if len(inclusionProofs) != len(helperProofs)+1 {
 		return false
 	}
 	// empty tree
-	if len(t.Leaves) == 0 {
+	if t.isEmptyTree() {
 		return true
 	}
-	root := t.RootNode.Value
+	root := t.root
 	node := inclusionProofs[0]
 	for i := 1; i < len(inclusionProofs); i++ {
 		switch helperProofs[i-1] {
@@ -621,5 +448,49 @@ func (t *Tree) VerifyMerkleProofs(inclusionProofs [][]byte, helperProofs []int)
 }
 
 func (t *Tree) IsEmptyTree() bool {
-	return len(t.Leaves) == 0
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.isEmptyTree()
+}
+
+func (t *Tree) isEmptyTree() bool {
+	return bytes.Equal(t.root, t.NilHashValueConst[t.MaxHeight])
+}
+
+func encodeNodeKey(key nodeKey) []byte {
+	buf := make([]byte, nodeKeySize)
+	buf[0] = nodeKeyPrefix
+	buf[1] = byte(key.height)
+	binary.BigEndian.PutUint64(buf[2:], uint64(key.index))
+	return buf
+}
+
+/*
+	encodeTreeMeta: the root of the empty tree tells whether the tree was built with the same nil hash and hasher
+*/
+func encodeTreeMeta(maxHeight int, version, oldestVersion int64, emptyRoot []byte) []byte {
+	buf := make([]byte, 3*binary.MaxVarintLen64, 3*binary.MaxVarintLen64+len(emptyRoot))
+	n := binary.PutUvarint(buf, uint64(maxHeight))
+	n += binary.PutUvarint(buf[n:], uint64(version))
+	n += binary.PutUvarint(buf[n:], uint64(oldestVersion))
+	return append(buf[:n], emptyRoot...)
+}
+
+func decodeTreeMeta(buf []byte) (maxHeight int, version, oldestVersion int64, emptyRoot []byte, err error) {
+	var fields [3]uint64
+	pos := 0
+	for i := range fields {
+		value, n := binary.Uvarint(buf[pos:])
+		if n <= 0 {
+			log.Println("[OpenTree] invalid tree meta")
+			return 0, 0, 0, nil, ErrTreeMismatch
+		}
+		fields[i] = value
+		pos += n
+	}
+	if fields[0] > maxTreeHeight || fields[1] > math.MaxInt64 || fields[2] > fields[1] {
+		log.Println("[OpenTree] invalid tree meta")
+		return 0, 0, 0, nil, ErrTreeMismatch
+	}
+	return int(fields[0]), int64(fields[1]), int64(fields[2]), buf[pos:], nil
 }
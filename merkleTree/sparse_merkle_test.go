@@ -53,16 +53,16 @@ func TestCreateLeafNode(t *testing.T) {
 	if err != nil {
 		panic(err)
 	}
-	log.Println(common.Bytes2Hex(tree.RootNode.Value))
+	log.Println(common.Bytes2Hex(tree.Root()))
 	hFunc := mimc.NewMiMC()
 	hFunc.Write([]byte("1"))
 	first := hFunc.Sum(nil)
 	tree.Update(0, first)
-	log.Println(common.Bytes2Hex(tree.RootNode.Value))
+	log.Println(common.Bytes2Hex(tree.Root()))
 	hFunc.Write([]byte("2"))
 	second := hFunc.Sum(nil)
 	tree.Update(1, second)
-	log.Println(common.Bytes2Hex(tree.RootNode.Value))
+	log.Println(common.Bytes2Hex(tree.Root()))
 }
 
 func TestNewTree(t *testing.T) {
@@ -81,7 +81,7 @@ func TestNewTree(t *testing.T) {
 	}
 	fmt.Println("BuildTree tree time:", time.Since(elapse))
 	fmt.Println("height:", tree.MaxHeight)
-	fmt.Println("root:", ToString(tree.RootNode.Value))
+	fmt.Println("root:", ToString(tree.Root()))
 	fmt.Println("nil root:", ToString(tree.NilHashValueConst[0]))
 	elapse = time.Now()
 	// verify index belongs to len(t.leaves)
@@ -116,7 +116,7 @@ func TestNewTree(t *testing.T) {
 	h.Write([]byte("modify"))
 	nVal := h.Sum([]byte{})
 	fmt.Println("nVal:", nVal)
-	err = tree.updateExistOrNext(6, nVal)
+	err = tree.Update(6, nVal)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -168,18 +168,10 @@ func TestNewTreeByMap(t *testing.T) {
 	hashVal2 := h.Sum(nil)
 	leaves := make(map[int64]*Node)
 	leaves[0] = &Node{
-		Value:  hashVal1,
-		Left:   nil,
-		Right:  nil,
-		Parent: nil,
-		Height: 0,
+		Value: hashVal1,
 	}
 	leaves[100] = &Node{
-		Value:  hashVal2,
-		Left:   nil,
-		Right:  nil,
-		Parent: nil,
-		Height: 0,
+		Value: hashVal2,
 	}
 	tree, err := NewTreeByMap(leaves, 16, NilHash, mimc.NewMiMC())
 	if err != nil {
@@ -231,7 +223,7 @@ func TestNewEmptyTree(t *testing.T) {
 	h.Reset()
 	h.Write([]byte("1"))
 	nVal := h.Sum([]byte{})
-	err = tree.updateExistOrNext(0, nVal)
+	err = tree.Update(0, nVal)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -252,8 +244,7 @@ func TestNewTreeByMapAndUpdate(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	fmt.Println(common.Bytes2Hex(treeByMap.RootNode.Value))
-	fmt.Println(len(treeByMap.Leaves))
+	fmt.Println(common.Bytes2Hex(treeByMap.Root()))
 
 	emptyTree, err := NewEmptyTree(2, NilHash, mimc.NewMiMC())
 	if err != nil {
@@ -263,6 +254,88 @@ func TestNewTreeByMapAndUpdate(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	fmt.Println(common.Bytes2Hex(emptyTree.RootNode.Value))
-	fmt.Println(len(emptyTree.Leaves))
+	fmt.Println(common.Bytes2Hex(emptyTree.Root()))
+	assert.Equal(t, treeByMap.Root(), emptyTree.Root())
+}
+
+func TestSparseUpdate(t *testing.T) {
+	hashState := MockState(3)
+	tree, err := NewEmptyTree(40, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	emptyRoot := tree.Root()
+	index := int64(1) << 39
+	err = tree.Update(index, hashState[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tree.Update(3, hashState[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := tree.Leaf(index - 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, NilHash, leaf)
+
+	treeByMap, err := NewTreeByMap(map[int64]*Node{3: CreateLeafNode(hashState[1]), index: CreateLeafNode(hashState[0])}, 40, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, tree.Root(), treeByMap.Root())
+
+	for _, i := range []int64{3, index, index + 1, 1<<40 - 1} {
+		proofs, helpers, err := tree.BuildMerkleProofs(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf, err := tree.Leaf(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		node := leaf
+		for h := range proofs {
+			if helpers[h] == Left {
+				node = tree.HashSubTrees(node, proofs[h])
+			} else {
+				node = tree.HashSubTrees(proofs[h], node)
+			}
+		}
+		assert.Equal(t, tree.Root(), node)
+	}
+
+	// nodes back to the empty subtree roots are not stored
+	store := NewMemoryNodeStore()
+	tree, err = OpenTree(store, 40, NilHash, NewMiMCHasher())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tree.Update(index, hashState[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tree.Commit(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the path, the journal, the root and the meta
+	assert.Equal(t, 44, store.Len())
+	err = tree.Update(index, NilHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tree.Commit(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, emptyRoot, tree.Root())
+	assert.True(t, tree.IsEmptyTree())
+	err = tree.Prune(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the root and the meta
+	assert.Equal(t, 2, store.Len())
 }
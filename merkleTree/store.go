@@ -0,0 +1,165 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrNodeNotFound = errors.New("[NodeStore] node not found")
+	ErrStoreClosed  = errors.New("[NodeStore] store is closed")
+)
+
+/*
+	NodeStore: key-value storage of the tree nodes, a Tree reads its nodes from the store
+	and writes the nodes updated since the last commit as one batch
+*/
+type NodeStore interface {
+	// Get returns ErrNodeNotFound if the key is not in the store
+	Get(key []byte) ([]byte, error)
+	// Write applies all the operations of the batch, or none of them
+	Write(batch *Batch) error
+	Close() error
+}
+
+/*
+	Batch: puts and deletes applied in order by NodeStore.Write
+*/
+type Batch struct {
+	ops []batchOp
+}
+
+type batchOp struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+func (b *Batch) Put(key, value []byte) {
+	b.ops = append(b.ops, batchOp{key: common.CopyBytes(key), value: common.CopyBytes(value)})
+}
+
+func (b *Batch) Delete(key []byte) {
+	b.ops = append(b.ops, batchOp{key: common.CopyBytes(key), delete: true})
+}
+
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+func (b *Batch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+/*
+	MemoryNodeStore: NodeStore kept in a map, used by the trees which are not persisted
+*/
+type MemoryNodeStore struct {
+	mu     sync.RWMutex
+	nodes  map[string][]byte
+	closed bool
+}
+
+func NewMemoryNodeStore() *MemoryNodeStore {
+	return &MemoryNodeStore{nodes: make(map[string][]byte)}
+}
+
+func (s *MemoryNodeStore) Get(key []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, ErrStoreClosed
+	}
+	value, ok := s.nodes[string(key)]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	return common.CopyBytes(value), nil
+}
+
+func (s *MemoryNodeStore) Write(batch *Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStoreClosed
+	}
+	for _, op := range batch.ops {
+		if op.delete {
+			delete(s.nodes, string(op.key))
+		} else {
+			s.nodes[string(op.key)] = common.CopyBytes(op.value)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryNodeStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+/*
+	Len: number of keys in the store
+*/
+func (s *MemoryNodeStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.nodes)
+}
+
+/*
+	NewPrefixNodeStore: view of the store where every key is prefixed, so that several trees
+	(such as the asset tree of every account) can share one store.
+	Closing the view doesn't close the underlying store.
+*/
+func NewPrefixNodeStore(store NodeStore, prefix []byte) NodeStore {
+	return &prefixNodeStore{store: store, prefix: common.CopyBytes(prefix)}
+}
+
+type prefixNodeStore struct {
+	store  NodeStore
+	prefix []byte
+}
+
+func (s *prefixNodeStore) key(key []byte) []byte {
+	prefixed := make([]byte, 0, len(s.prefix)+len(key))
+	prefixed = append(prefixed, s.prefix...)
+	return append(prefixed, key...)
+}
+
+func (s *prefixNodeStore) Get(key []byte) ([]byte, error) {
+	return s.store.Get(s.key(key))
+}
+
+func (s *prefixNodeStore) Write(batch *Batch) error {
+	prefixed := &Batch{ops: make([]batchOp, len(batch.ops))}
+	for i, op := range batch.ops {
+		prefixed.ops[i] = batchOp{key: s.key(op.key), value: op.value, delete: op.delete}
+	}
+	return s.store.Write(prefixed)
+}
+
+func (s *prefixNodeStore) Close() error {
+	return nil
+}
@@ -0,0 +1,327 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryNodeStore(t *testing.T) {
+	store := NewMemoryNodeStore()
+	_, err := store.Get([]byte("a"))
+	assert.Equal(t, ErrNodeNotFound, err)
+
+	batch := new(Batch)
+	batch.Put([]byte("a"), []byte{1})
+	batch.Put([]byte("b"), []byte{2})
+	batch.Delete([]byte("b"))
+	require.NoError(t, store.Write(batch))
+	value, err := store.Get([]byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1}, value)
+	_, err = store.Get([]byte("b"))
+	assert.Equal(t, ErrNodeNotFound, err)
+	assert.Equal(t, 1, store.Len())
+
+	prefixed := NewPrefixNodeStore(store, []byte("p/"))
+	batch.Reset()
+	batch.Put([]byte("a"), []byte{3})
+	require.NoError(t, prefixed.Write(batch))
+	value, err = prefixed.Get([]byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{3}, value)
+	value, err = store.Get([]byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1}, value)
+	require.NoError(t, prefixed.Close())
+
+	require.NoError(t, store.Close())
+	_, err = store.Get([]byte("a"))
+	assert.Equal(t, ErrStoreClosed, err)
+}
+
+func TestFileNodeStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes")
+	store, err := OpenFileNodeStore(path)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		batch := new(Batch)
+		batch.Put([]byte(strconv.Itoa(i)), []byte{byte(i)})
+		batch.Put([]byte("last"), []byte{byte(i)})
+		if i > 0 {
+			batch.Delete([]byte(strconv.Itoa(i - 1)))
+		}
+		require.NoError(t, store.Write(batch))
+	}
+	require.NoError(t, store.Close())
+
+	store, err = OpenFileNodeStore(path)
+	require.NoError(t, err)
+	value, err := store.Get([]byte("last"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{9}, value)
+	_, err = store.Get([]byte("8"))
+	assert.Equal(t, ErrNodeNotFound, err)
+
+	require.NoError(t, store.Compact())
+	value, err = store.Get([]byte("9"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{9}, value)
+	require.NoError(t, store.Close())
+
+	// a record torn by a crash is dropped on open
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = file.Write([]byte{0, 0, 0, 42, 1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	store, err = OpenFileNodeStore(path)
+	require.NoError(t, err)
+	value, err = store.Get([]byte("last"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{9}, value)
+	batch := new(Batch)
+	batch.Put([]byte("last"), []byte{10})
+	require.NoError(t, store.Write(batch))
+	require.NoError(t, store.Close())
+	store, err = OpenFileNodeStore(path)
+	require.NoError(t, err)
+	value, err = store.Get([]byte("last"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{10}, value)
+	require.NoError(t, store.Close())
+}
+
+func TestFileNodeStoreRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes")
+	store, err := OpenFileNodeStore(path)
+	require.NoError(t, err)
+	// more keys than the first index holds, so that it's rehashed
+	const nbKeys = 5000
+	for i := 0; i < nbKeys; i += 100 {
+		batch := new(Batch)
+		for j := i; j < i+100; j++ {
+			batch.Put([]byte(strconv.Itoa(j)), []byte(strconv.Itoa(j*j)))
+		}
+		require.NoError(t, store.Write(batch))
+	}
+	batch := new(Batch)
+	batch.Delete([]byte("7"))
+	batch.Put([]byte("8"), []byte("eight"))
+	require.NoError(t, store.Write(batch))
+	check := func(store *FileNodeStore) {
+		for i := 0; i < nbKeys; i++ {
+			value, err := store.Get([]byte(strconv.Itoa(i)))
+			switch i {
+			case 7:
+				assert.Equal(t, ErrNodeNotFound, err)
+			case 8:
+				require.NoError(t, err)
+				assert.Equal(t, []byte("eight"), value)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, []byte(strconv.Itoa(i*i)), value)
+			}
+		}
+	}
+	check(store)
+	require.NoError(t, store.Close())
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	logSize := info.Size()
+
+	// the index is synced by Close, nothing is replayed
+	store, err = OpenFileNodeStore(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), store.replayed)
+	check(store)
+	// a crash leaves the index dirty, it's rebuilt from the log
+	require.NoError(t, store.Write(batch))
+	store.index.file.Close()
+	store.file.Close()
+	store, err = OpenFileNodeStore(path)
+	require.NoError(t, err)
+	assert.Less(t, logSize-int64(len(fileStoreMagic)), store.replayed)
+	check(store)
+	require.NoError(t, store.Close())
+
+	require.NoError(t, os.Remove(indexPath(path)))
+	store, err = OpenFileNodeStore(path)
+	require.NoError(t, err)
+	assert.Less(t, logSize-int64(len(fileStoreMagic)), store.replayed)
+	check(store)
+	require.NoError(t, store.Close())
+	store, err = OpenFileNodeStore(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), store.replayed)
+	require.NoError(t, store.Close())
+}
+
+func TestFileNodeStoreAutoCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes")
+	store, err := OpenFileNodeStore(path)
+	require.NoError(t, err)
+	store.AutoCompactSize = 4096
+	value := make([]byte, 32)
+	for round := 0; round < 200; round++ {
+		batch := new(Batch)
+		for i := 0; i < 100; i++ {
+			value[0] = byte(round)
+			batch.Put([]byte(strconv.Itoa(i)), value)
+		}
+		require.NoError(t, store.Write(batch))
+	}
+	require.NoError(t, store.Close())
+	// 100 live values of 32 bytes, the 20000 values written take more than 600 KB
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Less(t, info.Size(), int64(16<<10))
+	info, err = os.Stat(indexPath(path))
+	require.NoError(t, err)
+	assert.Equal(t, slotOffset(minIndexSlots), info.Size())
+
+	store, err = OpenFileNodeStore(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), store.replayed)
+	for i := 0; i < 100; i++ {
+		value, err := store.Get([]byte(strconv.Itoa(i)))
+		require.NoError(t, err)
+		assert.Equal(t, byte(199), value[0])
+	}
+	require.NoError(t, store.Close())
+}
+
+func TestFileNodeStoreCompactFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes")
+	store, err := OpenFileNodeStore(path)
+	require.NoError(t, err)
+	store.AutoCompactSize = 1024
+	// the compacted file can't be created
+	require.NoError(t, os.Mkdir(path+".compact", 0700))
+	value := make([]byte, 32)
+	for round := 0; round < 100; round++ {
+		batch := new(Batch)
+		value[0] = byte(round)
+		batch.Put([]byte("key"), value)
+		require.NoError(t, store.Write(batch))
+	}
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Greater(t, info.Size(), int64(1024))
+	got, err := store.Get([]byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, byte(99), got[0])
+
+	// the next Write compacts
+	require.NoError(t, os.Remove(path+".compact"))
+	value[0] = 100
+	batch := new(Batch)
+	batch.Put([]byte("key"), value)
+	require.NoError(t, store.Write(batch))
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Less(t, info.Size(), int64(1024))
+	got, err = store.Get([]byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, byte(100), got[0])
+	require.NoError(t, store.Close())
+}
+
+func TestTreeRootsUnchanged(t *testing.T) {
+	hashState := MockState(6)
+	tree, err := NewTree(CreateLeaves(hashState), 5, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	assert.Equal(t, "1b7ae89ef8f59d6aadfd98b18f7a3f6c75cc49db16d35d97cf6cbb2cb90ee0a8", common.Bytes2Hex(tree.Root()))
+
+	tree, err = NewEmptyTree(16, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	assert.Equal(t, "0766473e337fea6d25a271ca700d2d322969eefc3e25f72871f3db87f62ffb0b", common.Bytes2Hex(tree.Root()))
+	require.NoError(t, tree.Update(0, hashState[1]))
+	require.NoError(t, tree.Update(5, hashState[2]))
+	require.NoError(t, tree.Update(100, hashState[3]))
+	require.NoError(t, tree.Update(5, hashState[4]))
+	assert.Equal(t, "08c85516f26acc469ac11a05ae7eb2f616f3bcefa9e56b168e56ec72ac1ef196", common.Bytes2Hex(tree.Root()))
+
+	proofs, helpers, err := tree.BuildMerkleProofs(5)
+	require.NoError(t, err)
+	assert.Equal(t, NilHash, proofs[0])
+	assert.Equal(t, "04cceecac1c6650d146d05085506fcc424fe5fe2b39e57c900529a7296939158", common.Bytes2Hex(proofs[3]))
+	assert.Equal(t, []int{1, 0, 1, 0}, helpers[:4])
+	proofs, helpers, err = tree.BuildMerkleProofs(300)
+	require.NoError(t, err)
+	assert.Equal(t, NilHash, proofs[0])
+	assert.Equal(t, "12a3638d0c9273127c724b04c8060b7adb67df071da24d8721585a5a0f3d863c", common.Bytes2Hex(proofs[8]))
+	assert.Equal(t, []int{0, 0, 1, 1, 0, 1, 0, 0, 1}, helpers[:9])
+
+	treeByMap, err := NewTreeByMap(map[int64]*Node{0: CreateLeafNode(hashState[1]), 100: CreateLeafNode(hashState[3])}, 16, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	assert.Equal(t, "1372b7586acccb39714d72a5744833e944bad40709149c458121b14af6444ca7", common.Bytes2Hex(treeByMap.Root()))
+	require.NoError(t, treeByMap.Update(5, hashState[4]))
+	assert.Equal(t, tree.Root(), treeByMap.Root())
+}
+
+func TestTreeOnFileNodeStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes")
+	store, err := OpenFileNodeStore(path)
+	require.NoError(t, err)
+	tree, err := OpenTree(store, 16, NilHash, NewMiMCHasher())
+	require.NoError(t, err)
+	memTree, err := NewEmptyTree(16, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+
+	hashState := MockState(20)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, tree.Update(int64(i*3), hashState[i]))
+		require.NoError(t, memTree.Update(int64(i*3), hashState[i]))
+	}
+	require.NoError(t, tree.Commit(1))
+	require.NoError(t, store.Close())
+
+	store, err = OpenFileNodeStore(path)
+	require.NoError(t, err)
+	_, err = OpenTree(store, 15, NilHash, NewMiMCHasher())
+	assert.Equal(t, ErrTreeMismatch, err)
+	tree, err = OpenTree(store, 16, NilHash, NewMiMCHasher())
+	require.NoError(t, err)
+	assert.Equal(t, memTree.Root(), tree.Root())
+	for i := 10; i < 20; i++ {
+		require.NoError(t, tree.Update(int64(i*3), hashState[i]))
+		require.NoError(t, memTree.Update(int64(i*3), hashState[i]))
+	}
+	assert.Equal(t, memTree.Root(), tree.Root())
+	for _, index := range []int64{0, 4, 27, 57, 1000} {
+		proofs, helpers, err := tree.BuildMerkleProofs(index)
+		require.NoError(t, err)
+		memProofs, memHelpers, err := memTree.BuildMerkleProofs(index)
+		require.NoError(t, err)
+		assert.Equal(t, memProofs, proofs)
+		assert.Equal(t, memHelpers, helpers)
+	}
+	leaf, err := tree.Leaf(27)
+	require.NoError(t, err)
+	assert.Equal(t, hashState[9], leaf)
+	require.NoError(t, store.Close())
+}
@@ -0,0 +1,505 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// key of the journal of a version: prefix and version
+	journalKeyPrefix = 'j'
+	// key of the root of a version: prefix and version
+	rootKeyPrefix  = 'r'
+	versionKeySize = 9
+)
+
+var (
+	ErrInvalidVersion  = errors.New("[smt] invalid version")
+	ErrVersionNotFound = errors.New("[smt] version is pruned or not committed")
+	ErrInvalidJournal  = errors.New("[smt] invalid version journal")
+)
+
+/*
+	journal: the nodes changed by a version with their values at the previous committed version,
+	a nil value is the root of an empty subtree
+*/
+type journal struct {
+	prevVersion int64
+	keys        []nodeKey
+	values      [][]byte
+}
+
+/*
+	Version: last committed version, 0 if nothing was committed
+*/
+func (t *Tree) Version() int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.version
+}
+
+/*
+	OldestVersion: oldest version which can still be read or rolled back to
+*/
+func (t *Tree) OldestVersion() int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.oldestVersion
+}
+
+/*
+	Commit: write the nodes updated since the last commit into the store as the given version, in one batch.
+	The versions must increase, such as the block numbers, the values replaced by the version are kept in its
+	journal so that the previous versions can still be read and rolled back to until they are pruned.
+	The nodes which are back to the root of an empty subtree are deleted so that only non-empty subtrees are stored.
+*/
+func (t *Tree) Commit(version int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if version <= t.version {
+		log.Println("[Commit] version should be larger than", t.version)
+		return ErrInvalidVersion
+	}
+	batch := new(Batch)
+	j := &journal{prevVersion: t.version}
+	for key, value := range t.dirty {
+		if bytes.Equal(value, t.NilHashValueConst[key.height]) {
+			value = nil
+		}
+		prev, err := t.storedNode(key)
+		if err != nil {
+			log.Println("[Commit] unable to read node:", err)
+			return err
+		}
+		if bytes.Equal(prev, value) {
+			continue
+		}
+		j.keys = append(j.keys, key)
+		j.values = append(j.values, prev)
+		if value == nil {
+			batch.Delete(encodeNodeKey(key))
+		} else {
+			batch.Put(encodeNodeKey(key), value)
+		}
+	}
+	batch.Put(encodeVersionKey(journalKeyPrefix, version), encodeJournal(j))
+	batch.Put(encodeVersionKey(rootKeyPrefix, version), t.root)
+	batch.Put(treeMetaKey, encodeTreeMeta(t.MaxHeight, version, t.oldestVersion, t.NilHashValueConst[t.MaxHeight]))
+	err := t.store.Write(batch)
+	if err != nil {
+		log.Println("[Commit] unable to write nodes:", err)
+		return err
+	}
+	t.version = version
+	t.dirty = make(map[nodeKey][]byte)
+	return nil
+}
+
+/*
+	RootAt: root of a committed version
+*/
+func (t *Tree) RootAt(version int64) ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.rootAt(version)
+}
+
+func (t *Tree) rootAt(version int64) ([]byte, error) {
+	if version < t.oldestVersion || version > t.version {
+		return nil, ErrVersionNotFound
+	}
+	if version == 0 {
+		return common.CopyBytes(t.NilHashValueConst[t.MaxHeight]), nil
+	}
+	root, err := t.store.Get(encodeVersionKey(rootKeyPrefix, version))
+	if errors.Is(err, ErrNodeNotFound) {
+		return nil, ErrVersionNotFound
+	}
+	if err != nil {
+		log.Println("[RootAt] unable to read root:", err)
+		return nil, err
+	}
+	return common.CopyBytes(root), nil
+}
+
+/*
+	Rollback: restore a committed version, the updates which are not committed and the later versions are dropped
+*/
+func (t *Tree) Rollback(version int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	root, err := t.rootAt(version)
+	if err != nil {
+		log.Println("[Rollback] unable to read root:", err)
+		return err
+	}
+	batch := new(Batch)
+	// from the last version down, so the value at the given version is written last
+	for v := t.version; v > version; {
+		j, err := t.journal(v)
+		if err != nil {
+			log.Println("[Rollback] unable to read journal:", err)
+			return err
+		}
+		for i, key := range j.keys {
+			if j.values[i] == nil {
+				batch.Delete(encodeNodeKey(key))
+			} else {
+				batch.Put(encodeNodeKey(key), j.values[i])
+			}
+		}
+		batch.Delete(encodeVersionKey(journalKeyPrefix, v))
+		batch.Delete(encodeVersionKey(rootKeyPrefix, v))
+		v = j.prevVersion
+	}
+	batch.Put(treeMetaKey, encodeTreeMeta(t.MaxHeight, version, t.oldestVersion, t.NilHashValueConst[t.MaxHeight]))
+	err = t.store.Write(batch)
+	if err != nil {
+		log.Println("[Rollback] unable to write nodes:", err)
+		return err
+	}
+	t.version = version
+	t.root = root
+	t.dirty = make(map[nodeKey][]byte)
+	t.rollbacks = append(t.rollbacks, version)
+	return nil
+}
+
+/*
+	Prune: drop the versions older than the given committed version, such as the last version minus a retention window
+*/
+func (t *Tree) Prune(version int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if version <= t.oldestVersion {
+		return nil
+	}
+	_, err := t.rootAt(version)
+	if err != nil {
+		log.Println("[Prune] unable to read root:", err)
+		return err
+	}
+	batch := new(Batch)
+	// the journal of a version is only needed to read the versions before it
+	for v := version; v > 0; {
+		if v < version {
+			batch.Delete(encodeVersionKey(rootKeyPrefix, v))
+		}
+		j, err := t.journal(v)
+		if errors.Is(err, ErrVersionNotFound) {
+			break
+		}
+		if err != nil {
+			log.Println("[Prune] unable to read journal:", err)
+			return err
+		}
+		batch.Delete(encodeVersionKey(journalKeyPrefix, v))
+		v = j.prevVersion
+	}
+	batch.Put(treeMetaKey, encodeTreeMeta(t.MaxHeight, t.version, version, t.NilHashValueConst[t.MaxHeight]))
+	err = t.store.Write(batch)
+	if err != nil {
+		log.Println("[Prune] unable to write nodes:", err)
+		return err
+	}
+	t.oldestVersion = version
+	t.trimRollbacks()
+	return nil
+}
+
+/*
+	trimRollbacks: a snapshot taken before a rollback to a pruned version is invalid, its version is either
+	rolled back or pruned, so the rollbacks up to the last one to a pruned version aren't needed anymore,
+	the snapshots taken before them are dropped
+*/
+func (t *Tree) trimRollbacks() {
+	trim := 0
+	for i, version := range t.rollbacks {
+		if version < t.oldestVersion {
+			trim = i + 1
+		}
+	}
+	if trim == 0 {
+		return
+	}
+	t.rollbacks = append([]int64{}, t.rollbacks[trim:]...)
+	t.trimmedRollbacks += trim
+}
+
+/*
+	TreeSnapshot: read-only view of a committed version, the updates committed later are undone with their journals.
+	It is safe for concurrent use, all the reads of a snapshot are at the same root while the tree is updated.
+*/
+type TreeSnapshot struct {
+	tree    *Tree
+	version int64
+	root    []byte
+	// journals of the versions up to loaded are in nodes, mu guards them
+	mu sync.RWMutex
+	// number of rollbacks of the tree when the nodes were loaded, including the trimmed ones
+	epoch  int
+	loaded int64
+	nodes  map[nodeKey][]byte
+	// the version was rolled back, it can't be read even if it is committed again
+	dropped bool
+}
+
+/*
+	Snapshot: view of a committed version, it stays valid after the next commits until the version is pruned or rolled back,
+	a version rolled back and committed again is read with a new snapshot
+*/
+func (t *Tree) Snapshot(version int64) (*TreeSnapshot, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.snapshot(version)
+}
+
+/*
+	LatestSnapshot: view of the last committed version
+*/
+func (t *Tree) LatestSnapshot() (*TreeSnapshot, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.snapshot(t.version)
+}
+
+func (t *Tree) snapshot(version int64) (*TreeSnapshot, error) {
+	root, err := t.rootAt(version)
+	if err != nil {
+		return nil, err
+	}
+	s := &TreeSnapshot{
+		tree:    t,
+		version: version,
+		root:    root,
+	}
+	s.reset()
+	return s, nil
+}
+
+func (s *TreeSnapshot) reset() {
+	s.epoch = s.tree.trimmedRollbacks + len(s.tree.rollbacks)
+	s.loaded = s.version
+	s.nodes = make(map[nodeKey][]byte)
+}
+
+/*
+	read: run f at the version of the snapshot, the tree is not updated until it returns
+*/
+func (s *TreeSnapshot) read(f func() error) error {
+	s.tree.mu.RLock()
+	defer s.tree.mu.RUnlock()
+	s.mu.Lock()
+	err := s.refresh()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return f()
+}
+
+/*
+	refresh: load the journals of the versions committed since the last read
+*/
+func (s *TreeSnapshot) refresh() error {
+	t := s.tree
+	if s.epoch < t.trimmedRollbacks {
+		s.dropped = true
+	} else {
+		for _, version := range t.rollbacks[s.epoch-t.trimmedRollbacks:] {
+			if s.version > version {
+				s.dropped = true
+			}
+		}
+	}
+	if s.dropped || s.version < t.oldestVersion || s.version > t.version {
+		return ErrVersionNotFound
+	}
+	if s.epoch != t.trimmedRollbacks+len(t.rollbacks) {
+		s.reset()
+	}
+	// the oldest journal after the snapshot holds the value at the snapshot
+	loaded := make(map[nodeKey][]byte)
+	for v := t.version; v > s.loaded; {
+		j, err := t.journal(v)
+		if err != nil {
+			log.Println("[TreeSnapshot] unable to read journal:", err)
+			return err
+		}
+		for i, key := range j.keys {
+			loaded[key] = j.values[i]
+		}
+		v = j.prevVersion
+	}
+	for key, value := range loaded {
+		if _, ok := s.nodes[key]; !ok {
+			s.nodes[key] = value
+		}
+	}
+	s.loaded = t.version
+	return nil
+}
+
+func (s *TreeSnapshot) node(height int, index int64) ([]byte, error) {
+	key := nodeKey{height: height, index: index}
+	value, ok := s.nodes[key]
+	if !ok {
+		var err error
+		value, err = s.tree.storedNode(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if value == nil {
+		return s.tree.NilHashValueConst[height], nil
+	}
+	return value, nil
+}
+
+func (s *TreeSnapshot) Version() int64 {
+	return s.version
+}
+
+func (s *TreeSnapshot) Root() []byte {
+	return common.CopyBytes(s.root)
+}
+
+/*
+	Leaf: value of the leaf at the version of the snapshot
+*/
+func (s *TreeSnapshot) Leaf(index int64) (leaf []byte, err error) {
+	err = s.read(func() error {
+		leaf, err = s.tree.leaf(index, s.node)
+		return err
+	})
+	return leaf, err
+}
+
+/*
+	BuildMerkleProofs: merkle proofs at the version of the snapshot, same as Tree.BuildMerkleProofs
+*/
+func (s *TreeSnapshot) BuildMerkleProofs(index int64) (merkleProofs [][]byte, helpers []int, err error) {
+	err = s.read(func() error {
+		merkleProofs, helpers, err = s.tree.buildMerkleProofs(index, s.node)
+		return err
+	})
+	return merkleProofs, helpers, err
+}
+
+/*
+	storedNode: committed value of the node, nil for the root of an empty subtree
+*/
+func (t *Tree) storedNode(key nodeKey) ([]byte, error) {
+	value, err := t.store.Get(encodeNodeKey(key))
+	if errors.Is(err, ErrNodeNotFound) {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (t *Tree) journal(version int64) (*journal, error) {
+	buf, err := t.store.Get(encodeVersionKey(journalKeyPrefix, version))
+	if errors.Is(err, ErrNodeNotFound) {
+		return nil, ErrVersionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeJournal(buf)
+}
+
+func encodeVersionKey(prefix byte, version int64) []byte {
+	buf := make([]byte, versionKeySize)
+	buf[0] = prefix
+	binary.BigEndian.PutUint64(buf[1:], uint64(version))
+	return buf
+}
+
+/*
+	encodeJournal: uvarint previous version, uvarint count, then for every node
+	its key and the uvarint length of the value plus one (0 for an empty subtree) followed by the value
+*/
+func encodeJournal(j *journal) []byte {
+	buf := make([]byte, 2*binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, uint64(j.prevVersion))
+	n += binary.PutUvarint(buf[n:], uint64(len(j.keys)))
+	buf = buf[:n]
+	var lenBuf [binary.MaxVarintLen64]byte
+	for i, key := range j.keys {
+		buf = append(buf, encodeNodeKey(key)...)
+		if j.values[i] == nil {
+			buf = append(buf, 0)
+			continue
+		}
+		n = binary.PutUvarint(lenBuf[:], uint64(len(j.values[i]))+1)
+		buf = append(buf, lenBuf[:n]...)
+		buf = append(buf, j.values[i]...)
+	}
+	return buf
+}
+
+func decodeJournal(buf []byte) (*journal, error) {
+	prevVersion, n := binary.Uvarint(buf)
+	if n <= 0 || int64(prevVersion) < 0 {
+		return nil, ErrInvalidJournal
+	}
+	pos := n
+	count, n := binary.Uvarint(buf[pos:])
+	if n <= 0 || count > uint64(len(buf)) {
+		return nil, ErrInvalidJournal
+	}
+	pos += n
+	j := &journal{
+		prevVersion: int64(prevVersion),
+		keys:        make([]nodeKey, count),
+		values:      make([][]byte, count),
+	}
+	for i := range j.keys {
+		if len(buf)-pos < nodeKeySize || buf[pos] != nodeKeyPrefix {
+			return nil, ErrInvalidJournal
+		}
+		j.keys[i] = nodeKey{
+			height: int(buf[pos+1]),
+			index:  int64(binary.BigEndian.Uint64(buf[pos+2:])),
+		}
+		pos += nodeKeySize
+		size, n := binary.Uvarint(buf[pos:])
+		if n <= 0 || size > uint64(len(buf)-pos-n)+1 {
+			return nil, ErrInvalidJournal
+		}
+		pos += n
+		if size == 0 {
+			continue
+		}
+		j.values[i] = common.CopyBytes(buf[pos : pos+int(size)-1])
+		pos += int(size) - 1
+	}
+	if pos != len(buf) {
+		return nil, ErrInvalidJournal
+	}
+	return j, nil
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+	versionedLeaves: the leaves of every version, version v writes the leaves v, 2v and 100+v
+*/
+func versionedLeaves(version int64) map[int64]*Node {
+	hashState := MockState(int(3*version + 3))
+	leaves := make(map[int64]*Node)
+	for v := int64(1); v <= version; v++ {
+		leaves[v] = CreateLeafNode(hashState[3*v])
+		leaves[2*v] = CreateLeafNode(hashState[3*v+1])
+		leaves[100+v] = CreateLeafNode(hashState[3*v+2])
+	}
+	return leaves
+}
+
+func commitVersion(t *testing.T, tree *Tree, version int64) {
+	leaves := versionedLeaves(version)
+	for _, index := range []int64{version, 2 * version, 100 + version} {
+		require.NoError(t, tree.Update(index, leaves[index].Value))
+	}
+	require.NoError(t, tree.Commit(version))
+}
+
+func assertVersion(t *testing.T, snapshot *TreeSnapshot, version int64) {
+	expected, err := NewTreeByMap(versionedLeaves(version), 10, NilHash, mimc.NewMiMC())
+	require.NoError(t, err)
+	assert.Equal(t, version, snapshot.Version())
+	assert.Equal(t, expected.Root(), snapshot.Root())
+	for _, index := range []int64{1, 4, 6, 103, 105, 1023} {
+		proofs, helpers, err := snapshot.BuildMerkleProofs(index)
+		require.NoError(t, err)
+		expectedProofs, expectedHelpers, err := expected.BuildMerkleProofs(index)
+		require.NoError(t, err)
+		assert.Equal(t, expectedProofs, proofs, "version %d index %d", version, index)
+		assert.Equal(t, expectedHelpers, helpers)
+		leaf, err := snapshot.Leaf(index)
+		require.NoError(t, err)
+		expectedLeaf, err := expected.Leaf(index)
+		require.NoError(t, err)
+		assert.Equal(t, expectedLeaf, leaf)
+	}
+}
+
+func TestVersionedTree(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes")
+	store, err := OpenFileNodeStore(path)
+	require.NoError(t, err)
+	tree, err := OpenTree(store, 10, NilHash, NewMiMCHasher())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), tree.Version())
+
+	var snapshot2 *TreeSnapshot
+	for v := int64(1); v <= 5; v++ {
+		commitVersion(t, tree, v)
+		if v == 2 {
+			snapshot2, err = tree.Snapshot(2)
+			require.NoError(t, err)
+			assertVersion(t, snapshot2, 2)
+		}
+	}
+	assert.Equal(t, ErrInvalidVersion, tree.Commit(5))
+	// the snapshot loads the journals committed after it
+	assertVersion(t, snapshot2, 2)
+	for v := int64(0); v <= 5; v++ {
+		snapshot, err := tree.Snapshot(v)
+		require.NoError(t, err)
+		assertVersion(t, snapshot, v)
+		root, err := tree.RootAt(v)
+		require.NoError(t, err)
+		assert.Equal(t, snapshot.Root(), root)
+	}
+	_, err = tree.Snapshot(6)
+	assert.Equal(t, ErrVersionNotFound, err)
+
+	// the updates which are not committed are dropped by the rollback
+	require.NoError(t, tree.Update(7, NilHash))
+	require.NoError(t, tree.Rollback(3))
+	assert.Equal(t, int64(3), tree.Version())
+	root, err := tree.RootAt(3)
+	require.NoError(t, err)
+	assert.Equal(t, root, tree.Root())
+	_, err = tree.RootAt(4)
+	assert.Equal(t, ErrVersionNotFound, err)
+	assertVersion(t, snapshot2, 2)
+	commitVersion(t, tree, 4)
+	current, err := tree.Snapshot(4)
+	require.NoError(t, err)
+	assertVersion(t, current, 4)
+
+	require.NoError(t, tree.Prune(3))
+	assert.Equal(t, int64(3), tree.OldestVersion())
+	_, _, err = snapshot2.BuildMerkleProofs(1)
+	assert.Equal(t, ErrVersionNotFound, err)
+	assert.Equal(t, ErrVersionNotFound, tree.Rollback(2))
+	require.NoError(t, store.Close())
+
+	store, err = OpenFileNodeStore(path)
+	require.NoError(t, err)
+	tree, err = OpenTree(store, 10, NilHash, NewMiMCHasher())
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), tree.Version())
+	assert.Equal(t, int64(3), tree.OldestVersion())
+	snapshot3, err := tree.Snapshot(3)
+	require.NoError(t, err)
+	assertVersion(t, snapshot3, 3)
+	require.NoError(t, tree.Rollback(3))
+	assertVersion(t, snapshot3, 3)
+	require.NoError(t, store.Close())
+}
+
+func TestSnapshotAfterRollback(t *testing.T) {
+	tree, err := OpenTree(NewMemoryNodeStore(), 10, NilHash, NewMiMCHasher())
+	require.NoError(t, err)
+	commitVersion(t, tree, 1)
+	commitVersion(t, tree, 2)
+	snapshot2, err := tree.Snapshot(2)
+	require.NoError(t, err)
+	require.NoError(t, tree.Rollback(1))
+	_, _, err = snapshot2.BuildMerkleProofs(1)
+	assert.Equal(t, ErrVersionNotFound, err)
+
+	// version 2 is committed again with other leaves
+	require.NoError(t, tree.Update(9, MockState(1)[0]))
+	require.NoError(t, tree.Commit(2))
+	assert.NotEqual(t, snapshot2.Root(), tree.Root())
+	_, _, err = snapshot2.BuildMerkleProofs(1)
+	assert.Equal(t, ErrVersionNotFound, err)
+	_, err = snapshot2.Leaf(9)
+	assert.Equal(t, ErrVersionNotFound, err)
+	current, err := tree.Snapshot(2)
+	require.NoError(t, err)
+	assert.Equal(t, tree.Root(), current.Root())
+	_, _, err = current.BuildMerkleProofs(1)
+	assert.NoError(t, err)
+}
+
+func TestPruneTrimsRollbacks(t *testing.T) {
+	tree, err := OpenTree(NewMemoryNodeStore(), 10, NilHash, NewMiMCHasher())
+	require.NoError(t, err)
+	for v := int64(1); v <= 3; v++ {
+		commitVersion(t, tree, v)
+	}
+	snapshot3, err := tree.Snapshot(3)
+	require.NoError(t, err)
+	require.NoError(t, tree.Rollback(2))
+	commitVersion(t, tree, 3)
+	commitVersion(t, tree, 4)
+	snapshot4, err := tree.Snapshot(4)
+	require.NoError(t, err)
+	require.NoError(t, tree.Rollback(4))
+	assert.Equal(t, []int64{2, 4}, tree.rollbacks)
+
+	// the rollback to 2 is only seen by snapshots which are rolled back or pruned
+	require.NoError(t, tree.Prune(3))
+	assert.Equal(t, []int64{4}, tree.rollbacks)
+	_, err = snapshot3.Leaf(1)
+	assert.Equal(t, ErrVersionNotFound, err)
+	assertVersion(t, snapshot4, 4)
+	current, err := tree.Snapshot(3)
+	require.NoError(t, err)
+	assertVersion(t, current, 3)
+}
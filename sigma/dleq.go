@@ -0,0 +1,104 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sigma
+
+import (
+	"log"
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/ffmath"
+	"github.com/bnb-chain/zkbnb-crypto/transcript"
+)
+
+/*
+	DLEQProof: Chaum-Pedersen proof that P1 = x*G1 and P2 = x*G2 for the same x,
+	with R1 = k*G1, R2 = k*G2 and Z = k + c*x. Such as for a twisted ElGamal ciphertext of a known amount b
+	under pk, CL = r*pk and CR - b*H = r*G show that it is well formed.
+*/
+type DLEQProof struct {
+	R1 tebn254.Point
+	R2 tebn254.Point
+	Z  *big.Int
+}
+
+/*
+	ProveDLEQ: proof that x*g1 and x*g2 have the same discrete log
+*/
+func ProveDLEQ(tr *transcript.Transcript, g1, g2 *tebn254.Point, x *big.Int) (*DLEQProof, error) {
+	if g1 == nil || g2 == nil || tebn254.IsZero(g1) || tebn254.IsZero(g2) || x == nil {
+		return nil, ErrInvalidStatement
+	}
+	k, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	x = ffmath.Mod(x, tebn254.Order)
+	p1 := tebn254.ScalarMul(g1, x)
+	p2 := tebn254.ScalarMul(g2, x)
+	proof := &DLEQProof{R1: *tebn254.ScalarMul(g1, k), R2: *tebn254.ScalarMul(g2, k)}
+	c := dleqChallenge(tr, g1, p1, g2, p2, proof)
+	proof.Z = ffmath.AddMod(k, ffmath.Multiply(c, x), tebn254.Order)
+	return proof, nil
+}
+
+/*
+	VerifyDLEQ: check that log_g1(p1) = log_g2(p2), Z*G1 = R1 + c*P1 and Z*G2 = R2 + c*P2
+*/
+func VerifyDLEQ(tr *transcript.Transcript, g1, p1, g2, p2 *tebn254.Point, proof *DLEQProof) error {
+	if proof == nil || proof.Z == nil || !validPoints(g1, p1, g2, p2, &proof.R1, &proof.R2) ||
+		tebn254.IsZero(g1) || tebn254.IsZero(g2) {
+		return ErrInvalidProof
+	}
+	c := dleqChallenge(tr, g1, p1, g2, p2, proof)
+	if !tebn254.ScalarMul(g1, proof.Z).Equal(tebn254.Add(&proof.R1, tebn254.ScalarMul(p1, c))) ||
+		!tebn254.ScalarMul(g2, proof.Z).Equal(tebn254.Add(&proof.R2, tebn254.ScalarMul(p2, c))) {
+		log.Println("[VerifyDLEQ] invalid proof")
+		return ErrVerifyFailed
+	}
+	return nil
+}
+
+func dleqChallenge(tr *transcript.Transcript, g1, p1, g2, p2 *tebn254.Point, proof *DLEQProof) *big.Int {
+	tr.AppendMessage("protocol", []byte("dleq"))
+	tr.AppendPoint("G1", g1)
+	tr.AppendPoint("P1", p1)
+	tr.AppendPoint("G2", g2)
+	tr.AppendPoint("P2", p2)
+	tr.AppendPoint("R1", &proof.R1)
+	tr.AppendPoint("R2", &proof.R2)
+	return tr.ChallengeScalar("c")
+}
+
+/*
+	MarshalBinary: R1, R2 then Z, 96 bytes
+*/
+func (proof *DLEQProof) MarshalBinary() ([]byte, error) {
+	if proof.Z == nil {
+		return nil, ErrInvalidProof
+	}
+	return appendScalar(appendPoint(appendPoint(nil, &proof.R1), &proof.R2), proof.Z), nil
+}
+
+func (proof *DLEQProof) UnmarshalBinary(buf []byte) error {
+	r := &proofReader{buf: buf}
+	r.readPoint(&proof.R1)
+	r.readPoint(&proof.R2)
+	proof.Z = r.readScalar()
+	return r.done()
+}
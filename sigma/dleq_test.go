@@ -0,0 +1,63 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sigma
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/elgamal"
+	"github.com/bnb-chain/zkbnb-crypto/transcript"
+)
+
+func TestDLEQ(t *testing.T) {
+	// a twisted ElGamal ciphertext of a known amount: CL = r*pk and CR - b*H = r*G
+	sk, err := tebn254.GenerateEddsaPrivateKey("sigma test seed")
+	require.NoError(t, err)
+	amount, r := big.NewInt(500), big.NewInt(987654321)
+	c, err := elgamal.EncryptWithRandomness(amount, r, &sk.PublicKey)
+	require.NoError(t, err)
+	proof, err := ProveDLEQ(transcript.NewMiMC(testDomain), tebn254.G, &sk.PublicKey.A, r)
+	require.NoError(t, err)
+	crMinusAmount := tebn254.Add(&c.CR, tebn254.Neg(tebn254.ScalarMul(tebn254.H, amount)))
+	assert.NoError(t, VerifyDLEQ(transcript.NewMiMC(testDomain), tebn254.G, crMinusAmount, &sk.PublicKey.A, &c.CL, proof))
+	// another amount
+	crMinusOther := tebn254.Add(&c.CR, tebn254.Neg(tebn254.ScalarMul(tebn254.H, big.NewInt(501))))
+	assert.Equal(t, ErrVerifyFailed, VerifyDLEQ(transcript.NewMiMC(testDomain), tebn254.G, crMinusOther, &sk.PublicKey.A, &c.CL, proof))
+	// the same x on one side only
+	assert.Equal(t, ErrVerifyFailed, VerifyDLEQ(
+		transcript.NewMiMC(testDomain), tebn254.G, crMinusAmount, &sk.PublicKey.A, tebn254.ScalarMul(&sk.PublicKey.A, big.NewInt(2)), proof,
+	))
+
+	buf, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	decoded := new(DLEQProof)
+	require.NoError(t, decoded.UnmarshalBinary(buf))
+	assert.Equal(t, proof, decoded)
+	// a point out of the subgroup
+	var lowOrder tebn254.Point
+	lowOrder.Y.SetOne()
+	lowOrder.Y.Neg(&lowOrder.Y)
+	copy(buf, tebn254.ToBytes(&lowOrder))
+	assert.Equal(t, ErrInvalidProof, decoded.UnmarshalBinary(buf))
+	assert.Equal(t, ErrInvalidProof, VerifyDLEQ(transcript.NewMiMC(testDomain), tebn254.G, &lowOrder, &sk.PublicKey.A, &c.CL, proof))
+}
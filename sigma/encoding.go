@@ -0,0 +1,111 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sigma
+
+import (
+	"bytes"
+	"log"
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/ffmath"
+)
+
+const scalarSize = 32
+
+func randomScalar() (*big.Int, error) {
+	k, err := ffmath.RandomValue(tebn254.Order)
+	if err != nil {
+		log.Println("[sigma] unable to generate random scalar:", err)
+		return nil, err
+	}
+	return k, nil
+}
+
+/*
+	validPoints: the points of a statement must be in the subgroup, so that they have no small order component
+*/
+func validPoints(points ...*tebn254.Point) bool {
+	for _, p := range points {
+		if p == nil || !tebn254.IsInSubGroup(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func appendPoint(buf []byte, p *tebn254.Point) []byte {
+	return append(buf, tebn254.ToBytes(p)...)
+}
+
+func appendScalar(buf []byte, s *big.Int) []byte {
+	return append(buf, s.FillBytes(make([]byte, scalarSize))...)
+}
+
+/*
+	proofReader: reads the points and the scalars of a proof, the points must be canonically encoded and in the
+	subgroup and the scalars below the order, the first error is kept
+*/
+type proofReader struct {
+	buf []byte
+	err error
+}
+
+func (r *proofReader) readPoint(p *tebn254.Point) {
+	if r.err != nil {
+		return
+	}
+	if len(r.buf) < tebn254.PointSize {
+		r.err = ErrInvalidProof
+		return
+	}
+	pBytes := r.buf[:tebn254.PointSize]
+	r.buf = r.buf[tebn254.PointSize:]
+	point, err := tebn254.FromBytes(pBytes)
+	if err != nil || !tebn254.IsInSubGroup(point) || !bytes.Equal(tebn254.ToBytes(point), pBytes) {
+		r.err = ErrInvalidProof
+		return
+	}
+	p.Set(point)
+}
+
+func (r *proofReader) readScalar() *big.Int {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.buf) < scalarSize {
+		r.err = ErrInvalidProof
+		return nil
+	}
+	s := new(big.Int).SetBytes(r.buf[:scalarSize])
+	r.buf = r.buf[scalarSize:]
+	if s.Cmp(tebn254.Order) >= 0 {
+		r.err = ErrInvalidProof
+	}
+	return s
+}
+
+/*
+	done: error of the reads, and ErrInvalidProof if bytes are left
+*/
+func (r *proofReader) done() error {
+	if r.err == nil && len(r.buf) != 0 {
+		r.err = ErrInvalidProof
+	}
+	return r.err
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sigma
+
+import (
+	"log"
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/ffmath"
+	"github.com/bnb-chain/zkbnb-crypto/transcript"
+)
+
+const MaxOrStatements = 64
+
+/*
+	OrProof: proof of knowledge of x such that Points[i] = x*Bases[i] for one of the statements i, without telling which.
+	The challenges of the other statements are chosen by the prover and their responses simulated, the challenges
+	sum to the challenge of the transcript, R_i = Z_i*B_i - C_i*P_i is recomputed by the verifier.
+	Such as for a commitment C = b*H + r*U, the statements C = r*U and C - H = r*U show that b is 0 or 1.
+*/
+type OrProof struct {
+	C []*big.Int
+	Z []*big.Int
+}
+
+/*
+	ProveOr: proof that the prover knows the discrete log x of points[index] in base bases[index]
+*/
+func ProveOr(tr *transcript.Transcript, bases, points []*tebn254.Point, index int, x *big.Int) (*OrProof, error) {
+	n := len(bases)
+	if n == 0 || n > MaxOrStatements || len(points) != n || index < 0 || index >= n || x == nil {
+		return nil, ErrInvalidStatement
+	}
+	for i := range bases {
+		if bases[i] == nil || points[i] == nil || tebn254.IsZero(bases[i]) {
+			return nil, ErrInvalidStatement
+		}
+	}
+	x = ffmath.Mod(x, tebn254.Order)
+	if !tebn254.ScalarMul(bases[index], x).Equal(points[index]) {
+		log.Println("[ProveOr] the witness doesn't match the statement:", index)
+		return nil, ErrInvalidWitness
+	}
+	proof := &OrProof{C: make([]*big.Int, n), Z: make([]*big.Int, n)}
+	commitments := make([]*tebn254.Point, n)
+	cSum := new(big.Int)
+	var k *big.Int
+	for i := range bases {
+		var err error
+		if i == index {
+			if k, err = randomScalar(); err != nil {
+				return nil, err
+			}
+			commitments[i] = tebn254.ScalarMul(bases[i], k)
+			continue
+		}
+		if proof.C[i], err = randomScalar(); err != nil {
+			return nil, err
+		}
+		if proof.Z[i], err = randomScalar(); err != nil {
+			return nil, err
+		}
+		commitments[i] = orCommitment(bases[i], points[i], proof.C[i], proof.Z[i])
+		cSum.Add(cSum, proof.C[i])
+	}
+	c := orChallenge(tr, bases, points, commitments)
+	proof.C[index] = ffmath.SubMod(c, cSum, tebn254.Order)
+	proof.Z[index] = ffmath.AddMod(k, ffmath.Multiply(proof.C[index], x), tebn254.Order)
+	return proof, nil
+}
+
+/*
+	VerifyOr: check that the prover knows the discrete log of one of the points in its base
+*/
+func VerifyOr(tr *transcript.Transcript, bases, points []*tebn254.Point, proof *OrProof) error {
+	n := len(bases)
+	if proof == nil || n == 0 || n > MaxOrStatements || len(points) != n || len(proof.C) != n || len(proof.Z) != n {
+		return ErrInvalidProof
+	}
+	if !validPoints(bases...) || !validPoints(points...) {
+		return ErrInvalidProof
+	}
+	commitments := make([]*tebn254.Point, n)
+	cSum := new(big.Int)
+	for i := range bases {
+		if tebn254.IsZero(bases[i]) || proof.C[i] == nil || proof.Z[i] == nil {
+			return ErrInvalidProof
+		}
+		commitments[i] = orCommitment(bases[i], points[i], proof.C[i], proof.Z[i])
+		cSum.Add(cSum, proof.C[i])
+	}
+	c := orChallenge(tr, bases, points, commitments)
+	if c.Cmp(cSum.Mod(cSum, tebn254.Order)) != 0 {
+		log.Println("[VerifyOr] invalid proof")
+		return ErrVerifyFailed
+	}
+	return nil
+}
+
+/*
+	orCommitment: R = z*B - c*P
+*/
+func orCommitment(base, point *tebn254.Point, c, z *big.Int) *tebn254.Point {
+	return tebn254.Add(tebn254.ScalarMul(base, z), tebn254.Neg(tebn254.ScalarMul(point, c)))
+}
+
+func orChallenge(tr *transcript.Transcript, bases, points, commitments []*tebn254.Point) *big.Int {
+	tr.AppendMessage("protocol", []byte("or"))
+	tr.AppendUint64("n", uint64(len(bases)))
+	for i := range bases {
+		tr.AppendPoint("B", bases[i])
+		tr.AppendPoint("P", points[i])
+	}
+	for _, R := range commitments {
+		tr.AppendPoint("R", R)
+	}
+	return tr.ChallengeScalar("c")
+}
+
+/*
+	MarshalBinary: number of statements then C and Z of every statement
+*/
+func (proof *OrProof) MarshalBinary() ([]byte, error) {
+	n := len(proof.C)
+	if n == 0 || n > MaxOrStatements || len(proof.Z) != n {
+		return nil, ErrInvalidProof
+	}
+	buf := []byte{byte(n)}
+	for i := range proof.C {
+		if proof.C[i] == nil || proof.Z[i] == nil {
+			return nil, ErrInvalidProof
+		}
+		buf = appendScalar(appendScalar(buf, proof.C[i]), proof.Z[i])
+	}
+	return buf, nil
+}
+
+func (proof *OrProof) UnmarshalBinary(buf []byte) error {
+	if len(buf) == 0 || buf[0] == 0 || buf[0] > MaxOrStatements {
+		return ErrInvalidProof
+	}
+	n := int(buf[0])
+	proof.C = make([]*big.Int, n)
+	proof.Z = make([]*big.Int, n)
+	r := &proofReader{buf: buf[1:]}
+	for i := 0; i < n; i++ {
+		proof.C[i] = r.readScalar()
+		proof.Z[i] = r.readScalar()
+	}
+	return r.done()
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sigma
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/commitment"
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/transcript"
+)
+
+/*
+	bitStatement: the commitment is to 0 or 1, C = r*U or C - H = r*U
+*/
+func bitStatement(c *commitment.Commitment) (bases, points []*tebn254.Point) {
+	bases = []*tebn254.Point{tebn254.U, tebn254.U}
+	points = []*tebn254.Point{c.Point(), tebn254.Add(c.Point(), tebn254.Neg(tebn254.H))}
+	return bases, points
+}
+
+func TestOrProof(t *testing.T) {
+	for _, bit := range []int64{0, 1} {
+		c, opening, err := commitment.CommitRandom(big.NewInt(bit))
+		require.NoError(t, err)
+		bases, points := bitStatement(c)
+		proof, err := ProveOr(transcript.NewMiMC(testDomain), bases, points, int(bit), opening.Blinding)
+		require.NoError(t, err)
+		assert.NoError(t, VerifyOr(transcript.NewMiMC(testDomain), bases, points, proof))
+		// the proof doesn't hold for another statement
+		assert.Equal(t, ErrVerifyFailed, VerifyOr(transcript.NewMiMC(testDomain), bases, []*tebn254.Point{points[1], points[0]}, proof))
+		_, err = ProveOr(transcript.NewMiMC(testDomain), bases, points, int(1-bit), opening.Blinding)
+		assert.Equal(t, ErrInvalidWitness, err)
+
+		buf, err := proof.MarshalBinary()
+		require.NoError(t, err)
+		decoded := new(OrProof)
+		require.NoError(t, decoded.UnmarshalBinary(buf))
+		assert.Equal(t, proof, decoded)
+		assert.Equal(t, ErrInvalidProof, decoded.UnmarshalBinary(buf[:len(buf)-1]))
+	}
+
+	// a commitment to 2 can't be proven
+	c, opening, err := commitment.CommitRandom(big.NewInt(2))
+	require.NoError(t, err)
+	bases, points := bitStatement(c)
+	for index := range bases {
+		_, err = ProveOr(transcript.NewMiMC(testDomain), bases, points, index, opening.Blinding)
+		assert.Equal(t, ErrInvalidWitness, err)
+	}
+
+	// one of several keys
+	x := big.NewInt(42)
+	bases = []*tebn254.Point{tebn254.G, tebn254.H, tebn254.U}
+	points = []*tebn254.Point{tebn254.ScalarMul(tebn254.G, big.NewInt(7)), tebn254.ScalarMul(tebn254.H, x), tebn254.U}
+	proof, err := ProveOr(transcript.NewKeccak(testDomain), bases, points, 1, x)
+	require.NoError(t, err)
+	assert.NoError(t, VerifyOr(transcript.NewKeccak(testDomain), bases, points, proof))
+	assert.Equal(t, ErrInvalidProof, VerifyOr(transcript.NewKeccak(testDomain), bases[:2], points[:2], proof))
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sigma
+
+import (
+	"log"
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/ffmath"
+	"github.com/bnb-chain/zkbnb-crypto/transcript"
+)
+
+/*
+	Sigma protocols over tebn254 made non-interactive with a transcript.Transcript: the prover and the verifier
+	create the transcript with the same domain and append the same context, such as the nonce of a service,
+	before the proof, the proof appends its statement and commitments then derives its challenge.
+*/
+
+/*
+	SchnorrProof: proof of knowledge of x such that P = x*B, with R = k*B and Z = k + c*x
+*/
+type SchnorrProof struct {
+	R tebn254.Point
+	Z *big.Int
+}
+
+/*
+	ProveSchnorr: proof of knowledge of x for P = x*base
+*/
+func ProveSchnorr(tr *transcript.Transcript, base *tebn254.Point, x *big.Int) (*SchnorrProof, error) {
+	if base == nil || tebn254.IsZero(base) || x == nil {
+		return nil, ErrInvalidStatement
+	}
+	k, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	P := tebn254.ScalarMul(base, ffmath.Mod(x, tebn254.Order))
+	proof := &SchnorrProof{R: *tebn254.ScalarMul(base, k)}
+	c := schnorrChallenge(tr, base, P, &proof.R)
+	proof.Z = ffmath.AddMod(k, ffmath.Multiply(c, x), tebn254.Order)
+	return proof, nil
+}
+
+/*
+	VerifySchnorr: check that the prover knows the discrete log of P in base base, Z*B = R + c*P
+*/
+func VerifySchnorr(tr *transcript.Transcript, base, P *tebn254.Point, proof *SchnorrProof) error {
+	if proof == nil || proof.Z == nil || base == nil || tebn254.IsZero(base) || !validPoints(base, P, &proof.R) {
+		return ErrInvalidProof
+	}
+	c := schnorrChallenge(tr, base, P, &proof.R)
+	expected := tebn254.Add(&proof.R, tebn254.ScalarMul(P, c))
+	if !tebn254.ScalarMul(base, proof.Z).Equal(expected) {
+		log.Println("[VerifySchnorr] invalid proof")
+		return ErrVerifyFailed
+	}
+	return nil
+}
+
+/*
+	ProveKeyOwnership: proof of knowledge of the private key of the layer 2 public key, the service appends its
+	nonce to the transcript first so that the proof can't be replayed
+*/
+func ProveKeyOwnership(tr *transcript.Transcript, sk *tebn254.PrivateKey) (*SchnorrProof, error) {
+	s, err := tebn254.PrivateKeyScalar(sk)
+	if err != nil {
+		return nil, ErrInvalidWitness
+	}
+	return ProveSchnorr(tr, tebn254.G, s)
+}
+
+func VerifyKeyOwnership(tr *transcript.Transcript, pk *tebn254.PublicKey, proof *SchnorrProof) error {
+	if pk == nil {
+		return ErrInvalidProof
+	}
+	return VerifySchnorr(tr, tebn254.G, &pk.A, proof)
+}
+
+func schnorrChallenge(tr *transcript.Transcript, base, P, R *tebn254.Point) *big.Int {
+	tr.AppendMessage("protocol", []byte("schnorr"))
+	tr.AppendPoint("B", base)
+	tr.AppendPoint("P", P)
+	tr.AppendPoint("R", R)
+	return tr.ChallengeScalar("c")
+}
+
+/*
+	MarshalBinary: R then Z, 64 bytes
+*/
+func (proof *SchnorrProof) MarshalBinary() ([]byte, error) {
+	if proof.Z == nil {
+		return nil, ErrInvalidProof
+	}
+	return appendScalar(appendPoint(nil, &proof.R), proof.Z), nil
+}
+
+func (proof *SchnorrProof) UnmarshalBinary(buf []byte) error {
+	r := &proofReader{buf: buf}
+	r.readPoint(&proof.R)
+	proof.Z = r.readScalar()
+	return r.done()
+}
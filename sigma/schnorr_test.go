@@ -0,0 +1,75 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sigma
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/transcript"
+)
+
+const testDomain = "ZkBNBSigmaTest"
+
+func TestSchnorr(t *testing.T) {
+	x := big.NewInt(123456789)
+	proof, err := ProveSchnorr(transcript.NewMiMC(testDomain), tebn254.H, x)
+	require.NoError(t, err)
+	P := tebn254.ScalarMul(tebn254.H, x)
+	assert.NoError(t, VerifySchnorr(transcript.NewMiMC(testDomain), tebn254.H, P, proof))
+	assert.Equal(t, ErrVerifyFailed, VerifySchnorr(transcript.NewMiMC(testDomain), tebn254.U, P, proof))
+	assert.Equal(t, ErrVerifyFailed, VerifySchnorr(transcript.NewMiMC("another domain"), tebn254.H, P, proof))
+	assert.Equal(t, ErrVerifyFailed, VerifySchnorr(transcript.NewMiMC(testDomain), tebn254.H, tebn254.Add(P, tebn254.H), proof))
+	_, err = ProveSchnorr(transcript.NewMiMC(testDomain), tebn254.ZeroPoint(), x)
+	assert.Equal(t, ErrInvalidStatement, err)
+
+	buf, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	decoded := new(SchnorrProof)
+	require.NoError(t, decoded.UnmarshalBinary(buf))
+	assert.Equal(t, proof, decoded)
+	assert.Equal(t, ErrInvalidProof, decoded.UnmarshalBinary(buf[1:]))
+	assert.Equal(t, ErrInvalidProof, decoded.UnmarshalBinary(append(buf, 0)))
+}
+
+func TestKeyOwnership(t *testing.T) {
+	sk, err := tebn254.GenerateEddsaPrivateKey("sigma test seed")
+	require.NoError(t, err)
+	// the service sends a nonce which both sides append
+	nonce := []byte("service nonce 1")
+	tr := transcript.NewKeccak(testDomain)
+	tr.AppendMessage("nonce", nonce)
+	proof, err := ProveKeyOwnership(tr, sk)
+	require.NoError(t, err)
+
+	tr = transcript.NewKeccak(testDomain)
+	tr.AppendMessage("nonce", nonce)
+	assert.NoError(t, VerifyKeyOwnership(tr, &sk.PublicKey, proof))
+	tr = transcript.NewKeccak(testDomain)
+	tr.AppendMessage("nonce", []byte("service nonce 2"))
+	assert.Equal(t, ErrVerifyFailed, VerifyKeyOwnership(tr, &sk.PublicKey, proof))
+	other, err := tebn254.GenerateEddsaPrivateKey("another sigma test seed")
+	require.NoError(t, err)
+	tr = transcript.NewKeccak(testDomain)
+	tr.AppendMessage("nonce", nonce)
+	assert.Equal(t, ErrVerifyFailed, VerifyKeyOwnership(tr, &other.PublicKey, proof))
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package transcript
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+)
+
+const (
+	// the messages are hashed in chunks of chunkSize bytes behind a zero byte, so that every MiMC block is below the modulus
+	chunkSize  = 31
+	scalarSize = 32
+)
+
+/*
+	Transcript: Fiat-Shamir transcript of a proof. Every message is absorbed with its label into a hash chain,
+	state = H(state || label || message) with length prefixed label and message, and a challenge is derived from
+	everything absorbed before it, so the prover and the verifier must absorb the same messages in the same order.
+	The domain passed to the constructor separates the protocols using the same hash.
+*/
+type Transcript struct {
+	newHash func() hash.Hash
+	state   []byte
+}
+
+/*
+	New: transcript of the protocol of the domain hashed with the hash of newHash
+*/
+func New(domain string, newHash func() hash.Hash) *Transcript {
+	t := &Transcript{newHash: newHash}
+	t.AppendMessage("domain", []byte(domain))
+	return t
+}
+
+/*
+	NewMiMC: transcript hashed with MiMC on bn254, the hash of the circuits
+*/
+func NewMiMC(domain string) *Transcript {
+	return New(domain, mimc.NewMiMC)
+}
+
+/*
+	NewKeccak: transcript hashed with Keccak256, the hash of the layer 1 contracts
+*/
+func NewKeccak(domain string) *Transcript {
+	return New(domain, sha3.NewLegacyKeccak256)
+}
+
+func (t *Transcript) AppendMessage(label string, msg []byte) {
+	t.state = t.hash(t.state, []byte(label), msg)
+}
+
+func (t *Transcript) AppendUint64(label string, v uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	t.AppendMessage(label, buf[:])
+}
+
+/*
+	AppendScalar: absorb the scalar modulo tebn254.Order
+*/
+func (t *Transcript) AppendScalar(label string, s *big.Int) {
+	v := new(big.Int).Mod(s, tebn254.Order)
+	t.AppendMessage(label, v.FillBytes(make([]byte, scalarSize)))
+}
+
+/*
+	AppendPoint: absorb the compressed point
+*/
+func (t *Transcript) AppendPoint(label string, p *tebn254.Point) {
+	t.AppendMessage(label, tebn254.ToBytes(p))
+}
+
+/*
+	ChallengeScalar: non-zero challenge modulo tebn254.Order, it is reduced from two digests to make its bias negligible
+*/
+func (t *Transcript) ChallengeScalar(label string) *big.Int {
+	for {
+		t.AppendMessage("challenge", []byte(label))
+		wide := append(t.hash(t.state, []byte("squeeze"), []byte{0}), t.hash(t.state, []byte("squeeze"), []byte{1})...)
+		c := new(big.Int).SetBytes(wide)
+		c.Mod(c, tebn254.Order)
+		if c.Sign() != 0 {
+			return c
+		}
+	}
+}
+
+/*
+	Clone: copy of the transcript, such as to derive challenges of a branch without changing the transcript
+*/
+func (t *Transcript) Clone() *Transcript {
+	return &Transcript{newHash: t.newHash, state: append([]byte{}, t.state...)}
+}
+
+func (t *Transcript) hash(state, label, msg []byte) []byte {
+	var buf []byte
+	buf = appendLengthPrefixed(buf, state)
+	buf = appendLengthPrefixed(buf, label)
+	buf = appendLengthPrefixed(buf, msg)
+	h := t.newHash()
+	chunk := make([]byte, chunkSize+1)
+	for i := 0; i < len(buf); i += chunkSize {
+		for j := range chunk {
+			chunk[j] = 0
+		}
+		copy(chunk[1:], buf[i:])
+		h.Write(chunk)
+	}
+	return h.Sum(nil)
+}
+
+func appendLengthPrefixed(buf, data []byte) []byte {
+	var prefix [8]byte
+	binary.BigEndian.PutUint64(prefix[:], uint64(len(data)))
+	buf = append(buf, prefix[:]...)
+	return append(buf, data...)
+}
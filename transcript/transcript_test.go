@@ -0,0 +1,55 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package transcript
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+)
+
+func TestTranscript(t *testing.T) {
+	for _, newTranscript := range []func(domain string) *Transcript{NewMiMC, NewKeccak} {
+		transcript := func(domain, label string, msg []byte) *Transcript {
+			tr := newTranscript(domain)
+			tr.AppendMessage(label, msg)
+			tr.AppendPoint("G", tebn254.G)
+			tr.AppendScalar("s", big.NewInt(7))
+			return tr
+		}
+		c := transcript("test", "msg", []byte("hello")).ChallengeScalar("c")
+		assert.Equal(t, c, transcript("test", "msg", []byte("hello")).ChallengeScalar("c"))
+		assert.True(t, c.Sign() > 0 && c.Cmp(tebn254.Order) < 0)
+		assert.NotEqual(t, c, transcript("test", "msg", []byte("hello")).ChallengeScalar("d"))
+		assert.NotEqual(t, c, transcript("another", "msg", []byte("hello")).ChallengeScalar("c"))
+		assert.NotEqual(t, c, transcript("test", "msg", []byte("hellO")).ChallengeScalar("c"))
+		// the label and the message don't run into each other
+		assert.NotEqual(t, c, transcript("test", "msgh", []byte("ello")).ChallengeScalar("c"))
+
+		// the challenges change the state, a clone doesn't change the transcript
+		tr := transcript("test", "msg", []byte("hello"))
+		clone := tr.Clone()
+		assert.Equal(t, c, clone.ChallengeScalar("c"))
+		assert.Equal(t, c, tr.ChallengeScalar("c"))
+		assert.NotEqual(t, c, tr.ChallengeScalar("c"))
+	}
+	assert.NotEqual(t, NewMiMC("test").ChallengeScalar("c"), NewKeccak("test").ChallengeScalar("c"))
+}
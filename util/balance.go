@@ -104,3 +104,23 @@ func CleanPackedFee(amount *big.Int) (nAmount *big.Int, err error) {
 	nAmount = ffmath.Multiply(oAmount, new(big.Int).Exp(big.NewInt(10), big.NewInt(exponent), nil))
 	return nAmount, nil
 }
+
+/*
+UnpackAmount: inverse of ToPackedAmount, the lower 5 bits are the exponent and the rest is the mantissa
+*/
+func UnpackAmount(packedAmount int64) *big.Int {
+	return unpack(packedAmount)
+}
+
+/*
+UnpackFee: inverse of ToPackedFee, the lower 5 bits are the exponent and the rest is the mantissa
+*/
+func UnpackFee(packedFee int64) *big.Int {
+	return unpack(packedFee)
+}
+
+func unpack(packed int64) *big.Int {
+	mantissa := big.NewInt(packed >> 5)
+	exponent := big.NewInt(packed & 31)
+	return ffmath.Multiply(mantissa, new(big.Int).Exp(big.NewInt(10), exponent, nil))
+}
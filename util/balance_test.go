@@ -14,3 +14,31 @@ func TestToPackedAmount(t *testing.T) {
 	}
 	fmt.Println(amount)
 }
+
+func TestUnpackAmount(t *testing.T) {
+	a, _ := new(big.Int).SetString("343597383671", 10)
+	cleaned, err := CleanPackedAmount(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packed, err := ToPackedAmount(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if UnpackAmount(packed).Cmp(cleaned) != 0 {
+		t.Fatalf("unpacked amount mismatch: %s != %s", UnpackAmount(packed).String(), cleaned.String())
+	}
+
+	fee := big.NewInt(123456789)
+	cleaned, err = CleanPackedFee(fee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packedFee, err := ToPackedFee(fee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if UnpackFee(packedFee).Cmp(cleaned) != 0 {
+		t.Fatalf("unpacked fee mismatch: %s != %s", UnpackFee(packedFee).String(), cleaned.String())
+	}
+}
@@ -0,0 +1,36 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+
+package src
+
+import (
+	"syscall/js"
+
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+/*
+	chainIdArg: the chain id is the last argument of the sign functions,
+	the callers written before it was an argument sign for txtypes.DefaultChainId
+*/
+func chainIdArg(args []js.Value, i int) int64 {
+	if len(args) <= i || args[i].IsUndefined() || args[i].IsNull() {
+		return txtypes.DefaultChainId
+	}
+	return int64(args[i].Int())
+}
@@ -29,16 +29,17 @@ import (
 
 func CreateCollectionTx() js.Func {
 	helperFunc := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		if len(args) != 2 {
+		if len(args) != 2 && len(args) != 3 {
 			return "invalid mint nft params"
 		}
 		seed := args[0].String()
 		segmentStr := args[1].String()
+		chainId := chainIdArg(args, 2)
 		sk, err := curve.GenerateEddsaPrivateKey(seed)
 		if err != nil {
 			return err.Error()
 		}
-		txInfo, err := txtypes.ConstructCreateCollectionTxInfo(sk, segmentStr)
+		txInfo, err := txtypes.ConstructCreateCollectionTxInfo(sk, segmentStr, chainId)
 		if err != nil {
 			log.Println("[CreateCollectionTx] unable to construct generic transfer:", err)
 			return err.Error()
@@ -29,16 +29,17 @@ import (
 
 func WithdrawNftTx() js.Func {
 	helperFunc := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		if len(args) != 2 {
+		if len(args) != 2 && len(args) != 3 {
 			return "invalid withdraw nft params"
 		}
 		seed := args[0].String()
 		segmentStr := args[1].String()
+		chainId := chainIdArg(args, 2)
 		sk, err := curve.GenerateEddsaPrivateKey(seed)
 		if err != nil {
 			return err.Error()
 		}
-		txInfo, err := txtypes.ConstructWithdrawNftTxInfo(sk, segmentStr)
+		txInfo, err := txtypes.ConstructWithdrawNftTxInfo(sk, segmentStr, chainId)
 		if err != nil {
 			log.Println("[WithdrawNftTx] unable to construct generic transfer:", err)
 			return err.Error()
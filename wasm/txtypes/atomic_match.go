@@ -49,7 +49,7 @@ type AtomicMatchSegmentFormat struct {
 /*
 ConstructMintNftTxInfo: construct mint nft tx, sign txInfo
 */
-func ConstructAtomicMatchTxInfo(sk *PrivateKey, segmentStr string) (txInfo *AtomicMatchTxInfo, err error) {
+func ConstructAtomicMatchTxInfo(sk *PrivateKey, segmentStr string, chainId int64) (txInfo *AtomicMatchTxInfo, err error) {
 	var segmentFormat *AtomicMatchSegmentFormat
 	err = json.Unmarshal([]byte(segmentStr), &segmentFormat)
 	if err != nil {
@@ -83,6 +83,7 @@ func ConstructAtomicMatchTxInfo(sk *PrivateKey, segmentStr string) (txInfo *Atom
 		GasFeeAssetId:     segmentFormat.GasFeeAssetId,
 		GasFeeAssetAmount: gasFeeAmount,
 		Nonce:             segmentFormat.Nonce,
+		ChainId:           chainId,
 		ExpiredAt:         segmentFormat.ExpiredAt,
 		Sig:               nil,
 	}
@@ -115,10 +116,21 @@ type AtomicMatchTxInfo struct {
 	CreatorAmount     *big.Int
 	TreasuryAmount    *big.Int
 	Nonce             int64
+	ChainId           int64
 	ExpiredAt         int64
 	Sig               []byte
 }
 
+func (txInfo *AtomicMatchTxInfo) UnmarshalJSON(data []byte) error {
+	type plain AtomicMatchTxInfo
+	decoded := plain{ChainId: DefaultChainId}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*txInfo = AtomicMatchTxInfo(decoded)
+	return nil
+}
+
 func (txInfo *AtomicMatchTxInfo) Validate() error {
 	// AccountIndex
 	if txInfo.AccountIndex < minAccountIndex {
@@ -176,6 +188,17 @@ func (txInfo *AtomicMatchTxInfo) Validate() error {
 		return ErrNonceTooLow
 	}
 
+	// ChainId
+	if txInfo.ChainId < minChainId {
+		return ErrChainIdTooLow
+	}
+	if txInfo.ChainId > maxChainId {
+		return ErrChainIdTooHigh
+	}
+	if txInfo.BuyOffer.ChainId != txInfo.ChainId || txInfo.SellOffer.ChainId != txInfo.ChainId {
+		return ErrOfferChainIdMismatch
+	}
+
 	return nil
 }
 
@@ -238,7 +261,7 @@ func (txInfo *AtomicMatchTxInfo) Hash(hFunc hash.Hash) (msgHash []byte, err erro
 		log.Println("[ComputeTransferMsgHash] unable to packed amount:", err.Error())
 		return nil, err
 	}
-	WriteInt64IntoBuf(&buf, ChainId, txInfo.AccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
+	WriteInt64IntoBuf(&buf, txInfo.ChainId, txInfo.AccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
 	WriteInt64IntoBuf(&buf, txInfo.GasAccountIndex, txInfo.GasFeeAssetId, packedFee)
 	WriteInt64IntoBuf(&buf, txInfo.BuyOffer.Type, txInfo.BuyOffer.OfferId, txInfo.BuyOffer.AccountIndex, txInfo.BuyOffer.NftIndex)
 	WriteInt64IntoBuf(&buf, txInfo.BuyOffer.AssetId, packedBuyAmount, txInfo.BuyOffer.ListedAt, txInfo.BuyOffer.ExpiredAt)
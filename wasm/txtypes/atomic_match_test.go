@@ -20,7 +20,10 @@ func TestValidateAtomicMatchTxInfo(t *testing.T) {
 		ListedAt:     time.Now().Add(time.Hour).UnixMilli(),
 		ExpiredAt:    time.Now().Add(time.Hour).UnixMilli(),
 		TreasuryRate: 10,
+		ChainId:      DefaultChainId,
 	}
+	otherChainOffer := *validOffer
+	otherChainOffer.ChainId = 2
 
 	testCases := []struct {
 		err      error
@@ -140,6 +143,36 @@ func TestValidateAtomicMatchTxInfo(t *testing.T) {
 				Nonce:             -1,
 			},
 		},
+		// offers signed for another chain
+		{
+			fmt.Errorf("ChainId of the offers should be the ChainId of the tx"),
+			&AtomicMatchTxInfo{
+				AccountIndex:      1,
+				BuyOffer:          validOffer,
+				SellOffer:         &otherChainOffer,
+				GasAccountIndex:   0,
+				GasFeeAssetId:     3,
+				GasFeeAssetAmount: big.NewInt(100),
+				ExpiredAt:         time.Now().Add(time.Hour).UnixMilli(),
+				Nonce:             1,
+				ChainId:           DefaultChainId,
+			},
+		},
+		// true
+		{
+			nil,
+			&AtomicMatchTxInfo{
+				AccountIndex:      1,
+				BuyOffer:          validOffer,
+				SellOffer:         validOffer,
+				GasAccountIndex:   0,
+				GasFeeAssetId:     3,
+				GasFeeAssetAmount: big.NewInt(100),
+				ExpiredAt:         time.Now().Add(time.Hour).UnixMilli(),
+				Nonce:             1,
+				ChainId:           DefaultChainId,
+			},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -39,7 +39,7 @@ type CancelOfferSegmentFormat struct {
 	Nonce             int64  `json:"nonce"`
 }
 
-func ConstructCancelOfferTxInfo(sk *PrivateKey, segmentStr string) (txInfo *CancelOfferTxInfo, err error) {
+func ConstructCancelOfferTxInfo(sk *PrivateKey, segmentStr string, chainId int64) (txInfo *CancelOfferTxInfo, err error) {
 	var segmentFormat *CancelOfferSegmentFormat
 	err = json.Unmarshal([]byte(segmentStr), &segmentFormat)
 	if err != nil {
@@ -60,6 +60,7 @@ func ConstructCancelOfferTxInfo(sk *PrivateKey, segmentStr string) (txInfo *Canc
 		GasFeeAssetAmount: gasFeeAmount,
 		ExpiredAt:         segmentFormat.ExpiredAt,
 		Nonce:             segmentFormat.Nonce,
+		ChainId:           chainId,
 		Sig:               nil,
 	}
 	// compute call data hash
@@ -89,9 +90,20 @@ type CancelOfferTxInfo struct {
 	GasFeeAssetAmount *big.Int
 	ExpiredAt         int64
 	Nonce             int64
+	ChainId           int64
 	Sig               []byte
 }
 
+func (txInfo *CancelOfferTxInfo) UnmarshalJSON(data []byte) error {
+	type plain CancelOfferTxInfo
+	decoded := plain{ChainId: DefaultChainId}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*txInfo = CancelOfferTxInfo(decoded)
+	return nil
+}
+
 func (txInfo *CancelOfferTxInfo) Validate() error {
 	// AccountIndex
 	if txInfo.AccountIndex < minAccountIndex {
@@ -138,6 +150,14 @@ func (txInfo *CancelOfferTxInfo) Validate() error {
 		return ErrNonceTooLow
 	}
 
+	// ChainId
+	if txInfo.ChainId < minChainId {
+		return ErrChainIdTooLow
+	}
+	if txInfo.ChainId > maxChainId {
+		return ErrChainIdTooHigh
+	}
+
 	return nil
 }
 
@@ -189,7 +209,7 @@ func (txInfo *CancelOfferTxInfo) Hash(hFunc hash.Hash) (msgHash []byte, err erro
 		log.Println("[ComputeTransferMsgHash] unable to packed amount:", err.Error())
 		return nil, err
 	}
-	WriteInt64IntoBuf(&buf, ChainId, txInfo.AccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
+	WriteInt64IntoBuf(&buf, txInfo.ChainId, txInfo.AccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
 	WriteInt64IntoBuf(&buf, txInfo.GasAccountIndex, txInfo.GasFeeAssetId, packedFee)
 	WriteInt64IntoBuf(&buf, txInfo.OfferId)
 	hFunc.Write(buf.Bytes())
@@ -114,6 +114,19 @@ func TestValidateCancelOfferTxInfo(t *testing.T) {
 				Nonce:             -1,
 			},
 		},
+		// ChainId
+		{
+			fmt.Errorf("ChainId should not be less than %d", minChainId),
+			&CancelOfferTxInfo{
+				AccountIndex:      1,
+				OfferId:           1,
+				GasAccountIndex:   0,
+				GasFeeAssetId:     3,
+				GasFeeAssetAmount: big.NewInt(100),
+				ExpiredAt:         time.Now().Add(time.Hour).UnixMilli(),
+				Nonce:             1,
+			},
+		},
 		// true
 		{
 			nil,
@@ -125,6 +138,7 @@ func TestValidateCancelOfferTxInfo(t *testing.T) {
 				GasFeeAssetAmount: big.NewInt(100),
 				ExpiredAt:         time.Now().Add(time.Hour).UnixMilli(),
 				Nonce:             1,
+				ChainId:           DefaultChainId,
 			},
 		},
 	}
@@ -31,8 +31,10 @@ type (
 	PrivateKey = eddsa.PrivateKey
 )
 
+// DefaultChainId is the chain id of the tx infos signed before it was part of
+// the signature, they decode with it when their ChainId is missing.
 const (
-	ChainId = 1
+	DefaultChainId = 1
 )
 
 const (
@@ -77,6 +79,9 @@ const (
 
 	minNonce int64 = 0
 
+	minChainId int64 = 1
+	maxChainId int64 = (1 << 32) - 1
+
 	minTreasuryRate int64 = 0
 	maxTreasuryRate int64 = 10000
 
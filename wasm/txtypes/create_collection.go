@@ -43,7 +43,7 @@ type CreateCollectionSegmentFormat struct {
 /*
 ConstructCreateCollectionTxInfo: construct mint nft tx, sign txInfo
 */
-func ConstructCreateCollectionTxInfo(sk *PrivateKey, segmentStr string) (txInfo *CreateCollectionTxInfo, err error) {
+func ConstructCreateCollectionTxInfo(sk *PrivateKey, segmentStr string, chainId int64) (txInfo *CreateCollectionTxInfo, err error) {
 	var segmentFormat *CreateCollectionSegmentFormat
 	err = json.Unmarshal([]byte(segmentStr), &segmentFormat)
 	if err != nil {
@@ -65,6 +65,7 @@ func ConstructCreateCollectionTxInfo(sk *PrivateKey, segmentStr string) (txInfo
 		GasFeeAssetAmount: gasFeeAmount,
 		ExpiredAt:         segmentFormat.ExpiredAt,
 		Nonce:             segmentFormat.Nonce,
+		ChainId:           chainId,
 		Sig:               nil,
 	}
 	// compute call data hash
@@ -96,9 +97,20 @@ type CreateCollectionTxInfo struct {
 	GasFeeAssetAmount *big.Int
 	ExpiredAt         int64
 	Nonce             int64
+	ChainId           int64
 	Sig               []byte
 }
 
+func (txInfo *CreateCollectionTxInfo) UnmarshalJSON(data []byte) error {
+	type plain CreateCollectionTxInfo
+	decoded := plain{ChainId: DefaultChainId}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*txInfo = CreateCollectionTxInfo(decoded)
+	return nil
+}
+
 func (txInfo *CreateCollectionTxInfo) Validate() error {
 	// AccountIndex
 	if txInfo.AccountIndex < minAccountIndex {
@@ -153,6 +165,14 @@ func (txInfo *CreateCollectionTxInfo) Validate() error {
 		return ErrNonceTooLow
 	}
 
+	// ChainId
+	if txInfo.ChainId < minChainId {
+		return ErrChainIdTooLow
+	}
+	if txInfo.ChainId > maxChainId {
+		return ErrChainIdTooHigh
+	}
+
 	return nil
 }
 
@@ -204,7 +224,7 @@ func (txInfo *CreateCollectionTxInfo) Hash(hFunc hash.Hash) (msgHash []byte, err
 		log.Println("[ComputeTransferMsgHash] unable to packed amount", err.Error())
 		return nil, err
 	}
-	WriteInt64IntoBuf(&buf, ChainId, txInfo.AccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
+	WriteInt64IntoBuf(&buf, txInfo.ChainId, txInfo.AccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
 	WriteInt64IntoBuf(&buf, txInfo.GasAccountIndex, txInfo.GasFeeAssetId, packedFee)
 	hFunc.Write(buf.Bytes())
 	msgHash = hFunc.Sum(nil)
@@ -150,6 +150,21 @@ func TestValidateCreateCollectionTxInfo(t *testing.T) {
 				Nonce:             -1,
 			},
 		},
+		// ChainId
+		{
+			fmt.Errorf("ChainId should not be less than %d", minChainId),
+			&CreateCollectionTxInfo{
+				AccountIndex:      1,
+				CollectionId:      5,
+				Name:              "test name",
+				Introduction:      "test introduction",
+				GasAccountIndex:   0,
+				GasFeeAssetId:     3,
+				GasFeeAssetAmount: big.NewInt(100),
+				ExpiredAt:         time.Now().Add(time.Hour).UnixMilli(),
+				Nonce:             1,
+			},
+		},
 		// true
 		{
 			nil,
@@ -163,6 +178,7 @@ func TestValidateCreateCollectionTxInfo(t *testing.T) {
 				GasFeeAssetAmount: big.NewInt(100),
 				ExpiredAt:         time.Now().Add(time.Hour).UnixMilli(),
 				Nonce:             1,
+				ChainId:           DefaultChainId,
 			},
 		},
 	}
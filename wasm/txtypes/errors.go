@@ -30,6 +30,8 @@ var (
 	ErrNftCollectionIdTooLow    = fmt.Errorf("NftCollectionId should not be less than %d", minCollectionId)
 	ErrNftCollectionIdTooHigh   = fmt.Errorf("NftCollectionId should not be larger than %d", maxCollectionId)
 	ErrCallDataHashInvalid      = fmt.Errorf("CallDataHash is invalid")
+	ErrChainIdTooLow            = fmt.Errorf("ChainId should not be less than %d", minChainId)
+	ErrChainIdTooHigh           = fmt.Errorf("ChainId should not be larger than %d", maxChainId)
 
 	ErrCreatorAccountIndexTooLow  = fmt.Errorf("CreatorAccountIndex should not be less than %d", minAccountIndex)
 	ErrCreatorAccountIndexTooHigh = fmt.Errorf("CreatorAccountIndex should not be larger than %d", maxAccountIndex)
@@ -43,4 +45,5 @@ var (
 	ErrToAddressInvalid           = fmt.Errorf("ToAddress is invalid")
 	ErrBuyOfferInvalid            = fmt.Errorf("BuyOffer is invalid")
 	ErrSellOfferInvalid           = fmt.Errorf("SellOffer is invalid")
+	ErrOfferChainIdMismatch       = fmt.Errorf("ChainId of the offers should be the ChainId of the tx")
 )
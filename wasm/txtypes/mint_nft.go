@@ -47,7 +47,7 @@ type MintNftSegmentFormat struct {
 	Nonce               int64  `json:"nonce"`
 }
 
-func ConstructMintNftTxInfo(sk *PrivateKey, segmentStr string) (txInfo *MintNftTxInfo, err error) {
+func ConstructMintNftTxInfo(sk *PrivateKey, segmentStr string, chainId int64) (txInfo *MintNftTxInfo, err error) {
 	var segmentFormat *MintNftSegmentFormat
 	err = json.Unmarshal([]byte(segmentStr), &segmentFormat)
 	if err != nil {
@@ -71,6 +71,7 @@ func ConstructMintNftTxInfo(sk *PrivateKey, segmentStr string) (txInfo *MintNftT
 		GasFeeAssetId:       segmentFormat.GasFeeAssetId,
 		GasFeeAssetAmount:   gasFeeAmount,
 		Nonce:               segmentFormat.Nonce,
+		ChainId:             chainId,
 		ExpiredAt:           segmentFormat.ExpiredAt,
 		Sig:                 nil,
 	}
@@ -106,9 +107,20 @@ type MintNftTxInfo struct {
 	GasFeeAssetAmount   *big.Int
 	ExpiredAt           int64
 	Nonce               int64
+	ChainId             int64
 	Sig                 []byte
 }
 
+func (txInfo *MintNftTxInfo) UnmarshalJSON(data []byte) error {
+	type plain MintNftTxInfo
+	decoded := plain{ChainId: DefaultChainId}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*txInfo = MintNftTxInfo(decoded)
+	return nil
+}
+
 func (txInfo *MintNftTxInfo) Validate() error {
 	// CreatorAccountIndex
 	if txInfo.CreatorAccountIndex < minAccountIndex {
@@ -184,6 +196,14 @@ func (txInfo *MintNftTxInfo) Validate() error {
 		return ErrNonceTooLow
 	}
 
+	// ChainId
+	if txInfo.ChainId < minChainId {
+		return ErrChainIdTooLow
+	}
+	if txInfo.ChainId > maxChainId {
+		return ErrChainIdTooHigh
+	}
+
 	return nil
 }
 
@@ -235,7 +255,7 @@ func (txInfo *MintNftTxInfo) Hash(hFunc hash.Hash) (msgHash []byte, err error) {
 		log.Println("[ComputeTransferMsgHash] unable to packed amount", err.Error())
 		return nil, err
 	}
-	WriteInt64IntoBuf(&buf, ChainId, txInfo.CreatorAccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
+	WriteInt64IntoBuf(&buf, txInfo.ChainId, txInfo.CreatorAccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
 	WriteInt64IntoBuf(&buf, txInfo.GasAccountIndex, txInfo.GasFeeAssetId, packedFee)
 	WriteInt64IntoBuf(&buf, txInfo.ToAccountIndex, txInfo.CreatorTreasuryRate, txInfo.NftCollectionId)
 	WriteBigIntIntoBuf(&buf, ffmath.Mod(new(big.Int).SetBytes(common.FromHex(txInfo.ToAccountNameHash)), curve.Modulus))
@@ -235,6 +235,23 @@ func TestValidateMintNftTxInfo(t *testing.T) {
 				Nonce:               -1,
 			},
 		},
+		// ChainId
+		{
+			fmt.Errorf("ChainId should not be less than %d", minChainId),
+			&MintNftTxInfo{
+				CreatorAccountIndex: 1,
+				ToAccountIndex:      2,
+				ToAccountNameHash:   hex.EncodeToString(bytes.Repeat([]byte{1}, 32)),
+				NftContentHash:      hex.EncodeToString(bytes.Repeat([]byte{1}, 32)),
+				NftCollectionId:     4,
+				CreatorTreasuryRate: 10,
+				GasAccountIndex:     0,
+				GasFeeAssetId:       3,
+				GasFeeAssetAmount:   big.NewInt(100),
+				ExpiredAt:           time.Now().Add(time.Hour).UnixMilli(),
+				Nonce:               1,
+			},
+		},
 		// true
 		{
 			nil,
@@ -250,6 +267,7 @@ func TestValidateMintNftTxInfo(t *testing.T) {
 				GasFeeAssetAmount:   big.NewInt(100),
 				ExpiredAt:           time.Now().Add(time.Hour).UnixMilli(),
 				Nonce:               1,
+				ChainId:             DefaultChainId,
 			},
 		},
 	}
@@ -46,7 +46,7 @@ type OfferSegmentFormat struct {
 	TreasuryRate int64  `json:"treasury_rate"`
 }
 
-func ConstructOfferTxInfo(sk *PrivateKey, segmentStr string) (txInfo *OfferTxInfo, err error) {
+func ConstructOfferTxInfo(sk *PrivateKey, segmentStr string, chainId int64) (txInfo *OfferTxInfo, err error) {
 	var segmentFormat *OfferSegmentFormat
 	err = json.Unmarshal([]byte(segmentStr), &segmentFormat)
 	if err != nil {
@@ -69,6 +69,7 @@ func ConstructOfferTxInfo(sk *PrivateKey, segmentStr string) (txInfo *OfferTxInf
 		ListedAt:     segmentFormat.ListedAt,
 		ExpiredAt:    segmentFormat.ExpiredAt,
 		TreasuryRate: segmentFormat.TreasuryRate,
+		ChainId:      chainId,
 		Sig:          nil,
 	}
 	// compute call data hash
@@ -99,9 +100,20 @@ type OfferTxInfo struct {
 	ListedAt     int64
 	ExpiredAt    int64
 	TreasuryRate int64
+	ChainId      int64
 	Sig          []byte
 }
 
+func (txInfo *OfferTxInfo) UnmarshalJSON(data []byte) error {
+	type plain OfferTxInfo
+	decoded := plain{ChainId: DefaultChainId}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*txInfo = OfferTxInfo(decoded)
+	return nil
+}
+
 func (txInfo *OfferTxInfo) Validate() error {
 	// Type
 	if txInfo.Type != BuyOfferType && txInfo.Type != SellOfferType {
@@ -160,6 +172,14 @@ func (txInfo *OfferTxInfo) Validate() error {
 	if txInfo.TreasuryRate > maxTreasuryRate {
 		return ErrTreasuryRateTooHigh
 	}
+
+	// ChainId
+	if txInfo.ChainId < minChainId {
+		return ErrChainIdTooLow
+	}
+	if txInfo.ChainId > maxChainId {
+		return ErrChainIdTooHigh
+	}
 	return nil
 }
 
@@ -211,9 +231,9 @@ func (txInfo *OfferTxInfo) Hash(hFunc hash.Hash) (msgHash []byte, err error) {
 		log.Println("[ComputeTransferMsgHash] unable to packed amount:", err.Error())
 		return nil, err
 	}
-	WriteInt64IntoBuf(&buf, txInfo.Type, txInfo.OfferId, txInfo.AccountIndex, txInfo.NftIndex)
-	WriteInt64IntoBuf(&buf, txInfo.AssetId, packedAmount, txInfo.ListedAt, txInfo.ExpiredAt)
-	WriteInt64IntoBuf(&buf, txInfo.TreasuryRate)
+	WriteInt64IntoBuf(&buf, txInfo.ChainId, txInfo.Type, txInfo.OfferId, txInfo.AccountIndex)
+	WriteInt64IntoBuf(&buf, txInfo.NftIndex, txInfo.AssetId, packedAmount, txInfo.ListedAt)
+	WriteInt64IntoBuf(&buf, txInfo.ExpiredAt, txInfo.TreasuryRate)
 	hFunc.Write(buf.Bytes())
 	msgHash = hFunc.Sum(nil)
 	return msgHash, nil
@@ -18,12 +18,16 @@
 package txtypes
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
 )
 
 func TestValidateOfferTxInfo(t *testing.T) {
@@ -178,6 +182,37 @@ func TestValidateOfferTxInfo(t *testing.T) {
 				TreasuryRate: maxTreasuryRate + 1,
 			},
 		},
+		// ChainId
+		{
+			fmt.Errorf("ChainId should not be less than %d", minChainId),
+			&OfferTxInfo{
+				Type:         1,
+				OfferId:      1,
+				AccountIndex: 3,
+				NftIndex:     4,
+				AssetId:      10,
+				AssetAmount:  big.NewInt(20),
+				ListedAt:     time.Now().Unix(),
+				ExpiredAt:    time.Now().Add(time.Hour).UnixMilli(),
+				TreasuryRate: 10,
+			},
+		},
+		// true
+		{
+			nil,
+			&OfferTxInfo{
+				Type:         1,
+				OfferId:      1,
+				AccountIndex: 3,
+				NftIndex:     4,
+				AssetId:      10,
+				AssetAmount:  big.NewInt(20),
+				ListedAt:     time.Now().Unix(),
+				ExpiredAt:    time.Now().Add(time.Hour).UnixMilli(),
+				TreasuryRate: 10,
+				ChainId:      DefaultChainId,
+			},
+		},
 	}
 
 	for index, testCase := range testCases {
@@ -185,3 +220,63 @@ func TestValidateOfferTxInfo(t *testing.T) {
 		require.Equalf(t, err, testCase.err, fmt.Sprintf("case %d: err should be the same", index))
 	}
 }
+
+func TestOfferTxInfoChainId(t *testing.T) {
+	sk, err := curve.GenerateEddsaPrivateKey("alice.legend")
+	require.NoError(t, err)
+	pubKey := hex.EncodeToString(sk.PublicKey.Bytes())
+	segment := fmt.Sprintf(`{"type":1,"offer_id":1,"account_index":3,"nft_index":4,"asset_id":0,`+
+		`"asset_amount":"10000","listed_at":%d,"expired_at":%d,"treasury_rate":200}`,
+		time.Now().UnixMilli(), time.Now().Add(time.Hour).UnixMilli())
+
+	txInfo, err := ConstructOfferTxInfo(sk, segment, DefaultChainId)
+	require.NoError(t, err)
+	require.Equal(t, int64(DefaultChainId), txInfo.ChainId)
+	require.NoError(t, txInfo.Validate())
+	require.NoError(t, txInfo.VerifySignature(pubKey))
+
+	// an offer signed for chain 1 is not valid on chain 2
+	txInfo.ChainId = 2
+	require.Error(t, txInfo.VerifySignature(pubKey))
+
+	otherTxInfo, err := ConstructOfferTxInfo(sk, segment, 2)
+	require.NoError(t, err)
+	require.NoError(t, otherTxInfo.VerifySignature(pubKey))
+	require.NotEqual(t, txInfo.Sig, otherTxInfo.Sig)
+}
+
+func TestOfferTxInfoWithoutChainId(t *testing.T) {
+	sk, err := curve.GenerateEddsaPrivateKey("alice.legend")
+	require.NoError(t, err)
+	pubKey := hex.EncodeToString(sk.PublicKey.Bytes())
+	segment := fmt.Sprintf(`{"type":1,"offer_id":1,"account_index":3,"nft_index":4,"asset_id":0,`+
+		`"asset_amount":"10000","listed_at":%d,"expired_at":%d,"treasury_rate":200}`,
+		time.Now().UnixMilli(), time.Now().Add(time.Hour).UnixMilli())
+	txInfo, err := ConstructOfferTxInfo(sk, segment, DefaultChainId)
+	require.NoError(t, err)
+
+	// a payload serialized before the chain id was signed has no ChainId
+	var fields map[string]interface{}
+	raw, err := json.Marshal(txInfo)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(raw, &fields))
+	delete(fields, "ChainId")
+	raw, err = json.Marshal(fields)
+	require.NoError(t, err)
+
+	var oldTxInfo OfferTxInfo
+	require.NoError(t, json.Unmarshal(raw, &oldTxInfo))
+	require.Equal(t, int64(DefaultChainId), oldTxInfo.ChainId)
+	require.NoError(t, oldTxInfo.Validate())
+	require.NoError(t, oldTxInfo.VerifySignature(pubKey))
+
+	// an explicit chain id is kept
+	otherTxInfo, err := ConstructOfferTxInfo(sk, segment, 2)
+	require.NoError(t, err)
+	raw, err = json.Marshal(otherTxInfo)
+	require.NoError(t, err)
+	var decoded OfferTxInfo
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	require.Equal(t, int64(2), decoded.ChainId)
+	require.NoError(t, decoded.VerifySignature(pubKey))
+}
@@ -48,7 +48,7 @@ type TransferSegmentFormat struct {
 	Nonce             int64  `json:"nonce"`
 }
 
-func ConstructTransferTxInfo(sk *PrivateKey, segmentStr string) (txInfo *TransferTxInfo, err error) {
+func ConstructTransferTxInfo(sk *PrivateKey, segmentStr string, chainId int64) (txInfo *TransferTxInfo, err error) {
 	var segmentFormat *TransferSegmentFormat
 	err = json.Unmarshal([]byte(segmentStr), &segmentFormat)
 	if err != nil {
@@ -80,6 +80,7 @@ func ConstructTransferTxInfo(sk *PrivateKey, segmentStr string) (txInfo *Transfe
 		CallData:          segmentFormat.CallData,
 		ExpiredAt:         segmentFormat.ExpiredAt,
 		Nonce:             segmentFormat.Nonce,
+		ChainId:           chainId,
 		Sig:               nil,
 	}
 	// compute call data hash
@@ -119,9 +120,20 @@ type TransferTxInfo struct {
 	CallDataHash      []byte
 	ExpiredAt         int64
 	Nonce             int64
+	ChainId           int64
 	Sig               []byte
 }
 
+func (txInfo *TransferTxInfo) UnmarshalJSON(data []byte) error {
+	type plain TransferTxInfo
+	decoded := plain{ChainId: DefaultChainId}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*txInfo = TransferTxInfo(decoded)
+	return nil
+}
+
 func (txInfo *TransferTxInfo) Validate() error {
 	if txInfo.FromAccountIndex < minAccountIndex {
 		return ErrFromAccountIndexTooLow
@@ -192,6 +204,14 @@ func (txInfo *TransferTxInfo) Validate() error {
 		return ErrCallDataHashInvalid
 	}
 
+	// ChainId
+	if txInfo.ChainId < minChainId {
+		return ErrChainIdTooLow
+	}
+	if txInfo.ChainId > maxChainId {
+		return ErrChainIdTooHigh
+	}
+
 	return nil
 }
 
@@ -248,7 +268,7 @@ func (txInfo *TransferTxInfo) Hash(hFunc hash.Hash) (msgHash []byte, err error)
 		log.Println("[ComputeTransferMsgHash] unable to packed amount", err.Error())
 		return nil, err
 	}
-	WriteInt64IntoBuf(&buf, ChainId, txInfo.FromAccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
+	WriteInt64IntoBuf(&buf, txInfo.ChainId, txInfo.FromAccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
 	WriteInt64IntoBuf(&buf, txInfo.GasAccountIndex, txInfo.GasFeeAssetId, packedFee)
 	WriteInt64IntoBuf(&buf, txInfo.ToAccountIndex, txInfo.AssetId, packedAmount)
 	buf.Write(ffmath.Mod(new(big.Int).SetBytes(common.FromHex(txInfo.ToAccountNameHash)), curve.Modulus).FillBytes(make([]byte, 32)))
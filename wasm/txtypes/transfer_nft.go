@@ -46,7 +46,7 @@ type TransferNftSegmentFormat struct {
 	Nonce             int64  `json:"nonce"`
 }
 
-func ConstructTransferNftTxInfo(sk *PrivateKey, segmentStr string) (txInfo *TransferNftTxInfo, err error) {
+func ConstructTransferNftTxInfo(sk *PrivateKey, segmentStr string, chainId int64) (txInfo *TransferNftTxInfo, err error) {
 	var segmentFormat *TransferNftSegmentFormat
 	err = json.Unmarshal([]byte(segmentStr), &segmentFormat)
 	if err != nil {
@@ -69,6 +69,7 @@ func ConstructTransferNftTxInfo(sk *PrivateKey, segmentStr string) (txInfo *Tran
 		GasFeeAssetAmount: gasFeeAmount,
 		ExpiredAt:         segmentFormat.ExpiredAt,
 		Nonce:             segmentFormat.Nonce,
+		ChainId:           chainId,
 		Sig:               nil,
 	}
 	// compute msg hash
@@ -105,9 +106,20 @@ type TransferNftTxInfo struct {
 	CallDataHash      []byte
 	ExpiredAt         int64
 	Nonce             int64
+	ChainId           int64
 	Sig               []byte
 }
 
+func (txInfo *TransferNftTxInfo) UnmarshalJSON(data []byte) error {
+	type plain TransferNftTxInfo
+	decoded := plain{ChainId: DefaultChainId}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*txInfo = TransferNftTxInfo(decoded)
+	return nil
+}
+
 func (txInfo *TransferNftTxInfo) Validate() error {
 	// FromAccountIndex
 	if txInfo.FromAccountIndex < minAccountIndex {
@@ -175,6 +187,14 @@ func (txInfo *TransferNftTxInfo) Validate() error {
 		return ErrNonceTooLow
 	}
 
+	// ChainId
+	if txInfo.ChainId < minChainId {
+		return ErrChainIdTooLow
+	}
+	if txInfo.ChainId > maxChainId {
+		return ErrChainIdTooHigh
+	}
+
 	return nil
 }
 
@@ -226,7 +246,7 @@ func (txInfo *TransferNftTxInfo) Hash(hFunc hash.Hash) (msgHash []byte, err erro
 		log.Println("[ComputeTransferMsgHash] unable to packed amount", err.Error())
 		return nil, err
 	}
-	WriteInt64IntoBuf(&buf, ChainId, txInfo.FromAccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
+	WriteInt64IntoBuf(&buf, txInfo.ChainId, txInfo.FromAccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
 	WriteInt64IntoBuf(&buf, txInfo.GasAccountIndex, txInfo.GasFeeAssetId, packedFee)
 	WriteInt64IntoBuf(&buf, txInfo.ToAccountIndex, txInfo.NftIndex)
 	buf.Write(ffmath.Mod(new(big.Int).SetBytes(common.FromHex(txInfo.ToAccountNameHash)), curve.Modulus).FillBytes(make([]byte, 32)))
@@ -26,6 +26,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
 )
 
 func TestValidateTransferTxInfo(t *testing.T) {
@@ -263,6 +265,23 @@ func TestValidateTransferTxInfo(t *testing.T) {
 				CallDataHash:      bytes.Repeat([]byte{1}, 31),
 			},
 		},
+		// ChainId
+		{
+			fmt.Errorf("ChainId should not be less than %d", minChainId),
+			&TransferTxInfo{
+				FromAccountIndex:  1,
+				ToAccountIndex:    1,
+				AssetId:           1,
+				AssetAmount:       big.NewInt(1),
+				GasAccountIndex:   0,
+				GasFeeAssetId:     3,
+				GasFeeAssetAmount: big.NewInt(100),
+				ExpiredAt:         time.Now().Add(time.Hour).UnixMilli(),
+				Nonce:             1,
+				ToAccountNameHash: hex.EncodeToString(bytes.Repeat([]byte{1}, 32)),
+				CallDataHash:      bytes.Repeat([]byte{1}, 32),
+			},
+		},
 		// true
 		{
 			nil,
@@ -278,6 +297,7 @@ func TestValidateTransferTxInfo(t *testing.T) {
 				Nonce:             1,
 				ToAccountNameHash: hex.EncodeToString(bytes.Repeat([]byte{1}, 32)),
 				CallDataHash:      bytes.Repeat([]byte{1}, 32),
+				ChainId:           DefaultChainId,
 			},
 		},
 	}
@@ -286,3 +306,31 @@ func TestValidateTransferTxInfo(t *testing.T) {
 		require.Equalf(t, testCase.err, err, "err should be the same")
 	}
 }
+
+func TestTransferTxInfoChainId(t *testing.T) {
+	sk, err := curve.GenerateEddsaPrivateKey("alice.legend")
+	require.NoError(t, err)
+	pubKey := hex.EncodeToString(sk.PublicKey.Bytes())
+	segment := fmt.Sprintf(`{"from_account_index":1,"to_account_index":2,"to_account_name":"%s",`+
+		`"asset_id":0,"asset_amount":"100000","gas_account_index":0,"gas_fee_asset_id":0,`+
+		`"gas_fee_asset_amount":"100","memo":"","call_data":"","expired_at":%d,"nonce":1}`,
+		hex.EncodeToString(bytes.Repeat([]byte{1}, 32)), time.Now().Add(time.Hour).UnixMilli())
+
+	txInfo, err := ConstructTransferTxInfo(sk, segment, DefaultChainId)
+	require.NoError(t, err)
+	require.Equal(t, int64(DefaultChainId), txInfo.ChainId)
+	require.NoError(t, txInfo.Validate())
+	require.NoError(t, txInfo.VerifySignature(pubKey))
+
+	// a signature for chain 1 is not valid on chain 2
+	txInfo.ChainId = 2
+	require.Error(t, txInfo.VerifySignature(pubKey))
+
+	otherTxInfo, err := ConstructTransferTxInfo(sk, segment, 2)
+	require.NoError(t, err)
+	require.NoError(t, otherTxInfo.VerifySignature(pubKey))
+	require.NotEqual(t, txInfo.Sig, otherTxInfo.Sig)
+	otherTxInfo.Sig = txInfo.Sig
+	otherTxInfo.ChainId = DefaultChainId
+	require.NoError(t, otherTxInfo.VerifySignature(pubKey))
+}
@@ -41,7 +41,7 @@ type WithdrawSegmentFormat struct {
 	Nonce             int64  `json:"nonce"`
 }
 
-func ConstructWithdrawTxInfo(sk *PrivateKey, segmentStr string) (txInfo *WithdrawTxInfo, err error) {
+func ConstructWithdrawTxInfo(sk *PrivateKey, segmentStr string, chainId int64) (txInfo *WithdrawTxInfo, err error) {
 	var segmentFormat *WithdrawSegmentFormat
 	err = json.Unmarshal([]byte(segmentStr), &segmentFormat)
 	if err != nil {
@@ -70,6 +70,7 @@ func ConstructWithdrawTxInfo(sk *PrivateKey, segmentStr string) (txInfo *Withdra
 		ToAddress:         segmentFormat.ToAddress,
 		ExpiredAt:         segmentFormat.ExpiredAt,
 		Nonce:             segmentFormat.Nonce,
+		ChainId:           chainId,
 		Sig:               nil,
 	}
 	// compute call data hash
@@ -101,9 +102,20 @@ type WithdrawTxInfo struct {
 	ToAddress         string
 	ExpiredAt         int64
 	Nonce             int64
+	ChainId           int64
 	Sig               []byte
 }
 
+func (txInfo *WithdrawTxInfo) UnmarshalJSON(data []byte) error {
+	type plain WithdrawTxInfo
+	decoded := plain{ChainId: DefaultChainId}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*txInfo = WithdrawTxInfo(decoded)
+	return nil
+}
+
 func (txInfo *WithdrawTxInfo) Validate() error {
 	if txInfo.FromAccountIndex < minAccountIndex {
 		return ErrFromAccountIndexTooLow
@@ -162,6 +174,14 @@ func (txInfo *WithdrawTxInfo) Validate() error {
 		return ErrToAddressInvalid
 	}
 
+	// ChainId
+	if txInfo.ChainId < minChainId {
+		return ErrChainIdTooLow
+	}
+	if txInfo.ChainId > maxChainId {
+		return ErrChainIdTooHigh
+	}
+
 	return nil
 }
 
@@ -213,7 +233,7 @@ func (txInfo *WithdrawTxInfo) Hash(hFunc hash.Hash) (msgHash []byte, err error)
 		log.Println("[ComputeTransferMsgHash] unable to packed amount: ", err.Error())
 		return nil, err
 	}
-	WriteInt64IntoBuf(&buf, ChainId, txInfo.FromAccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
+	WriteInt64IntoBuf(&buf, txInfo.ChainId, txInfo.FromAccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
 	WriteInt64IntoBuf(&buf, txInfo.GasAccountIndex, txInfo.GasFeeAssetId, packedFee)
 	WriteInt64IntoBuf(&buf, txInfo.AssetId)
 	WriteBigIntIntoBuf(&buf, txInfo.AssetAmount)
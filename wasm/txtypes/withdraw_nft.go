@@ -40,7 +40,7 @@ type WithdrawNftSegmentFormat struct {
 	Nonce             int64  `json:"nonce"`
 }
 
-func ConstructWithdrawNftTxInfo(sk *PrivateKey, segmentStr string) (txInfo *WithdrawNftTxInfo, err error) {
+func ConstructWithdrawNftTxInfo(sk *PrivateKey, segmentStr string, chainId int64) (txInfo *WithdrawNftTxInfo, err error) {
 	var segmentFormat *WithdrawNftSegmentFormat
 	err = json.Unmarshal([]byte(segmentStr), &segmentFormat)
 	if err != nil {
@@ -62,6 +62,7 @@ func ConstructWithdrawNftTxInfo(sk *PrivateKey, segmentStr string) (txInfo *With
 		GasFeeAssetAmount: gasFeeAmount,
 		ExpiredAt:         segmentFormat.ExpiredAt,
 		Nonce:             segmentFormat.Nonce,
+		ChainId:           chainId,
 		Sig:               nil,
 	}
 	// compute call data hash
@@ -99,9 +100,20 @@ type WithdrawNftTxInfo struct {
 	GasFeeAssetAmount      *big.Int
 	ExpiredAt              int64
 	Nonce                  int64
+	ChainId                int64
 	Sig                    []byte
 }
 
+func (txInfo *WithdrawNftTxInfo) UnmarshalJSON(data []byte) error {
+	type plain WithdrawNftTxInfo
+	decoded := plain{ChainId: DefaultChainId}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*txInfo = WithdrawNftTxInfo(decoded)
+	return nil
+}
+
 func (txInfo *WithdrawNftTxInfo) Validate() error {
 	// AccountIndex
 	if txInfo.AccountIndex < minAccountIndex {
@@ -156,6 +168,14 @@ func (txInfo *WithdrawNftTxInfo) Validate() error {
 		return ErrNonceTooLow
 	}
 
+	// ChainId
+	if txInfo.ChainId < minChainId {
+		return ErrChainIdTooLow
+	}
+	if txInfo.ChainId > maxChainId {
+		return ErrChainIdTooHigh
+	}
+
 	return nil
 }
 
@@ -207,7 +227,7 @@ func (txInfo *WithdrawNftTxInfo) Hash(hFunc hash.Hash) (msgHash []byte, err erro
 		log.Println("[ComputeTransferMsgHash] unable to packed amount", err.Error())
 		return nil, err
 	}
-	WriteInt64IntoBuf(&buf, ChainId, txInfo.AccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
+	WriteInt64IntoBuf(&buf, txInfo.ChainId, txInfo.AccountIndex, txInfo.Nonce, txInfo.ExpiredAt)
 	WriteInt64IntoBuf(&buf, txInfo.GasAccountIndex, txInfo.GasFeeAssetId, packedFee)
 	WriteInt64IntoBuf(&buf, txInfo.NftIndex)
 	buf.Write(PaddingAddressToBytes32(txInfo.ToAddress))
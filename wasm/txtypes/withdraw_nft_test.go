@@ -197,6 +197,26 @@ func TestValidateWithdrawNftTxInfo(t *testing.T) {
 				Nonce:                  -1,
 			},
 		},
+		// ChainId
+		{
+			fmt.Errorf("ChainId should not be less than %d", minChainId),
+			&WithdrawNftTxInfo{
+				AccountIndex:           1,
+				CreatorAccountIndex:    1,
+				CreatorAccountNameHash: bytes.Repeat([]byte{1}, 32),
+				NftIndex:               5,
+				NftContentHash:         bytes.Repeat([]byte{1}, 32),
+				NftL1Address:           "0x299d17c8b4e9967385dc9a3bb78f2a43f5a13bd9",
+				NftL1TokenId:           big.NewInt(11),
+				CollectionId:           11,
+				ToAddress:              "0x299d17c8b4e9967385dc9a3bb78f2a43f5a13bd0",
+				GasAccountIndex:        0,
+				GasFeeAssetId:          3,
+				GasFeeAssetAmount:      big.NewInt(100),
+				ExpiredAt:              time.Now().Add(time.Hour).UnixMilli(),
+				Nonce:                  1,
+			},
+		},
 		// true
 		{
 			nil,
@@ -215,6 +235,7 @@ func TestValidateWithdrawNftTxInfo(t *testing.T) {
 				GasFeeAssetAmount:      big.NewInt(100),
 				ExpiredAt:              time.Now().Add(time.Hour).UnixMilli(),
 				Nonce:                  1,
+				ChainId:                DefaultChainId,
 			},
 		},
 	}
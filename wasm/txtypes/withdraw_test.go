@@ -186,6 +186,21 @@ func TestValidateWithdrawTxInfo(t *testing.T) {
 				Nonce:             1,
 			},
 		},
+		// ChainId
+		{
+			fmt.Errorf("ChainId should not be less than %d", minChainId),
+			&WithdrawTxInfo{
+				FromAccountIndex:  1,
+				AssetId:           1,
+				AssetAmount:       big.NewInt(1),
+				GasAccountIndex:   0,
+				GasFeeAssetId:     3,
+				ToAddress:         "0x299d17c8b4e9967385dc9a3bb78f2a43f5a13bd0",
+				GasFeeAssetAmount: big.NewInt(100),
+				ExpiredAt:         time.Now().Add(time.Hour).UnixMilli(),
+				Nonce:             1,
+			},
+		},
 		// true
 		{
 			nil,
@@ -199,6 +214,7 @@ func TestValidateWithdrawTxInfo(t *testing.T) {
 				GasFeeAssetAmount: big.NewInt(100),
 				ExpiredAt:         time.Now().Add(time.Hour).UnixMilli(),
 				Nonce:             1,
+				ChainId:           DefaultChainId,
 			},
 		},
 	}